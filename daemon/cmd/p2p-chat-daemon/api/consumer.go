@@ -31,6 +31,24 @@ func (c *Consumer) Start() {
 	c.bus.Subscribe(c.eventsChan, events.GroupChatMessageReceivedEvent{})
 	c.bus.Subscribe(c.eventsChan, events.MessageSentEvent{})
 	c.bus.Subscribe(c.eventsChan, events.GroupChatMessageSentEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupMessageDeliveredEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupInvitationReceivedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupInvitationResponseReceivedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupEpochChangedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupMetadataUpdatedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupJoinRequestReceivedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupFileUpdatedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.ChannelPostAddedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.ChannelReactionAddedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.ChannelUpdatedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendRemovedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendRequestCancelledEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendIntroductionReceivedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.HandleConflictEvent{})
+	c.bus.Subscribe(c.eventsChan, events.NearbyPeerUpdatedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.NearbyPeerLostEvent{})
+	c.bus.Subscribe(c.eventsChan, events.ProfileCardUpdatedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendPresenceChangedEvent{})
 
 	go c.listen()
 }
@@ -66,6 +84,118 @@ func (c *Consumer) handleEvent(event interface{}) {
 	case events.GroupChatMessageReceivedEvent:
 		c.HandleGroupMessageReceived(ev.Message)
 		return
+
+	case events.GroupMessageDeliveredEvent:
+		c.sendWsEvent(WsMsgTypeGroupMessageDelivery, ev.Report)
+		return
+
+	case events.GroupInvitationReceivedEvent:
+		c.sendWsEvent(WsMsgTypeGroupInvitation, WsGroupInvitationPayload{
+			GroupId:       ev.Invitation.GroupId,
+			InviterPeerId: ev.Invitation.InviterPeerId,
+			Name:          ev.Invitation.Name,
+			MemberPeers:   ev.Invitation.MemberPeers,
+		})
+		return
+
+	case events.GroupInvitationResponseReceivedEvent:
+		c.sendWsEvent(WsMsgTypeGroupInvitationResponse, WsGroupInvitationResponsePayload{
+			GroupId:         ev.GroupId,
+			ResponderPeerId: ev.ResponderPeerId,
+			IsAccepted:      ev.IsAccepted,
+		})
+		return
+
+	case events.GroupEpochChangedEvent:
+		c.sendWsEvent(WsMsgTypeGroupEpochChanged, WsGroupEpochChangedPayload{
+			GroupId:     ev.GroupId,
+			Epoch:       ev.Epoch,
+			Added:       ev.Added,
+			Removed:     ev.Removed,
+			RemovedSelf: ev.RemovedSelf,
+		})
+		return
+
+	case events.GroupMetadataUpdatedEvent:
+		c.sendWsEvent(WsMsgTypeGroupMetadataUpdated, WsGroupMetadataUpdatedPayload{
+			GroupId:     ev.Metadata.GroupId,
+			Name:        ev.Metadata.Name,
+			Description: ev.Metadata.Description,
+			AvatarHash:  ev.Metadata.AvatarHash,
+			Version:     ev.Metadata.Version,
+			UpdatedBy:   ev.Metadata.UpdatedBy,
+		})
+		return
+
+	case events.GroupJoinRequestReceivedEvent:
+		c.sendWsEvent(WsMsgTypeGroupJoinRequest, ev.Request)
+		return
+
+	case events.GroupFileUpdatedEvent:
+		c.sendWsEvent(WsMsgTypeGroupFileUpdated, ev.File)
+		return
+
+	case events.ChannelPostAddedEvent:
+		c.sendWsEvent(WsMsgTypeChannelPost, ev.Post)
+		return
+
+	case events.ChannelReactionAddedEvent:
+		c.sendWsEvent(WsMsgTypeChannelReaction, WsChannelReactionPayload{
+			ChannelId:     ev.Reaction.ChannelId,
+			PostId:        ev.Reaction.PostId,
+			ReactorPeerId: ev.Reaction.ReactorPeerId,
+			Reaction:      ev.Reaction.Reaction,
+		})
+		return
+
+	case events.ChannelUpdatedEvent:
+		c.sendWsEvent(WsMsgTypeChannelUpdated, ev.Channel)
+		return
+
+	case events.FriendRemovedEvent:
+		c.sendWsEvent(WsMsgTypeFriendRemoved, WsFriendRemovedPayload{
+			PeerId:    ev.PeerId,
+			Initiated: ev.Initiated,
+		})
+		return
+
+	case events.FriendIntroductionReceivedEvent:
+		c.sendWsEvent(WsMsgTypeFriendIntroduction, ev.Introduction)
+		return
+
+	case events.NearbyPeerUpdatedEvent:
+		c.sendWsEvent(WsMsgTypeNearbyPeer, ev.Peer)
+		return
+
+	case events.ProfileCardUpdatedEvent:
+		c.sendWsEvent(WsMsgTypeProfileCard, ev.Card)
+		return
+
+	case events.FriendPresenceChangedEvent:
+		payload := WsFriendPresencePayload{PeerId: ev.PeerId, Status: ev.Presence.Status, Message: ev.Presence.Message}
+		if !ev.LastSeen.IsZero() {
+			payload.LastSeen = &ev.LastSeen
+		}
+		c.sendWsEvent(WsMsgTypeFriendPresence, payload)
+		return
+
+	case events.NearbyPeerLostEvent:
+		c.sendWsEvent(WsMsgTypeNearbyPeerLost, WsNearbyPeerLostPayload{PeerId: ev.PeerId})
+		return
+
+	case events.HandleConflictEvent:
+		c.sendWsEvent(WsMsgTypeHandleConflict, WsHandleConflictPayload{
+			Handle:      ev.Handle,
+			OwnerPeerId: ev.OwnerPeerId,
+		})
+		return
+
+	case events.FriendRequestCancelledEvent:
+		c.sendWsEvent(WsMsgTypeFriendRequestCancelled, WsFriendRequestCancelledPayload{
+			PeerId:    ev.PeerId,
+			Initiated: ev.Initiated,
+		})
+		return
 	}
 }
 
@@ -118,3 +248,20 @@ func (c *Consumer) HandleGroupMessageReceived(message events.GroupChatMessage) {
 
 	c.apiHandler.send(wsMsgBytes)
 }
+
+// sendWsEvent wraps a payload in a WsMessage of the given type and pushes it to the UI.
+func (c *Consumer) sendWsEvent(msgType WsMessageType, payload interface{}) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal %s payload: %v", msgType, err)
+		return
+	}
+
+	wsMsgBytes, err := json.Marshal(WsMessage{Type: msgType, Payload: payloadBytes})
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal %s message: %v", msgType, err)
+		return
+	}
+
+	c.apiHandler.send(wsMsgBytes)
+}
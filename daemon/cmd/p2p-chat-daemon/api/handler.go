@@ -2,10 +2,16 @@ package api
 
 import (
 	"github.com/gorilla/websocket"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/blocklist"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/card"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/channel"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/chat"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/connection"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/handle"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/nearby"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/presence"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
 	"sync"
@@ -16,9 +22,15 @@ type ApiHandler struct {
 	appState          *core.AppState
 	eventBus          *bus.EventBus
 	chatService       *chat.Service
+	channelService    *channel.Service
 	profileService    *profile.Service
 	connectionService *connection.Service
 	displayNameRepo   storage.DisplayNameRepository
+	blocklistService  *blocklist.Service
+	handleService     *handle.Service
+	nearbyService     *nearby.Service
+	cardService       *card.Service
+	presenceService   *presence.Service
 	wsConn            *websocket.Conn
 	wsMu              sync.RWMutex
 }
@@ -28,9 +40,15 @@ func newAPIHandler(
 	appState *core.AppState,
 	eventBus *bus.EventBus,
 	chatService *chat.Service,
+	channelService *channel.Service,
 	profileService *profile.Service,
 	connectionService *connection.Service,
 	displayNameRepo storage.DisplayNameRepository,
+	blocklistService *blocklist.Service,
+	handleService *handle.Service,
+	nearbyService *nearby.Service,
+	cardService *card.Service,
+	presenceService *presence.Service,
 ) *ApiHandler {
 	if appState == nil {
 		panic("appState cannot be nil for apiHandler")
@@ -40,8 +58,14 @@ func newAPIHandler(
 		appState:          appState,
 		eventBus:          eventBus,
 		chatService:       chatService,
+		channelService:    channelService,
 		profileService:    profileService,
 		connectionService: connectionService,
 		displayNameRepo:   displayNameRepo,
+		blocklistService:  blocklistService,
+		handleService:     handleService,
+		nearbyService:     nearbyService,
+		cardService:       cardService,
+		presenceService:   presenceService,
 	}
 }
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/blocklist"
+)
+
+// handleGetBlockedPeers handles GET requests to /blocks
+func (h *ApiHandler) handleGetBlockedPeers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	blocked, err := h.blocklistService.GetBlocked()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting blocked peers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, blocked, "blocked peers")
+}
+
+// handleBlockPeer handles POST requests to /blocks/add
+func (h *ApiHandler) handleBlockPeer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BlockPeerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.PeerId == "" {
+		http.Error(w, "Missing 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.blocklistService.Block(req.PeerId, req.Reason); err != nil {
+		log.Printf("API Handler: Error blocking %s: %v", req.PeerId, err)
+		writeBlockError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Peer blocked successfully")
+}
+
+// handleUnblockPeer handles POST requests to /blocks/remove
+func (h *ApiHandler) handleUnblockPeer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UnblockPeerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.PeerId == "" {
+		http.Error(w, "Missing 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.blocklistService.Unblock(req.PeerId); err != nil {
+		log.Printf("API Handler: Error unblocking %s: %v", req.PeerId, err)
+		writeBlockError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Peer unblocked successfully")
+}
+
+func writeBlockError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, blocklist.ErrInvalidBlock):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Peer is not blocked", http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("Error updating block list: %v", err), http.StatusInternalServerError)
+	}
+}
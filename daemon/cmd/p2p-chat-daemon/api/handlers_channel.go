@@ -0,0 +1,235 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/channel"
+)
+
+// handleCreateChannel handles POST requests to /channel
+func (h *ApiHandler) handleCreateChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.channelService.CreateChannel(req.Name, req.Description)
+	if err != nil {
+		log.Printf("API Handler: Error creating channel: %v", err)
+		writeChannelError(w, "Error creating channel", err)
+		return
+	}
+
+	writeJSON(w, created, "channel")
+}
+
+// handleGetChannels handles GET requests to /channels
+func (h *ApiHandler) handleGetChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channels, err := h.channelService.GetChannels()
+	if err != nil {
+		log.Printf("API Handler: Error getting channels: %v", err)
+		http.Error(w, fmt.Sprintf("Error getting channels: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, channels, "channels")
+}
+
+// handleSubscribeChannel handles POST requests to /channel/subscribe
+func (h *ApiHandler) handleSubscribeChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SubscribeChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.ChannelId == "" || req.OwnerPeerId == "" {
+		http.Error(w, "Missing 'channel_id' or 'owner_peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	subscribed, err := h.channelService.Subscribe(req.ChannelId, req.OwnerPeerId, req.ViaPeerId)
+	if err != nil {
+		log.Printf("API Handler: Error subscribing to channel %s: %v", req.ChannelId, err)
+		writeChannelError(w, "Error subscribing to channel", err)
+		return
+	}
+
+	writeJSON(w, subscribed, "channel")
+}
+
+// handleUnsubscribeChannel handles POST requests to /channel/unsubscribe
+func (h *ApiHandler) handleUnsubscribeChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.ChannelId == "" {
+		http.Error(w, "Missing 'channel_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.channelService.Unsubscribe(req.ChannelId); err != nil {
+		log.Printf("API Handler: Error unsubscribing from channel %s: %v", req.ChannelId, err)
+		writeChannelError(w, "Error unsubscribing from channel", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Unsubscribed from channel successfully")
+}
+
+// handleUpdateChannelAuthors handles POST requests to /channel/authors
+func (h *ApiHandler) handleUpdateChannelAuthors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UpdateChannelAuthorsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.ChannelId == "" {
+		http.Error(w, "Missing 'channel_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.channelService.UpdateChannelAuthors(req.ChannelId, req.Authors)
+	if err != nil {
+		log.Printf("API Handler: Error updating authors of channel %s: %v", req.ChannelId, err)
+		writeChannelError(w, "Error updating channel authors", err)
+		return
+	}
+
+	writeJSON(w, updated, "channel")
+}
+
+// handleChannelPost handles POST requests to /channel/post
+func (h *ApiHandler) handleChannelPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ChannelPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.ChannelId == "" || req.Message == "" {
+		http.Error(w, "Missing 'channel_id' or 'message' in request", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.channelService.Post(req.ChannelId, req.Message)
+	if err != nil {
+		log.Printf("API Handler: Error posting to channel %s: %v", req.ChannelId, err)
+		writeChannelError(w, "Error posting to channel", err)
+		return
+	}
+
+	writeJSON(w, post, "post")
+}
+
+// handleGetChannelPosts handles POST requests to /channel/posts
+func (h *ApiHandler) handleGetChannelPosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	posts, err := h.channelService.GetPosts(req.ChannelId)
+	if err != nil {
+		log.Printf("API Handler: Error getting posts of channel %s: %v", req.ChannelId, err)
+		http.Error(w, fmt.Sprintf("Error getting channel posts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, posts, "posts")
+}
+
+// handleChannelReaction handles POST requests to /channel/react
+func (h *ApiHandler) handleChannelReaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ChannelReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.ChannelId == "" || req.PostId == "" {
+		http.Error(w, "Missing 'channel_id' or 'post_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.channelService.React(req.ChannelId, req.PostId, req.Reaction); err != nil {
+		log.Printf("API Handler: Error reacting on channel %s: %v", req.ChannelId, err)
+		writeChannelError(w, "Error reacting to post", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Reaction sent successfully")
+}
+
+// writeChannelError maps channel service errors to HTTP status codes.
+func writeChannelError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, channel.ErrInvalidChannelContent):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, channel.ErrNotChannelOwner), errors.Is(err, channel.ErrNotChannelAuthor):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Channel not found", http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}, name string) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("API Handler: Error encoding %s: %v", name, err)
+	}
+}
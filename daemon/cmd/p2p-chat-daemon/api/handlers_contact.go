@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"strconv"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	contactQRDefaultSize = 256
+	contactQRMaxSize     = 1024
+	contactListMaxSize   = 16 * 1024 * 1024
+)
+
+// handleGetContactURI handles GET requests to /profile/contact
+func (h *ApiHandler) handleGetContactURI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	uri, err := h.profileService.ContactURI(r.URL.Query().Get("name"))
+	if err != nil {
+		log.Printf("API Handler: Error creating contact URI: %v", err)
+		writeContactError(w, err)
+		return
+	}
+
+	writeJSON(w, ContactURIResponse{URI: uri}, "contact URI")
+}
+
+// handleGetContactQR handles GET requests to /profile/contact/qr
+func (h *ApiHandler) handleGetContactQR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	size := contactQRDefaultSize
+	if sizeParam := r.URL.Query().Get("size"); sizeParam != "" {
+		parsed, err := strconv.Atoi(sizeParam)
+		if err != nil || parsed <= 0 || parsed > contactQRMaxSize {
+			http.Error(w, fmt.Sprintf("'size' must be between 1 and %d", contactQRMaxSize), http.StatusBadRequest)
+			return
+		}
+		size = parsed
+	}
+
+	uri, err := h.profileService.ContactURI(r.URL.Query().Get("name"))
+	if err != nil {
+		log.Printf("API Handler: Error creating contact URI: %v", err)
+		writeContactError(w, err)
+		return
+	}
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, size)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error encoding QR code: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	if _, err := w.Write(png); err != nil {
+		log.Printf("API Handler: Error writing contact QR code: %v", err)
+	}
+}
+
+// handleImportContact handles POST requests to /profile/contact/import
+func (h *ApiHandler) handleImportContact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ImportContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.URI == "" {
+		http.Error(w, "Missing 'uri' in request", http.StatusBadRequest)
+		return
+	}
+
+	contact, err := h.profileService.ImportContactURI(req.URI, req.Message)
+	if err != nil {
+		log.Printf("API Handler: Error importing contact: %v", err)
+		writeContactError(w, err)
+		return
+	}
+
+	// The suggested name becomes the friend's display name unless we already picked one.
+	if contact.Name != "" {
+		if _, err := h.displayNameRepo.GetByEntity(r.Context(), contact.PeerId, "friend"); err != nil {
+			if err := h.displayNameRepo.Store(r.Context(), storage.DisplayName{
+				EntityID:    contact.PeerId,
+				EntityType:  "friend",
+				DisplayName: contact.Name,
+			}); err != nil {
+				log.Printf("API Handler: Error storing display name for %s: %v", contact.PeerId, err)
+			}
+		}
+	}
+
+	writeJSON(w, contact, "imported contact")
+}
+
+// handleExportContacts handles GET requests to /profile/contacts/export
+func (h *ApiHandler) handleExportContacts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	export, err := h.profileService.ExportContacts()
+	if err != nil {
+		log.Printf("API Handler: Error exporting contacts: %v", err)
+		http.Error(w, fmt.Sprintf("Error exporting contacts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="contacts.json"`)
+	writeJSON(w, export, "contact list")
+}
+
+// handleImportContacts handles POST requests to /profile/contacts/import
+func (h *ApiHandler) handleImportContacts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, contactListMaxSize)
+
+	var req ImportContactsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.profileService.ImportContacts(req.Export, req.OnConflict)
+	if err != nil {
+		log.Printf("API Handler: Error importing contacts: %v", err)
+		if errors.Is(err, profile.ErrInvalidContactExport) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error importing contacts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, report, "contact import report")
+}
+
+func writeContactError(w http.ResponseWriter, err error) {
+	if errors.Is(err, profile.ErrInvalidContact) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Error handling contact: %v", err), http.StatusInternalServerError)
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
+)
+
+// handleSetContactNote handles POST requests to /profile/friend/note
+func (h *ApiHandler) handleSetContactNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ContactNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.PeerId == "" {
+		http.Error(w, "Missing 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.SetContactNote(req.PeerId, req.Note); err != nil {
+		log.Printf("API Handler: Error setting note on %s: %v", req.PeerId, err)
+		writeContactLabelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Note updated successfully")
+}
+
+// handleAddContactTag handles POST requests to /profile/friend/tag
+func (h *ApiHandler) handleAddContactTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ContactTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.PeerId == "" || req.Tag == "" {
+		http.Error(w, "Missing 'peer_id' or 'tag' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.AddContactTag(req.PeerId, req.Tag); err != nil {
+		log.Printf("API Handler: Error tagging %s: %v", req.PeerId, err)
+		writeContactLabelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Tag added successfully")
+}
+
+// handleRemoveContactTag handles POST requests to /profile/friend/tag/remove
+func (h *ApiHandler) handleRemoveContactTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ContactTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.PeerId == "" || req.Tag == "" {
+		http.Error(w, "Missing 'peer_id' or 'tag' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.RemoveContactTag(req.PeerId, req.Tag); err != nil {
+		log.Printf("API Handler: Error removing tag from %s: %v", req.PeerId, err)
+		writeContactLabelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Tag removed successfully")
+}
+
+// handleGetCircles handles GET requests to /circles
+func (h *ApiHandler) handleGetCircles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	circles, err := h.profileService.GetCircles()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting circles: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, circles, "circles")
+}
+
+// handleCreateCircle handles POST requests to /circles/create
+func (h *ApiHandler) handleCreateCircle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateCircleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	circle, err := h.profileService.CreateCircle(req.Name, req.Members)
+	if err != nil {
+		log.Printf("API Handler: Error creating circle %s: %v", req.Name, err)
+		writeContactLabelError(w, err)
+		return
+	}
+
+	writeJSON(w, circle, "circle")
+}
+
+// handleRenameCircle handles POST requests to /circles/rename
+func (h *ApiHandler) handleRenameCircle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RenameCircleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.CircleId == "" {
+		http.Error(w, "Missing 'circle_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.RenameCircle(req.CircleId, req.Name); err != nil {
+		log.Printf("API Handler: Error renaming circle %s: %v", req.CircleId, err)
+		writeContactLabelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Circle renamed successfully")
+}
+
+// handleDeleteCircle handles POST requests to /circles/delete
+func (h *ApiHandler) handleDeleteCircle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CircleMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.CircleId == "" {
+		http.Error(w, "Missing 'circle_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.DeleteCircle(req.CircleId); err != nil {
+		log.Printf("API Handler: Error deleting circle %s: %v", req.CircleId, err)
+		writeContactLabelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Circle deleted successfully")
+}
+
+// handleAddCircleMember handles POST requests to /circles/members/add
+func (h *ApiHandler) handleAddCircleMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CircleMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.CircleId == "" || req.PeerId == "" {
+		http.Error(w, "Missing 'circle_id' or 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.AddCircleMember(req.CircleId, req.PeerId); err != nil {
+		log.Printf("API Handler: Error adding %s to circle %s: %v", req.PeerId, req.CircleId, err)
+		writeContactLabelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Circle member added successfully")
+}
+
+// handleRemoveCircleMember handles POST requests to /circles/members/remove
+func (h *ApiHandler) handleRemoveCircleMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CircleMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.CircleId == "" || req.PeerId == "" {
+		http.Error(w, "Missing 'circle_id' or 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.RemoveCircleMember(req.CircleId, req.PeerId); err != nil {
+		log.Printf("API Handler: Error removing %s from circle %s: %v", req.PeerId, req.CircleId, err)
+		writeContactLabelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Circle member removed successfully")
+}
+
+func writeContactLabelError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, profile.ErrInvalidLabel):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, profile.ErrNotFriend):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Not found", http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("Error updating contact labels: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -2,9 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/chat"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
+	"time"
 )
 
 // handleCreateGroupChat handles POST requests to /group-chat
@@ -20,9 +25,13 @@ func (h *ApiHandler) handleCreateGroupChat(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err := h.chatService.CreateGroup(req.MemberPeerIds, req.ChatName)
+	err := h.chatService.CreateGroup(req.MemberPeerIds, req.Circles, req.ChatName)
 	if err != nil {
 		log.Printf("API Handler: Error creating group chat: %v", err)
+		if errors.Is(err, profile.ErrInvalidLabel) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Error creating group chat: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -44,15 +53,14 @@ func (h *ApiHandler) handleSendGroupMessage(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err := h.chatService.SendGroupMessage(req.GroupId, req.Message)
+	report, err := h.chatService.SendGroupMessage(req.GroupId, req.Message)
 	if err != nil {
 		log.Printf("API Handler: Error sending group chat message: %v", err)
 		http.Error(w, fmt.Sprintf("Error sending group chat message: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Group chat message sent successfully")
+	writeJSON(w, report, "group delivery report")
 }
 
 func (h *ApiHandler) handleGetGroupMessages(w http.ResponseWriter, r *http.Request) {
@@ -84,6 +92,328 @@ func (h *ApiHandler) handleGetGroupMessages(w http.ResponseWriter, r *http.Reque
 	w.Write(responseBytes)
 }
 
+// handleSyncGroupHistory handles POST requests to /group-chat/sync
+func (h *ApiHandler) handleSyncGroupHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SyncGroupHistoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" {
+		http.Error(w, "Missing 'group_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	received, err := h.chatService.SyncGroupHistory(req.GroupId)
+	if err != nil {
+		log.Printf("API Handler: Error syncing group chat history: %v", err)
+		http.Error(w, fmt.Sprintf("Error syncing group chat history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responseBytes, err := json.Marshal(SyncGroupHistoryResponse{GroupId: req.GroupId, Received: received})
+	if err != nil {
+		log.Printf("API Handler: Error marshalling group sync response to JSON: %v", err)
+		http.Error(w, "Failed to prepare group sync response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseBytes)
+}
+
+func (h *ApiHandler) handleGetGroupInvitations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	invitations, err := h.chatService.GetGroupInvitations()
+	if err != nil {
+		log.Printf("API Handler: Error getting group invitations: %v", err)
+		http.Error(w, fmt.Sprintf("Error getting group invitations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responseBytes, err := json.Marshal(invitations)
+	if err != nil {
+		log.Printf("API Handler: Error marshalling group invitations to JSON: %v", err)
+		http.Error(w, "Failed to prepare group invitations response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseBytes)
+}
+
+// handleGroupInvitationResponse handles PATCH requests to /group-chat/invitation/response
+func (h *ApiHandler) handleGroupInvitationResponse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GroupInvitationResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" {
+		http.Error(w, "Missing 'group_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.chatService.RespondToGroupInvitation(req.GroupId, req.IsAccepted)
+	if err != nil {
+		if errors.Is(err, chat.ErrInvitationNotPending) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("API Handler: Error responding to group invitation: %v", err)
+		http.Error(w, fmt.Sprintf("Error responding to group invitation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Responded to group invitation successfully")
+}
+
+// handleRemoveGroupMember handles POST requests to /group-chat/member/remove
+func (h *ApiHandler) handleRemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RemoveGroupMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" || req.PeerId == "" {
+		http.Error(w, "Missing 'group_id' or 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.chatService.RemoveGroupMember(req.GroupId, req.PeerId)
+	if err != nil {
+		log.Printf("API Handler: Error removing group member: %v", err)
+		if errors.Is(err, identity.ErrNotGroupAdmin) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error removing group member: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Group member removed successfully")
+}
+
+// handleRotateGroupKeys handles POST requests to /group-chat/keys/rotate
+func (h *ApiHandler) handleRotateGroupKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RotateGroupKeysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" {
+		http.Error(w, "Missing 'group_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.chatService.RotateGroupKeys(req.GroupId)
+	if err != nil {
+		log.Printf("API Handler: Error rotating group keys: %v", err)
+		http.Error(w, fmt.Sprintf("Error rotating group keys: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Group keys rotated successfully")
+}
+
+// handleUpdateGroupMetadata handles PATCH requests to /group-chat/metadata
+func (h *ApiHandler) handleUpdateGroupMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UpdateGroupMetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" {
+		http.Error(w, "Missing 'group_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	metadata, err := h.chatService.UpdateGroupMetadata(req.GroupId, req.Name, req.Description, req.AvatarHash)
+	if err != nil {
+		log.Printf("API Handler: Error updating group metadata: %v", err)
+		if errors.Is(err, chat.ErrInvalidGroupMetadata) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, identity.ErrNotGroupAdmin) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error updating group metadata: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(metadata); err != nil {
+		log.Printf("API Handler: Error encoding group metadata: %v", err)
+	}
+}
+
+// handleCreateGroupInvite handles POST requests to /group-chat/invite
+func (h *ApiHandler) handleCreateGroupInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateGroupInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" {
+		http.Error(w, "Missing 'group_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	invite, err := h.chatService.CreateGroupInvite(req.GroupId, time.Duration(req.ExpiresInSeconds)*time.Second, req.SingleUse)
+	if err != nil {
+		log.Printf("API Handler: Error creating group invite: %v", err)
+		if errors.Is(err, chat.ErrInvalidGroupInvite) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, identity.ErrNotGroupAdmin) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error creating group invite: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(invite); err != nil {
+		log.Printf("API Handler: Error encoding group invite: %v", err)
+	}
+}
+
+// handleGetGroupInvites handles POST requests to /group-chat/invites
+func (h *ApiHandler) handleGetGroupInvites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GetGroupInvitesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	invites, err := h.chatService.GetGroupInvites(req.GroupId)
+	if err != nil {
+		log.Printf("API Handler: Error getting group invites: %v", err)
+		http.Error(w, fmt.Sprintf("Error getting group invites: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(invites); err != nil {
+		log.Printf("API Handler: Error encoding group invites: %v", err)
+	}
+}
+
+// handleRevokeGroupInvite handles POST requests to /group-chat/invite/revoke
+func (h *ApiHandler) handleRevokeGroupInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RevokeGroupInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" || req.TokenId == "" {
+		http.Error(w, "Missing 'group_id' or 'token_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.chatService.RevokeGroupInvite(req.GroupId, req.TokenId); err != nil {
+		log.Printf("API Handler: Error revoking group invite: %v", err)
+		http.Error(w, fmt.Sprintf("Error revoking group invite: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Group invite revoked successfully")
+}
+
+// handleRedeemGroupInvite handles POST requests to /group-chat/invite/redeem
+func (h *ApiHandler) handleRedeemGroupInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RedeemGroupInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.URI == "" {
+		http.Error(w, "Missing 'uri' in request", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.chatService.RedeemGroupInvite(req.URI, req.AdminPeerId)
+	if err != nil {
+		log.Printf("API Handler: Error redeeming group invite: %v", err)
+		if errors.Is(err, chat.ErrInvalidGroupInvite) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error redeeming group invite: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(invitation); err != nil {
+		log.Printf("API Handler: Error encoding group invitation: %v", err)
+	}
+}
+
 func (h *ApiHandler) handleGetGroups(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
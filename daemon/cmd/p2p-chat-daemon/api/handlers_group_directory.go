@@ -0,0 +1,153 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/chat"
+)
+
+// handleSetGroupPublic handles POST requests to /group-chat/public
+func (h *ApiHandler) handleSetGroupPublic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SetGroupPublicRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" {
+		http.Error(w, "Missing 'group_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.chatService.SetGroupPublic(req.GroupId, req.Public); err != nil {
+		log.Printf("API Handler: Error changing visibility of group %s: %v", req.GroupId, err)
+		http.Error(w, fmt.Sprintf("Error changing group visibility: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Group visibility updated successfully")
+}
+
+// handleSearchGroupDirectory handles POST requests to /group-directory/search
+func (h *ApiHandler) handleSearchGroupDirectory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SearchGroupDirectoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	listings, err := h.chatService.BrowseGroupDirectory(req.Query, req.Refresh)
+	if err != nil {
+		log.Printf("API Handler: Error browsing group directory: %v", err)
+		http.Error(w, fmt.Sprintf("Error browsing group directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, listings, "group directory")
+}
+
+// handleJoinPublicGroup handles POST requests to /group-directory/join
+func (h *ApiHandler) handleJoinPublicGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req JoinPublicGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" {
+		http.Error(w, "Missing 'group_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.chatService.RequestToJoinGroup(req.GroupId, req.Message)
+	if err != nil {
+		log.Printf("API Handler: Error requesting to join group %s: %v", req.GroupId, err)
+		writeGroupJoinRequestError(w, "Error requesting to join group", err)
+		return
+	}
+
+	writeJSON(w, invitation, "group invitation")
+}
+
+// handleGetGroupJoinRequests handles POST requests to /group-chat/join-requests
+func (h *ApiHandler) handleGetGroupJoinRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GetGroupJoinRequestsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	requests, err := h.chatService.GetGroupJoinRequests(req.GroupId)
+	if err != nil {
+		log.Printf("API Handler: Error getting group join requests: %v", err)
+		http.Error(w, fmt.Sprintf("Error getting group join requests: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, requests, "group join requests")
+}
+
+// handleGroupJoinRequestResponse handles POST requests to /group-chat/join-request/response
+func (h *ApiHandler) handleGroupJoinRequestResponse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GroupJoinRequestResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" || req.PeerId == "" {
+		http.Error(w, "Missing 'group_id' or 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.chatService.RespondToGroupJoinRequest(req.GroupId, req.PeerId, req.IsAccepted); err != nil {
+		log.Printf("API Handler: Error responding to join request of %s for group %s: %v", req.PeerId, req.GroupId, err)
+		writeGroupJoinRequestError(w, "Error responding to join request", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Join request answered successfully")
+}
+
+// writeGroupJoinRequestError maps group directory errors to HTTP status codes.
+func writeGroupJoinRequestError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, chat.ErrInvalidGroupJoinRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Group or join request not found", http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+	}
+}
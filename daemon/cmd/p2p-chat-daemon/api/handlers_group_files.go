@@ -0,0 +1,151 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/chat"
+	"strconv"
+)
+
+// handleUploadGroupFile handles POST requests to /group-chat/file
+func (h *ApiHandler) handleUploadGroupFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The content is base64 encoded in the JSON body.
+	r.Body = http.MaxBytesReader(w, r.Body, chat.GroupFileMaxSize/3*4+64*1024)
+
+	var req UploadGroupFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" || req.Name == "" {
+		http.Error(w, "Missing 'group_id' or 'name' in request", http.StatusBadRequest)
+		return
+	}
+
+	file, err := h.chatService.UploadGroupFile(req.GroupId, req.Name, req.MimeType, req.Content)
+	if err != nil {
+		log.Printf("API Handler: Error uploading file to group %s: %v", req.GroupId, err)
+		writeGroupFileError(w, "Error uploading group file", err)
+		return
+	}
+
+	writeJSON(w, file, "group file")
+}
+
+// handleGetGroupFiles handles POST requests to /group-chat/files
+func (h *ApiHandler) handleGetGroupFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GetGroupFilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" {
+		http.Error(w, "Missing 'group_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	files, err := h.chatService.GetGroupFiles(req.GroupId)
+	if err != nil {
+		log.Printf("API Handler: Error getting files of group %s: %v", req.GroupId, err)
+		http.Error(w, fmt.Sprintf("Error getting group files: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, files, "group files")
+}
+
+// handleFetchGroupFile handles POST requests to /group-chat/file/fetch
+func (h *ApiHandler) handleFetchGroupFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GroupFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" || req.FileId == "" {
+		http.Error(w, "Missing 'group_id' or 'file_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	file, err := h.chatService.FetchGroupFile(req.GroupId, req.FileId)
+	if err != nil {
+		log.Printf("API Handler: Error fetching file %s of group %s: %v", req.FileId, req.GroupId, err)
+		writeGroupFileError(w, "Error fetching group file", err)
+		return
+	}
+
+	writeJSON(w, file, "group file")
+}
+
+// handleGetGroupFileContent handles POST requests to /group-chat/file/content
+func (h *ApiHandler) handleGetGroupFileContent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GroupFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupId == "" || req.FileId == "" {
+		http.Error(w, "Missing 'group_id' or 'file_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	file, content, err := h.chatService.ReadGroupFile(req.GroupId, req.FileId)
+	if err != nil {
+		log.Printf("API Handler: Error reading file %s of group %s: %v", req.FileId, req.GroupId, err)
+		writeGroupFileError(w, "Error reading group file", err)
+		return
+	}
+
+	contentType := file.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	if _, err := w.Write(content); err != nil {
+		log.Printf("API Handler: Error writing file %s: %v", req.FileId, err)
+	}
+}
+
+// writeGroupFileError maps group file errors to HTTP status codes.
+func writeGroupFileError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, chat.ErrInvalidGroupFile):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, chat.ErrGroupFileIncomplete):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "File not found", http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+	}
+}
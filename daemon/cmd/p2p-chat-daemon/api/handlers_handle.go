@@ -0,0 +1,152 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/handle"
+)
+
+// handleGetHandleClaims handles GET requests to /handles
+func (h *ApiHandler) handleGetHandleClaims(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, err := h.handleService.GetClaims()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting handle claims: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, claims, "handle claims")
+}
+
+// handleClaimHandle handles POST requests to /handles/claim
+func (h *ApiHandler) handleClaimHandle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HandleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.Handle == "" {
+		http.Error(w, "Missing 'handle' in request", http.StatusBadRequest)
+		return
+	}
+
+	claim, err := h.handleService.Claim(req.Handle)
+	if err != nil {
+		log.Printf("API Handler: Error claiming handle %s: %v", req.Handle, err)
+		writeHandleError(w, err)
+		return
+	}
+
+	writeJSON(w, claim, "handle claim")
+}
+
+// handleReleaseHandle handles POST requests to /handles/release
+func (h *ApiHandler) handleReleaseHandle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HandleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.Handle == "" {
+		http.Error(w, "Missing 'handle' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.handleService.Release(req.Handle); err != nil {
+		log.Printf("API Handler: Error releasing handle %s: %v", req.Handle, err)
+		writeHandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Handle released successfully")
+}
+
+// handleResolveHandle handles GET requests to /handles/resolve?handle=
+func (h *ApiHandler) handleResolveHandle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.URL.Query().Get("handle")
+	if name == "" {
+		http.Error(w, "Missing 'handle' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	resolved, err := h.handleService.Resolve(name)
+	if err != nil {
+		log.Printf("API Handler: Error resolving handle %s: %v", name, err)
+		writeHandleError(w, err)
+		return
+	}
+
+	writeJSON(w, resolved, "resolved handle")
+}
+
+// handleTrustHandle handles POST requests to /handles/trust, pinning a handle to the peer
+// the user confirmed
+func (h *ApiHandler) handleTrustHandle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TrustHandleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.Handle == "" || req.PeerId == "" {
+		http.Error(w, "Missing 'handle' or 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	pin, err := h.handleService.Trust(req.Handle, req.PeerId)
+	if err != nil {
+		log.Printf("API Handler: Error trusting handle %s: %v", req.Handle, err)
+		writeHandleError(w, err)
+		return
+	}
+
+	writeJSON(w, pin, "handle pin")
+}
+
+func writeHandleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, handle.ErrInvalidHandle):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, handle.ErrHandleTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, handle.ErrHandleNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Handle not claimed", http.StatusNotFound)
+	case errors.Is(err, handle.ErrRegistryUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, fmt.Sprintf("Error processing handle request: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
+)
+
+// handleIntroduceFriends handles POST requests to /profile/introduction
+func (h *ApiHandler) handleIntroduceFriends(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req IntroduceFriendsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.PeerA == "" || req.PeerB == "" {
+		http.Error(w, "Missing 'peer_a' or 'peer_b' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.IntroduceFriends(req.PeerA, req.PeerB, req.Message); err != nil {
+		log.Printf("API Handler: Error introducing %s and %s: %v", req.PeerA, req.PeerB, err)
+		writeIntroductionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Introduction sent successfully")
+}
+
+// handleGetIntroductions handles GET requests to /profile/introductions
+func (h *ApiHandler) handleGetIntroductions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	introductions, err := h.profileService.GetIntroductions()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting introductions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, introductions, "introductions")
+}
+
+// handleIntroductionResponse handles POST requests to /profile/introduction/response
+func (h *ApiHandler) handleIntroductionResponse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req IntroductionResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.IntroId == "" || req.Action == "" {
+		http.Error(w, "Missing 'intro_id' or 'action' in request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.RespondToIntroduction(req.IntroId, req.Action); err != nil {
+		log.Printf("API Handler: Error responding to introduction %s: %v", req.IntroId, err)
+		writeIntroductionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Responded to introduction successfully")
+}
+
+func writeIntroductionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, profile.ErrInvalidIntroduction):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, profile.ErrNotFriend):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Introduction not found", http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("Error handling introduction: %v", err), http.StatusInternalServerError)
+	}
+}
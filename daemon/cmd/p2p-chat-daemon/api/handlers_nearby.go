@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/nearby"
+)
+
+// handleGetNearbyPeers handles GET requests to /nearby
+func (h *ApiHandler) handleGetNearbyPeers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, h.nearbyService.GetNearby(), "nearby peers")
+}
+
+// handleGetNearbyName handles GET requests to /nearby/name/get
+func (h *ApiHandler) handleGetNearbyName(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, err := h.nearbyService.GetDisplayName()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting nearby display name: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, NearbyNameRequest{DisplayName: name}, "nearby display name")
+}
+
+// handleSetNearbyName handles POST requests to /nearby/name, setting the display name we
+// announce to nearby peers.
+func (h *ApiHandler) handleSetNearbyName(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req NearbyNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.nearbyService.SetDisplayName(req.DisplayName); err != nil {
+		log.Printf("API Handler: Error setting nearby display name: %v", err)
+		if errors.Is(err, nearby.ErrInvalidDisplayName) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error setting nearby display name: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Nearby display name updated successfully")
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/presence"
+)
+
+// handleGetPresence handles GET requests to /presence
+func (h *ApiHandler) handleGetPresence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	own, err := h.presenceService.GetOwnPresence()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting presence: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, own, "presence")
+}
+
+// handleSetPresence handles POST requests to /presence/update
+func (h *ApiHandler) handleSetPresence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SetPresenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	own, err := h.presenceService.SetOwnPresence(req.Status, req.Message)
+	if err != nil {
+		log.Printf("API Handler: Error setting presence: %v", err)
+		writePresenceError(w, err, "Error setting presence")
+		return
+	}
+
+	writeJSON(w, own, "presence")
+}
+
+// handleGetPresenceRules handles GET requests to /presence/rules
+func (h *ApiHandler) handleGetPresenceRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rules, err := h.presenceService.GetRules()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting presence rules: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, rules, "presence rules")
+}
+
+// handleSetPresenceRule handles POST requests to /presence/rules/update. A rule hiding
+// nothing removes the friend's or circle's rule.
+func (h *ApiHandler) handleSetPresenceRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SetPresenceRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.presenceService.SetRule(req.SubjectType, req.SubjectId, req.HidePresence, req.HideLastSeen)
+	if err != nil {
+		log.Printf("API Handler: Error setting presence rule: %v", err)
+		writePresenceError(w, err, "Error setting presence rule")
+		return
+	}
+
+	writeJSON(w, rule, "presence rule")
+}
+
+func writePresenceError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, presence.ErrInvalidPresence) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+}
@@ -1,11 +1,15 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"log"
 	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
 )
 
 // handleSendMessage handles POST requests to /profile/friends/request
@@ -21,7 +25,7 @@ func (h *ApiHandler) handleFriendRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := h.profileService.SendFriendRequest(req.ReceiverPeerId)
+	err := h.profileService.SendFriendRequest(req.ReceiverPeerId, req.Message)
 
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error sending friends request: %v", err), http.StatusInternalServerError)
@@ -56,6 +60,78 @@ func (h *ApiHandler) handleFriendRequestResponse(w http.ResponseWriter, r *http.
 	fmt.Fprintf(w, "Responded To Friend Request successfully")
 }
 
+// handleCancelFriendRequest handles POST requests to /profile/friend/cancel
+func (h *ApiHandler) handleCancelFriendRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CancelFriendRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.PeerId == "" {
+		http.Error(w, "Missing 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.profileService.CancelFriendRequest(req.PeerId)
+	if err != nil {
+		log.Printf("API Handler: Error cancelling friend request to %s: %v", req.PeerId, err)
+		switch {
+		case errors.Is(err, profile.ErrNoPendingRequest):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Friend request not found", http.StatusNotFound)
+		default:
+			http.Error(w, fmt.Sprintf("Error cancelling friend request: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Friend request cancelled successfully")
+}
+
+// handleRemoveFriend handles POST requests to /profile/friend/remove
+func (h *ApiHandler) handleRemoveFriend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RemoveFriendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.PeerId == "" {
+		http.Error(w, "Missing 'peer_id' in request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.profileService.Unfriend(req.PeerId, req.PurgeHistory)
+	if err != nil {
+		log.Printf("API Handler: Error removing friend %s: %v", req.PeerId, err)
+		switch {
+		case errors.Is(err, profile.ErrNotFriend):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Friend not found", http.StatusNotFound)
+		default:
+			http.Error(w, fmt.Sprintf("Error removing friend: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Friend removed successfully")
+}
+
 func (h *ApiHandler) handleGetFriends(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -68,7 +144,16 @@ func (h *ApiHandler) handleGetFriends(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cards, err := h.cardService.GetCards()
+	if err != nil {
+		log.Printf("API Handler: Error getting profile cards: %v", err)
+	}
+
 	for i := range friends {
+		if profileCard, ok := cards[friends[i].PeerID]; ok {
+			friends[i].ProfileCard = &profileCard
+		}
+
 		peerID, err := peer.Decode(friends[i].PeerID)
 		if err != nil {
 			log.Printf("API Handler: Error decoding peer ID %s: %v", friends[i].PeerID, err)
@@ -78,6 +163,12 @@ func (h *ApiHandler) handleGetFriends(w http.ResponseWriter, r *http.Request) {
 
 		friends[i].IsOnline = h.connectionService.IsOnline(peerID)
 
+		// Friends hiding from us, or invisible, answer pings but say they are offline.
+		if presence, ok := h.presenceService.FriendPresence(friends[i].PeerID); ok {
+			friends[i].Presence = &presence
+			friends[i].IsOnline = friends[i].IsOnline && presence.Status != types.PresenceOffline
+		}
+
 		displayName, err := h.displayNameRepo.GetByEntity(r.Context(), friends[i].PeerID, "friend")
 		if err != nil {
 			if err.Error() != "sql: no rows in result set" {
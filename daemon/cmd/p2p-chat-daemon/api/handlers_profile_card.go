@@ -0,0 +1,114 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/card"
+)
+
+// handleGetProfileCard handles GET requests to /profile/card
+func (h *ApiHandler) handleGetProfileCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profileCard, err := h.cardService.GetOwnCard()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting profile card: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, profileCard, "profile card")
+}
+
+// handleUpdateProfileCard handles POST requests to /profile/card/update
+func (h *ApiHandler) handleUpdateProfileCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UpdateProfileCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	profileCard, err := h.cardService.UpdateOwnCard(req.DisplayName, req.Bio, req.AvatarHash)
+	if err != nil {
+		log.Printf("API Handler: Error updating profile card: %v", err)
+		if errors.Is(err, card.ErrInvalidProfileCard) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error updating profile card: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, profileCard, "profile card")
+}
+
+// handleUploadAvatar handles POST requests to /profile/avatar
+func (h *ApiHandler) handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, card.AvatarMaxSize/3*4+1024)
+
+	var req UploadAvatarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := h.cardService.UploadAvatar(req.MimeType, req.Content)
+	if err != nil {
+		log.Printf("API Handler: Error uploading avatar: %v", err)
+		if errors.Is(err, card.ErrInvalidAvatar) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error uploading avatar: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, UploadAvatarResponse{AvatarHash: hash}, "avatar hash")
+}
+
+// handleGetAvatar handles GET requests to /profile/avatar/get
+func (h *ApiHandler) handleGetAvatar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := r.URL.Query().Get("hash")
+	if hash == "" {
+		http.Error(w, "Missing 'hash' query parameter", http.StatusBadRequest)
+		return
+	}
+
+	avatar, err := h.cardService.GetAvatar(hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Avatar not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error getting avatar: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Avatars never change under the same hash.
+	w.Header().Set("Content-Type", avatar.MimeType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if _, err := w.Write(avatar.Content); err != nil {
+		log.Printf("API Handler: Error writing avatar %s: %v", hash, err)
+	}
+}
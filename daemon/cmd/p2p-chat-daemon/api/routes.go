@@ -16,13 +16,84 @@ func setupRoutes(mux *http.ServeMux, handler *ApiHandler) {
 
 	mux.HandleFunc("/api/profile/friend/request", handler.handleFriendRequest)
 	mux.HandleFunc("/api/profile/friend/response", handler.handleFriendRequestResponse)
+	mux.HandleFunc("/api/profile/friend/cancel", handler.handleCancelFriendRequest)
+	mux.HandleFunc("/api/profile/friend/remove", handler.handleRemoveFriend)
 	mux.HandleFunc("/api/profile/friends", handler.handleGetFriends)
 	mux.HandleFunc("/api/profile/friendRequests", handler.handleGetFriendRequests)
+	mux.HandleFunc("/api/profile/friend/note", handler.handleSetContactNote)
+	mux.HandleFunc("/api/profile/friend/tag", handler.handleAddContactTag)
+	mux.HandleFunc("/api/profile/friend/tag/remove", handler.handleRemoveContactTag)
+	mux.HandleFunc("/api/profile/introduction", handler.handleIntroduceFriends)
+	mux.HandleFunc("/api/profile/introductions", handler.handleGetIntroductions)
+	mux.HandleFunc("/api/profile/introduction/response", handler.handleIntroductionResponse)
+	mux.HandleFunc("/api/profile/contact", handler.handleGetContactURI)
+	mux.HandleFunc("/api/profile/contact/qr", handler.handleGetContactQR)
+	mux.HandleFunc("/api/profile/contact/import", handler.handleImportContact)
+	mux.HandleFunc("/api/profile/contacts/export", handler.handleExportContacts)
+	mux.HandleFunc("/api/profile/contacts/import", handler.handleImportContacts)
+	mux.HandleFunc("/api/profile/card", handler.handleGetProfileCard)
+	mux.HandleFunc("/api/profile/card/update", handler.handleUpdateProfileCard)
+	mux.HandleFunc("/api/profile/avatar", handler.handleUploadAvatar)
+	mux.HandleFunc("/api/profile/avatar/get", handler.handleGetAvatar)
+
+	mux.HandleFunc("/api/presence", handler.handleGetPresence)
+	mux.HandleFunc("/api/presence/update", handler.handleSetPresence)
+	mux.HandleFunc("/api/presence/rules", handler.handleGetPresenceRules)
+	mux.HandleFunc("/api/presence/rules/update", handler.handleSetPresenceRule)
+
+	mux.HandleFunc("/api/blocks", handler.handleGetBlockedPeers)
+	mux.HandleFunc("/api/blocks/add", handler.handleBlockPeer)
+	mux.HandleFunc("/api/blocks/remove", handler.handleUnblockPeer)
+
+	mux.HandleFunc("/api/circles", handler.handleGetCircles)
+	mux.HandleFunc("/api/circles/create", handler.handleCreateCircle)
+	mux.HandleFunc("/api/circles/rename", handler.handleRenameCircle)
+	mux.HandleFunc("/api/circles/delete", handler.handleDeleteCircle)
+	mux.HandleFunc("/api/circles/members/add", handler.handleAddCircleMember)
+	mux.HandleFunc("/api/circles/members/remove", handler.handleRemoveCircleMember)
+
+	mux.HandleFunc("/api/nearby", handler.handleGetNearbyPeers)
+	mux.HandleFunc("/api/nearby/name", handler.handleSetNearbyName)
+	mux.HandleFunc("/api/nearby/name/get", handler.handleGetNearbyName)
+
+	mux.HandleFunc("/api/handles", handler.handleGetHandleClaims)
+	mux.HandleFunc("/api/handles/claim", handler.handleClaimHandle)
+	mux.HandleFunc("/api/handles/release", handler.handleReleaseHandle)
+	mux.HandleFunc("/api/handles/resolve", handler.handleResolveHandle)
+	mux.HandleFunc("/api/handles/trust", handler.handleTrustHandle)
 
 	mux.HandleFunc("/api/group-chat", handler.handleCreateGroupChat)
 	mux.HandleFunc("/api/group-chats", handler.handleGetGroups)
 	mux.HandleFunc("/api/group-chat/send", handler.handleSendGroupMessage)
 	mux.HandleFunc("/api/group-chat/messages", handler.handleGetGroupMessages)
+	mux.HandleFunc("/api/group-chat/sync", handler.handleSyncGroupHistory)
+	mux.HandleFunc("/api/group-chat/invitations", handler.handleGetGroupInvitations)
+	mux.HandleFunc("/api/group-chat/invitation/response", handler.handleGroupInvitationResponse)
+	mux.HandleFunc("/api/group-chat/member/remove", handler.handleRemoveGroupMember)
+	mux.HandleFunc("/api/group-chat/keys/rotate", handler.handleRotateGroupKeys)
+	mux.HandleFunc("/api/group-chat/metadata", handler.handleUpdateGroupMetadata)
+	mux.HandleFunc("/api/group-chat/invite", handler.handleCreateGroupInvite)
+	mux.HandleFunc("/api/group-chat/invites", handler.handleGetGroupInvites)
+	mux.HandleFunc("/api/group-chat/invite/revoke", handler.handleRevokeGroupInvite)
+	mux.HandleFunc("/api/group-chat/invite/redeem", handler.handleRedeemGroupInvite)
+	mux.HandleFunc("/api/group-chat/public", handler.handleSetGroupPublic)
+	mux.HandleFunc("/api/group-chat/join-requests", handler.handleGetGroupJoinRequests)
+	mux.HandleFunc("/api/group-chat/join-request/response", handler.handleGroupJoinRequestResponse)
+	mux.HandleFunc("/api/group-chat/file", handler.handleUploadGroupFile)
+	mux.HandleFunc("/api/group-chat/files", handler.handleGetGroupFiles)
+	mux.HandleFunc("/api/group-chat/file/fetch", handler.handleFetchGroupFile)
+	mux.HandleFunc("/api/group-chat/file/content", handler.handleGetGroupFileContent)
+	mux.HandleFunc("/api/group-directory/search", handler.handleSearchGroupDirectory)
+	mux.HandleFunc("/api/group-directory/join", handler.handleJoinPublicGroup)
+
+	mux.HandleFunc("/api/channel", handler.handleCreateChannel)
+	mux.HandleFunc("/api/channels", handler.handleGetChannels)
+	mux.HandleFunc("/api/channel/subscribe", handler.handleSubscribeChannel)
+	mux.HandleFunc("/api/channel/unsubscribe", handler.handleUnsubscribeChannel)
+	mux.HandleFunc("/api/channel/authors", handler.handleUpdateChannelAuthors)
+	mux.HandleFunc("/api/channel/post", handler.handleChannelPost)
+	mux.HandleFunc("/api/channel/posts", handler.handleGetChannelPosts)
+	mux.HandleFunc("/api/channel/react", handler.handleChannelReaction)
 
 	mux.HandleFunc("/api/ws", handler.handleWebSocket)
 
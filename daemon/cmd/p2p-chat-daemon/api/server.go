@@ -9,10 +9,16 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/blocklist"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/card"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/channel"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/chat"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/connection"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/handle"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/nearby"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/presence"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
 	"strings"
@@ -28,16 +34,22 @@ func StartAPIServer(
 	appState *core.AppState,
 	bus *bus.EventBus,
 	chatService *chat.Service,
+	channelService *channel.Service,
 	profileService *profile.Service,
 	connectionService *connection.Service,
 	displayNameRepo storage.DisplayNameRepository,
+	blocklistService *blocklist.Service,
+	handleService *handle.Service,
+	nearbyService *nearby.Service,
+	cardService *card.Service,
+	presenceService *presence.Service,
 ) (net.Listener, *http.Server, *ApiHandler, error) {
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
 
-	handler := newAPIHandler(appState, bus, chatService, profileService, connectionService, displayNameRepo)
+	handler := newAPIHandler(appState, bus, chatService, channelService, profileService, connectionService, displayNameRepo, blocklistService, handleService, nearbyService, cardService, presenceService)
 
 	mux := http.NewServeMux()
 	setupRoutes(mux, handler)
@@ -92,7 +92,8 @@ func (h *ApiHandler) readLoop(conn *websocket.Conn) {
 			var req WsGroupMessageRequestPayload
 			json.Unmarshal(msg.Payload, &req)
 
-			h.chatService.SendGroupMessage(req.GroupId, req.Message)
+			// Delivery reports arrive as GROUP_MESSAGE_DELIVERY events.
+			go h.chatService.SendGroupMessage(req.GroupId, req.Message)
 		}
 	}
 }
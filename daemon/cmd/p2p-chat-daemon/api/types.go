@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/json"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
 )
 
 type StatusResponse struct {
@@ -32,6 +34,65 @@ type WsGroupMessageRequestPayload struct {
 
 type FriendRequest struct {
 	ReceiverPeerId string `json:"receiver_peer_id"`
+	Message        string `json:"message"`
+}
+
+type ContactURIResponse struct {
+	URI string `json:"uri"`
+}
+
+type ImportContactRequest struct {
+	URI     string `json:"uri"`
+	Message string `json:"message"`
+}
+
+// ImportContactsRequest carries a contact list export. OnConflict is "keep" (the default)
+// or "replace", and decides whether our names and notes or the imported ones win.
+type ImportContactsRequest struct {
+	Export     types.ContactExport `json:"export"`
+	OnConflict string              `json:"on_conflict"`
+}
+
+type UpdateProfileCardRequest struct {
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarHash  string `json:"avatar_hash"`
+}
+
+type SetPresenceRequest struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type SetPresenceRuleRequest struct {
+	SubjectType  string `json:"subject_type"`
+	SubjectId    string `json:"subject_id"`
+	HidePresence bool   `json:"hide_presence"`
+	HideLastSeen bool   `json:"hide_last_seen"`
+}
+
+type UploadAvatarRequest struct {
+	MimeType string `json:"mime_type"`
+	Content  []byte `json:"content"`
+}
+
+type UploadAvatarResponse struct {
+	AvatarHash string `json:"avatar_hash"`
+}
+
+type IntroduceFriendsRequest struct {
+	PeerA   string `json:"peer_a"`
+	PeerB   string `json:"peer_b"`
+	Message string `json:"message"`
+}
+
+type IntroductionResponseRequest struct {
+	IntroId string `json:"intro_id"`
+	Action  string `json:"action"`
+}
+
+type CancelFriendRequestRequest struct {
+	PeerId string `json:"peer_id"`
 }
 
 type FriendRequestResponse struct {
@@ -39,8 +100,61 @@ type FriendRequestResponse struct {
 	IsAccepted bool   `json:"is_accepted"`
 }
 
+type RemoveFriendRequest struct {
+	PeerId       string `json:"peer_id"`
+	PurgeHistory bool   `json:"purge_history"`
+}
+
+type BlockPeerRequest struct {
+	PeerId string `json:"peer_id"`
+	Reason string `json:"reason"`
+}
+
+type ContactNoteRequest struct {
+	PeerId string `json:"peer_id"`
+	Note   string `json:"note"`
+}
+
+type ContactTagRequest struct {
+	PeerId string `json:"peer_id"`
+	Tag    string `json:"tag"`
+}
+
+type CreateCircleRequest struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type RenameCircleRequest struct {
+	CircleId string `json:"circle_id"`
+	Name     string `json:"name"`
+}
+
+type CircleMemberRequest struct {
+	CircleId string `json:"circle_id"`
+	PeerId   string `json:"peer_id"`
+}
+
+type NearbyNameRequest struct {
+	DisplayName string `json:"display_name"`
+}
+
+type HandleRequest struct {
+	Handle string `json:"handle"`
+}
+
+type TrustHandleRequest struct {
+	Handle string `json:"handle"`
+	PeerId string `json:"peer_id"`
+}
+
+type UnblockPeerRequest struct {
+	PeerId string `json:"peer_id"`
+}
+
 type CreateGroupChatRequest struct {
 	MemberPeerIds []string `json:"member_peers"`
+	Circles       []string `json:"circles"`
 	ChatName      string   `json:"name"`
 }
 
@@ -53,6 +167,128 @@ type GetGroupChatMessagesRequest struct {
 	GroupId string `json:"group_id"`
 }
 
+type SyncGroupHistoryRequest struct {
+	GroupId string `json:"group_id"`
+}
+
+type SyncGroupHistoryResponse struct {
+	GroupId  string `json:"group_id"`
+	Received int    `json:"received"`
+}
+
+type GroupInvitationResponseRequest struct {
+	GroupId    string `json:"group_id"`
+	IsAccepted bool   `json:"is_accepted"`
+}
+
+type RemoveGroupMemberRequest struct {
+	GroupId string `json:"group_id"`
+	PeerId  string `json:"peer_id"`
+}
+
+type RotateGroupKeysRequest struct {
+	GroupId string `json:"group_id"`
+}
+
+type UpdateGroupMetadataRequest struct {
+	GroupId     string `json:"group_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	AvatarHash  string `json:"avatar_hash"`
+}
+
+type CreateGroupInviteRequest struct {
+	GroupId          string `json:"group_id"`
+	ExpiresInSeconds int64  `json:"expires_in_seconds"`
+	SingleUse        bool   `json:"single_use"`
+}
+
+type GetGroupInvitesRequest struct {
+	GroupId string `json:"group_id"`
+}
+
+type RevokeGroupInviteRequest struct {
+	GroupId string `json:"group_id"`
+	TokenId string `json:"token_id"`
+}
+
+type RedeemGroupInviteRequest struct {
+	URI         string `json:"uri"`
+	AdminPeerId string `json:"admin_peer_id,omitempty"`
+}
+
+type SetGroupPublicRequest struct {
+	GroupId string `json:"group_id"`
+	Public  bool   `json:"public"`
+}
+
+type SearchGroupDirectoryRequest struct {
+	Query   string `json:"query"`
+	Refresh bool   `json:"refresh"`
+}
+
+type JoinPublicGroupRequest struct {
+	GroupId string `json:"group_id"`
+	Message string `json:"message"`
+}
+
+type GetGroupJoinRequestsRequest struct {
+	GroupId string `json:"group_id"`
+}
+
+type GroupJoinRequestResponseRequest struct {
+	GroupId    string `json:"group_id"`
+	PeerId     string `json:"peer_id"`
+	IsAccepted bool   `json:"is_accepted"`
+}
+
+type UploadGroupFileRequest struct {
+	GroupId  string `json:"group_id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Content  []byte `json:"content"`
+}
+
+type GetGroupFilesRequest struct {
+	GroupId string `json:"group_id"`
+}
+
+type GroupFileRequest struct {
+	GroupId string `json:"group_id"`
+	FileId  string `json:"file_id"`
+}
+
+type CreateChannelRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type SubscribeChannelRequest struct {
+	ChannelId   string `json:"channel_id"`
+	OwnerPeerId string `json:"owner_peer_id"`
+	ViaPeerId   string `json:"via_peer_id,omitempty"`
+}
+
+type ChannelRequest struct {
+	ChannelId string `json:"channel_id"`
+}
+
+type UpdateChannelAuthorsRequest struct {
+	ChannelId string   `json:"channel_id"`
+	Authors   []string `json:"authors"`
+}
+
+type ChannelPostRequest struct {
+	ChannelId string `json:"channel_id"`
+	Message   string `json:"message"`
+}
+
+type ChannelReactionRequest struct {
+	ChannelId string `json:"channel_id"`
+	PostId    string `json:"post_id"`
+	Reaction  string `json:"reaction"`
+}
+
 type GetChatMessagesRequest struct {
 	PeerId string `json:"peer_id"`
 }
@@ -62,6 +298,27 @@ type WsMessageType string
 const (
 	WsMsgTypeDirectMessage WsMessageType = "DIRECT_MESSAGE"
 	WsMsgTypeGroupMessage  WsMessageType = "GROUP_MESSAGE"
+
+	WsMsgTypeGroupMessageDelivery    WsMessageType = "GROUP_MESSAGE_DELIVERY"
+	WsMsgTypeGroupInvitation         WsMessageType = "GROUP_INVITATION"
+	WsMsgTypeGroupInvitationResponse WsMessageType = "GROUP_INVITATION_RESPONSE"
+	WsMsgTypeGroupEpochChanged       WsMessageType = "GROUP_EPOCH_CHANGED"
+	WsMsgTypeGroupMetadataUpdated    WsMessageType = "GROUP_METADATA_UPDATED"
+	WsMsgTypeGroupJoinRequest        WsMessageType = "GROUP_JOIN_REQUEST"
+	WsMsgTypeGroupFileUpdated        WsMessageType = "GROUP_FILE_UPDATED"
+
+	WsMsgTypeChannelPost     WsMessageType = "CHANNEL_POST"
+	WsMsgTypeChannelReaction WsMessageType = "CHANNEL_REACTION"
+	WsMsgTypeChannelUpdated  WsMessageType = "CHANNEL_UPDATED"
+
+	WsMsgTypeFriendRemoved          WsMessageType = "FRIEND_REMOVED"
+	WsMsgTypeFriendRequestCancelled WsMessageType = "FRIEND_REQUEST_CANCELLED"
+	WsMsgTypeFriendIntroduction     WsMessageType = "FRIEND_INTRODUCTION"
+	WsMsgTypeHandleConflict         WsMessageType = "HANDLE_CONFLICT"
+	WsMsgTypeNearbyPeer             WsMessageType = "NEARBY_PEER"
+	WsMsgTypeNearbyPeerLost         WsMessageType = "NEARBY_PEER_LOST"
+	WsMsgTypeProfileCard            WsMessageType = "PROFILE_CARD"
+	WsMsgTypeFriendPresence         WsMessageType = "FRIEND_PRESENCE"
 )
 
 type WsMessage struct {
@@ -80,3 +337,66 @@ type WsGroupMessagePayload struct {
 	SenderPeerId string `json:"sender_peer_id"`
 	Message      string `json:"message"`
 }
+
+type WsGroupInvitationPayload struct {
+	GroupId       string   `json:"group_id"`
+	InviterPeerId string   `json:"inviter_peer_id"`
+	Name          string   `json:"name"`
+	MemberPeers   []string `json:"member_peers"`
+}
+
+type WsGroupInvitationResponsePayload struct {
+	GroupId         string `json:"group_id"`
+	ResponderPeerId string `json:"responder_peer_id"`
+	IsAccepted      bool   `json:"is_accepted"`
+}
+
+type WsGroupEpochChangedPayload struct {
+	GroupId     string   `json:"group_id"`
+	Epoch       int64    `json:"epoch"`
+	Added       []string `json:"added"`
+	Removed     []string `json:"removed"`
+	RemovedSelf bool     `json:"removed_self"`
+}
+
+type WsGroupMetadataUpdatedPayload struct {
+	GroupId     string `json:"group_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	AvatarHash  string `json:"avatar_hash"`
+	Version     int64  `json:"version"`
+	UpdatedBy   string `json:"updated_by"`
+}
+
+type WsFriendRemovedPayload struct {
+	PeerId    string `json:"peer_id"`
+	Initiated bool   `json:"initiated"`
+}
+
+type WsFriendRequestCancelledPayload struct {
+	PeerId    string `json:"peer_id"`
+	Initiated bool   `json:"initiated"`
+}
+
+type WsNearbyPeerLostPayload struct {
+	PeerId string `json:"peer_id"`
+}
+
+type WsFriendPresencePayload struct {
+	PeerId   string     `json:"peer_id"`
+	Status   string     `json:"status"`
+	Message  string     `json:"message,omitempty"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+type WsHandleConflictPayload struct {
+	Handle      string `json:"handle"`
+	OwnerPeerId string `json:"owner_peer_id"`
+}
+
+type WsChannelReactionPayload struct {
+	ChannelId     string `json:"channel_id"`
+	PostId        string `json:"post_id"`
+	ReactorPeerId string `json:"reactor_peer_id"`
+	Reaction      string `json:"reaction"`
+}
@@ -0,0 +1,162 @@
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const blockReasonMaxLength = 200
+
+var ErrInvalidBlock = errors.New("invalid block")
+
+// Service keeps the list of blocked peers. It is the connection gater of the node, so
+// blocked peers can neither dial us nor be dialed, and the node drops any stream they
+// still manage to open.
+type Service struct {
+	ctx      context.Context
+	appState *core.AppState
+	repo     storage.BlockedPeerRepository
+	mu       sync.RWMutex
+	blocked  map[peer.ID]struct{}
+}
+
+// NewBlocklistService loads the stored block list.
+func NewBlocklistService(ctx context.Context, appState *core.AppState, repo storage.BlockedPeerRepository) (*Service, error) {
+	s := &Service{
+		ctx:      ctx,
+		appState: appState,
+		repo:     repo,
+		blocked:  make(map[peer.ID]struct{}),
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	entries, err := repo.GetBlocked(loadCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blocked peers: %w", err)
+	}
+
+	for _, entry := range entries {
+		pid, err := peer.Decode(entry.PeerId)
+		if err != nil {
+			log.Printf("Blocklist: Skipping invalid blocked peer %s: %v", entry.PeerId, err)
+			continue
+		}
+		s.blocked[pid] = struct{}{}
+	}
+
+	return s, nil
+}
+
+// Block blocks a peer and closes every connection we have with it.
+func (s *Service) Block(peerId string, reason string) error {
+	pid, err := peer.Decode(peerId)
+	if err != nil {
+		return fmt.Errorf("%w: invalid peer ID: %v", ErrInvalidBlock, err)
+	}
+
+	if len([]rune(reason)) > blockReasonMaxLength {
+		return fmt.Errorf("%w: reason longer than %d characters", ErrInvalidBlock, blockReasonMaxLength)
+	}
+
+	if s.appState.Node != nil && pid == (*s.appState.Node).ID() {
+		return fmt.Errorf("%w: cannot block ourselves", ErrInvalidBlock)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.Block(ctx, peerId, reason); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.blocked[pid] = struct{}{}
+	s.mu.Unlock()
+
+	if s.appState.Node != nil {
+		if err := (*s.appState.Node).Network().ClosePeer(pid); err != nil {
+			log.Printf("Blocklist: Error closing connections to %s: %v", pid.ShortString(), err)
+		}
+	}
+
+	log.Printf("Blocklist: Blocked %s", pid.ShortString())
+	return nil
+}
+
+// Unblock lifts a block. It returns sql.ErrNoRows if the peer was not blocked.
+func (s *Service) Unblock(peerId string) error {
+	pid, err := peer.Decode(peerId)
+	if err != nil {
+		return fmt.Errorf("%w: invalid peer ID: %v", ErrInvalidBlock, err)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.Unblock(ctx, peerId); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.blocked, pid)
+	s.mu.Unlock()
+
+	log.Printf("Blocklist: Unblocked %s", pid.ShortString())
+	return nil
+}
+
+// GetBlocked returns every blocked peer.
+func (s *Service) GetBlocked() ([]types.BlockedPeer, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.repo.GetBlocked(ctx)
+}
+
+// IsBlocked reports whether a peer is blocked.
+func (s *Service) IsBlocked(id peer.ID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, blocked := s.blocked[id]
+	return blocked
+}
+
+// InterceptPeerDial refuses to dial blocked peers.
+func (s *Service) InterceptPeerDial(id peer.ID) bool {
+	return !s.IsBlocked(id)
+}
+
+// InterceptAddrDial refuses to dial blocked peers on any address.
+func (s *Service) InterceptAddrDial(id peer.ID, _ multiaddr.Multiaddr) bool {
+	return !s.IsBlocked(id)
+}
+
+// InterceptAccept lets every inbound connection through; the remote peer is only known
+// once the connection is secured.
+func (s *Service) InterceptAccept(network.ConnMultiaddrs) bool {
+	return true
+}
+
+// InterceptSecured drops connections with blocked peers, inbound and outbound.
+func (s *Service) InterceptSecured(_ network.Direction, id peer.ID, _ network.ConnMultiaddrs) bool {
+	return !s.IsBlocked(id)
+}
+
+func (s *Service) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) {
+	return true, 0
+}
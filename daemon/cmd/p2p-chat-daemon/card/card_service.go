@@ -0,0 +1,281 @@
+package card
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	displayNameMaxLength = 64
+	bioMaxLength         = 500
+	AvatarMaxSize        = 256 * 1024
+
+	// unusedAvatarGrace keeps freshly uploaded avatars around until a card uses them.
+	unusedAvatarGrace = time.Hour
+)
+
+var (
+	ErrInvalidProfileCard = errors.New("invalid profile card")
+	ErrInvalidAvatar      = errors.New("invalid avatar")
+)
+
+var avatarMimeTypes = map[string]struct{}{
+	"image/png":  {},
+	"image/jpeg": {},
+	"image/gif":  {},
+	"image/webp": {},
+}
+
+// Service publishes our profile card to our friends and keeps theirs. The card itself is
+// small and signed; avatars are fetched separately by hash, and only when they changed.
+type Service struct {
+	ctx              context.Context
+	appState         *core.AppState
+	bus              *bus.EventBus
+	cardRepo         storage.ProfileCardRepository
+	avatarRepo       storage.AvatarRepository
+	relationshipRepo storage.RelationshipRepository
+	mu               sync.Mutex
+	fetching         map[peer.ID]struct{}
+}
+
+func NewProfileCardService(
+	ctx context.Context,
+	appState *core.AppState,
+	bus *bus.EventBus,
+	cardRepo storage.ProfileCardRepository,
+	avatarRepo storage.AvatarRepository,
+	relationshipRepo storage.RelationshipRepository,
+) *Service {
+	return &Service{
+		ctx:              ctx,
+		appState:         appState,
+		bus:              bus,
+		cardRepo:         cardRepo,
+		avatarRepo:       avatarRepo,
+		relationshipRepo: relationshipRepo,
+		fetching:         make(map[peer.ID]struct{}),
+	}
+}
+
+func (s *Service) Register() {
+	(*s.appState.Node).SetStreamHandler(core.ProfileCardProtocolID, s.handleProfileCardStream)
+
+	notifiee := &network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			s.FetchCard(conn.RemotePeer())
+		},
+	}
+	(*s.appState.Node).Network().Notify(notifiee)
+
+	go func() {
+		<-s.ctx.Done()
+		(*s.appState.Node).Network().StopNotify(notifiee)
+	}()
+}
+
+// GetOwnCard returns our profile card. Before we ever publish one it is empty, at version 0.
+func (s *Service) GetOwnCard() (*types.ProfileCard, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.getCard(ctx, (*s.appState.Node).ID().String())
+}
+
+// UpdateOwnCard publishes a new version of our profile card and tells the friends we are
+// connected to. The others fetch it when they next connect to us.
+func (s *Service) UpdateOwnCard(displayName string, bio string, avatarHash string) (*types.ProfileCard, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	if avatarHash != "" {
+		exists, err := s.avatarRepo.Has(ctx, avatarHash)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: avatar %s was not uploaded", ErrInvalidProfileCard, avatarHash)
+		}
+	}
+
+	s.mu.Lock()
+	current, err := s.getCard(ctx, (*s.appState.Node).ID().String())
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	data := types.ProfileCardData{
+		PeerId:      current.PeerId,
+		DisplayName: strings.TrimSpace(displayName),
+		Bio:         strings.TrimSpace(bio),
+		AvatarHash:  avatarHash,
+		Version:     current.Version + 1,
+		UpdatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := validateProfileCard(data); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	card, err := s.storeCard(ctx, types.SignedProfileCard{Data: data, Signature: signature})
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.avatarRepo.DeleteUnused(ctx, time.Now().Add(-unusedAvatarGrace)); err != nil {
+		log.Printf("Profile Card: Error deleting unused avatars: %v", err)
+	}
+
+	go s.announceChange()
+
+	return card, nil
+}
+
+// UploadAvatar stores an image to be used as an avatar and returns its hash.
+func (s *Service) UploadAvatar(mimeType string, content []byte) (string, error) {
+	if _, ok := avatarMimeTypes[mimeType]; !ok {
+		return "", fmt.Errorf("%w: unsupported image type %q", ErrInvalidAvatar, mimeType)
+	}
+	if len(content) == 0 || len(content) > AvatarMaxSize {
+		return "", fmt.Errorf("%w: images must be between 1 byte and %d KiB", ErrInvalidAvatar, AvatarMaxSize/1024)
+	}
+
+	digest := sha256.Sum256(content)
+	hash := hex.EncodeToString(digest[:])
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.avatarRepo.Store(ctx, types.Avatar{Hash: hash, MimeType: mimeType, Content: content}); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// GetAvatar returns an avatar we uploaded or fetched. It returns sql.ErrNoRows if we do
+// not have it.
+func (s *Service) GetAvatar(hash string) (*types.Avatar, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.avatarRepo.Get(ctx, hash)
+}
+
+// GetCards returns the profile cards we have, keyed by peer ID.
+func (s *Service) GetCards() (map[string]types.ProfileCard, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.cardRepo.GetAll(ctx)
+}
+
+func (s *Service) getCard(ctx context.Context, peerId string) (*types.ProfileCard, error) {
+	card, err := s.cardRepo.Get(ctx, peerId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &types.ProfileCard{PeerId: peerId}, nil
+	}
+	return card, err
+}
+
+// storeCard keeps a signed card if it is newer than the one we have.
+func (s *Service) storeCard(ctx context.Context, signed types.SignedProfileCard) (*types.ProfileCard, error) {
+	data := signed.Data
+
+	current, err := s.getCard(ctx, data.PeerId)
+	if err != nil {
+		return nil, err
+	}
+	if data.Version <= current.Version {
+		return current, nil
+	}
+
+	cardBytes, err := json.Marshal(signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal profile card: %w", err)
+	}
+
+	updatedAt, err := time.Parse(time.RFC3339, data.UpdatedAt)
+	if err != nil {
+		updatedAt = time.Now()
+	}
+
+	card := types.ProfileCard{
+		PeerId:      data.PeerId,
+		DisplayName: data.DisplayName,
+		Bio:         data.Bio,
+		AvatarHash:  data.AvatarHash,
+		Version:     data.Version,
+		UpdatedAt:   updatedAt,
+		Card:        cardBytes,
+	}
+	if err := s.cardRepo.Store(ctx, card); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Profile Card: %s is now '%s' (version %d)", data.PeerId, data.DisplayName, data.Version)
+	s.bus.PublishAsync(events.ProfileCardUpdatedEvent{Card: card})
+
+	return &card, nil
+}
+
+func (s *Service) isFriend(ctx context.Context, id peer.ID) bool {
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, id.String())
+	return err == nil && relationship.Status == types.FriendStatusApproved
+}
+
+func validateProfileCard(data types.ProfileCardData) error {
+	if utf8.RuneCountInString(data.DisplayName) > displayNameMaxLength {
+		return fmt.Errorf("%w: display name is longer than %d characters", ErrInvalidProfileCard, displayNameMaxLength)
+	}
+
+	if utf8.RuneCountInString(data.Bio) > bioMaxLength {
+		return fmt.Errorf("%w: bio is longer than %d characters", ErrInvalidProfileCard, bioMaxLength)
+	}
+
+	if data.AvatarHash != "" {
+		if decoded, err := hex.DecodeString(data.AvatarHash); err != nil || len(decoded) != sha256.Size {
+			return fmt.Errorf("%w: avatar hash must be a hex encoded SHA-256 digest", ErrInvalidProfileCard)
+		}
+	}
+
+	if data.Version <= 0 {
+		return fmt.Errorf("%w: version must be positive", ErrInvalidProfileCard)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package card
+
+import (
+	"context"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+type Consumer struct {
+	bus         *bus.EventBus
+	ctx         context.Context
+	cardService *Service
+	eventsChan  chan interface{}
+}
+
+func NewConsumer(eventBus *bus.EventBus, cardService *Service, ctx context.Context) *Consumer {
+	return &Consumer{
+		bus:         eventBus,
+		ctx:         ctx,
+		cardService: cardService,
+		eventsChan:  make(chan interface{}),
+	}
+}
+
+func (c *Consumer) Start() {
+	log.Println("profile card consumer started")
+	c.bus.Subscribe(c.eventsChan, events.FriendResponseReceivedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendResponseSentEvent{})
+
+	go c.listen()
+}
+
+func (c *Consumer) listen() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			log.Println("profile card consumer stopped")
+			return
+
+		case event := <-c.eventsChan:
+			c.handleEvent(event)
+		}
+	}
+}
+
+// handleEvent fetches the card of a new friend, who may already be connected to us.
+func (c *Consumer) handleEvent(event interface{}) {
+	switch event := event.(type) {
+
+	case events.FriendResponseReceivedEvent:
+		if event.Status == types.FriendStatusApproved {
+			c.fetchCard(event.SenderPeerId)
+		}
+		return
+
+	case events.FriendResponseSentEvent:
+		if event.IsAccepted {
+			c.fetchCard(event.PeerId)
+		}
+		return
+	}
+}
+
+func (c *Consumer) fetchCard(peerId string) {
+	id, err := peer.Decode(peerId)
+	if err != nil {
+		return
+	}
+	c.cardService.FetchCard(id)
+}
@@ -0,0 +1,282 @@
+package card
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	profileCardRequestMaxSize = 1024
+	// profileCardResponseMaxSize fits a base64 encoded avatar.
+	profileCardResponseMaxSize = AvatarMaxSize/3*4 + 16*1024
+	profileCardTimeout         = 30 * time.Second
+)
+
+// handleProfileCardStream serves our card and avatar to friends, and fetches a friend's
+// card when they tell us it changed. Other peers learn nothing.
+func (s *Service) handleProfileCardStream(stream network.Stream) {
+	remotePeerId := stream.Conn().RemotePeer()
+	defer stream.Close()
+
+	stream.SetReadDeadline(time.Now().Add(10 * time.Second))
+	requestBytes, err := io.ReadAll(io.LimitReader(stream, profileCardRequestMaxSize))
+	if err != nil {
+		log.Printf("Profile Card: Error reading request from %s: %v", remotePeerId.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	var request types.ProfileCardRequest
+	if err := json.Unmarshal(requestBytes, &request); err != nil {
+		log.Printf("Profile Card: Error deserializing request from %s: %v", remotePeerId.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	if !s.isFriend(ctx, remotePeerId) {
+		log.Printf("Profile Card: Ignoring request from %s, who is not a friend", remotePeerId.ShortString())
+		stream.Reset()
+		return
+	}
+
+	var response types.ProfileCardResponse
+	switch request.Type {
+	case types.ProfileCardRequestChanged:
+		s.FetchCard(remotePeerId)
+		return
+
+	case types.ProfileCardRequestGet:
+		own, err := s.getCard(ctx, (*s.appState.Node).ID().String())
+		if err != nil {
+			log.Printf("Profile Card: Error loading our card: %v", err)
+			stream.Reset()
+			return
+		}
+		if own.Version > request.KnownVersion {
+			var signed types.SignedProfileCard
+			if err := json.Unmarshal(own.Card, &signed); err != nil {
+				log.Printf("Profile Card: Error deserializing our card: %v", err)
+				stream.Reset()
+				return
+			}
+			response.Card = &signed
+		}
+
+	case types.ProfileCardRequestAvatar:
+		// Only our current avatar is served, not whatever else we have stored.
+		own, err := s.getCard(ctx, (*s.appState.Node).ID().String())
+		if err != nil || own.AvatarHash == "" || own.AvatarHash != request.AvatarHash {
+			stream.Reset()
+			return
+		}
+		avatar, err := s.avatarRepo.Get(ctx, own.AvatarHash)
+		if err != nil {
+			log.Printf("Profile Card: Error loading our avatar: %v", err)
+			stream.Reset()
+			return
+		}
+		response.AvatarMimeType = avatar.MimeType
+		response.Avatar = avatar.Content
+
+	default:
+		log.Printf("Profile Card: Unknown request type %q from %s", request.Type, remotePeerId.ShortString())
+		stream.Reset()
+		return
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Profile Card: Error marshalling response: %v", err)
+		stream.Reset()
+		return
+	}
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Profile Card: Error answering %s: %v", remotePeerId.ShortString(), err)
+		stream.Reset()
+	}
+}
+
+// FetchCard fetches a friend's card in the background if it changed since we last got it,
+// along with its avatar if we do not have that yet.
+func (s *Service) FetchCard(id peer.ID) {
+	s.mu.Lock()
+	if _, busy := s.fetching[id]; busy {
+		s.mu.Unlock()
+		return
+	}
+	s.fetching[id] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.fetching, id)
+			s.mu.Unlock()
+		}()
+
+		if err := s.fetchCard(id); err != nil {
+			log.Printf("Profile Card: Error fetching card of %s: %v", id.ShortString(), err)
+		}
+	}()
+}
+
+func (s *Service) fetchCard(id peer.ID) error {
+	ctx, cancel := context.WithTimeout(s.ctx, profileCardTimeout)
+	defer cancel()
+
+	if !s.isFriend(ctx, id) {
+		return nil
+	}
+
+	current, err := s.getCard(ctx, id.String())
+	if err != nil {
+		return err
+	}
+
+	response, err := s.request(ctx, id, types.ProfileCardRequest{Type: types.ProfileCardRequestGet, KnownVersion: current.Version})
+	if err != nil {
+		return err
+	}
+
+	if response.Card != nil {
+		data := response.Card.Data
+		if data.PeerId != id.String() {
+			return fmt.Errorf("card of %s was served by %s", data.PeerId, id)
+		}
+		if err := validateProfileCard(data); err != nil {
+			return err
+		}
+		if err := identity.VerifyPayload(data.PeerId, data, response.Card.Signature); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		current, err = s.storeCard(ctx, *response.Card)
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.fetchAvatar(ctx, id, current.AvatarHash)
+}
+
+func (s *Service) fetchAvatar(ctx context.Context, id peer.ID, hash string) error {
+	if hash == "" {
+		return nil
+	}
+	if exists, err := s.avatarRepo.Has(ctx, hash); err != nil || exists {
+		return err
+	}
+
+	response, err := s.request(ctx, id, types.ProfileCardRequest{Type: types.ProfileCardRequestAvatar, AvatarHash: hash})
+	if err != nil {
+		return fmt.Errorf("failed to fetch avatar: %w", err)
+	}
+
+	if _, ok := avatarMimeTypes[response.AvatarMimeType]; !ok {
+		return fmt.Errorf("avatar has unsupported type %q", response.AvatarMimeType)
+	}
+	if len(response.Avatar) > AvatarMaxSize {
+		return fmt.Errorf("avatar larger than %d KiB", AvatarMaxSize/1024)
+	}
+	digest := sha256.Sum256(response.Avatar)
+	if hex.EncodeToString(digest[:]) != hash {
+		return fmt.Errorf("avatar does not match hash %s", hash)
+	}
+
+	return s.avatarRepo.Store(ctx, types.Avatar{Hash: hash, MimeType: response.AvatarMimeType, Content: response.Avatar})
+}
+
+// announceChange tells the friends we are connected to that our card changed.
+func (s *Service) announceChange() {
+	ctx, cancel := context.WithTimeout(s.ctx, profileCardTimeout)
+	defer cancel()
+
+	friends, err := s.relationshipRepo.GetAcceptedRelations(ctx)
+	if err != nil {
+		log.Printf("Profile Card: Error loading friends: %v", err)
+		return
+	}
+
+	requestBytes, err := json.Marshal(types.ProfileCardRequest{Type: types.ProfileCardRequestChanged})
+	if err != nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, friend := range friends {
+		id, err := peer.Decode(friend.PeerID)
+		if err != nil || (*s.appState.Node).Network().Connectedness(id) != network.Connected {
+			continue
+		}
+
+		wg.Add(1)
+		go func(id peer.ID) {
+			defer wg.Done()
+
+			stream, err := (*s.appState.Node).NewStream(ctx, id, core.ProfileCardProtocolID)
+			if err != nil {
+				log.Printf("Profile Card: Could not tell %s about our new card: %v", id.ShortString(), err)
+				return
+			}
+			defer stream.Close()
+
+			if _, err := stream.Write(requestBytes); err != nil {
+				stream.Reset()
+			}
+		}(id)
+	}
+	wg.Wait()
+}
+
+func (s *Service) request(ctx context.Context, id peer.ID, request types.ProfileCardRequest) (*types.ProfileCardResponse, error) {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	stream, err := (*s.appState.Node).NewStream(ctx, id, core.ProfileCardProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(requestBytes); err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to write request: %w", err)
+	}
+	if err := stream.CloseWrite(); err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to close request: %w", err)
+	}
+
+	stream.SetReadDeadline(time.Now().Add(profileCardTimeout))
+	responseBytes, err := io.ReadAll(io.LimitReader(stream, profileCardResponseMaxSize))
+	if err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var response types.ProfileCardResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to deserialize response: %w", err)
+	}
+	return &response, nil
+}
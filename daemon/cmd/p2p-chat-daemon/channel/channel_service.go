@@ -0,0 +1,775 @@
+package channel
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/pubsub"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	channelNameMaxLength        = 100
+	channelDescriptionMaxLength = 1000
+	channelPostMaxLength        = 4000
+	channelReactionMaxLength    = 16
+
+	// channelInfoPostLimit is how many recent posts a new subscriber receives.
+	channelInfoPostLimit       = 100
+	channelPostsLimit          = 1000
+	channelInfoMaxRequestSize  = 4 * 1024
+	channelInfoMaxResponseSize = 16 * 1024 * 1024
+)
+
+var (
+	ErrInvalidChannelContent = errors.New("invalid channel content")
+	ErrNotChannelOwner       = errors.New("only the channel owner can do this")
+	ErrNotChannelAuthor      = errors.New("only channel authors can post")
+)
+
+// Service runs broadcast channels: read-only topics where only the authors named in the
+// owner's signed descriptor can post, while any subscriber can react.
+type Service struct {
+	appState      *core.AppState
+	bus           *bus.EventBus
+	pubSubService *pubsub.Service
+	channelRepo   storage.ChannelRepository
+	gater         pubsub.Gater
+	mu            sync.Mutex
+}
+
+// NewChannelService creates a new broadcast channel service
+func NewChannelService(
+	app *core.AppState,
+	bus *bus.EventBus,
+	pubSubService *pubsub.Service,
+	channelRepo storage.ChannelRepository,
+	gater pubsub.Gater) *Service {
+
+	return &Service{
+		appState:      app,
+		bus:           bus,
+		pubSubService: pubSubService,
+		channelRepo:   channelRepo,
+		gater:         gater,
+	}
+}
+
+// Register registers the channel protocol handler and joins every channel we follow
+func (s *Service) Register() {
+	log.Printf("Registering channel protocol handler (%s)...", core.ChannelInfoProtocolID)
+
+	(*s.appState.Node).SetStreamHandler(core.ChannelInfoProtocolID, s.handleChannelInfoStream)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	channels, err := s.channelRepo.GetChannels(ctx)
+	if err != nil {
+		log.Printf("Error getting channels: %v", err)
+		return
+	}
+
+	selfId := (*s.appState.Node).ID().String()
+	for _, channel := range channels {
+		if err := s.pubSubService.JoinChannelTopic(channel.ChannelId); err != nil {
+			log.Printf("Error joining channel %s: %v", channel.ChannelId, err)
+			continue
+		}
+
+		// Pick up descriptor changes made while we were offline.
+		if channel.OwnerPeerId != selfId {
+			go s.refreshChannel(channel)
+		}
+	}
+}
+
+// CreateChannel creates a channel owned by us, with us as its only author.
+func (s *Service) CreateChannel(name string, description string) (*types.Channel, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	selfId := (*s.appState.Node).ID().String()
+	channelId, _ := uuid.NewRandom()
+
+	data := types.ChannelDescriptorData{
+		ChannelId:   channelId.String(),
+		Name:        name,
+		Description: description,
+		OwnerPeerId: selfId,
+		Authors:     []string{selfId},
+		Version:     1,
+		UpdatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+
+	channel, _, err := s.signDescriptor(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.channelRepo.StoreChannel(ctx, *channel); err != nil {
+		return nil, err
+	}
+
+	if err := s.pubSubService.JoinChannelTopic(channel.ChannelId); err != nil {
+		return nil, err
+	}
+
+	log.Printf("CHANNEL: Created channel %s '%s'", channel.ChannelId, channel.Name)
+	return channel, nil
+}
+
+// UpdateChannelAuthors replaces the list of peers allowed to post. The owner always stays an author.
+func (s *Service) UpdateChannelAuthors(channelId string, authors []string) (*types.Channel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	current, err := s.channelRepo.GetChannel(ctx, channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.OwnerPeerId != (*s.appState.Node).ID().String() {
+		return nil, ErrNotChannelOwner
+	}
+
+	for _, author := range authors {
+		if _, err := peer.Decode(author); err != nil {
+			return nil, fmt.Errorf("%w: invalid author peer ID %s", ErrInvalidChannelContent, author)
+		}
+	}
+	if !slices.Contains(authors, current.OwnerPeerId) {
+		authors = append(authors, current.OwnerPeerId)
+	}
+
+	data := types.ChannelDescriptorData{
+		ChannelId:   current.ChannelId,
+		Name:        current.Name,
+		Description: current.Description,
+		OwnerPeerId: current.OwnerPeerId,
+		Authors:     authors,
+		Version:     current.Version + 1,
+		UpdatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+
+	channel, descriptorBytes, err := s.signDescriptor(data)
+	if err != nil {
+		return nil, err
+	}
+	channel.JoinedAt = current.JoinedAt
+
+	if err := s.channelRepo.StoreChannel(ctx, *channel); err != nil {
+		return nil, err
+	}
+
+	if err := s.publish(channelId, types.ChannelContentDescriptor, descriptorBytes); err != nil {
+		return nil, err
+	}
+
+	s.bus.PublishAsync(events.ChannelUpdatedEvent{Channel: *channel})
+	return channel, nil
+}
+
+// Subscribe starts following a channel. Its descriptor and recent posts are fetched from
+// viaPeerId, or from the owner if empty, and must be signed by ownerPeerId.
+func (s *Service) Subscribe(channelId string, ownerPeerId string, viaPeerId string) (*types.Channel, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	if viaPeerId == "" {
+		viaPeerId = ownerPeerId
+	}
+	viaPID, err := peer.Decode(viaPeerId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer ID %s: %w", viaPeerId, err)
+	}
+
+	response, err := s.requestChannelInfo(viaPID, channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := s.parseDescriptor(channelId, response.Descriptor)
+	if err != nil {
+		return nil, err
+	}
+	if channel.OwnerPeerId != ownerPeerId {
+		return nil, fmt.Errorf("%w: channel %s is owned by %s, not %s", ErrInvalidChannelContent, channelId, channel.OwnerPeerId, ownerPeerId)
+	}
+	channel.JoinedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := s.channelRepo.StoreChannel(ctx, *channel); err != nil {
+		return nil, err
+	}
+
+	for _, postBytes := range response.Posts {
+		if err := s.applyPost(ctx, channel, postBytes); err != nil {
+			log.Printf("CHANNEL: Skipping post of channel %s from %s: %v", channelId, viaPID.ShortString(), err)
+		}
+	}
+
+	if err := s.pubSubService.JoinChannelTopic(channelId); err != nil {
+		return nil, err
+	}
+
+	log.Printf("CHANNEL: Subscribed to channel %s '%s'", channel.ChannelId, channel.Name)
+	return channel, nil
+}
+
+// Unsubscribe stops following a channel and forgets its posts.
+func (s *Service) Unsubscribe(channelId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	channel, err := s.channelRepo.GetChannel(ctx, channelId)
+	if err != nil {
+		return err
+	}
+
+	if channel.OwnerPeerId == (*s.appState.Node).ID().String() {
+		return fmt.Errorf("cannot unsubscribe from a channel we own")
+	}
+
+	if err := s.pubSubService.LeaveTopic(core.ChannelTopic + channelId); err != nil {
+		log.Printf("CHANNEL: Error leaving channel %s: %v", channelId, err)
+	}
+
+	return s.channelRepo.DeleteChannel(ctx, channelId)
+}
+
+// Post publishes a message to a channel we are an author of.
+func (s *Service) Post(channelId string, message string) (*types.ChannelPost, error) {
+	if utf8.RuneCountInString(message) == 0 || utf8.RuneCountInString(message) > channelPostMaxLength {
+		return nil, fmt.Errorf("%w: post must be between 1 and %d characters", ErrInvalidChannelContent, channelPostMaxLength)
+	}
+
+	selfId := (*s.appState.Node).ID().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	isAuthor, err := s.channelRepo.IsAuthor(ctx, channelId, selfId)
+	if err != nil {
+		return nil, err
+	}
+	if !isAuthor {
+		return nil, ErrNotChannelAuthor
+	}
+
+	postId, _ := uuid.NewRandom()
+	now := time.Now()
+	data := types.ChannelPostData{
+		Id:           postId.String(),
+		ChannelId:    channelId,
+		AuthorPeerId: selfId,
+		Message:      message,
+		Time:         now.UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign post: %w", err)
+	}
+
+	postBytes, err := json.Marshal(types.SignedChannelPost{Data: data, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal post: %w", err)
+	}
+
+	post := types.ChannelPost{
+		Id:           data.Id,
+		ChannelId:    channelId,
+		AuthorPeerId: selfId,
+		Message:      message,
+		Time:         now,
+		Reactions:    map[string]int{},
+		Envelope:     postBytes,
+	}
+
+	if _, err := s.channelRepo.StorePost(ctx, post); err != nil {
+		return nil, err
+	}
+
+	if err := s.publish(channelId, types.ChannelContentPost, postBytes); err != nil {
+		return nil, err
+	}
+
+	s.bus.PublishAsync(events.ChannelPostAddedEvent{Post: post})
+	return &post, nil
+}
+
+// React publishes a reaction to a post. Every subscriber may react.
+func (s *Service) React(channelId string, postId string, reaction string) error {
+	if reaction == "" || utf8.RuneCountInString(reaction) > channelReactionMaxLength {
+		return fmt.Errorf("%w: reaction must be between 1 and %d characters", ErrInvalidChannelContent, channelReactionMaxLength)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := s.channelRepo.GetChannel(ctx, channelId); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	data := types.ChannelReactionData{
+		ChannelId:     channelId,
+		PostId:        postId,
+		ReactorPeerId: (*s.appState.Node).ID().String(),
+		Reaction:      reaction,
+		Time:          now.UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return fmt.Errorf("failed to sign reaction: %w", err)
+	}
+
+	reactionBytes, err := json.Marshal(types.SignedChannelReaction{Data: data, Signature: signature})
+	if err != nil {
+		return fmt.Errorf("failed to marshal reaction: %w", err)
+	}
+
+	stored := types.ChannelReaction{
+		ChannelId:     channelId,
+		PostId:        postId,
+		ReactorPeerId: data.ReactorPeerId,
+		Reaction:      reaction,
+		Time:          now,
+		Envelope:      reactionBytes,
+	}
+
+	isNew, err := s.channelRepo.StoreReaction(ctx, stored)
+	if err != nil {
+		return err
+	}
+	if !isNew {
+		return nil
+	}
+
+	if err := s.publish(channelId, types.ChannelContentReaction, reactionBytes); err != nil {
+		return err
+	}
+
+	s.bus.PublishAsync(events.ChannelReactionAddedEvent{Reaction: stored})
+	return nil
+}
+
+func (s *Service) GetChannels() ([]types.Channel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.channelRepo.GetChannels(ctx)
+}
+
+func (s *Service) GetPosts(channelId string) ([]types.ChannelPost, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.channelRepo.GetPosts(ctx, channelId, channelPostsLimit)
+}
+
+// ApplyChannelContent stores content received on a channel topic. The topic validator has
+// already checked its signature and that its signer may publish it.
+func (s *Service) ApplyChannelContent(channelId string, contentType string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	channel, err := s.channelRepo.GetChannel(ctx, channelId)
+	if err != nil {
+		return err
+	}
+
+	switch contentType {
+	case types.ChannelContentDescriptor:
+		return s.applyDescriptor(ctx, channel, payload)
+	case types.ChannelContentPost:
+		return s.applyPost(ctx, channel, payload)
+	case types.ChannelContentReaction:
+		return s.applyReaction(ctx, payload)
+	}
+
+	return fmt.Errorf("%w: unknown content type %q", ErrInvalidChannelContent, contentType)
+}
+
+func (s *Service) applyDescriptor(ctx context.Context, current *types.Channel, descriptorBytes []byte) error {
+	channel, err := s.parseDescriptor(current.ChannelId, descriptorBytes)
+	if err != nil {
+		return err
+	}
+
+	if channel.OwnerPeerId != current.OwnerPeerId {
+		return fmt.Errorf("%w: descriptor signed by %s, channel is owned by %s", ErrInvalidChannelContent, channel.OwnerPeerId, current.OwnerPeerId)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest, err := s.channelRepo.GetChannel(ctx, current.ChannelId)
+	if err != nil {
+		return err
+	}
+	if channel.Version <= latest.Version {
+		return nil
+	}
+	channel.JoinedAt = latest.JoinedAt
+
+	if err := s.channelRepo.StoreChannel(ctx, *channel); err != nil {
+		return err
+	}
+
+	s.bus.PublishAsync(events.ChannelUpdatedEvent{Channel: *channel})
+	return nil
+}
+
+func (s *Service) applyPost(ctx context.Context, channel *types.Channel, postBytes []byte) error {
+	var signed types.SignedChannelPost
+	if err := json.Unmarshal(postBytes, &signed); err != nil {
+		return fmt.Errorf("%w: malformed post: %v", ErrInvalidChannelContent, err)
+	}
+	data := signed.Data
+
+	if data.ChannelId != channel.ChannelId {
+		return fmt.Errorf("%w: post %s addressed to channel %s", ErrInvalidChannelContent, data.Id, data.ChannelId)
+	}
+	if !slices.Contains(channel.Authors, data.AuthorPeerId) {
+		return fmt.Errorf("%w: %s is not an author", ErrNotChannelAuthor, data.AuthorPeerId)
+	}
+	if utf8.RuneCountInString(data.Message) > channelPostMaxLength {
+		return fmt.Errorf("%w: post %s is too long", ErrInvalidChannelContent, data.Id)
+	}
+	if err := identity.VerifyPayload(data.AuthorPeerId, data, signed.Signature); err != nil {
+		return err
+	}
+	if s.isBlocked(data.AuthorPeerId) {
+		return fmt.Errorf("author %s is blocked", data.AuthorPeerId)
+	}
+
+	postTime, err := time.Parse(time.RFC3339, data.Time)
+	if err != nil {
+		return fmt.Errorf("%w: post %s has invalid time: %v", ErrInvalidChannelContent, data.Id, err)
+	}
+
+	post := types.ChannelPost{
+		Id:           data.Id,
+		ChannelId:    data.ChannelId,
+		AuthorPeerId: data.AuthorPeerId,
+		Message:      data.Message,
+		Time:         postTime,
+		Reactions:    map[string]int{},
+		Envelope:     postBytes,
+	}
+
+	isNew, err := s.channelRepo.StorePost(ctx, post)
+	if err != nil {
+		return err
+	}
+
+	if isNew {
+		s.bus.PublishAsync(events.ChannelPostAddedEvent{Post: post})
+	}
+	return nil
+}
+
+func (s *Service) applyReaction(ctx context.Context, reactionBytes []byte) error {
+	var signed types.SignedChannelReaction
+	if err := json.Unmarshal(reactionBytes, &signed); err != nil {
+		return fmt.Errorf("%w: malformed reaction: %v", ErrInvalidChannelContent, err)
+	}
+	data := signed.Data
+
+	if data.Reaction == "" || utf8.RuneCountInString(data.Reaction) > channelReactionMaxLength {
+		return fmt.Errorf("%w: reaction is empty or too long", ErrInvalidChannelContent)
+	}
+
+	if s.isBlocked(data.ReactorPeerId) {
+		return fmt.Errorf("reactor %s is blocked", data.ReactorPeerId)
+	}
+
+	reactionTime, err := time.Parse(time.RFC3339, data.Time)
+	if err != nil {
+		reactionTime = time.Now()
+	}
+
+	reaction := types.ChannelReaction{
+		ChannelId:     data.ChannelId,
+		PostId:        data.PostId,
+		ReactorPeerId: data.ReactorPeerId,
+		Reaction:      data.Reaction,
+		Time:          reactionTime,
+		Envelope:      reactionBytes,
+	}
+
+	isNew, err := s.channelRepo.StoreReaction(ctx, reaction)
+	if err != nil {
+		return err
+	}
+
+	if isNew {
+		s.bus.PublishAsync(events.ChannelReactionAddedEvent{Reaction: reaction})
+	}
+	return nil
+}
+
+func (s *Service) isBlocked(peerId string) bool {
+	id, err := peer.Decode(peerId)
+	return err == nil && s.gater.IsBlocked(id)
+}
+
+// refreshChannel fetches the current descriptor of a channel from its owner.
+func (s *Service) refreshChannel(channel types.Channel) {
+	ownerPID, err := peer.Decode(channel.OwnerPeerId)
+	if err != nil {
+		return
+	}
+
+	response, err := s.requestChannelInfo(ownerPID, channel.ChannelId)
+	if err != nil {
+		log.Printf("CHANNEL: Could not refresh channel %s from its owner: %v", channel.ChannelId, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := s.applyDescriptor(ctx, &channel, response.Descriptor); err != nil {
+		log.Printf("CHANNEL: Rejected descriptor of channel %s: %v", channel.ChannelId, err)
+		return
+	}
+
+	latest, err := s.channelRepo.GetChannel(ctx, channel.ChannelId)
+	if err != nil {
+		return
+	}
+	for _, postBytes := range response.Posts {
+		if err := s.applyPost(ctx, latest, postBytes); err != nil {
+			log.Printf("CHANNEL: Skipping post of channel %s: %v", channel.ChannelId, err)
+		}
+	}
+}
+
+// parseDescriptor verifies a signed descriptor and turns it into a channel.
+func (s *Service) parseDescriptor(channelId string, descriptorBytes []byte) (*types.Channel, error) {
+	var signed types.SignedChannelDescriptor
+	if err := json.Unmarshal(descriptorBytes, &signed); err != nil {
+		return nil, fmt.Errorf("%w: malformed descriptor: %v", ErrInvalidChannelContent, err)
+	}
+	data := signed.Data
+
+	if data.ChannelId != channelId {
+		return nil, fmt.Errorf("%w: descriptor of channel %s", ErrInvalidChannelContent, data.ChannelId)
+	}
+	if err := validateDescriptor(data); err != nil {
+		return nil, err
+	}
+	if err := identity.VerifyPayload(data.OwnerPeerId, data, signed.Signature); err != nil {
+		return nil, err
+	}
+
+	return &types.Channel{
+		ChannelId:   data.ChannelId,
+		Name:        data.Name,
+		Description: data.Description,
+		OwnerPeerId: data.OwnerPeerId,
+		Authors:     data.Authors,
+		Version:     data.Version,
+		Descriptor:  descriptorBytes,
+	}, nil
+}
+
+func (s *Service) signDescriptor(data types.ChannelDescriptorData) (*types.Channel, []byte, error) {
+	if err := validateDescriptor(data); err != nil {
+		return nil, nil, err
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign channel descriptor: %w", err)
+	}
+
+	descriptorBytes, err := json.Marshal(types.SignedChannelDescriptor{Data: data, Signature: signature})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal channel descriptor: %w", err)
+	}
+
+	return &types.Channel{
+		ChannelId:   data.ChannelId,
+		Name:        data.Name,
+		Description: data.Description,
+		OwnerPeerId: data.OwnerPeerId,
+		Authors:     data.Authors,
+		Version:     data.Version,
+		JoinedAt:    time.Now(),
+		Descriptor:  descriptorBytes,
+	}, descriptorBytes, nil
+}
+
+func validateDescriptor(data types.ChannelDescriptorData) error {
+	if data.Name == "" || utf8.RuneCountInString(data.Name) > channelNameMaxLength {
+		return fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidChannelContent, channelNameMaxLength)
+	}
+	if utf8.RuneCountInString(data.Description) > channelDescriptionMaxLength {
+		return fmt.Errorf("%w: description is longer than %d characters", ErrInvalidChannelContent, channelDescriptionMaxLength)
+	}
+	if !slices.Contains(data.Authors, data.OwnerPeerId) {
+		return fmt.Errorf("%w: the owner must be an author", ErrInvalidChannelContent)
+	}
+	if data.Version <= 0 {
+		return fmt.Errorf("%w: version must be positive", ErrInvalidChannelContent)
+	}
+	return nil
+}
+
+func (s *Service) publish(channelId string, contentType string, payload []byte) error {
+	envelopeBytes, err := json.Marshal(types.ChannelEnvelope{ContentType: contentType, Payload: payload})
+	if err != nil {
+		return fmt.Errorf("failed to marshal channel envelope: %w", err)
+	}
+
+	return s.pubSubService.Publish(envelopeBytes, core.ChannelTopic+channelId)
+}
+
+// handleChannelInfoStream serves the descriptor and recent posts of a channel we follow.
+// Channels are public, so anyone who knows the channel ID may ask.
+func (s *Service) handleChannelInfoStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	log.Printf("Channel Info: Received new stream from %s", peerID.ShortString())
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, channelInfoMaxRequestSize))
+	if err != nil {
+		log.Printf("Channel Info Handler: Error reading request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var request ChannelInfoRequest
+	if err := json.Unmarshal(receivedBytes, &request); err != nil {
+		log.Printf("Channel Info Handler: Error deserializing request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	channel, err := s.channelRepo.GetChannel(ctx, request.ChannelId)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Channel Info Handler: Error loading channel %s: %v", request.ChannelId, err)
+		}
+		stream.Reset()
+		return
+	}
+
+	posts, err := s.channelRepo.GetPosts(ctx, request.ChannelId, channelInfoPostLimit)
+	if err != nil {
+		log.Printf("Channel Info Handler: Error loading posts of channel %s: %v", request.ChannelId, err)
+		stream.Reset()
+		return
+	}
+
+	response := ChannelInfoResponse{Descriptor: channel.Descriptor, Posts: make([][]byte, 0, len(posts))}
+	for _, post := range posts {
+		response.Posts = append(response.Posts, post.Envelope)
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Channel Info Handler: Error marshaling response for %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Channel Info Handler: Error writing response to %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	stream.Close()
+}
+
+func (s *Service) requestChannelInfo(targetPID peer.ID, channelId string) (*ChannelInfoResponse, error) {
+	if err := s.connectToPeer(targetPID); err != nil {
+		return nil, err
+	}
+
+	requestBytes, err := json.Marshal(ChannelInfoRequest{ChannelId: channelId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal channel info request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.ChannelInfoProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(requestBytes); err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to write channel info request: %w", err)
+	}
+	stream.CloseWrite()
+
+	responseBytes, err := io.ReadAll(io.LimitReader(stream, channelInfoMaxResponseSize))
+	if err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to read channel info response: %w", err)
+	}
+
+	var response ChannelInfoResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse channel info response: %w", err)
+	}
+
+	return &response, nil
+}
+
+func (s *Service) connectToPeer(targetPID peer.ID) error {
+	if (*s.appState.Node).Network().Connectedness(targetPID) == network.Connected {
+		return nil
+	}
+
+	addrInfo := (*s.appState.Node).Peerstore().PeerInfo(targetPID)
+	if len(addrInfo.Addrs) == 0 {
+		return fmt.Errorf("cannot connect to peer %s: no known addresses", targetPID.ShortString())
+	}
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer connectCancel()
+
+	if err := (*s.appState.Node).Connect(connectCtx, addrInfo); err != nil {
+		return fmt.Errorf("failed to establish connection with peer %s: %w", targetPID.ShortString(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+)
+
+type Consumer struct {
+	appState       *core.AppState
+	bus            *bus.EventBus
+	ctx            context.Context
+	channelService *Service
+	eventsChan     chan interface{}
+}
+
+func NewConsumer(appState *core.AppState, eventBus *bus.EventBus, channelService *Service, ctx context.Context) (*Consumer, error) {
+	if appState == nil {
+		return nil, errors.New("appState is nil")
+	}
+	return &Consumer{appState: appState, bus: eventBus, ctx: ctx, channelService: channelService, eventsChan: make(chan interface{})}, nil
+}
+
+func (c *Consumer) Start() {
+	log.Println("channel consumer started")
+	c.bus.Subscribe(c.eventsChan, events.ChannelContentReceivedEvent{})
+
+	go c.listen()
+}
+
+func (c *Consumer) listen() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			log.Println("channel consumer stopped")
+			return
+
+		case event := <-c.eventsChan:
+			c.handleEvent(event)
+		}
+	}
+}
+
+func (c *Consumer) handleEvent(event interface{}) {
+	switch event := event.(type) {
+
+	case events.ChannelContentReceivedEvent:
+		if err := c.channelService.ApplyChannelContent(event.ChannelId, event.ContentType, event.Payload); err != nil {
+			log.Printf("Channel Consumer: Rejected %s on channel %s: %v", event.ContentType, event.ChannelId, err)
+		}
+		return
+	}
+}
@@ -0,0 +1,11 @@
+package channel
+
+// ChannelInfoRequest asks a peer that follows a channel for its descriptor and recent posts.
+type ChannelInfoRequest struct {
+	ChannelId string
+}
+
+type ChannelInfoResponse struct {
+	Descriptor []byte   // signed channel descriptor
+	Posts      [][]byte // signed posts, oldest first
+}
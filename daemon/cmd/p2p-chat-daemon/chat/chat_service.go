@@ -14,6 +14,7 @@ import (
 	"io"
 	"log"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/discovery"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
@@ -27,6 +28,13 @@ import (
 	"time"
 )
 
+// groupMaxParents caps how many concurrent heads a new group message references.
+const (
+	groupMaxParents = 10
+	// groupRequestMaxSize bounds a group creation request, which lists the invited members.
+	groupRequestMaxSize = 256 * 1024
+)
+
 type Service struct {
 	appState             *core.AppState
 	bus                  *bus.EventBus
@@ -36,10 +44,26 @@ type Service struct {
 	KeyRepository        storage.KeyRepository
 	messageRepository    storage.MessageRepository
 	pubSubService        *pubsub.Service
+	groupInvitationRepo  storage.GroupInvitationRepository
+	groupMetadataRepo    storage.GroupMetadataRepository
+	groupInviteRepo      storage.GroupInviteRepository
+	groupDirectoryRepo   storage.GroupDirectoryRepository
+	groupFileRepo        storage.GroupFileRepository
+	dhtDiscovery         *discovery.DHTDiscovery
 	groupChats           map[string][]string
+	syncingGroups        map[string]bool
+	memberSyncs          map[string]time.Time
+	fetchingFiles        map[string]bool
+	rendezvousTags       map[string][]string
+	gater                Gater
 	mu                   sync.Mutex
 }
 
+// Gater tells which peers are blocked.
+type Gater interface {
+	IsBlocked(id peer.ID) bool
+}
+
 // NewProtocolHandler creates a new chat protocol handler
 func NewProtocolHandler(
 	app *core.AppState,
@@ -49,7 +73,13 @@ func NewProtocolHandler(
 	groupMemberRepo storage.GroupMemberRepository,
 	keyRepo storage.KeyRepository,
 	pubSubService *pubsub.Service,
-	messageRepo storage.MessageRepository) *Service {
+	messageRepo storage.MessageRepository,
+	groupInvitationRepo storage.GroupInvitationRepository,
+	groupMetadataRepo storage.GroupMetadataRepository,
+	groupInviteRepo storage.GroupInviteRepository,
+	groupDirectoryRepo storage.GroupDirectoryRepository,
+	groupFileRepo storage.GroupFileRepository,
+	gater Gater) *Service {
 
 	return &Service{
 		appState:             app,
@@ -60,6 +90,16 @@ func NewProtocolHandler(
 		KeyRepository:        keyRepo,
 		pubSubService:        pubSubService,
 		messageRepository:    messageRepo,
+		groupInvitationRepo:  groupInvitationRepo,
+		groupMetadataRepo:    groupMetadataRepo,
+		groupInviteRepo:      groupInviteRepo,
+		groupDirectoryRepo:   groupDirectoryRepo,
+		groupFileRepo:        groupFileRepo,
+		syncingGroups:        make(map[string]bool),
+		memberSyncs:          make(map[string]time.Time),
+		fetchingFiles:        make(map[string]bool),
+		rendezvousTags:       make(map[string][]string),
+		gater:                gater,
 	}
 }
 
@@ -69,6 +109,24 @@ func (s *Service) Register() {
 
 	(*s.appState.Node).SetStreamHandler(core.ChatProtocolID, s.handleChatStream)
 	(*s.appState.Node).SetStreamHandler(core.GroupChatProtocolID, s.handleGroupRequest)
+	(*s.appState.Node).SetStreamHandler(core.GroupHistoryProtocolID, s.handleGroupHistoryStream)
+	(*s.appState.Node).SetStreamHandler(core.GroupInvitationResponseProtocolID, s.handleGroupInvitationResponseStream)
+	(*s.appState.Node).SetStreamHandler(core.GroupWelcomeProtocolID, s.handleGroupWelcomeStream)
+	(*s.appState.Node).SetStreamHandler(core.GroupEpochsProtocolID, s.handleGroupEpochsStream)
+	(*s.appState.Node).SetStreamHandler(core.GroupInviteProtocolID, s.handleGroupInviteStream)
+	(*s.appState.Node).SetStreamHandler(core.GroupDirectoryProtocolID, s.handleGroupDirectoryStream)
+	(*s.appState.Node).SetStreamHandler(core.GroupJoinRequestProtocolID, s.handleGroupJoinRequestStream)
+	(*s.appState.Node).SetStreamHandler(core.GroupFileProtocolID, s.handleGroupFileStream)
+	(*s.appState.Node).SetStreamHandler(core.GroupDeliveryProtocolID, s.handleGroupDeliveryStream)
+
+	s.profileService.RegisterOutboxSender(groupInvitationResponseOutbox, s.sendQueuedGroupInvitationResponse)
+
+	// Any group member coming online, friend or not, may hold messages we missed.
+	(*s.appState.Node).Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			go s.SyncGroupsWithMember(conn.RemotePeer().String())
+		},
+	})
 
 	s.startListeningToGroupChatMessages()
 }
@@ -89,6 +147,10 @@ func (s *Service) startListeningToGroupChatMessages() {
 	s.groupChats = groupChats
 
 	for id, _ := range s.groupChats {
+		if !s.groupKeyStoreService.HasGroupKeys(id) {
+			log.Printf("No keys for group chat %s, not listening", id)
+			continue
+		}
 		log.Printf("Starting to listen to group chat messages for topic %s", core.GroupChatTopic+id)
 		s.pubSubService.JoinTopic(core.GroupChatTopic+id, id)
 	}
@@ -149,7 +211,7 @@ func (s *Service) SendMessage(targetPeerId string, message string) error {
 	}
 
 	if s.appState.State != core.StateRunning || s.appState.Node == nil {
-		return errors.New(fmt.Sprintf(fmt.Sprintf("Node is not ready (state: %s)", s.appState.State)))
+		return fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
 	}
 
 	if targetPID == (*s.appState.Node).ID() {
@@ -236,21 +298,30 @@ func (s *Service) SendMessage(targetPeerId string, message string) error {
 	return nil
 }
 
-// CreateGroup creates a group
-func (s *Service) CreateGroup(peers []string, groupChatName string) error {
+// CreateGroup creates a group with the given peers and the friends in the given circles.
+func (s *Service) CreateGroup(peers []string, circleIds []string, groupChatName string) error {
+	if len(circleIds) > 0 {
+		circleMembers, err := s.profileService.CircleMembers(circleIds)
+		if err != nil {
+			return err
+		}
+		peers = mergeGroupPeers(peers, circleMembers)
+	}
+
 	id := uuid.New().String()
 
-	k, e := s.groupKeyStoreService.GenerateNewKey(id, groupChatName)
+	e := s.groupKeyStoreService.CreateGroup(id, groupChatName)
 
 	if e != nil {
-		log.Printf("GROUP Chat API: Error generating new key: %v", e)
+		log.Printf("GROUP Chat API: Error creating group keys: %v", e)
 		return e
 	}
 
+	members := append([]string{(*s.appState.Node).ID().String()}, peers...)
+
 	req := GroupChatRequest{
-		MemberPeers: peers,
+		MemberPeers: members,
 		Name:        groupChatName,
-		Key:         k,
 		Id:          id,
 	}
 
@@ -322,7 +393,7 @@ func (s *Service) CreateGroup(peers []string, groupChatName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err = s.groupMemberRepo.AddMembers(ctx, id, peers)
+	err = s.groupMemberRepo.AddMembers(ctx, id, members)
 
 	if err != nil {
 		log.Printf("GROUP Chat API: Error adding members to group: %v", err)
@@ -337,15 +408,46 @@ func (s *Service) CreateGroup(peers []string, groupChatName string) error {
 		return err
 	}
 
+	s.refreshGroupRendezvous(id)
 	return nil
 }
 
+// mergeGroupPeers appends the circle members not already listed.
+func mergeGroupPeers(peers []string, circleMembers []string) []string {
+	seen := map[string]struct{}{}
+	for _, p := range peers {
+		seen[p] = struct{}{}
+	}
+
+	for _, member := range circleMembers {
+		if _, dup := seen[member]; dup {
+			continue
+		}
+		seen[member] = struct{}{}
+		peers = append(peers, member)
+	}
+	return peers
+}
+
 // handleGroupRequest processes incoming group creation request
 func (s *Service) handleGroupRequest(stream network.Stream) {
 	peerID := stream.Conn().RemotePeer()
 	log.Printf("GroupRequest: Received new stream from %s", peerID.ShortString())
 
-	receivedBytes, err := io.ReadAll(stream)
+	isFriend, err := s.profileService.IsFriend(peerID.String())
+	if err != nil {
+		log.Printf("Group Request Handler: Error checking friendship with %s: %v", peerID.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	if !isFriend {
+		log.Printf("Group Request Handler: Rejecting group invitation from %s, they are not a friend", peerID.ShortString())
+		stream.Reset()
+		return
+	}
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupRequestMaxSize))
 
 	if err != nil {
 		log.Printf("Group Request Handler: Error reading group request from %s: %v", peerID.String(), err)
@@ -365,65 +467,98 @@ func (s *Service) handleGroupRequest(stream network.Stream) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err = s.KeyRepository.Store(ctx, types.GroupKey{
-		GroupId:   request.Id,
-		Key:       request.Key,
-		Name:      request.Name,
-		CreatedAt: time.Now(),
-	})
-
-	if err != nil {
-		log.Printf("Group Request Handler: Error storing group key: %v", err)
+	invitation := types.GroupInvitation{
+		GroupId:       request.Id,
+		InviterPeerId: peerID.String(),
+		Name:          request.Name,
+		MemberPeers:   request.MemberPeers,
+		Status:        types.GroupInvitationPending,
+		ReceivedAt:    time.Now(),
 	}
 
-	err = s.groupMemberRepo.AddMembers(ctx, request.Id, request.MemberPeers)
+	err = s.groupInvitationRepo.Store(ctx, invitation)
 
 	if err != nil {
-		log.Printf("Group Request Handler: Error adding members to group: %v", err)
+		log.Printf("Group Request Handler: Error storing group invitation: %v", err)
+		stream.Reset()
+		return
 	}
 
-	log.Printf("GROUP Chat API: joining topic: %s", core.GroupChatTopic+request.Id)
-
-	err = s.pubSubService.JoinTopic(core.GroupChatTopic+request.Id, request.Id)
-	if err != nil {
-		log.Printf("GROUP Chat API: Error joining topic: %v", err)
-	}
+	log.Printf("Group Request Handler: Stored invitation to group %s from %s, waiting for user decision", request.Id, peerID.ShortString())
+	s.bus.PublishAsync(events.GroupInvitationReceivedEvent{Invitation: invitation})
 
 	stream.Close()
 }
 
-func (s *Service) SendGroupMessage(groupId string, message string) error {
+// SendGroupMessage publishes a message to a group and reports which members it reached.
+func (s *Service) SendGroupMessage(groupId string, message string) (*types.GroupDeliveryReport, error) {
 	messageID, _ := uuid.NewRandom()
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	heads, err := s.messageRepository.GetGroupHeads(ctx, groupId, groupMaxParents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest group messages: %w", err)
+	}
+
 	pubSubMessage := types.GroupChatMessage{
+		Id:           messageID.String(),
+		GroupId:      groupId,
 		SenderPeerId: (*s.appState.Node).ID().String(),
 		Message:      message,
 		Time:         time.Now(),
-		Id:           messageID.String(),
+		Parents:      heads,
 	}
 
-	pubSubMessageBytes, err := json.Marshal(pubSubMessage)
+	hash, err := identity.HashPayload(pubSubMessage)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return nil, fmt.Errorf("failed to hash message: %w", err)
 	}
 
-	encryptedMessage, err := s.groupKeyStoreService.Encrypt(groupId, pubSubMessageBytes)
+	signature, err := identity.SignPayload(s.appState.PrivKey, pubSubMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
 
+	pubSubMessageBytes, err := json.Marshal(types.SignedGroupChatMessage{
+		Data:            pubSubMessage,
+		SenderSignature: signature,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to encrypt message: %w", err)
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	s.pubSubService.Publish(encryptedMessage, core.GroupChatTopic+groupId)
+	encryptedMessage, err := s.groupKeyStoreService.Encrypt(groupId, hash, pubSubMessageBytes)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
+	// A message nobody received yet is still kept; members pick it up through history sync.
+	report, err := s.publishGroupContent(groupId, encryptedMessage)
+	if report == nil {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("GROUP Chat: Message %s to group %s reached nobody: %v", pubSubMessage.Id, groupId, err)
+	}
+	report.MessageId = pubSubMessage.Id
 
 	mes := events.GroupChatMessage{
 		GroupId:      groupId,
+		MessageId:    pubSubMessage.Id,
+		Hash:         hash,
+		Parents:      pubSubMessage.Parents,
 		Message:      message,
-		SenderPeerId: (*s.appState.Node).ID().String(),
-		Time:         time.Now(),
+		SenderPeerId: pubSubMessage.SenderPeerId,
+		Time:         pubSubMessage.Time,
+		Envelope:     pubSubMessageBytes,
 	}
 	s.bus.PublishAsync(events.GroupChatMessageSentEvent{Message: mes})
+	s.bus.PublishAsync(events.GroupMessageDeliveredEvent{Report: *report})
 
-	return nil
+	return report, nil
 }
 
 func (s *Service) GetGroupMessages(groupId string) (GroupChatMessages, error) {
@@ -436,9 +571,19 @@ func (s *Service) GetGroupMessages(groupId string) (GroupChatMessages, error) {
 		return GroupChatMessages{}, err
 	}
 
+	parents, err := s.messageRepository.GetGroupMessageParents(ctx, groupId)
+	if err != nil {
+		return GroupChatMessages{}, err
+	}
+
+	missing, err := s.messageRepository.GetMissingParents(ctx, groupId)
+	if err != nil {
+		return GroupChatMessages{}, err
+	}
+
 	groupChatMessages := make([]GroupChatMessage, 0)
 
-	for _, m := range messages {
+	for _, m := range orderGroupMessages(messages, parents) {
 		decryptedMessage, err := crypto_utils.DecryptDataWithKey(
 			s.appState.DbKey,
 			m.EncryptedContent,
@@ -451,12 +596,13 @@ func (s *Service) GetGroupMessages(groupId string) (GroupChatMessages, error) {
 		}
 
 		groupChatMessages = append(groupChatMessages, GroupChatMessage{
+			Id:           m.MessageID,
 			SenderPeerId: m.SenderPeerID,
 			Time:         m.SentAt,
 			Message:      string(decryptedMessage),
 		})
 	}
-	return GroupChatMessages{Messages: groupChatMessages}, nil
+	return GroupChatMessages{Messages: groupChatMessages, MissingMessages: len(missing)}, nil
 }
 
 func (s *Service) GetMessages(peerId string) (Messages, error) {
@@ -499,5 +645,42 @@ func (s *Service) GetGroups() ([]storage.GroupInfo, error) {
 		return nil, err
 	}
 
+	metadata, err := s.groupMetadataRepo.GetAll(context.Background())
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, g := range groups {
+		m, ok := metadata[g.GroupID]
+		if !ok {
+			continue
+		}
+		groups[i].Name = m.Name
+		groups[i].Description = m.Description
+		groups[i].AvatarHash = m.AvatarHash
+	}
+
 	return groups, nil
 }
+
+// connectToPeer makes sure a connection to the target exists, dialing known addresses when needed.
+func (s *Service) connectToPeer(targetPID peer.ID) error {
+	if (*s.appState.Node).Network().Connectedness(targetPID) == network.Connected {
+		return nil
+	}
+
+	addrInfo := (*s.appState.Node).Peerstore().PeerInfo(targetPID)
+	if len(addrInfo.Addrs) == 0 {
+		return fmt.Errorf("cannot connect to peer %s: no known addresses", targetPID.ShortString())
+	}
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer connectCancel()
+
+	if err := (*s.appState.Node).Connect(connectCtx, addrInfo); err != nil {
+		return fmt.Errorf("failed to establish connection with peer %s: %w", targetPID.ShortString(), err)
+	}
+
+	return nil
+}
@@ -14,18 +14,19 @@ import (
 )
 
 type Consumer struct {
-	appState   *core.AppState
-	bus        *bus.EventBus
-	ctx        context.Context
-	chatRepo   storage.MessageRepository
-	eventsChan chan interface{}
+	appState    *core.AppState
+	bus         *bus.EventBus
+	ctx         context.Context
+	chatRepo    storage.MessageRepository
+	chatService *Service
+	eventsChan  chan interface{}
 }
 
-func NewConsumer(appState *core.AppState, eventBus *bus.EventBus, repo storage.MessageRepository, ctx context.Context) (*Consumer, error) {
+func NewConsumer(appState *core.AppState, eventBus *bus.EventBus, repo storage.MessageRepository, chatService *Service, ctx context.Context) (*Consumer, error) {
 	if appState == nil {
 		return nil, errors.New("appState is nil")
 	}
-	return &Consumer{appState: appState, bus: eventBus, ctx: ctx, chatRepo: repo, eventsChan: make(chan interface{})}, nil
+	return &Consumer{appState: appState, bus: eventBus, ctx: ctx, chatRepo: repo, chatService: chatService, eventsChan: make(chan interface{})}, nil
 }
 
 func (c *Consumer) Start() {
@@ -34,6 +35,12 @@ func (c *Consumer) Start() {
 	c.bus.Subscribe(c.eventsChan, events.MessageReceivedEvent{})
 	c.bus.Subscribe(c.eventsChan, events.GroupChatMessageReceivedEvent{})
 	c.bus.Subscribe(c.eventsChan, events.GroupChatMessageSentEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupEpochChangedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupEpochBehindEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupMetadataReceivedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupInviteRevocationReceivedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.GroupFileAnnouncedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendRemovedEvent{})
 
 	go c.listen()
 }
@@ -73,6 +80,49 @@ func (c *Consumer) handleEvent(event interface{}) {
 		log.Println("received group chat message received event")
 		c.handleGroupChatMessageSentEvent(event.Message)
 		return
+
+	case events.GroupEpochChangedEvent:
+		log.Printf("received group epoch changed event for group %s", event.GroupId)
+		c.chatService.ApplyGroupEpochChange(event)
+		return
+
+	case events.GroupEpochBehindEvent:
+		log.Printf("group %s is ahead of our keys, catching up", event.GroupId)
+		go c.chatService.SyncGroupHistory(event.GroupId)
+		return
+
+	case events.GroupMetadataReceivedEvent:
+		if err := c.chatService.ApplyGroupMetadata(event.GroupId, event.SenderPeerId, event.Update); err != nil {
+			log.Printf("Chat Consumer: Rejected metadata update for group %s: %v", event.GroupId, err)
+		}
+		return
+
+	case events.GroupInviteRevocationReceivedEvent:
+		if err := c.chatService.ApplyGroupInviteRevocation(event.GroupId, event.SenderPeerId, event.Revocation); err != nil {
+			log.Printf("Chat Consumer: Rejected invite revocation for group %s: %v", event.GroupId, err)
+		}
+		return
+
+	case events.GroupFileAnnouncedEvent:
+		if err := c.chatService.ApplyGroupFileAnnouncement(event.GroupId, event.SenderPeerId, event.Announcement); err != nil {
+			log.Printf("Chat Consumer: Rejected file announcement for group %s: %v", event.GroupId, err)
+		}
+		return
+
+	case events.FriendRemovedEvent:
+		if event.PurgeHistory {
+			c.handleFriendHistoryPurge(event.PeerId)
+		}
+		return
+	}
+}
+
+func (c *Consumer) handleFriendHistoryPurge(peerId string) {
+	storeCtx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	if _, err := c.chatRepo.DeleteMessagesByPeerID(storeCtx, peerId); err != nil {
+		log.Printf("Chat Consumer: Error purging conversation with %s: %v", peerId, err)
 	}
 }
 
@@ -128,11 +178,29 @@ func (c *Consumer) SaveGroupChatMessage(event events.GroupChatMessage) {
 		return
 	}
 
+	var encryptedEnvelope []byte
+	if event.Envelope != nil {
+		encryptedEnvelope, err = crypto_utils.EncryptDataWithKey(c.appState.DbKey, event.Envelope, core.DefaultCryptoConfig)
+		if err != nil {
+			log.Printf("Chat Consumer: ERROR - Failed to encrypt group chat message envelope: %v", err)
+			return
+		}
+	}
+
+	sentAt := event.Time
+	if sentAt.IsZero() {
+		sentAt = time.Now()
+	}
+
 	msg := types.StoredGroupMessage{
-		GroupID:          event.GroupId,
-		SenderPeerID:     event.SenderPeerId,
-		EncryptedContent: encryptedMesasge,
-		SentAt:           time.Now(),
+		GroupID:           event.GroupId,
+		MessageID:         event.MessageId,
+		Hash:              event.Hash,
+		Parents:           event.Parents,
+		SenderPeerID:      event.SenderPeerId,
+		EncryptedContent:  encryptedMesasge,
+		EncryptedEnvelope: encryptedEnvelope,
+		SentAt:            sentAt,
 	}
 	err = c.chatRepo.StoreGroupMessage(storeCtx, msg)
 
@@ -140,4 +208,19 @@ func (c *Consumer) SaveGroupChatMessage(event events.GroupChatMessage) {
 		log.Printf("Chat Consumer: ERROR - Failed to store group chat message: %v", err)
 		return
 	}
+
+	if len(event.Parents) == 0 {
+		return
+	}
+
+	missing, err := c.chatRepo.GetMissingParents(storeCtx, event.GroupId)
+	if err != nil {
+		log.Printf("Chat Consumer: ERROR - Failed to check group %s for gaps: %v", event.GroupId, err)
+		return
+	}
+
+	if len(missing) > 0 {
+		log.Printf("Chat Consumer: Group %s is missing %d message(s), requesting history", event.GroupId, len(missing))
+		go c.chatService.SyncGroupHistory(event.GroupId)
+	}
 }
@@ -0,0 +1,187 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// groupDirectFanoutMaxMembers is the largest group that gets direct streams to members
+	// outside the topic mesh. Larger groups rely on gossip alone.
+	groupDirectFanoutMaxMembers = 32
+	groupDirectDeliveryTimeout  = 15 * time.Second
+	groupDirectDeliveryMaxSize  = 1 << 20
+)
+
+// publishGroupContent publishes sealed content on the group topic and, for small groups,
+// sends it over a direct stream to every member that is not in the topic mesh. Receivers
+// drop whichever copy arrives second.
+func (s *Service) publishGroupContent(groupId string, sealed []byte) (*types.GroupDeliveryReport, error) {
+	topicName := core.GroupChatTopic + groupId
+	report := &types.GroupDeliveryReport{
+		GroupId:   groupId,
+		Gossip:    []string{},
+		Direct:    []string{},
+		Unreached: []string{},
+	}
+
+	meshPeers := make(map[string]bool)
+	publishErr := s.pubSubService.Publish(sealed, topicName)
+	if publishErr != nil {
+		log.Printf("GROUP Delivery: Error publishing to group %s: %v", groupId, publishErr)
+	} else {
+		for _, pid := range s.pubSubService.TopicPeers(topicName) {
+			meshPeers[pid.String()] = true
+		}
+	}
+
+	members, err := s.groupKeyStoreService.TreeMembers(groupId)
+	if err != nil || len(members) == 0 {
+		members, err = s.groupMemberRepo.GetMembers(context.Background(), groupId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	selfId := (*s.appState.Node).ID().String()
+	var outside []string
+	for _, member := range members {
+		if member == selfId {
+			continue
+		}
+		if meshPeers[member] {
+			report.Gossip = append(report.Gossip, member)
+		} else {
+			outside = append(outside, member)
+		}
+	}
+
+	if len(members) > groupDirectFanoutMaxMembers {
+		report.Unreached = append(report.Unreached, outside...)
+		return report, publishErr
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, member := range outside {
+		wg.Add(1)
+		go func(member string) {
+			defer wg.Done()
+
+			err := s.sendGroupDirectDelivery(member, GroupDirectDelivery{GroupId: groupId, Data: sealed})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("GROUP Delivery: Could not reach %s directly for group %s: %v", member, groupId, err)
+				report.Unreached = append(report.Unreached, member)
+				return
+			}
+			report.Direct = append(report.Direct, member)
+		}(member)
+	}
+	wg.Wait()
+
+	if publishErr != nil && len(report.Direct) == 0 && len(members) > 1 {
+		return report, publishErr
+	}
+
+	log.Printf("GROUP Delivery: Group %s content reached %d members by gossip, %d directly, %d unreached",
+		groupId, len(report.Gossip), len(report.Direct), len(report.Unreached))
+	return report, nil
+}
+
+func (s *Service) sendGroupDirectDelivery(targetPeerId string, delivery GroupDirectDelivery) error {
+	targetPID, err := peer.Decode(targetPeerId)
+	if err != nil {
+		return fmt.Errorf("invalid peer ID: %w", err)
+	}
+
+	deliveryBytes, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), groupDirectDeliveryTimeout)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.GroupDeliveryProtocolID)
+	if err != nil {
+		return fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	stream.SetDeadline(time.Now().Add(groupDirectDeliveryTimeout))
+
+	if _, err := stream.Write(deliveryBytes); err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to write delivery: %w", err)
+	}
+	stream.CloseWrite()
+
+	responseBytes, err := io.ReadAll(io.LimitReader(stream, groupDirectDeliveryMaxSize))
+	if err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to read delivery response: %w", err)
+	}
+
+	var response GroupDirectDeliveryResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return fmt.Errorf("failed to parse delivery response: %w", err)
+	}
+	if !response.Accepted {
+		return fmt.Errorf("delivery refused: %s", response.Error)
+	}
+
+	return nil
+}
+
+// handleGroupDeliveryStream accepts group content a member sent us directly because we were
+// not in its topic mesh.
+func (s *Service) handleGroupDeliveryStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupDirectDeliveryMaxSize))
+	if err != nil {
+		log.Printf("Group Delivery Handler: Error reading delivery from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var delivery GroupDirectDelivery
+	if err := json.Unmarshal(receivedBytes, &delivery); err != nil {
+		log.Printf("Group Delivery Handler: Error deserializing delivery from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	response := GroupDirectDeliveryResponse{Accepted: true}
+	if err := s.pubSubService.DeliverGroupContent(delivery.GroupId, peerID, delivery.Data); err != nil {
+		response = GroupDirectDeliveryResponse{Error: err.Error()}
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Group Delivery Handler: Error marshaling response for %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Group Delivery Handler: Error writing response to %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+	stream.Close()
+}
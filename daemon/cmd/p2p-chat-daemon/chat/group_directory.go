@@ -0,0 +1,609 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/discovery"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+const (
+	// groupDirectoryNamespace is advertised on the DHT by every peer publishing a public group.
+	groupDirectoryNamespace = "p2p-chat/public-groups"
+	// groupDirectoryGroupPrefix is advertised per public group, so requests to join can reach
+	// any publishing member.
+	groupDirectoryGroupPrefix = "p2p-chat/public-group/"
+
+	groupDirectoryProviderLimit = 20
+	groupDirectoryMaxSize       = 1 << 20
+	groupJoinRequestMaxSize     = 64 * 1024
+	groupJoinRequestMaxAge      = 10 * time.Minute
+	groupJoinMessageMaxLength   = 500
+)
+
+var ErrInvalidGroupJoinRequest = errors.New("invalid group join request")
+
+// SetDHTDiscovery hands the service the DHT once the node is up, and starts advertising the
+// groups we are a member of and the groups we published.
+func (s *Service) SetDHTDiscovery(dhtDiscovery *discovery.DHTDiscovery) {
+	s.mu.Lock()
+	s.dhtDiscovery = dhtDiscovery
+	s.mu.Unlock()
+
+	if dhtDiscovery == nil {
+		return
+	}
+
+	s.startGroupRendezvous(dhtDiscovery)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	groupIds, err := s.groupDirectoryRepo.GetPublished(ctx)
+	if err != nil {
+		log.Printf("GROUP Directory: Error getting published groups: %v", err)
+		return
+	}
+
+	for _, groupId := range groupIds {
+		dhtDiscovery.AddNamespace(groupDirectoryNamespace)
+		dhtDiscovery.AddNamespace(groupDirectoryGroupPrefix + groupId)
+	}
+}
+
+// SetGroupPublic opts a group in or out of the public directory. Public groups are
+// advertised on the DHT and accept requests to join from anyone.
+func (s *Service) SetGroupPublic(groupId string, public bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if !public {
+		if err := s.groupDirectoryRepo.Unpublish(ctx, groupId); err != nil {
+			return err
+		}
+		if dhtDiscovery := s.getDHTDiscovery(); dhtDiscovery != nil {
+			dhtDiscovery.RemoveNamespace(groupDirectoryGroupPrefix + groupId)
+			if published, err := s.groupDirectoryRepo.GetPublished(ctx); err == nil && len(published) == 0 {
+				dhtDiscovery.RemoveNamespace(groupDirectoryNamespace)
+			}
+		}
+		return nil
+	}
+
+	if err := s.requireGroupAdmin(ctx, groupId); err != nil {
+		return err
+	}
+
+	if err := s.groupDirectoryRepo.Publish(ctx, groupId); err != nil {
+		return err
+	}
+
+	if dhtDiscovery := s.getDHTDiscovery(); dhtDiscovery != nil {
+		dhtDiscovery.AddNamespace(groupDirectoryNamespace)
+		dhtDiscovery.AddNamespace(groupDirectoryGroupPrefix + groupId)
+	}
+
+	log.Printf("GROUP Directory: Group %s is now public", groupId)
+	return nil
+}
+
+// BrowseGroupDirectory returns the public groups whose name or description contains query.
+// With refresh set, peers advertising public groups are asked for their current descriptors
+// first; otherwise only groups seen earlier are searched.
+func (s *Service) BrowseGroupDirectory(query string, refresh bool) ([]types.GroupDirectoryListing, error) {
+	if refresh {
+		if err := s.refreshGroupDirectory(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.groupDirectoryRepo.SearchListings(ctx, query)
+}
+
+// RequestToJoinGroup asks a member of a public group to add us. The publisher of the
+// listing is tried first, then any other member advertising the group. A member that
+// approves the request sends a welcome, as for a regular invitation.
+func (s *Service) RequestToJoinGroup(groupId string, message string) (*types.GroupInvitation, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	if utf8.RuneCountInString(message) > groupJoinMessageMaxLength {
+		return nil, fmt.Errorf("%w: message longer than %d characters", ErrInvalidGroupJoinRequest, groupJoinMessageMaxLength)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	listing, err := s.groupDirectoryRepo.GetListing(ctx, groupId)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.groupKeyStoreService.HasGroupKeys(groupId) {
+		return nil, fmt.Errorf("already a member of group %s", groupId)
+	}
+
+	keyPackage, err := s.groupKeyStoreService.NewKeyPackage(groupId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key package: %w", err)
+	}
+
+	data := types.GroupJoinRequestData{
+		GroupId:         groupId,
+		RequesterPeerId: (*s.appState.Node).ID().String(),
+		Message:         message,
+		KeyPackage:      *keyPackage,
+		Timestamp:       time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+	request := types.SignedGroupJoinRequest{Data: data, Signature: signature}
+
+	targets := []peer.AddrInfo{}
+	if publisherPID, err := peer.Decode(listing.PublisherPeerId); err == nil {
+		targets = append(targets, peer.AddrInfo{ID: publisherPID})
+	}
+	if dhtDiscovery := s.getDHTDiscovery(); dhtDiscovery != nil {
+		findCtx, findCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		providers, err := dhtDiscovery.FindProviders(findCtx, groupDirectoryGroupPrefix+groupId, groupDirectoryProviderLimit)
+		findCancel()
+		if err != nil {
+			log.Printf("GROUP Directory: Error finding members of group %s: %v", groupId, err)
+		}
+		for _, provider := range providers {
+			if provider.ID.String() != listing.PublisherPeerId {
+				targets = append(targets, provider)
+			}
+		}
+	}
+
+	lastErr := fmt.Errorf("no member of group %s could be reached", groupId)
+	for _, target := range targets {
+		(*s.appState.Node).Peerstore().AddAddrs(target.ID, target.Addrs, peerstore.TempAddrTTL)
+
+		response, err := s.sendGroupJoinRequest(target.ID, request)
+		if err == nil && !response.Queued {
+			err = fmt.Errorf("%w: %s", ErrInvalidGroupJoinRequest, response.Error)
+		}
+		if err != nil {
+			log.Printf("GROUP Directory: Join request for group %s to %s failed: %v", groupId, target.ID.ShortString(), err)
+			lastErr = err
+			continue
+		}
+
+		invitation := types.GroupInvitation{
+			GroupId:       groupId,
+			InviterPeerId: target.ID.String(),
+			Name:          response.Name,
+			MemberPeers:   response.Members,
+			Status:        types.GroupInvitationAccepted,
+			ReceivedAt:    time.Now(),
+		}
+		if invitation.MemberPeers == nil {
+			invitation.MemberPeers = []string{}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = s.groupInvitationRepo.Replace(ctx, invitation)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("GROUP Directory: %s queued our request to join group %s", target.ID.ShortString(), groupId)
+		return &invitation, nil
+	}
+
+	return nil, lastErr
+}
+
+// GetGroupJoinRequests lists the pending requests to join our public groups, of a single
+// group if groupId is set.
+func (s *Service) GetGroupJoinRequests(groupId string) ([]types.GroupJoinRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.groupDirectoryRepo.GetJoinRequests(ctx, groupId, types.GroupInvitationPending)
+}
+
+// RespondToGroupJoinRequest approves or declines a pending request to join a public group.
+// Approving adds the requester to the key tree and welcomes them.
+func (s *Service) RespondToGroupJoinRequest(groupId string, peerId string, isAccepted bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	request, err := s.groupDirectoryRepo.GetJoinRequest(ctx, groupId, peerId)
+	if err != nil {
+		return err
+	}
+	if request.Status != types.GroupInvitationPending {
+		return fmt.Errorf("join request of %s for group %s was already answered", peerId, groupId)
+	}
+
+	if !isAccepted {
+		return s.groupDirectoryRepo.UpdateJoinRequestStatus(ctx, groupId, peerId, types.GroupInvitationDeclined)
+	}
+
+	if err := s.requireGroupAdmin(ctx, groupId); err != nil {
+		return err
+	}
+
+	var keyPackage types.GroupKeyPackage
+	if err := json.Unmarshal(request.KeyPackage, &keyPackage); err != nil {
+		return fmt.Errorf("failed to parse key package of %s: %w", peerId, err)
+	}
+
+	if err := s.groupDirectoryRepo.UpdateJoinRequestStatus(ctx, groupId, peerId, types.GroupInvitationAccepted); err != nil {
+		return err
+	}
+
+	log.Printf("GROUP Directory: Approved request of %s to join group %s", peerId, groupId)
+	go s.addGroupMember(groupId, keyPackage)
+	return nil
+}
+
+func (s *Service) refreshGroupDirectory() error {
+	dhtDiscovery := s.getDHTDiscovery()
+	if dhtDiscovery == nil {
+		return fmt.Errorf("DHT is not ready")
+	}
+
+	findCtx, findCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	providers, err := dhtDiscovery.FindProviders(findCtx, groupDirectoryNamespace, groupDirectoryProviderLimit)
+	findCancel()
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, provider := range providers {
+		wg.Add(1)
+		go func(pi peer.AddrInfo) {
+			defer wg.Done()
+			(*s.appState.Node).Peerstore().AddAddrs(pi.ID, pi.Addrs, peerstore.TempAddrTTL)
+
+			descriptors, err := s.requestGroupDirectory(pi.ID, GroupDirectoryRequest{})
+			if err != nil {
+				log.Printf("GROUP Directory: Error getting public groups of %s: %v", pi.ID.ShortString(), err)
+				return
+			}
+
+			for _, descriptorBytes := range descriptors {
+				if err := s.storeGroupDirectoryListing(pi.ID.String(), descriptorBytes); err != nil {
+					log.Printf("GROUP Directory: Discarding descriptor from %s: %v", pi.ID.ShortString(), err)
+				}
+			}
+		}(provider)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (s *Service) storeGroupDirectoryListing(senderPeerId string, descriptorBytes []byte) error {
+	var descriptor types.GroupDirectoryDescriptor
+	if err := json.Unmarshal(descriptorBytes, &descriptor); err != nil {
+		return fmt.Errorf("malformed descriptor: %w", err)
+	}
+	data := descriptor.Data
+
+	if data.PublisherPeerId != senderPeerId {
+		return fmt.Errorf("descriptor published by %s was served by %s", data.PublisherPeerId, senderPeerId)
+	}
+	if data.GroupId == "" || data.Topic != core.GroupChatTopic+data.GroupId {
+		return fmt.Errorf("descriptor of group %s has topic %s", data.GroupId, data.Topic)
+	}
+	if utf8.RuneCountInString(data.Name) > groupNameMaxLength || utf8.RuneCountInString(data.Description) > groupDescriptionMaxLength {
+		return fmt.Errorf("descriptor of group %s is too long", data.GroupId)
+	}
+	if err := identity.VerifyPayload(data.PublisherPeerId, data, descriptor.Signature); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.groupDirectoryRepo.StoreListing(ctx, types.GroupDirectoryListing{
+		GroupId:         data.GroupId,
+		Name:            data.Name,
+		Description:     data.Description,
+		Topic:           data.Topic,
+		MemberCount:     data.MemberCount,
+		PublisherPeerId: data.PublisherPeerId,
+		SeenAt:          time.Now(),
+		Descriptor:      descriptorBytes,
+	})
+}
+
+// handleGroupDirectoryStream serves signed descriptors of the public groups we publish.
+func (s *Service) handleGroupDirectoryStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	log.Printf("GroupDirectory: Received new stream from %s", peerID.ShortString())
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupJoinRequestMaxSize))
+	if err != nil {
+		log.Printf("Group Directory Handler: Error reading request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var request GroupDirectoryRequest
+	if err := json.Unmarshal(receivedBytes, &request); err != nil {
+		log.Printf("Group Directory Handler: Error deserializing request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	groupIds, err := s.groupDirectoryRepo.GetPublished(ctx)
+	if err != nil {
+		log.Printf("Group Directory Handler: Error getting published groups: %v", err)
+		stream.Reset()
+		return
+	}
+
+	response := GroupDirectoryResponse{Descriptors: [][]byte{}}
+	for _, groupId := range groupIds {
+		if request.GroupId != "" && request.GroupId != groupId {
+			continue
+		}
+		descriptorBytes, err := s.buildGroupDirectoryDescriptor(ctx, groupId)
+		if err != nil {
+			log.Printf("Group Directory Handler: Error describing group %s: %v", groupId, err)
+			continue
+		}
+		response.Descriptors = append(response.Descriptors, descriptorBytes)
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Group Directory Handler: Error marshaling response for %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Group Directory Handler: Error writing response to %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+	stream.Close()
+}
+
+func (s *Service) buildGroupDirectoryDescriptor(ctx context.Context, groupId string) ([]byte, error) {
+	metadata, err := s.getGroupMetadata(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.groupKeyStoreService.TreeMembers(groupId)
+	if err != nil || len(members) == 0 {
+		members, err = s.groupMemberRepo.GetMembers(ctx, groupId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data := types.GroupDirectoryDescriptorData{
+		GroupId:         groupId,
+		Name:            metadata.Name,
+		Description:     metadata.Description,
+		Topic:           core.GroupChatTopic + groupId,
+		MemberCount:     len(members),
+		PublisherPeerId: (*s.appState.Node).ID().String(),
+		PublishedAt:     time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(types.GroupDirectoryDescriptor{Data: data, Signature: signature})
+}
+
+// handleGroupJoinRequestStream queues a request to join one of our public groups until the
+// user approves or declines it.
+func (s *Service) handleGroupJoinRequestStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	log.Printf("GroupJoinRequest: Received new stream from %s", peerID.ShortString())
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupJoinRequestMaxSize))
+	if err != nil {
+		log.Printf("Group Join Request Handler: Error reading request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var request types.SignedGroupJoinRequest
+	if err := json.Unmarshal(receivedBytes, &request); err != nil {
+		log.Printf("Group Join Request Handler: Error deserializing request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response := GroupJoinRequestResponse{Queued: true}
+	joinRequest, err := s.validateGroupJoinRequest(ctx, peerID.String(), request)
+	if err == nil {
+		err = s.groupDirectoryRepo.StoreJoinRequest(ctx, *joinRequest)
+	}
+	if err != nil {
+		log.Printf("Group Join Request Handler: Refusing request from %s: %v", peerID.ShortString(), err)
+		response = GroupJoinRequestResponse{Error: err.Error()}
+	} else {
+		if metadata, err := s.getGroupMetadata(ctx, joinRequest.GroupId); err == nil {
+			response.Name = metadata.Name
+		}
+		response.Members, _ = s.groupKeyStoreService.TreeMembers(joinRequest.GroupId)
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Group Join Request Handler: Error marshaling response for %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Group Join Request Handler: Error writing response to %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+	stream.Close()
+
+	if response.Queued {
+		s.bus.PublishAsync(events.GroupJoinRequestReceivedEvent{Request: *joinRequest})
+	}
+}
+
+func (s *Service) validateGroupJoinRequest(ctx context.Context, senderPeerId string, request types.SignedGroupJoinRequest) (*types.GroupJoinRequest, error) {
+	data := request.Data
+
+	if data.RequesterPeerId != senderPeerId {
+		return nil, fmt.Errorf("%w: request was signed by %s", ErrInvalidGroupJoinRequest, data.RequesterPeerId)
+	}
+	if err := identity.VerifyPayload(data.RequesterPeerId, data, request.Signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGroupJoinRequest, err)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, data.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid timestamp: %v", ErrInvalidGroupJoinRequest, err)
+	}
+	if age := time.Since(timestamp); age > groupJoinRequestMaxAge || age < -groupJoinRequestMaxAge {
+		return nil, fmt.Errorf("%w: stale request", ErrInvalidGroupJoinRequest)
+	}
+
+	if utf8.RuneCountInString(data.Message) > groupJoinMessageMaxLength {
+		return nil, fmt.Errorf("%w: message too long", ErrInvalidGroupJoinRequest)
+	}
+
+	keyPackage := data.KeyPackage
+	if keyPackage.Data.PeerId != senderPeerId || keyPackage.Data.GroupId != data.GroupId {
+		return nil, fmt.Errorf("%w: key package does not belong to the sender", ErrInvalidGroupJoinRequest)
+	}
+	if err := identity.VerifyKeyPackage(keyPackage); err != nil {
+		return nil, err
+	}
+
+	published, err := s.groupDirectoryRepo.IsPublished(ctx, data.GroupId)
+	if err != nil {
+		return nil, err
+	}
+	if !published {
+		return nil, fmt.Errorf("%w: group is not public", ErrInvalidGroupJoinRequest)
+	}
+
+	if err := s.requireGroupAdmin(ctx, data.GroupId); err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupMemberRepo.IsMember(ctx, data.GroupId, senderPeerId)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		return nil, fmt.Errorf("%w: already a member", ErrInvalidGroupJoinRequest)
+	}
+
+	keyPackageBytes, err := json.Marshal(keyPackage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.GroupJoinRequest{
+		GroupId:    data.GroupId,
+		PeerId:     senderPeerId,
+		Message:    data.Message,
+		Status:     types.GroupInvitationPending,
+		ReceivedAt: time.Now(),
+		KeyPackage: keyPackageBytes,
+	}, nil
+}
+
+func (s *Service) requestGroupDirectory(targetPID peer.ID, request GroupDirectoryRequest) ([][]byte, error) {
+	var response GroupDirectoryResponse
+	if err := s.exchangeGroupDirectory(targetPID, core.GroupDirectoryProtocolID, request, &response, groupDirectoryMaxSize); err != nil {
+		return nil, err
+	}
+	return response.Descriptors, nil
+}
+
+func (s *Service) sendGroupJoinRequest(targetPID peer.ID, request types.SignedGroupJoinRequest) (*GroupJoinRequestResponse, error) {
+	var response GroupJoinRequestResponse
+	if err := s.exchangeGroupDirectory(targetPID, core.GroupJoinRequestProtocolID, request, &response, groupJoinRequestMaxSize); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (s *Service) exchangeGroupDirectory(targetPID peer.ID, protocolID protocol.ID, request interface{}, response interface{}, maxSize int64) error {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if err := s.connectToPeer(targetPID); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, protocolID)
+	if err != nil {
+		return fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(requestBytes); err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+	stream.CloseWrite()
+
+	responseBytes, err := io.ReadAll(io.LimitReader(stream, maxSize))
+	if err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if err := json.Unmarshal(responseBytes, response); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) getDHTDiscovery() *discovery.DHTDiscovery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dhtDiscovery
+}
@@ -0,0 +1,598 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/crypto_utils"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	GroupFileMaxSize = 64 << 20
+
+	groupFileChunkSize         = 256 * 1024
+	groupFileNameMaxLength     = 255
+	groupFileChunksPerRequest  = 8
+	groupFileFetchRounds       = 5
+	groupFileRequestMaxSize    = 64 * 1024
+	groupFileResponseMaxSize   = 4 << 20
+	groupFileFetchRoundTimeout = 2 * time.Minute
+)
+
+var (
+	ErrInvalidGroupFile    = errors.New("invalid group file")
+	ErrGroupFileIncomplete = errors.New("group file is not fully available locally")
+)
+
+// UploadGroupFile adds a file to the library of a group. The chunks stay with us and are
+// fetched by members on demand; the announcement tells them what to fetch.
+func (s *Service) UploadGroupFile(groupId string, name string, mimeType string, content []byte) (*types.GroupFile, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > groupFileNameMaxLength {
+		return nil, fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidGroupFile, groupFileNameMaxLength)
+	}
+	if len(content) == 0 || len(content) > GroupFileMaxSize {
+		return nil, fmt.Errorf("%w: size must be 1 to %d bytes", ErrInvalidGroupFile, GroupFileMaxSize)
+	}
+
+	if !s.groupKeyStoreService.HasGroupKeys(groupId) {
+		return nil, fmt.Errorf("not a member of group %s", groupId)
+	}
+
+	key := make([]byte, core.DefaultCryptoConfig.ArgonKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate file key: %w", err)
+	}
+
+	var chunks [][]byte
+	var chunkHashes []string
+	for offset := 0; offset < len(content); offset += groupFileChunkSize {
+		end := min(offset+groupFileChunkSize, len(content))
+		chunk, err := crypto_utils.EncryptDataWithKey(key, content[offset:end], core.DefaultCryptoConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt chunk: %w", err)
+		}
+		chunks = append(chunks, chunk)
+		chunkHashes = append(chunkHashes, hashGroupFileChunk(chunk))
+	}
+
+	contentHash := sha256.Sum256(content)
+	data := types.GroupFileAnnouncementData{
+		FileId:         groupFileId(chunkHashes),
+		GroupId:        groupId,
+		Name:           name,
+		MimeType:       mimeType,
+		Size:           int64(len(content)),
+		ChunkSize:      groupFileChunkSize,
+		ChunkHashes:    chunkHashes,
+		ContentHash:    hex.EncodeToString(contentHash[:]),
+		Key:            key,
+		UploaderPeerId: (*s.appState.Node).ID().String(),
+		UploadedAt:     time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	announcementBytes, err := json.Marshal(types.GroupFileAnnouncement{Data: data, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file announcement: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for i, chunk := range chunks {
+		if err := s.groupFileRepo.StoreChunk(ctx, data.FileId, i, chunk); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := s.storeGroupFile(ctx, data, announcementBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := s.groupKeyStoreService.Seal(groupId, types.GroupContentFile, announcementBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt file announcement: %w", err)
+	}
+
+	if _, err := s.publishGroupContent(groupId, sealed); err != nil {
+		return nil, err
+	}
+
+	log.Printf("GROUP Files: Shared %s (%d bytes, %d chunks) in group %s", data.FileId, data.Size, len(chunks), groupId)
+	return file, nil
+}
+
+// ApplyGroupFileAnnouncement adds a file announced by another member to our copy of the
+// library. Its chunks are only fetched on request.
+func (s *Service) ApplyGroupFileAnnouncement(groupId string, senderPeerId string, announcementBytes []byte) error {
+	var announcement types.GroupFileAnnouncement
+	if err := json.Unmarshal(announcementBytes, &announcement); err != nil {
+		return fmt.Errorf("malformed file announcement: %w", err)
+	}
+	data := announcement.Data
+
+	if data.GroupId != groupId || data.UploaderPeerId != senderPeerId {
+		return fmt.Errorf("file announcement by %s for group %s does not match its sender", data.UploaderPeerId, data.GroupId)
+	}
+
+	if err := validateGroupFileAnnouncement(data); err != nil {
+		return err
+	}
+
+	if err := identity.VerifyPayload(data.UploaderPeerId, data, announcement.Signature); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := s.storeGroupFile(ctx, data, announcementBytes)
+	return err
+}
+
+// GetGroupFiles lists the library of a group and how much of each file we hold.
+func (s *Service) GetGroupFiles(groupId string) ([]types.GroupFile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.groupFileRepo.GetFiles(ctx, groupId)
+}
+
+// FetchGroupFile starts downloading the chunks of a file we do not hold yet from the other
+// members. Progress is reported with GroupFileUpdatedEvent.
+func (s *Service) FetchGroupFile(groupId string, fileId string) (*types.GroupFile, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	file, err := s.groupFileRepo.GetFile(ctx, groupId, fileId)
+	if err != nil {
+		return nil, err
+	}
+	if file.Complete {
+		return file, nil
+	}
+
+	s.mu.Lock()
+	if s.fetchingFiles[fileId] {
+		s.mu.Unlock()
+		return file, nil
+	}
+	s.fetchingFiles[fileId] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.fetchingFiles, fileId)
+			s.mu.Unlock()
+		}()
+
+		if err := s.fetchGroupFile(groupId, fileId); err != nil {
+			log.Printf("GROUP Files: Fetching %s of group %s stopped: %v", fileId, groupId, err)
+		}
+	}()
+
+	return file, nil
+}
+
+// ReadGroupFile decrypts a file we hold completely.
+func (s *Service) ReadGroupFile(groupId string, fileId string) (*types.GroupFile, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	data, err := s.getGroupFileAnnouncement(ctx, groupId, fileId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := s.groupFileRepo.GetFile(ctx, groupId, fileId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !file.Complete {
+		return nil, nil, ErrGroupFileIncomplete
+	}
+
+	content := make([]byte, 0, data.Size)
+	for i := range data.ChunkHashes {
+		chunk, err := s.groupFileRepo.GetChunk(ctx, fileId, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		plaintext, err := crypto_utils.DecryptDataWithKey(data.Key, chunk, core.DefaultCryptoConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt chunk %d of file %s: %w", i, fileId, err)
+		}
+		content = append(content, plaintext...)
+	}
+
+	contentHash := sha256.Sum256(content)
+	if hex.EncodeToString(contentHash[:]) != data.ContentHash {
+		return nil, nil, fmt.Errorf("%w: content of %s does not match its hash", ErrInvalidGroupFile, fileId)
+	}
+
+	return file, content, nil
+}
+
+func (s *Service) fetchGroupFile(groupId string, fileId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	data, err := s.getGroupFileAnnouncement(ctx, groupId, fileId)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	selfId := (*s.appState.Node).ID().String()
+	members, err := s.groupKeyStoreService.TreeMembers(groupId)
+	if err != nil || len(members) == 0 {
+		members, err = s.groupMemberRepo.GetMembers(context.Background(), groupId)
+		if err != nil {
+			return err
+		}
+	}
+
+	for round := 0; round < groupFileFetchRounds; round++ {
+		roundCtx, roundCancel := context.WithTimeout(context.Background(), groupFileFetchRoundTimeout)
+		fetched, missing, err := s.fetchGroupFileRound(roundCtx, data, members, selfId)
+		roundCancel()
+		if err != nil {
+			return err
+		}
+
+		if file, err := s.groupFileRepo.GetFile(context.Background(), groupId, fileId); err == nil && fetched > 0 {
+			s.bus.PublishAsync(events.GroupFileUpdatedEvent{File: *file})
+		}
+
+		if missing == 0 {
+			log.Printf("GROUP Files: Fetched all of %s in group %s", fileId, groupId)
+			return nil
+		}
+		if fetched == 0 {
+			return fmt.Errorf("%d chunks are not available from any reachable member", missing)
+		}
+	}
+
+	return fmt.Errorf("file still incomplete after %d rounds", groupFileFetchRounds)
+}
+
+// fetchGroupFileRound asks every reachable member which chunks they hold and requests each
+// missing chunk from one of them, spreading the chunks over the holders. It returns how
+// many chunks were stored and how many were still missing before the round.
+func (s *Service) fetchGroupFileRound(ctx context.Context, data *types.GroupFileAnnouncementData, members []string, selfId string) (int, int, error) {
+	held, err := s.groupFileRepo.GetChunkIndexes(ctx, data.FileId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	missing := make(map[int]bool)
+	for i := range data.ChunkHashes {
+		missing[i] = true
+	}
+	for _, i := range held {
+		delete(missing, i)
+	}
+	if len(missing) == 0 {
+		return 0, 0, nil
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	holders := make(map[int][]peer.ID)
+	for _, member := range members {
+		if member == selfId {
+			continue
+		}
+		pid, err := peer.Decode(member)
+		if err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(pid peer.ID) {
+			defer wg.Done()
+			response, err := s.requestGroupFile(pid, GroupFileRequest{GroupId: data.GroupId, FileId: data.FileId})
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			for _, i := range response.Have {
+				if missing[i] {
+					holders[i] = append(holders[i], pid)
+				}
+			}
+			mu.Unlock()
+		}(pid)
+	}
+	wg.Wait()
+
+	// Rarest chunks first, each assigned to its least loaded holder.
+	indexes := make([]int, 0, len(holders))
+	for i := range holders {
+		indexes = append(indexes, i)
+	}
+	sort.Slice(indexes, func(a, b int) bool {
+		if len(holders[indexes[a]]) != len(holders[indexes[b]]) {
+			return len(holders[indexes[a]]) < len(holders[indexes[b]])
+		}
+		return indexes[a] < indexes[b]
+	})
+
+	assigned := make(map[peer.ID][]int)
+	for _, i := range indexes {
+		best := holders[i][0]
+		for _, pid := range holders[i][1:] {
+			if len(assigned[pid]) < len(assigned[best]) {
+				best = pid
+			}
+		}
+		assigned[best] = append(assigned[best], i)
+	}
+
+	var fetched int
+	for pid, chunks := range assigned {
+		wg.Add(1)
+		go func(pid peer.ID, chunks []int) {
+			defer wg.Done()
+			for start := 0; start < len(chunks); start += groupFileChunksPerRequest {
+				if ctx.Err() != nil {
+					return
+				}
+				batch := chunks[start:min(start+groupFileChunksPerRequest, len(chunks))]
+				response, err := s.requestGroupFile(pid, GroupFileRequest{GroupId: data.GroupId, FileId: data.FileId, Chunks: batch})
+				if err != nil {
+					log.Printf("GROUP Files: Error fetching chunks of %s from %s: %v", data.FileId, pid.ShortString(), err)
+					return
+				}
+				for _, i := range batch {
+					chunk, ok := response.Chunks[i]
+					if !ok {
+						continue
+					}
+					if hashGroupFileChunk(chunk) != data.ChunkHashes[i] {
+						log.Printf("GROUP Files: Discarding corrupt chunk %d of %s from %s", i, data.FileId, pid.ShortString())
+						continue
+					}
+					if err := s.groupFileRepo.StoreChunk(ctx, data.FileId, i, chunk); err != nil {
+						log.Printf("GROUP Files: Error storing chunk %d of %s: %v", i, data.FileId, err)
+						continue
+					}
+					mu.Lock()
+					fetched++
+					mu.Unlock()
+				}
+			}
+		}(pid, chunks)
+	}
+	wg.Wait()
+
+	return fetched, len(missing), nil
+}
+
+// handleGroupFileStream serves the chunks of group files we hold to other members.
+func (s *Service) handleGroupFileStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupFileRequestMaxSize))
+	if err != nil {
+		log.Printf("Group File Handler: Error reading request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var request GroupFileRequest
+	if err := json.Unmarshal(receivedBytes, &request); err != nil {
+		log.Printf("Group File Handler: Error deserializing request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	isMember, err := s.groupMemberRepo.IsMember(ctx, request.GroupId, peerID.String())
+	if err != nil || !isMember {
+		members, _ := s.groupKeyStoreService.TreeMembers(request.GroupId)
+		if !containsPeer(members, peerID.String()) {
+			log.Printf("Group File Handler: Refusing file request from non-member %s for group %s", peerID.ShortString(), request.GroupId)
+			stream.Reset()
+			return
+		}
+	}
+
+	if _, err := s.groupFileRepo.GetFile(ctx, request.GroupId, request.FileId); err != nil {
+		log.Printf("Group File Handler: Unknown file %s requested by %s: %v", request.FileId, peerID.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	response := GroupFileResponse{Chunks: make(map[int][]byte)}
+	if len(request.Chunks) == 0 {
+		response.Have, err = s.groupFileRepo.GetChunkIndexes(ctx, request.FileId)
+		if err != nil {
+			log.Printf("Group File Handler: Error getting chunks of %s: %v", request.FileId, err)
+			stream.Reset()
+			return
+		}
+	}
+
+	for _, i := range request.Chunks[:min(len(request.Chunks), groupFileChunksPerRequest)] {
+		chunk, err := s.groupFileRepo.GetChunk(ctx, request.FileId, i)
+		if err != nil {
+			continue
+		}
+		response.Chunks[i] = chunk
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Group File Handler: Error marshaling response for %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Group File Handler: Error writing response to %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+	stream.Close()
+}
+
+func (s *Service) requestGroupFile(targetPID peer.ID, request GroupFileRequest) (*GroupFileResponse, error) {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file request: %w", err)
+	}
+
+	if err := s.connectToPeer(targetPID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.GroupFileProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(requestBytes); err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to write file request: %w", err)
+	}
+	stream.CloseWrite()
+
+	responseBytes, err := io.ReadAll(io.LimitReader(stream, groupFileResponseMaxSize))
+	if err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to read file response: %w", err)
+	}
+
+	var response GroupFileResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse file response: %w", err)
+	}
+
+	return &response, nil
+}
+
+func (s *Service) storeGroupFile(ctx context.Context, data types.GroupFileAnnouncementData, announcementBytes []byte) (*types.GroupFile, error) {
+	encryptedAnnouncement, err := crypto_utils.EncryptDataWithKey(s.appState.DbKey, announcementBytes, core.DefaultCryptoConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt file announcement: %w", err)
+	}
+
+	uploadedAt, err := time.Parse(time.RFC3339, data.UploadedAt)
+	if err != nil {
+		uploadedAt = time.Now()
+	}
+
+	stored, err := s.groupFileRepo.StoreFile(ctx, types.GroupFile{
+		FileId:         data.FileId,
+		GroupId:        data.GroupId,
+		Name:           data.Name,
+		MimeType:       data.MimeType,
+		Size:           data.Size,
+		ChunkCount:     len(data.ChunkHashes),
+		ContentHash:    data.ContentHash,
+		UploaderPeerId: data.UploaderPeerId,
+		UploadedAt:     uploadedAt,
+	}, encryptedAnnouncement)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.groupFileRepo.GetFile(ctx, data.GroupId, data.FileId)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored {
+		s.bus.PublishAsync(events.GroupFileUpdatedEvent{File: *file})
+	}
+	return file, nil
+}
+
+func (s *Service) getGroupFileAnnouncement(ctx context.Context, groupId string, fileId string) (*types.GroupFileAnnouncementData, error) {
+	encryptedAnnouncement, err := s.groupFileRepo.GetAnnouncement(ctx, groupId, fileId)
+	if err != nil {
+		return nil, err
+	}
+
+	announcementBytes, err := crypto_utils.DecryptDataWithKey(s.appState.DbKey, encryptedAnnouncement, core.DefaultCryptoConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt announcement of file %s: %w", fileId, err)
+	}
+
+	var announcement types.GroupFileAnnouncement
+	if err := json.Unmarshal(announcementBytes, &announcement); err != nil {
+		return nil, fmt.Errorf("malformed announcement of file %s: %w", fileId, err)
+	}
+
+	return &announcement.Data, nil
+}
+
+func validateGroupFileAnnouncement(data types.GroupFileAnnouncementData) error {
+	if data.Name == "" || utf8.RuneCountInString(data.Name) > groupFileNameMaxLength {
+		return fmt.Errorf("%w: invalid name", ErrInvalidGroupFile)
+	}
+	if data.Size <= 0 || data.Size > GroupFileMaxSize || data.ChunkSize <= 0 || data.ChunkSize > groupFileChunkSize {
+		return fmt.Errorf("%w: invalid size", ErrInvalidGroupFile)
+	}
+	if int64(len(data.ChunkHashes)) != (data.Size+int64(data.ChunkSize)-1)/int64(data.ChunkSize) {
+		return fmt.Errorf("%w: %d chunks do not cover %d bytes", ErrInvalidGroupFile, len(data.ChunkHashes), data.Size)
+	}
+	if data.FileId != groupFileId(data.ChunkHashes) {
+		return fmt.Errorf("%w: file ID does not match its chunks", ErrInvalidGroupFile)
+	}
+	if len(data.Key) != int(core.DefaultCryptoConfig.ArgonKeyLen) {
+		return fmt.Errorf("%w: invalid key", ErrInvalidGroupFile)
+	}
+	return nil
+}
+
+func hashGroupFileChunk(chunk []byte) string {
+	hash := sha256.Sum256(chunk)
+	return hex.EncodeToString(hash[:])
+}
+
+// groupFileId derives the ID of a file from the hashes of its encrypted chunks, so the
+// announcement commits to every chunk.
+func groupFileId(chunkHashes []string) string {
+	hash := sha256.Sum256([]byte(strings.Join(chunkHashes, "\n")))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,400 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/crypto_utils"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	groupHistoryBatchLimit      = 500
+	groupHistoryHeadsLimit      = 50
+	groupMissingHashesLimit     = 1000
+	groupHistoryMaxRequestSize  = 64 << 10
+	groupHistoryMaxResponseSize = 16 << 20
+
+	// groupMemberSyncInterval is how long a connecting peer waits before it triggers
+	// another catch-up; connections to the same peer often come up in bursts.
+	groupMemberSyncInterval = time.Minute
+)
+
+// handleGroupHistoryStream serves stored group messages to a member catching up on missed messages
+func (s *Service) handleGroupHistoryStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	log.Printf("GroupHistory: Received new stream from %s", peerID.ShortString())
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupHistoryMaxRequestSize))
+	if err != nil {
+		log.Printf("Group History Handler: Error reading history request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var request GroupHistoryRequest
+	if err := json.Unmarshal(receivedBytes, &request); err != nil {
+		log.Printf("Group History Handler: Error deserializing history request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Invitees are listed as members before they accept; only the key tree says who joined.
+	members, err := s.groupKeyStoreService.TreeMembers(request.GroupId)
+	if err != nil || !containsPeer(members, peerID.String()) {
+		log.Printf("Group History Handler: %s is not a member of group %s, refusing", peerID.ShortString(), request.GroupId)
+		stream.Reset()
+		return
+	}
+
+	limit := request.Limit
+	if limit <= 0 || limit > groupHistoryBatchLimit {
+		limit = groupHistoryBatchLimit
+	}
+	if len(request.Heads) > groupHistoryHeadsLimit {
+		request.Heads = request.Heads[:groupHistoryHeadsLimit]
+	}
+	if len(request.MissingHashes) > groupMissingHashesLimit {
+		request.MissingHashes = request.MissingHashes[:groupMissingHashesLimit]
+	}
+
+	parents, err := s.messageRepository.GetGroupMessageParents(ctx, request.GroupId)
+	if err != nil {
+		log.Printf("Group History Handler: Error loading message graph for group %s: %v", request.GroupId, err)
+		stream.Reset()
+		return
+	}
+
+	hashes, err := s.messageRepository.GetGroupMessageHashes(ctx, request.GroupId)
+	if err != nil {
+		log.Printf("Group History Handler: Error loading history for group %s: %v", request.GroupId, err)
+		stream.Reset()
+		return
+	}
+
+	// Everything the requester's heads descend from, as far as our graph knows, is theirs
+	// already; every other message we hold is missing on their side, wherever it sits.
+	missing := make(map[string]bool, len(request.MissingHashes))
+	for _, hash := range request.MissingHashes {
+		missing[hash] = true
+	}
+	known := ancestorsOf(request.Heads, parents, missing)
+
+	var wanted []string
+	for _, hash := range hashes {
+		if known[hash] {
+			continue
+		}
+		wanted = append(wanted, hash)
+		if len(wanted) == limit {
+			break
+		}
+	}
+
+	stored, err := s.messageRepository.GetGroupMessagesByHashes(ctx, request.GroupId, wanted)
+	if err != nil {
+		log.Printf("Group History Handler: Error loading history for group %s: %v", request.GroupId, err)
+		stream.Reset()
+		return
+	}
+
+	requested, err := s.messageRepository.GetGroupMessagesByHashes(ctx, request.GroupId, request.MissingHashes)
+	if err != nil {
+		log.Printf("Group History Handler: Error loading requested messages for group %s: %v", request.GroupId, err)
+		stream.Reset()
+		return
+	}
+
+	for _, m := range requested {
+		if m.ID <= 0 || containsMessage(stored, m.ID) {
+			continue
+		}
+		stored = append(stored, m)
+	}
+
+	response := GroupHistoryResponse{Envelopes: make([][]byte, 0, len(stored))}
+	for _, m := range orderGroupMessages(stored, parents) {
+		envelope, err := crypto_utils.DecryptDataWithKey(s.appState.DbKey, m.EncryptedEnvelope, core.DefaultCryptoConfig)
+		if err != nil {
+			log.Printf("Group History Handler: Error decrypting stored envelope %s: %v", m.MessageID, err)
+			continue
+		}
+		response.Envelopes = append(response.Envelopes, envelope)
+	}
+
+	if metadata, err := s.groupMetadataRepo.Get(ctx, request.GroupId); err == nil {
+		response.Metadata = metadata.Update
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Group History Handler: Error marshaling history response for %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Group History Handler: Error writing history response to %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	log.Printf("Group History Handler: Served %d messages of group %s to %s", len(response.Envelopes), request.GroupId, peerID.ShortString())
+	stream.Close()
+}
+
+// SyncGroupsWithMember catches up on every group in which the given peer holds a leaf of
+// the key tree. It runs whenever a connection to a peer comes up, at most once a
+// groupMemberSyncInterval per peer.
+func (s *Service) SyncGroupsWithMember(peerId string) {
+	now := time.Now()
+
+	s.mu.Lock()
+	if last, ok := s.memberSyncs[peerId]; ok && now.Sub(last) < groupMemberSyncInterval {
+		s.mu.Unlock()
+		return
+	}
+	for id, last := range s.memberSyncs {
+		if now.Sub(last) >= groupMemberSyncInterval {
+			delete(s.memberSyncs, id)
+		}
+	}
+	s.memberSyncs[peerId] = now
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	groups, err := s.groupMemberRepo.GetGroupsWithMembers(ctx)
+	cancel()
+
+	if err != nil {
+		log.Printf("Group History: Error getting groups: %v", err)
+		return
+	}
+
+	for groupId := range groups {
+		members, err := s.groupKeyStoreService.TreeMembers(groupId)
+		if err != nil || !containsPeer(members, peerId) {
+			continue
+		}
+		if _, err := s.SyncGroupHistory(groupId); err != nil {
+			log.Printf("Group History: Error syncing group %s: %v", groupId, err)
+		}
+	}
+}
+
+// SyncGroupHistory asks reachable members of a group for the key commits and messages
+// we missed. Returns the number of new messages accepted.
+func (s *Service) SyncGroupHistory(groupId string) (int, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return 0, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	s.mu.Lock()
+	if s.syncingGroups[groupId] {
+		s.mu.Unlock()
+		return 0, nil
+	}
+	s.syncingGroups[groupId] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.syncingGroups, groupId)
+		s.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	members, err := s.groupMemberRepo.GetMembers(ctx, groupId)
+	if err != nil {
+		return 0, err
+	}
+
+	request := GroupHistoryRequest{
+		GroupId: groupId,
+		Limit:   groupHistoryBatchLimit,
+	}
+
+	request.Heads, err = s.messageRepository.GetGroupHeads(ctx, groupId, groupHistoryHeadsLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	request.MissingHashes, err = s.messageRepository.GetMissingParents(ctx, groupId)
+	if err != nil {
+		return 0, err
+	}
+
+	selfID := (*s.appState.Node).ID()
+	seen := make(map[string]bool)
+	accepted := 0
+
+	for _, member := range members {
+		memberPID, err := peer.Decode(member)
+		if err != nil || memberPID == selfID {
+			continue
+		}
+
+		if (*s.appState.Node).Network().Connectedness(memberPID) != network.Connected {
+			continue
+		}
+
+		if s.groupKeyStoreService.HasEpochState(groupId) {
+			if err := s.syncGroupEpochs(memberPID, groupId); err != nil {
+				log.Printf("Group History: Error catching up keys of group %s from %s: %v", groupId, memberPID.ShortString(), err)
+			}
+		}
+
+		envelopes, metadata, err := s.requestGroupHistory(memberPID, request)
+		if err != nil {
+			log.Printf("Group History: Error requesting history of group %s from %s: %v", groupId, memberPID.ShortString(), err)
+			continue
+		}
+
+		if metadata != nil {
+			if err := s.ApplyGroupMetadata(groupId, "", metadata); err != nil {
+				log.Printf("Group History: Rejected metadata of group %s from %s: %v", groupId, memberPID.ShortString(), err)
+			}
+		}
+
+		for _, envelope := range envelopes {
+			ok, err := s.acceptHistoricalMessage(groupId, envelope, seen)
+			if err != nil {
+				log.Printf("Group History: Rejected message from %s: %v", memberPID.ShortString(), err)
+				continue
+			}
+			if ok {
+				accepted++
+			}
+		}
+	}
+
+	log.Printf("Group History: Accepted %d new messages for group %s", accepted, groupId)
+	return accepted, nil
+}
+
+func (s *Service) requestGroupHistory(targetPID peer.ID, request GroupHistoryRequest) ([][]byte, []byte, error) {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal history request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.GroupHistoryProtocolID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(requestBytes); err != nil {
+		stream.Reset()
+		return nil, nil, fmt.Errorf("failed to write history request: %w", err)
+	}
+	stream.CloseWrite()
+
+	responseBytes, err := io.ReadAll(io.LimitReader(stream, groupHistoryMaxResponseSize))
+	if err != nil {
+		stream.Reset()
+		return nil, nil, fmt.Errorf("failed to read history response: %w", err)
+	}
+
+	var response GroupHistoryResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse history response: %w", err)
+	}
+
+	return response.Envelopes, response.Metadata, nil
+}
+
+// acceptHistoricalMessage verifies a relayed envelope and publishes it when it is new.
+func (s *Service) acceptHistoricalMessage(groupId string, envelope []byte, seen map[string]bool) (bool, error) {
+	var signed types.SignedGroupChatMessage
+	if err := json.Unmarshal(envelope, &signed); err != nil {
+		return false, fmt.Errorf("malformed envelope: %w", err)
+	}
+	message := signed.Data
+
+	if message.GroupId != groupId || message.Id == "" {
+		return false, fmt.Errorf("envelope %s does not belong to group %s", message.Id, groupId)
+	}
+
+	if seen[message.Id] {
+		return false, nil
+	}
+
+	senderPID, err := peer.Decode(message.SenderPeerId)
+	if err != nil {
+		return false, fmt.Errorf("invalid sender %s: %w", message.SenderPeerId, err)
+	}
+	if s.gater.IsBlocked(senderPID) {
+		return false, fmt.Errorf("sender %s is blocked", message.SenderPeerId)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	isMember, err := s.groupMemberRepo.IsMember(ctx, groupId, message.SenderPeerId)
+	if err != nil {
+		return false, err
+	}
+	if !isMember {
+		return false, fmt.Errorf("sender %s is not a member of group %s", message.SenderPeerId, groupId)
+	}
+
+	if err := identity.VerifyPayload(message.SenderPeerId, message, signed.SenderSignature); err != nil {
+		return false, err
+	}
+
+	hash, err := identity.HashPayload(message)
+	if err != nil {
+		return false, err
+	}
+
+	exists, err := s.messageRepository.HasGroupMessage(ctx, groupId, message.Id)
+	if err != nil {
+		return false, err
+	}
+
+	seen[message.Id] = true
+	if exists {
+		return false, nil
+	}
+
+	s.bus.PublishAsync(events.GroupChatMessageReceivedEvent{Message: events.GroupChatMessage{
+		GroupId:      groupId,
+		MessageId:    message.Id,
+		Hash:         hash,
+		Parents:      message.Parents,
+		SenderPeerId: message.SenderPeerId,
+		Message:      message.Message,
+		Time:         message.Time,
+		Envelope:     envelope,
+	}})
+
+	return true, nil
+}
+
+func containsMessage(messages []types.StoredGroupMessage, rowID int64) bool {
+	for _, m := range messages {
+		if m.ID == rowID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// groupInvitationResponseOutbox is the outbox kind of an invitee's decision, keyed by group.
+	groupInvitationResponseOutbox = "group-invitation-response"
+	// groupInvitationResponseMaxSize bounds a decision, which carries at most a key package.
+	groupInvitationResponseMaxSize = 64 * 1024
+)
+
+var ErrInvitationNotPending = errors.New("group invitation is not pending")
+
+// GetGroupInvitations returns invitations waiting for the user's decision.
+func (s *Service) GetGroupInvitations() ([]types.GroupInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitations, err := s.groupInvitationRepo.GetPending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitations == nil {
+		invitations = []types.GroupInvitation{}
+	}
+
+	return invitations, nil
+}
+
+// RespondToGroupInvitation accepts or declines a pending invitation and queues the decision
+// for the inviter, who may be offline.
+func (s *Service) RespondToGroupInvitation(groupId string, isAccepted bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitation, err := s.groupInvitationRepo.GetByGroupId(ctx, groupId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no invitation found for group %s", groupId)
+		}
+		return err
+	}
+
+	if invitation.Status != types.GroupInvitationPending {
+		return ErrInvitationNotPending
+	}
+
+	status := types.GroupInvitationDeclined
+	var keyPackage *types.GroupKeyPackage
+	if isAccepted {
+		keyPackage, err = s.groupKeyStoreService.NewKeyPackage(groupId)
+		if err != nil {
+			return fmt.Errorf("failed to create key package: %w", err)
+		}
+		status = types.GroupInvitationAccepted
+	}
+
+	if err := s.groupInvitationRepo.UpdateStatus(ctx, groupId, status); err != nil {
+		return fmt.Errorf("failed to update invitation status: %w", err)
+	}
+
+	var payload string
+	if keyPackage != nil {
+		keyPackageBytes, err := json.Marshal(keyPackage)
+		if err != nil {
+			return fmt.Errorf("failed to marshal key package: %w", err)
+		}
+		payload = string(keyPackageBytes)
+	}
+
+	s.profileService.QueueOutboxMessage(invitation.InviterPeerId, groupInvitationResponseOutbox, groupId, payload)
+	return nil
+}
+
+// sendQueuedGroupInvitationResponse rebuilds a queued decision from the invitation and the
+// key package it was made with.
+func (s *Service) sendQueuedGroupInvitationResponse(ctx context.Context, targetPID peer.ID, groupId string, entry types.FriendOutboxEntry) error {
+	if time.Since(entry.CreatedAt) > groupInviteMaxLifetime {
+		return profile.ErrOutboxObsolete
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	invitation, err := s.groupInvitationRepo.GetByGroupId(lookupCtx, groupId)
+	cancel()
+	if errors.Is(err, sql.ErrNoRows) {
+		return profile.ErrOutboxObsolete
+	}
+	if err != nil {
+		return err
+	}
+
+	// The invitation was renewed, or came from someone else, since we decided.
+	if invitation.InviterPeerId != targetPID.String() || invitation.Status == types.GroupInvitationPending {
+		return profile.ErrOutboxObsolete
+	}
+
+	isAccepted := invitation.Status == types.GroupInvitationAccepted
+	var keyPackage *types.GroupKeyPackage
+	if isAccepted {
+		if err := json.Unmarshal([]byte(entry.Payload), &keyPackage); err != nil || keyPackage == nil {
+			log.Printf("GROUP Invitation: Dropping decision for group %s without a usable key package", groupId)
+			return profile.ErrOutboxObsolete
+		}
+	}
+
+	return s.sendGroupInvitationResponse(ctx, targetPID, groupId, isAccepted, keyPackage)
+}
+
+func (s *Service) sendGroupInvitationResponse(ctx context.Context, targetPID peer.ID, groupId string, isAccepted bool, keyPackage *types.GroupKeyPackage) error {
+	data := types.GroupInvitationResponseData{
+		GroupId:         groupId,
+		ResponderPeerID: (*s.appState.Node).ID().String(),
+		IsAccepted:      isAccepted,
+		Timestamp:       time.Now().Format(time.RFC3339),
+		KeyPackage:      keyPackage,
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return err
+	}
+
+	responseBytes, err := json.Marshal(types.GroupInvitationResponse{
+		Data:            data,
+		SenderSignature: signature,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal invitation response: %w", err)
+	}
+
+	if err := s.connectToPeer(targetPID); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.GroupInvitationResponseProtocolID)
+	if err != nil {
+		return fmt.Errorf("failed to open stream to %s: %w", targetPID.ShortString(), err)
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to write invitation response: %w", err)
+	}
+
+	log.Printf("GROUP Invitation: Sent decision (accepted=%t) for group %s to %s", isAccepted, groupId, targetPID.ShortString())
+	return stream.Close()
+}
+
+// handleGroupInvitationResponseStream processes an invitee's decision on a group we created
+func (s *Service) handleGroupInvitationResponseStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	log.Printf("GroupInvitationResponse: Received new stream from %s", peerID.ShortString())
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupInvitationResponseMaxSize))
+	if err != nil {
+		log.Printf("Group Invitation Response Handler: Error reading response from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+	stream.Close()
+
+	var response types.GroupInvitationResponse
+	if err := json.Unmarshal(receivedBytes, &response); err != nil {
+		log.Printf("Group Invitation Response Handler: Error deserializing response from %s: %v", peerID.String(), err)
+		return
+	}
+
+	if response.Data.ResponderPeerID != peerID.String() {
+		log.Printf("Group Invitation Response Handler: Responder %s does not match stream peer %s", response.Data.ResponderPeerID, peerID.String())
+		return
+	}
+
+	if err := identity.VerifyPayload(peerID.String(), response.Data, response.SenderSignature); err != nil {
+		log.Printf("Group Invitation Response Handler: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	isMember, err := s.groupMemberRepo.IsMember(ctx, response.Data.GroupId, peerID.String())
+	if err != nil || !isMember {
+		log.Printf("Group Invitation Response Handler: %s was not invited to group %s", peerID.ShortString(), response.Data.GroupId)
+		return
+	}
+
+	if !response.Data.IsAccepted {
+		if err := s.groupMemberRepo.RemoveMember(ctx, response.Data.GroupId, peerID.String()); err != nil {
+			log.Printf("Group Invitation Response Handler: Error removing %s from group %s: %v", peerID.ShortString(), response.Data.GroupId, err)
+		}
+	} else if kp := response.Data.KeyPackage; kp == nil || kp.Data.PeerId != peerID.String() {
+		log.Printf("Group Invitation Response Handler: %s accepted group %s without a valid key package", peerID.ShortString(), response.Data.GroupId)
+		return
+	} else {
+		go s.addGroupMember(response.Data.GroupId, *kp)
+	}
+
+	log.Printf("Group Invitation Response Handler: %s responded to group %s (accepted=%t)", peerID.ShortString(), response.Data.GroupId, response.Data.IsAccepted)
+	s.bus.PublishAsync(events.GroupInvitationResponseReceivedEvent{
+		GroupId:         response.Data.GroupId,
+		ResponderPeerId: peerID.String(),
+		IsAccepted:      response.Data.IsAccepted,
+	})
+}
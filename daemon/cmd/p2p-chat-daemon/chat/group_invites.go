@@ -0,0 +1,498 @@
+package chat
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	groupInviteDefaultLifetime = 7 * 24 * time.Hour
+	groupInviteMaxLifetime     = 30 * 24 * time.Hour
+	groupInviteMaxSize         = 64 * 1024
+)
+
+var ErrInvalidGroupInvite = errors.New("invalid group invite")
+
+// CreateGroupInvite issues a signed token that lets whoever holds it join the group.
+func (s *Service) CreateGroupInvite(groupId string, lifetime time.Duration, singleUse bool) (*types.GroupInvite, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	if lifetime <= 0 {
+		lifetime = groupInviteDefaultLifetime
+	}
+	if lifetime > groupInviteMaxLifetime {
+		return nil, fmt.Errorf("%w: invites expire after at most %s", ErrInvalidGroupInvite, groupInviteMaxLifetime)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.requireGroupAdmin(ctx, groupId); err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.getGroupMetadata(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	for _, addr := range (*s.appState.Node).Addrs() {
+		addrs = append(addrs, addr.String())
+	}
+
+	tokenId, _ := uuid.NewRandom()
+	now := time.Now().UTC()
+	data := types.GroupInviteTokenData{
+		TokenId:      tokenId.String(),
+		GroupId:      groupId,
+		GroupName:    metadata.Name,
+		IssuerPeerId: (*s.appState.Node).ID().String(),
+		IssuerAddrs:  addrs,
+		SingleUse:    singleUse,
+		IssuedAt:     now.Format(time.RFC3339),
+		ExpiresAt:    now.Add(lifetime).Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenBytes, err := json.Marshal(types.GroupInviteToken{Data: data, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal invite token: %w", err)
+	}
+
+	invite := types.GroupInvite{
+		TokenId:      data.TokenId,
+		GroupId:      groupId,
+		IssuerPeerId: data.IssuerPeerId,
+		SingleUse:    singleUse,
+		ExpiresAt:    now.Add(lifetime),
+		CreatedAt:    now,
+		URI:          encodeGroupInviteURI(tokenBytes),
+		Token:        tokenBytes,
+	}
+
+	if err := s.groupInviteRepo.Store(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	log.Printf("GROUP Invite: Issued invite %s for group %s (single use: %t, expires %s)", invite.TokenId, groupId, singleUse, data.ExpiresAt)
+	return &invite, nil
+}
+
+// GetGroupInvites lists the invite tokens of a group that we issued or that were redeemed with us.
+func (s *Service) GetGroupInvites(groupId string) ([]types.GroupInvite, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invites, err := s.groupInviteRepo.GetByGroup(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range invites {
+		invites[i].URI = encodeGroupInviteURI(invites[i].Token)
+	}
+
+	return invites, nil
+}
+
+// RevokeGroupInvite stops a token from being honoured, by us and by every other admin.
+func (s *Service) RevokeGroupInvite(groupId string, tokenId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.requireGroupAdmin(ctx, groupId); err != nil {
+		return err
+	}
+
+	data := types.GroupInviteRevocationData{
+		TokenId:       tokenId,
+		GroupId:       groupId,
+		RevokerPeerId: (*s.appState.Node).ID().String(),
+		RevokedAt:     time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return err
+	}
+
+	revocationBytes, err := json.Marshal(types.GroupInviteRevocation{Data: data, Signature: signature})
+	if err != nil {
+		return fmt.Errorf("failed to marshal invite revocation: %w", err)
+	}
+
+	if err := s.groupInviteRepo.Revoke(ctx, tokenId, groupId); err != nil {
+		return err
+	}
+
+	sealed, err := s.groupKeyStoreService.Seal(groupId, types.GroupContentRevocation, revocationBytes)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt invite revocation: %w", err)
+	}
+
+	_, err = s.publishGroupContent(groupId, sealed)
+	return err
+}
+
+// ApplyGroupInviteRevocation records a revocation published by another admin of the group.
+func (s *Service) ApplyGroupInviteRevocation(groupId string, senderPeerId string, revocationBytes []byte) error {
+	var revocation types.GroupInviteRevocation
+	if err := json.Unmarshal(revocationBytes, &revocation); err != nil {
+		return fmt.Errorf("malformed invite revocation: %w", err)
+	}
+	data := revocation.Data
+
+	if data.GroupId != groupId || data.RevokerPeerId != senderPeerId {
+		return fmt.Errorf("revocation by %s for group %s does not match its sender", data.RevokerPeerId, data.GroupId)
+	}
+
+	if err := identity.VerifyPayload(data.RevokerPeerId, data, revocation.Signature); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return s.groupInviteRepo.Revoke(ctx, data.TokenId, groupId)
+}
+
+// RedeemGroupInvite presents a token to an admin of its group. adminPeerId defaults to the
+// issuer of the token. On success the admin adds us to the key tree and sends a welcome.
+func (s *Service) RedeemGroupInvite(uri string, adminPeerId string) (*types.GroupInvitation, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	token, err := parseGroupInviteURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	data := token.Data
+
+	if err := verifyGroupInviteToken(token, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if adminPeerId == "" {
+		adminPeerId = data.IssuerPeerId
+	}
+	if data.SingleUse && adminPeerId != data.IssuerPeerId {
+		return nil, fmt.Errorf("%w: single-use invites can only be redeemed with their issuer", ErrInvalidGroupInvite)
+	}
+
+	adminPID, err := peer.Decode(adminPeerId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin peer ID: %w", err)
+	}
+
+	if adminPeerId == data.IssuerPeerId {
+		for _, a := range data.IssuerAddrs {
+			if addr, err := multiaddr.NewMultiaddr(a); err == nil {
+				(*s.appState.Node).Peerstore().AddAddr(adminPID, addr, peerstore.TempAddrTTL)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if s.groupKeyStoreService.HasGroupKeys(data.GroupId) {
+		return nil, fmt.Errorf("already a member of group %s", data.GroupId)
+	}
+
+	keyPackage, err := s.groupKeyStoreService.NewKeyPackage(data.GroupId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key package: %w", err)
+	}
+
+	// The welcome may arrive before the admin's response, so it has to find an accepted
+	// invitation from the admin already.
+	invitation := types.GroupInvitation{
+		GroupId:       data.GroupId,
+		InviterPeerId: adminPeerId,
+		Name:          data.GroupName,
+		MemberPeers:   []string{},
+		Status:        types.GroupInvitationAccepted,
+		ReceivedAt:    time.Now(),
+	}
+	if err := s.groupInvitationRepo.Replace(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	response, err := s.requestGroupInviteRedeem(adminPID, GroupInviteRedeemRequest{Token: *token, KeyPackage: *keyPackage})
+	if err == nil && !response.Accepted {
+		err = fmt.Errorf("%w: %s", ErrInvalidGroupInvite, response.Error)
+	}
+	if err != nil {
+		if updateErr := s.groupInvitationRepo.UpdateStatus(ctx, data.GroupId, types.GroupInvitationDeclined); updateErr != nil {
+			log.Printf("GROUP Invite: Error updating invitation to group %s: %v", data.GroupId, updateErr)
+		}
+		return nil, err
+	}
+
+	invitation.Name = response.Name
+	invitation.MemberPeers = response.Members
+	if err := s.groupInvitationRepo.Replace(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	log.Printf("GROUP Invite: %s accepted our invite to group %s, waiting for welcome", adminPID.ShortString(), data.GroupId)
+	return &invitation, nil
+}
+
+// handleGroupInviteStream validates an invite token presented by someone who wants to join,
+// and adds them to the group if it holds up.
+func (s *Service) handleGroupInviteStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	log.Printf("GroupInvite: Received new stream from %s", peerID.ShortString())
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupInviteMaxSize))
+	if err != nil {
+		log.Printf("Group Invite Handler: Error reading request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var request GroupInviteRedeemRequest
+	if err := json.Unmarshal(receivedBytes, &request); err != nil {
+		log.Printf("Group Invite Handler: Error deserializing request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	response := GroupInviteRedeemResponse{Accepted: true}
+	groupId := request.Token.Data.GroupId
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.validateGroupInviteRedeem(ctx, peerID.String(), request); err != nil {
+		log.Printf("Group Invite Handler: Refusing invite %s from %s: %v", request.Token.Data.TokenId, peerID.ShortString(), err)
+		response = GroupInviteRedeemResponse{Error: err.Error()}
+	} else {
+		metadata, err := s.getGroupMetadata(ctx, groupId)
+		if err == nil {
+			response.Name = metadata.Name
+		}
+		response.Members, _ = s.groupKeyStoreService.TreeMembers(groupId)
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Group Invite Handler: Error marshaling response for %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Group Invite Handler: Error writing response to %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+	stream.Close()
+
+	if response.Accepted {
+		log.Printf("GROUP Invite: %s joins group %s with invite %s", peerID.ShortString(), groupId, request.Token.Data.TokenId)
+		go s.addGroupMember(groupId, request.KeyPackage)
+	}
+}
+
+func (s *Service) validateGroupInviteRedeem(ctx context.Context, senderPeerId string, request GroupInviteRedeemRequest) error {
+	token := request.Token
+	data := token.Data
+	selfId := (*s.appState.Node).ID().String()
+
+	if err := verifyGroupInviteToken(&token, time.Now()); err != nil {
+		return err
+	}
+
+	keyPackage := request.KeyPackage
+	if keyPackage.Data.PeerId != senderPeerId || keyPackage.Data.GroupId != data.GroupId {
+		return fmt.Errorf("%w: key package does not belong to the sender", ErrInvalidGroupInvite)
+	}
+	if err := identity.VerifyKeyPackage(keyPackage); err != nil {
+		return err
+	}
+
+	if err := s.requireGroupAdmin(ctx, data.GroupId); err != nil {
+		return err
+	}
+
+	if data.SingleUse && data.IssuerPeerId != selfId {
+		return fmt.Errorf("%w: single-use invites can only be redeemed with their issuer", ErrInvalidGroupInvite)
+	}
+
+	if data.IssuerPeerId != selfId {
+		isMember, err := s.groupMemberRepo.IsMember(ctx, data.GroupId, data.IssuerPeerId)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return fmt.Errorf("%w: issuer is no longer a member", ErrInvalidGroupInvite)
+		}
+	}
+
+	isMember, err := s.groupMemberRepo.IsMember(ctx, data.GroupId, senderPeerId)
+	if err != nil {
+		return err
+	}
+	if isMember {
+		return fmt.Errorf("%w: already a member", ErrInvalidGroupInvite)
+	}
+
+	tokenBytes, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	expiresAt, _ := time.Parse(time.RFC3339, data.ExpiresAt)
+
+	accepted, err := s.groupInviteRepo.RecordUse(ctx, types.GroupInvite{
+		TokenId:      data.TokenId,
+		GroupId:      data.GroupId,
+		IssuerPeerId: data.IssuerPeerId,
+		SingleUse:    data.SingleUse,
+		ExpiresAt:    expiresAt,
+		Token:        tokenBytes,
+	})
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return fmt.Errorf("%w: revoked or already used", ErrInvalidGroupInvite)
+	}
+
+	return nil
+}
+
+// requireGroupAdmin checks that we can add members to a group: we must hold an admin leaf
+// of its key tree. Every other member checks the same when our commit arrives.
+func (s *Service) requireGroupAdmin(ctx context.Context, groupId string) error {
+	selfId := (*s.appState.Node).ID().String()
+	isMember, err := s.groupMemberRepo.IsMember(ctx, groupId, selfId)
+	if err != nil {
+		return err
+	}
+	if !isMember || !s.groupKeyStoreService.HasEpochState(groupId) {
+		return fmt.Errorf("%w: group %s", identity.ErrNotGroupAdmin, groupId)
+	}
+
+	isAdmin, err := s.groupKeyStoreService.IsAdmin(groupId, selfId)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return fmt.Errorf("%w: group %s", identity.ErrNotGroupAdmin, groupId)
+	}
+	return nil
+}
+
+func (s *Service) requestGroupInviteRedeem(targetPID peer.ID, request GroupInviteRedeemRequest) (*GroupInviteRedeemResponse, error) {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal invite request: %w", err)
+	}
+
+	if err := s.connectToPeer(targetPID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.GroupInviteProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(requestBytes); err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to write invite request: %w", err)
+	}
+	stream.CloseWrite()
+
+	responseBytes, err := io.ReadAll(io.LimitReader(stream, groupInviteMaxSize))
+	if err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to read invite response: %w", err)
+	}
+
+	var response GroupInviteRedeemResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse invite response: %w", err)
+	}
+
+	return &response, nil
+}
+
+func verifyGroupInviteToken(token *types.GroupInviteToken, now time.Time) error {
+	data := token.Data
+
+	if data.TokenId == "" || data.GroupId == "" {
+		return fmt.Errorf("%w: token is incomplete", ErrInvalidGroupInvite)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, data.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%w: invalid expiry: %v", ErrInvalidGroupInvite, err)
+	}
+	if now.After(expiresAt) {
+		return fmt.Errorf("%w: expired at %s", ErrInvalidGroupInvite, data.ExpiresAt)
+	}
+
+	if err := identity.VerifyPayload(data.IssuerPeerId, data, token.Signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidGroupInvite, err)
+	}
+
+	return nil
+}
+
+func encodeGroupInviteURI(tokenBytes []byte) string {
+	if len(tokenBytes) == 0 {
+		return ""
+	}
+	return types.GroupInviteURIPrefix + base64.RawURLEncoding.EncodeToString(tokenBytes)
+}
+
+func parseGroupInviteURI(uri string) (*types.GroupInviteToken, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(uri), types.GroupInviteURIPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an invite link", ErrInvalidGroupInvite)
+	}
+
+	tokenBytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGroupInvite, err)
+	}
+
+	var token types.GroupInviteToken
+	if err := json.Unmarshal(tokenBytes, &token); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGroupInvite, err)
+	}
+
+	return &token, nil
+}
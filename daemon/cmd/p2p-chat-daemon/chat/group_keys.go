@@ -0,0 +1,376 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	groupEpochsBatchLimit      = 100
+	groupWelcomeMaxSize        = 1 << 20
+	groupEpochsMaxResponseSize = 16 << 20
+)
+
+// addGroupMember commits the key package of a member that accepted our invitation and welcomes them.
+func (s *Service) addGroupMember(groupId string, keyPackage types.GroupKeyPackage) {
+	sealedCommit, welcomes, err := s.groupKeyStoreService.CommitAdd(groupId, []types.GroupKeyPackage{keyPackage})
+	if err != nil {
+		log.Printf("GROUP Keys: Error adding %s to group %s: %v", keyPackage.Data.PeerId, groupId, err)
+		return
+	}
+
+	if _, err := s.publishGroupContent(groupId, sealedCommit); err != nil {
+		log.Printf("GROUP Keys: Error publishing commit for group %s: %v", groupId, err)
+	}
+	s.refreshGroupRendezvous(groupId)
+
+	for _, welcome := range welcomes {
+		if err := s.sendGroupWelcome(keyPackage.Data.PeerId, welcome); err != nil {
+			log.Printf("GROUP Keys: Error sending welcome for group %s to %s: %v", groupId, keyPackage.Data.PeerId, err)
+		}
+	}
+}
+
+// RemoveGroupMember removes a member from a group. The removed member cannot read anything sent afterwards.
+func (s *Service) RemoveGroupMember(groupId string, peerId string) error {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	sealedCommit, err := s.groupKeyStoreService.CommitRemove(groupId, []string{peerId})
+	if err != nil {
+		return fmt.Errorf("failed to remove %s from group %s: %w", peerId, groupId, err)
+	}
+
+	epoch, _ := s.groupKeyStoreService.CurrentEpoch(groupId)
+	s.bus.PublishAsync(events.GroupEpochChangedEvent{GroupId: groupId, Epoch: epoch, Removed: []string{peerId}})
+
+	_, err = s.publishGroupContent(groupId, sealedCommit)
+	return err
+}
+
+// RotateGroupKeys refreshes our keys in a group so that a leaked state stops being useful.
+func (s *Service) RotateGroupKeys(groupId string) error {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	sealedCommit, err := s.groupKeyStoreService.CommitUpdate(groupId)
+	if err != nil {
+		return fmt.Errorf("failed to update keys of group %s: %w", groupId, err)
+	}
+	s.refreshGroupRendezvous(groupId)
+
+	_, err = s.publishGroupContent(groupId, sealedCommit)
+	return err
+}
+
+// ApplyGroupEpochChange keeps the stored member list in line with the key tree.
+func (s *Service) ApplyGroupEpochChange(change events.GroupEpochChangedEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if change.RemovedSelf {
+		log.Printf("GROUP Keys: We were removed from group %s, leaving topic", change.GroupId)
+		if err := s.pubSubService.LeaveTopic(core.GroupChatTopic + change.GroupId); err != nil {
+			log.Printf("GROUP Keys: Error leaving topic of group %s: %v", change.GroupId, err)
+		}
+		if err := s.groupMemberRepo.RemoveMember(ctx, change.GroupId, (*s.appState.Node).ID().String()); err != nil {
+			log.Printf("GROUP Keys: Error updating members of group %s: %v", change.GroupId, err)
+		}
+		s.stopGroupRendezvous(change.GroupId)
+		return
+	}
+
+	s.refreshGroupRendezvous(change.GroupId)
+
+	if err := s.addMissingMembers(ctx, change.GroupId, change.Added); err != nil {
+		log.Printf("GROUP Keys: Error updating members of group %s: %v", change.GroupId, err)
+	}
+
+	for _, removed := range change.Removed {
+		if err := s.groupMemberRepo.RemoveMember(ctx, change.GroupId, removed); err != nil {
+			log.Printf("GROUP Keys: Error removing %s from group %s: %v", removed, change.GroupId, err)
+		}
+	}
+}
+
+func (s *Service) addMissingMembers(ctx context.Context, groupId string, peers []string) error {
+	var missing []string
+	for _, p := range peers {
+		isMember, err := s.groupMemberRepo.IsMember(ctx, groupId, p)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			missing = append(missing, p)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+	return s.groupMemberRepo.AddMembers(ctx, groupId, missing)
+}
+
+func (s *Service) sendGroupWelcome(targetPeerId string, welcome types.GroupWelcome) error {
+	targetPID, err := peer.Decode(targetPeerId)
+	if err != nil {
+		return fmt.Errorf("invalid peer ID: %w", err)
+	}
+
+	welcomeBytes, err := json.Marshal(welcome)
+	if err != nil {
+		return fmt.Errorf("failed to marshal welcome: %w", err)
+	}
+
+	if err := s.connectToPeer(targetPID); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.GroupWelcomeProtocolID)
+	if err != nil {
+		return fmt.Errorf("failed to open stream to %s: %w", targetPID.ShortString(), err)
+	}
+
+	if _, err := stream.Write(welcomeBytes); err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to write welcome: %w", err)
+	}
+
+	log.Printf("GROUP Keys: Sent welcome for group %s to %s", welcome.Data.GroupId, targetPID.ShortString())
+	return stream.Close()
+}
+
+// handleGroupWelcomeStream joins a group whose invitation we accepted, once a member has added us to its key tree
+func (s *Service) handleGroupWelcomeStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	log.Printf("GroupWelcome: Received new stream from %s", peerID.ShortString())
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupWelcomeMaxSize))
+	if err != nil {
+		log.Printf("Group Welcome Handler: Error reading welcome from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+	stream.Close()
+
+	var welcome types.GroupWelcome
+	if err := json.Unmarshal(receivedBytes, &welcome); err != nil {
+		log.Printf("Group Welcome Handler: Error deserializing welcome from %s: %v", peerID.String(), err)
+		return
+	}
+
+	if welcome.Data.CommitterPeerId != peerID.String() {
+		log.Printf("Group Welcome Handler: Welcome signed by %s was sent by %s", welcome.Data.CommitterPeerId, peerID.String())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitation, err := s.groupInvitationRepo.GetByGroupId(ctx, welcome.Data.GroupId)
+	if err != nil || invitation.Status != types.GroupInvitationAccepted {
+		log.Printf("Group Welcome Handler: No accepted invitation for group %s", welcome.Data.GroupId)
+		return
+	}
+
+	if !containsPeer(invitation.MemberPeers, peerID.String()) && invitation.InviterPeerId != peerID.String() {
+		log.Printf("Group Welcome Handler: %s is not a member of group %s", peerID.ShortString(), welcome.Data.GroupId)
+		return
+	}
+
+	if err := s.groupKeyStoreService.JoinFromWelcome(welcome); err != nil {
+		log.Printf("Group Welcome Handler: Error joining group %s: %v", welcome.Data.GroupId, err)
+		return
+	}
+
+	treeMembers, err := s.groupKeyStoreService.TreeMembers(welcome.Data.GroupId)
+	if err != nil {
+		log.Printf("Group Welcome Handler: Error reading members of group %s: %v", welcome.Data.GroupId, err)
+		return
+	}
+
+	if err := s.addMissingMembers(ctx, welcome.Data.GroupId, append(invitation.MemberPeers, treeMembers...)); err != nil {
+		log.Printf("Group Welcome Handler: Error adding members to group %s: %v", welcome.Data.GroupId, err)
+		return
+	}
+
+	log.Printf("GROUP Chat API: joining topic: %s", core.GroupChatTopic+welcome.Data.GroupId)
+	if err := s.pubSubService.JoinTopic(core.GroupChatTopic+welcome.Data.GroupId, welcome.Data.GroupId); err != nil {
+		log.Printf("Group Welcome Handler: Error joining topic of group %s: %v", welcome.Data.GroupId, err)
+		return
+	}
+
+	s.bus.PublishAsync(events.GroupEpochChangedEvent{GroupId: welcome.Data.GroupId, Epoch: welcome.Data.Epoch})
+}
+
+// handleGroupEpochsStream serves the commits we applied to a member whose keys fell behind
+func (s *Service) handleGroupEpochsStream(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	log.Printf("GroupEpochs: Received new stream from %s", peerID.ShortString())
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, groupHistoryMaxRequestSize))
+	if err != nil {
+		log.Printf("Group Epochs Handler: Error reading request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var request GroupEpochsRequest
+	if err := json.Unmarshal(receivedBytes, &request); err != nil {
+		log.Printf("Group Epochs Handler: Error deserializing request from %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Invitees are listed as members before they accept; only the key tree says who joined.
+	members, err := s.groupKeyStoreService.TreeMembers(request.GroupId)
+	if err != nil || !containsPeer(members, peerID.String()) {
+		log.Printf("Group Epochs Handler: %s is not a member of group %s, refusing", peerID.ShortString(), request.GroupId)
+		stream.Reset()
+		return
+	}
+
+	commits, err := s.KeyRepository.GetCommitsFrom(ctx, request.GroupId, request.FromEpoch, groupEpochsBatchLimit)
+	if err != nil {
+		log.Printf("Group Epochs Handler: Error loading commits of group %s: %v", request.GroupId, err)
+		stream.Reset()
+		return
+	}
+
+	response := GroupEpochsResponse{Commits: make([][]byte, 0, len(commits))}
+	for _, c := range commits {
+		response.Commits = append(response.Commits, c.Commit)
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Group Epochs Handler: Error marshaling response for %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if _, err := stream.Write(responseBytes); err != nil {
+		log.Printf("Group Epochs Handler: Error writing response to %s: %v", peerID.String(), err)
+		stream.Reset()
+		return
+	}
+
+	stream.Close()
+}
+
+// syncGroupEpochs applies the commits a member has and we missed while offline.
+func (s *Service) syncGroupEpochs(targetPID peer.ID, groupId string) error {
+	for {
+		epoch, err := s.groupKeyStoreService.CurrentEpoch(groupId)
+		if err != nil {
+			return err
+		}
+
+		commits, err := s.requestGroupEpochs(targetPID, GroupEpochsRequest{GroupId: groupId, FromEpoch: epoch})
+		if err != nil {
+			return err
+		}
+
+		applied := 0
+		for _, commitBytes := range commits {
+			var commit types.GroupCommit
+			if err := json.Unmarshal(commitBytes, &commit); err != nil {
+				return fmt.Errorf("malformed commit: %w", err)
+			}
+
+			change, err := s.groupKeyStoreService.ApplyCommit(groupId, commit)
+			if errors.Is(err, identity.ErrGroupEpochAhead) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if change == nil {
+				continue
+			}
+
+			applied++
+			s.bus.PublishAsync(events.GroupEpochChangedEvent{
+				GroupId:     change.GroupId,
+				Epoch:       change.Epoch,
+				Added:       change.Added,
+				Removed:     change.Removed,
+				RemovedSelf: change.RemovedSelf,
+			})
+			if change.RemovedSelf {
+				return nil
+			}
+		}
+
+		if applied == 0 {
+			return nil
+		}
+		log.Printf("Group Keys: Caught up %d epoch(s) of group %s from %s", applied, groupId, targetPID.ShortString())
+	}
+}
+
+func (s *Service) requestGroupEpochs(targetPID peer.ID, request GroupEpochsRequest) ([][]byte, error) {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal epochs request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.GroupEpochsProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(requestBytes); err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to write epochs request: %w", err)
+	}
+	stream.CloseWrite()
+
+	responseBytes, err := io.ReadAll(io.LimitReader(stream, groupEpochsMaxResponseSize))
+	if err != nil {
+		stream.Reset()
+		return nil, fmt.Errorf("failed to read epochs response: %w", err)
+	}
+
+	var response GroupEpochsResponse
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse epochs response: %w", err)
+	}
+
+	return response.Commits, nil
+}
+
+func containsPeer(peers []string, peerId string) bool {
+	for _, p := range peers {
+		if p == peerId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	groupNameMaxLength        = 100
+	groupDescriptionMaxLength = 1000
+)
+
+var ErrInvalidGroupMetadata = errors.New("invalid group metadata")
+
+// UpdateGroupMetadata changes the name, description and avatar of a group for every member.
+// Only admins may do so.
+func (s *Service) UpdateGroupMetadata(groupId string, name string, description string, avatarHash string) (*types.GroupMetadata, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	selfId := (*s.appState.Node).ID().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mayEdit, err := s.mayEditGroupMetadata(ctx, groupId, selfId)
+	if err != nil {
+		return nil, err
+	}
+	if !mayEdit {
+		return nil, fmt.Errorf("%w: group %s", identity.ErrNotGroupAdmin, groupId)
+	}
+
+	current, err := s.getGroupMetadata(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := types.GroupMetadataData{
+		GroupId:     groupId,
+		Name:        name,
+		Description: description,
+		AvatarHash:  avatarHash,
+		Version:     current.Version + 1,
+		UpdatedBy:   selfId,
+		UpdatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := validateGroupMetadata(data); err != nil {
+		return nil, err
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	updateBytes, err := json.Marshal(types.GroupMetadataUpdate{Data: data, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata update: %w", err)
+	}
+
+	metadata, err := s.storeGroupMetadata(ctx, data, updateBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := s.groupKeyStoreService.Seal(groupId, types.GroupContentMetadata, updateBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt metadata update: %w", err)
+	}
+
+	if _, err := s.publishGroupContent(groupId, sealed); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// ApplyGroupMetadata applies a signed metadata update from an admin. senderPeerId is empty
+// when the update was relayed during history sync rather than published by its author.
+func (s *Service) ApplyGroupMetadata(groupId string, senderPeerId string, updateBytes []byte) error {
+	var update types.GroupMetadataUpdate
+	if err := json.Unmarshal(updateBytes, &update); err != nil {
+		return fmt.Errorf("malformed metadata update: %w", err)
+	}
+	data := update.Data
+
+	if data.GroupId != groupId {
+		return fmt.Errorf("metadata for group %s arrived on group %s", data.GroupId, groupId)
+	}
+
+	if senderPeerId != "" && data.UpdatedBy != senderPeerId {
+		return fmt.Errorf("metadata update by %s was published by %s", data.UpdatedBy, senderPeerId)
+	}
+
+	if err := validateGroupMetadata(data); err != nil {
+		return err
+	}
+
+	if err := identity.VerifyPayload(data.UpdatedBy, data, update.Signature); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mayEdit, err := s.mayEditGroupMetadata(ctx, groupId, data.UpdatedBy)
+	if err != nil {
+		return err
+	}
+	if !mayEdit {
+		return fmt.Errorf("%w: %s cannot change the metadata of group %s", identity.ErrNotGroupAdmin, data.UpdatedBy, groupId)
+	}
+
+	_, err = s.storeGroupMetadata(ctx, data, updateBytes)
+	return err
+}
+
+// mayEditGroupMetadata reports whether a peer may change the metadata of a group. Since the
+// highest version wins, an edit right is also the right to outbid every later edit, so it is
+// kept to admins. Groups created before admins existed have no key tree to ask, and there
+// any member may edit as before.
+func (s *Service) mayEditGroupMetadata(ctx context.Context, groupId string, peerId string) (bool, error) {
+	isMember, err := s.groupMemberRepo.IsMember(ctx, groupId, peerId)
+	if err != nil || !isMember {
+		return false, err
+	}
+
+	if !s.groupKeyStoreService.HasEpochState(groupId) {
+		return true, nil
+	}
+	return s.groupKeyStoreService.IsAdmin(groupId, peerId)
+}
+
+// storeGroupMetadata keeps the update if it is newer than what we have. Updates with the same
+// version are ordered by hash so that every member settles on the same one.
+func (s *Service) storeGroupMetadata(ctx context.Context, data types.GroupMetadataData, updateBytes []byte) (*types.GroupMetadata, error) {
+	hash, err := identity.HashPayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.getGroupMetadata(ctx, data.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Version < current.Version || (data.Version == current.Version && hash <= current.Hash) {
+		return current, nil
+	}
+
+	updatedAt, err := time.Parse(time.RFC3339, data.UpdatedAt)
+	if err != nil {
+		updatedAt = time.Now()
+	}
+
+	metadata := types.GroupMetadata{
+		GroupId:     data.GroupId,
+		Name:        data.Name,
+		Description: data.Description,
+		AvatarHash:  data.AvatarHash,
+		Version:     data.Version,
+		UpdatedBy:   data.UpdatedBy,
+		UpdatedAt:   updatedAt,
+		Hash:        hash,
+		Update:      updateBytes,
+	}
+
+	if err := s.groupMetadataRepo.Store(ctx, metadata); err != nil {
+		return nil, err
+	}
+
+	log.Printf("GROUP Metadata: Group %s is now '%s' (version %d, by %s)", data.GroupId, data.Name, data.Version, data.UpdatedBy)
+	s.bus.PublishAsync(events.GroupMetadataUpdatedEvent{Metadata: metadata})
+
+	return &metadata, nil
+}
+
+// getGroupMetadata returns the stored metadata of a group, falling back to the name it was created with.
+func (s *Service) getGroupMetadata(ctx context.Context, groupId string) (*types.GroupMetadata, error) {
+	metadata, err := s.groupMetadataRepo.Get(ctx, groupId)
+	if err == nil {
+		return metadata, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	groupKey, err := s.KeyRepository.GetKey(ctx, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("unknown group %s: %w", groupId, err)
+	}
+
+	return &types.GroupMetadata{GroupId: groupId, Name: groupKey.Name}, nil
+}
+
+func validateGroupMetadata(data types.GroupMetadataData) error {
+	if data.Name == "" || utf8.RuneCountInString(data.Name) > groupNameMaxLength {
+		return fmt.Errorf("%w: name must be between 1 and %d characters", ErrInvalidGroupMetadata, groupNameMaxLength)
+	}
+
+	if utf8.RuneCountInString(data.Description) > groupDescriptionMaxLength {
+		return fmt.Errorf("%w: description is longer than %d characters", ErrInvalidGroupMetadata, groupDescriptionMaxLength)
+	}
+
+	if data.AvatarHash != "" {
+		if decoded, err := hex.DecodeString(data.AvatarHash); err != nil || len(decoded) != 32 {
+			return fmt.Errorf("%w: avatar hash must be a hex encoded SHA-256 digest", ErrInvalidGroupMetadata)
+		}
+	}
+
+	if data.Version <= 0 {
+		return fmt.Errorf("%w: version must be positive", ErrInvalidGroupMetadata)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"container/heap"
+	"fmt"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+)
+
+// orderGroupMessages sorts messages so that every message comes after the parents it references.
+// Concurrent messages are ordered by sender time, then hash, so every member computes the same order
+// from the same set of messages. Parents that are not part of the set are treated as gaps and ignored.
+func orderGroupMessages(messages []types.StoredGroupMessage, parents map[string][]string) []types.StoredGroupMessage {
+	index := make(map[string]int, len(messages))
+	for i, m := range messages {
+		index[messageKey(m)] = i
+	}
+
+	children := make(map[int][]int)
+	inDegree := make([]int, len(messages))
+	for i, m := range messages {
+		if m.Hash == "" {
+			continue
+		}
+		for _, parent := range parents[m.Hash] {
+			p, ok := index[parent]
+			if !ok || p == i {
+				continue
+			}
+			children[p] = append(children[p], i)
+			inDegree[i]++
+		}
+	}
+
+	ready := &messageHeap{messages: messages}
+	for i := range messages {
+		if inDegree[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+
+	ordered := make([]types.StoredGroupMessage, 0, len(messages))
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		m := messages[i]
+		m.Parents = parents[m.Hash]
+		ordered = append(ordered, m)
+
+		for _, child := range children[i] {
+			inDegree[child]--
+			if inDegree[child] == 0 {
+				heap.Push(ready, child)
+			}
+		}
+	}
+
+	return ordered
+}
+
+// ancestorsOf returns heads and every message reachable from them through parents. Hashes in
+// stop are neither included nor walked past.
+func ancestorsOf(heads []string, parents map[string][]string, stop map[string]bool) map[string]bool {
+	reached := make(map[string]bool)
+	pending := append([]string(nil), heads...)
+
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if reached[hash] || stop[hash] {
+			continue
+		}
+		reached[hash] = true
+		pending = append(pending, parents[hash]...)
+	}
+
+	return reached
+}
+
+func messageKey(m types.StoredGroupMessage) string {
+	if m.Hash != "" {
+		return m.Hash
+	}
+	return fmt.Sprintf("row:%d", m.ID)
+}
+
+// messageHeap is a min-heap of message indexes ordered by sender time, then hash.
+type messageHeap struct {
+	messages []types.StoredGroupMessage
+	items    []int
+}
+
+func (h *messageHeap) Len() int { return len(h.items) }
+
+func (h *messageHeap) Less(a, b int) bool {
+	ma, mb := h.messages[h.items[a]], h.messages[h.items[b]]
+	if !ma.SentAt.Equal(mb.SentAt) {
+		return ma.SentAt.Before(mb.SentAt)
+	}
+	return messageKey(ma) < messageKey(mb)
+}
+
+func (h *messageHeap) Swap(a, b int) { h.items[a], h.items[b] = h.items[b], h.items[a] }
+
+func (h *messageHeap) Push(x interface{}) { h.items = append(h.items, x.(int)) }
+
+func (h *messageHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
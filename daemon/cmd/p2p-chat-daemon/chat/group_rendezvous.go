@@ -0,0 +1,215 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/discovery"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// groupRendezvousPrefix precedes the tags group members advertise on the DHT. The tags
+	// are derived from the group keys, so the DHT learns neither the group nor its members'
+	// relation to each other.
+	groupRendezvousPrefix = "p2p-chat/group-rendezvous/"
+
+	groupRendezvousInterval    = 1 * time.Minute
+	groupRendezvousPeerLimit   = 20
+	groupRendezvousFindTimeout = 30 * time.Second
+	groupRendezvousDialTimeout = 20 * time.Second
+)
+
+// startGroupRendezvous advertises our membership of every group we hold keys for and keeps
+// looking for the other members until the DHT shuts down.
+func (s *Service) startGroupRendezvous(dhtDiscovery *discovery.DHTDiscovery) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	groups, err := s.groupMemberRepo.GetGroupsWithMembers(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("GROUP Rendezvous: Error getting groups: %v", err)
+	}
+
+	for groupId := range groups {
+		s.refreshGroupRendezvous(groupId)
+	}
+
+	go s.runGroupRendezvous(dhtDiscovery.Context())
+}
+
+func (s *Service) runGroupRendezvous(ctx context.Context) {
+	ticker := time.NewTicker(groupRendezvousInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			groupIds := make([]string, 0, len(s.rendezvousTags))
+			for groupId := range s.rendezvousTags {
+				groupIds = append(groupIds, groupId)
+			}
+			s.mu.Unlock()
+
+			for _, groupId := range groupIds {
+				s.updateGroupRendezvous(groupId)
+				s.findGroupMembers(ctx, groupId)
+			}
+		}
+	}
+}
+
+// refreshGroupRendezvous brings the advertised tags of a group in line with its current keys
+// and looks for members right away. It is called whenever the keys may have changed.
+func (s *Service) refreshGroupRendezvous(groupId string) {
+	dhtDiscovery := s.getDHTDiscovery()
+	if dhtDiscovery == nil {
+		return
+	}
+
+	if s.updateGroupRendezvous(groupId) {
+		go s.findGroupMembers(dhtDiscovery.Context(), groupId)
+	}
+}
+
+// updateGroupRendezvous advertises the tags of a group we hold keys for and withdraws tags
+// of keys we no longer hold. It reports whether we still take part in the group.
+func (s *Service) updateGroupRendezvous(groupId string) bool {
+	dhtDiscovery := s.getDHTDiscovery()
+	if dhtDiscovery == nil {
+		return false
+	}
+
+	tags, err := s.groupKeyStoreService.RendezvousTags(groupId)
+	if err != nil {
+		if !errors.Is(err, identity.ErrNoGroupState) {
+			log.Printf("GROUP Rendezvous: Error deriving rendezvous tags of group %s: %v", groupId, err)
+		}
+		tags = nil
+	}
+
+	current := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		current[tag] = true
+	}
+
+	s.mu.Lock()
+	previous := s.rendezvousTags[groupId]
+	if len(tags) == 0 {
+		delete(s.rendezvousTags, groupId)
+	} else {
+		s.rendezvousTags[groupId] = tags
+	}
+	s.mu.Unlock()
+
+	for _, tag := range previous {
+		if !current[tag] {
+			dhtDiscovery.RemoveNamespace(groupRendezvousPrefix + tag)
+		}
+	}
+	for _, tag := range tags {
+		dhtDiscovery.AddNamespace(groupRendezvousPrefix + tag)
+	}
+
+	return len(tags) > 0
+}
+
+// stopGroupRendezvous withdraws every tag of a group we left.
+func (s *Service) stopGroupRendezvous(groupId string) {
+	s.mu.Lock()
+	tags := s.rendezvousTags[groupId]
+	delete(s.rendezvousTags, groupId)
+	s.mu.Unlock()
+
+	dhtDiscovery := s.getDHTDiscovery()
+	if dhtDiscovery == nil {
+		return
+	}
+	for _, tag := range tags {
+		dhtDiscovery.RemoveNamespace(groupRendezvousPrefix + tag)
+	}
+}
+
+// findGroupMembers connects to members advertising the group's tags, so the group topic mesh
+// forms even when we share no other discovery path with them. Only peers we know to be
+// members are dialed; a former member may still know a retained key.
+func (s *Service) findGroupMembers(ctx context.Context, groupId string) {
+	dhtDiscovery := s.getDHTDiscovery()
+	if dhtDiscovery == nil {
+		return
+	}
+
+	members, err := s.groupKeyStoreService.TreeMembers(groupId)
+	if err != nil || len(members) == 0 {
+		dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		members, err = s.groupMemberRepo.GetMembers(dbCtx, groupId)
+		cancel()
+		if err != nil {
+			log.Printf("GROUP Rendezvous: Error getting members of group %s: %v", groupId, err)
+			return
+		}
+	}
+
+	host := *s.appState.Node
+	missing := make(map[peer.ID]bool)
+	for _, member := range members {
+		pid, err := peer.Decode(member)
+		if err != nil || pid == host.ID() {
+			continue
+		}
+		if host.Network().Connectedness(pid) != network.Connected {
+			missing[pid] = true
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	tags := s.rendezvousTags[groupId]
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, tag := range tags {
+		findCtx, cancel := context.WithTimeout(ctx, groupRendezvousFindTimeout)
+		providers, err := dhtDiscovery.FindProviders(findCtx, groupRendezvousPrefix+tag, groupRendezvousPeerLimit)
+		cancel()
+		if err != nil {
+			log.Printf("GROUP Rendezvous: Error looking up members of group %s: %v", groupId, err)
+			continue
+		}
+
+		for _, provider := range providers {
+			if !missing[provider.ID] || len(provider.Addrs) == 0 {
+				continue
+			}
+			delete(missing, provider.ID)
+
+			wg.Add(1)
+			go func(pi peer.AddrInfo) {
+				defer wg.Done()
+
+				dialCtx, dialCancel := context.WithTimeout(ctx, groupRendezvousDialTimeout)
+				defer dialCancel()
+
+				if err := host.Connect(dialCtx, pi); err != nil {
+					log.Printf("GROUP Rendezvous: Could not connect to %s of group %s: %v", pi.ID.ShortString(), groupId, err)
+					return
+				}
+				log.Printf("GROUP Rendezvous: Connected to %s of group %s", pi.ID.ShortString(), groupId)
+			}(provider)
+		}
+
+		if len(missing) == 0 {
+			break
+		}
+	}
+	wg.Wait()
+}
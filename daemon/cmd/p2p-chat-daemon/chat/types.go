@@ -1,19 +1,23 @@
 package chat
 
-import "time"
+import (
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
 
 type GroupChatRequest struct {
 	MemberPeers []string
-	Key         []byte
 	Name        string
 	Id          string
 }
 
 type GroupChatMessages struct {
-	Messages []GroupChatMessage
+	Messages        []GroupChatMessage
+	MissingMessages int
 }
 
 type GroupChatMessage struct {
+	Id           string
 	SenderPeerId string
 	Message      string
 	Time         time.Time
@@ -28,3 +32,83 @@ type Message struct {
 	Message    string
 	IsOutgoing bool
 }
+
+// GroupHistoryRequest describes what a member already has by its DAG heads; the responder
+// sends the messages that are not among their ancestors. MissingHashes are parents the member
+// knows it lacks, which the responder neither counts as known nor walks past.
+type GroupHistoryRequest struct {
+	GroupId       string
+	Heads         []string
+	Limit         int
+	MissingHashes []string
+}
+
+type GroupHistoryResponse struct {
+	Envelopes [][]byte
+	Metadata  []byte // latest signed metadata update, if any
+}
+
+type GroupEpochsRequest struct {
+	GroupId   string
+	FromEpoch int64
+}
+
+type GroupEpochsResponse struct {
+	Commits [][]byte
+}
+
+// GroupInviteRedeemRequest presents an invite token to a group admin, with the key package
+// we want to join the group's key tree with.
+type GroupInviteRedeemRequest struct {
+	Token      types.GroupInviteToken
+	KeyPackage types.GroupKeyPackage
+}
+
+type GroupInviteRedeemResponse struct {
+	Accepted bool
+	Error    string
+	Name     string
+	Members  []string
+}
+
+// GroupDirectoryRequest asks a peer for the descriptors of the public groups it advertises,
+// or of one group if GroupId is set.
+type GroupDirectoryRequest struct {
+	GroupId string
+}
+
+type GroupDirectoryResponse struct {
+	Descriptors [][]byte
+}
+
+type GroupJoinRequestResponse struct {
+	Queued  bool
+	Error   string
+	Name    string
+	Members []string
+}
+
+// GroupFileRequest asks a member for chunks of a file. Without Chunks it only asks which
+// chunks the member holds.
+type GroupFileRequest struct {
+	GroupId string
+	FileId  string
+	Chunks  []int
+}
+
+type GroupFileResponse struct {
+	Have   []int
+	Chunks map[int][]byte
+}
+
+// GroupDirectDelivery carries sealed group content over a direct stream, for members the
+// topic mesh does not reach.
+type GroupDirectDelivery struct {
+	GroupId string
+	Data    []byte
+}
+
+type GroupDirectDeliveryResponse struct {
+	Accepted bool
+	Error    string
+}
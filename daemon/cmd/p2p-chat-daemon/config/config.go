@@ -34,12 +34,26 @@ type P2PConfig struct {
 	MDNSServiceTag      string
 }
 
+// PubSubConfig holds the gossipsub router settings. Scores decay over time, so a peer that
+// stops misbehaving regains its standing.
+type PubSubConfig struct {
+	EnablePeerScoring    bool
+	FloodPublish         bool
+	GossipThreshold      float64 // below it, a peer gets no gossip from us
+	PublishThreshold     float64 // below it, a peer is left out of flood publishing
+	GraylistThreshold    float64 // below it, everything from a peer is ignored
+	GroupTopicWeight     float64
+	ChannelTopicWeight   float64
+	InvalidMessageWeight float64 // applied to the square of a peer's invalid messages
+}
+
 type APIConfig struct {
 	ListenAddr string
 }
 
 type Config struct {
 	P2P         P2PConfig
+	PubSub      PubSubConfig
 	API         APIConfig
 	AppDataPath string
 }
@@ -54,9 +68,24 @@ func Load() (*Config, error) {
 	apiListenAddr := flag.String("api", defaultAPIAddr, "Host and port for the API server (e.g., 127.0.0.1:0)")
 	keyFileName := flag.String("key", defaultKeyFileName, "Private key file name")
 	dbFileName := flag.String("db", "chat.db", "Database file name")
+	peerScoring := flag.Bool("pubsubscore", true, "Score gossipsub peers and drop those that misbehave.")
+	floodPublish := flag.Bool("floodpublish", true, "Publish own messages to every topic peer above the publish threshold, not just the mesh.")
+	gossipThreshold := flag.Float64("gossipthreshold", -500, "Score below which a peer gets no gossip.")
+	publishThreshold := flag.Float64("publishthreshold", -1000, "Score below which a peer is left out of flood publishing.")
+	graylistThreshold := flag.Float64("graylistthreshold", -2500, "Score below which everything from a peer is ignored.")
+	groupTopicWeight := flag.Float64("groupweight", 1, "Weight of group topic scores.")
+	channelTopicWeight := flag.Float64("channelweight", 0.5, "Weight of channel topic scores.")
+	invalidMessageWeight := flag.Float64("invalidweight", -100, "Score weight of invalid messages (applied to their square).")
 
 	flag.Parse()
 
+	if *gossipThreshold > 0 || *publishThreshold > *gossipThreshold || *graylistThreshold > *publishThreshold {
+		return nil, errors.New("pubsub thresholds must satisfy 0 >= gossipthreshold >= publishthreshold >= graylistthreshold")
+	}
+	if *groupTopicWeight < 0 || *channelTopicWeight < 0 || *invalidMessageWeight > 0 {
+		return nil, errors.New("pubsub topic weights must not be negative and invalidweight must not be positive")
+	}
+
 	configDir, err := os.UserConfigDir()
 	if err != nil {
 		return nil, fmt.Errorf("could not determine user config directory: %w", err)
@@ -99,6 +128,16 @@ func Load() (*Config, error) {
 			EnableMDNS:          *enableMDNS,
 			MDNSServiceTag:      *mdnsTag,
 		},
+		PubSub: PubSubConfig{
+			EnablePeerScoring:    *peerScoring,
+			FloodPublish:         *floodPublish,
+			GossipThreshold:      *gossipThreshold,
+			PublishThreshold:     *publishThreshold,
+			GraylistThreshold:    *graylistThreshold,
+			GroupTopicWeight:     *groupTopicWeight,
+			ChannelTopicWeight:   *channelTopicWeight,
+			InvalidMessageWeight: *invalidMessageWeight,
+		},
 		API: APIConfig{
 			ListenAddr: *apiListenAddr,
 		},
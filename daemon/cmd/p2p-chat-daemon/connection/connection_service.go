@@ -81,6 +81,15 @@ func (s *Service) IsOnline(id peer.ID) bool {
 	return isOnline
 }
 
+// Forget drops the known status of a peer that is no longer a friend. Check rounds only
+// ping approved friends, so the peer is not pinged again.
+func (s *Service) Forget(id peer.ID) {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+
+	delete(s.lastKnownStatus, id)
+}
+
 func (s *Service) statusCheckLoop() {
 	defer s.wg.Done()
 	log.Println("Connection Service: Status check loop initiated.")
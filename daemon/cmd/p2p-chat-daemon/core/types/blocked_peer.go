@@ -0,0 +1,10 @@
+package types
+
+import "time"
+
+// BlockedPeer is a peer we refuse to connect to or accept streams from.
+type BlockedPeer struct {
+	PeerId    string    `json:"peer_id"`
+	Reason    string    `json:"reason"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
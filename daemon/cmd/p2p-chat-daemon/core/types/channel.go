@@ -0,0 +1,93 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ChannelContentDescriptor = "descriptor"
+	ChannelContentPost       = "post"
+	ChannelContentReaction   = "reaction"
+)
+
+// ChannelDescriptorData describes a broadcast channel. Only Authors may post; the owner is
+// always one of them and is the only one who can change the descriptor.
+type ChannelDescriptorData struct {
+	ChannelId   string   `json:"channel_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	OwnerPeerId string   `json:"owner_peer_id"`
+	Authors     []string `json:"authors"`
+	Version     int64    `json:"version"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+type SignedChannelDescriptor struct {
+	Data      ChannelDescriptorData `json:"data"`
+	Signature []byte                `json:"signature"`
+}
+
+type ChannelPostData struct {
+	Id           string `json:"id"`
+	ChannelId    string `json:"channel_id"`
+	AuthorPeerId string `json:"author_peer_id"`
+	Message      string `json:"message"`
+	Time         string `json:"time"`
+}
+
+// SignedChannelPost is a post as published on the channel topic and served to new subscribers.
+type SignedChannelPost struct {
+	Data      ChannelPostData `json:"data"`
+	Signature []byte          `json:"signature"`
+}
+
+type ChannelReactionData struct {
+	ChannelId     string `json:"channel_id"`
+	PostId        string `json:"post_id"`
+	ReactorPeerId string `json:"reactor_peer_id"`
+	Reaction      string `json:"reaction"`
+	Time          string `json:"time"`
+}
+
+type SignedChannelReaction struct {
+	Data      ChannelReactionData `json:"data"`
+	Signature []byte              `json:"signature"`
+}
+
+// ChannelEnvelope frames everything published on a channel topic.
+type ChannelEnvelope struct {
+	ContentType string          `json:"content_type"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// Channel is a broadcast channel we own or subscribe to.
+type Channel struct {
+	ChannelId   string    `json:"channel_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	OwnerPeerId string    `json:"owner_peer_id"`
+	Authors     []string  `json:"authors"`
+	Version     int64     `json:"version"`
+	JoinedAt    time.Time `json:"joined_at"`
+	Descriptor  []byte    `json:"-"` // signed descriptor, served to new subscribers
+}
+
+type ChannelPost struct {
+	Id           string         `json:"id"`
+	ChannelId    string         `json:"channel_id"`
+	AuthorPeerId string         `json:"author_peer_id"`
+	Message      string         `json:"message"`
+	Time         time.Time      `json:"time"`
+	Reactions    map[string]int `json:"reactions"`
+	Envelope     []byte         `json:"-"`
+}
+
+type ChannelReaction struct {
+	ChannelId     string
+	PostId        string
+	ReactorPeerId string
+	Reaction      string
+	Time          time.Time
+	Envelope      []byte
+}
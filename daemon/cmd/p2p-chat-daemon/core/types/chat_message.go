@@ -31,6 +31,7 @@ const (
 	FriendStatusPending                      // 2 - Request RECEIVED by us from them, awaiting our action
 	FriendStatusApproved                     // 3 - Friends (request accepted by us or them)
 	FriendStatusRejected                     // 4 - Friends (request rejected)
+	FriendStatusRemoved                      // 5 - Friendship ended by us or them
 )
 
 // String makes FriendStatus implement fmt.Stringer
@@ -44,6 +45,10 @@ func (s FriendStatus) String() string {
 		return "Pending"
 	case FriendStatusApproved:
 		return "Approved"
+	case FriendStatusRejected:
+		return "Rejected"
+	case FriendStatusRemoved:
+		return "Removed"
 	default:
 		return fmt.Sprintf("Unknown(%d)", s)
 	}
@@ -57,6 +62,21 @@ type FriendRelationship struct {
 	ApprovedAt  time.Time
 	IsOnline    bool
 	DisplayName string `json:"display_name,omitempty"`
+	// IntroMessage, ExpiresAt and RequestNonce describe the friend request while it is
+	// pending.
+	IntroMessage string    `json:"intro_message,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	RequestNonce string    `json:"-"`
+	// Note, Tags and Circles are our own labels for a friend.
+	Note    string   `json:"note,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Circles []string `json:"circles,omitempty"`
+	// LastSeen is when the friend was last seen online, if they share it with us.
+	LastSeen time.Time `json:"last_seen,omitempty"`
+	// Presence is the friend's current status, as they last told us.
+	Presence *Presence `json:"presence,omitempty"`
+	// ProfileCard is how the friend presents itself; DisplayName is what we call them.
+	ProfileCard *ProfileCard `json:"profile_card,omitempty"`
 }
 
 type GroupKey struct {
@@ -67,16 +87,29 @@ type GroupKey struct {
 }
 
 type StoredGroupMessage struct {
-	ID               int64
-	GroupID          string
-	SenderPeerID     string
-	EncryptedContent []byte
-	SentAt           time.Time
+	ID                int64
+	GroupID           string
+	MessageID         string
+	Hash              string
+	Parents           []string
+	SenderPeerID      string
+	EncryptedContent  []byte
+	EncryptedEnvelope []byte
+	SentAt            time.Time
 }
 
 type GroupChatMessage struct {
 	Id           string
+	GroupId      string
 	SenderPeerId string
 	Message      string
 	Time         time.Time
+	Parents      []string // hashes of the group's latest messages the sender had seen
+}
+
+// SignedGroupChatMessage is the envelope published on group topics and served
+// from history, so that any member can relay it without being able to forge it.
+type SignedGroupChatMessage struct {
+	Data            GroupChatMessage `json:"data"`
+	SenderSignature []byte           `json:"signature"`
 }
@@ -0,0 +1,11 @@
+package types
+
+const ContactURIPrefix = "p2pchat://add?"
+
+// ContactCardData is what a contact URI vouches for: who we are, where to reach us and
+// the name we suggest. The owner's signature keeps addresses and name from being swapped.
+type ContactCardData struct {
+	PeerId string   `json:"peer_id"`
+	Addrs  []string `json:"addrs"`
+	Name   string   `json:"name"`
+}
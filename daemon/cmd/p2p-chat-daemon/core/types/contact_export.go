@@ -0,0 +1,61 @@
+package types
+
+const (
+	ContactImportKeep    = "keep"
+	ContactImportReplace = "replace"
+
+	ContactImportAdded     = "added"
+	ContactImportMerged    = "merged"
+	ContactImportRequested = "requested"
+	ContactImportSkipped   = "skipped"
+)
+
+// ExportedContact is a contact as written to a contact list export. Status is the name of
+// the relationship's FriendStatus.
+type ExportedContact struct {
+	PeerId      string   `json:"peer_id"`
+	Addrs       []string `json:"addrs"`
+	DisplayName string   `json:"display_name,omitempty"`
+	Note        string   `json:"note,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Circles     []string `json:"circles,omitempty"`
+	Status      string   `json:"status"`
+	RequestedAt string   `json:"requested_at,omitempty"`
+	ApprovedAt  string   `json:"approved_at,omitempty"`
+}
+
+// ContactExportData is a contact list as exported by PeerId. Its signature lets an import
+// tell whether the list was exported under the importing identity.
+type ContactExportData struct {
+	PeerId     string            `json:"peer_id"`
+	ExportedAt string            `json:"exported_at"`
+	Contacts   []ExportedContact `json:"contacts"`
+}
+
+type ContactExport struct {
+	Data      ContactExportData `json:"data"`
+	Signature []byte            `json:"signature"`
+}
+
+// ContactImportConflict is a value in the import that differed from ours, and which of the
+// two was kept.
+type ContactImportConflict struct {
+	Field    string `json:"field"`
+	Local    string `json:"local"`
+	Imported string `json:"imported"`
+	Kept     string `json:"kept"`
+}
+
+// ContactImportResult tells what the import did with one contact.
+type ContactImportResult struct {
+	PeerId    string                  `json:"peer_id"`
+	Outcome   string                  `json:"outcome"`
+	Reason    string                  `json:"reason,omitempty"`
+	Conflicts []ContactImportConflict `json:"conflicts,omitempty"`
+}
+
+type ContactImportReport struct {
+	ExportedBy   string                `json:"exported_by"`
+	SameIdentity bool                  `json:"same_identity"`
+	Results      []ContactImportResult `json:"results"`
+}
@@ -0,0 +1,19 @@
+package types
+
+import "time"
+
+// ContactLabels are the private notes and tags we keep on a contact, and the names of the
+// circles it belongs to.
+type ContactLabels struct {
+	Note    string
+	Tags    []string
+	Circles []string
+}
+
+// Circle is a named group of friends, such as "work" or "family".
+type Circle struct {
+	CircleId  string    `json:"circle_id"`
+	Name      string    `json:"name"`
+	Members   []string  `json:"members"`
+	CreatedAt time.Time `json:"created_at"`
+}
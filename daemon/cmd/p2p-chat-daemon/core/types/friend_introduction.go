@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+const (
+	IntroductionStatusPending    = "pending"
+	IntroductionStatusAutoAccept = "auto_accept"
+	IntroductionStatusAccepted   = "accepted"
+	IntroductionStatusDismissed  = "dismissed"
+)
+
+// FriendIntroductionData introduces PeerId to the recipient on behalf of a mutual friend.
+// The introducer sends one to each side, each naming the other.
+type FriendIntroductionData struct {
+	IntroId          string   `json:"intro_id"`
+	IntroducerPeerId string   `json:"introducer_id"`
+	RecipientPeerId  string   `json:"recipient_id"`
+	PeerId           string   `json:"peer_id"`
+	Addrs            []string `json:"addrs"`
+	DisplayName      string   `json:"display_name"`
+	Message          string   `json:"message,omitempty"`
+	Timestamp        string   `json:"timestamp"`
+}
+
+type FriendIntroduction struct {
+	Data      FriendIntroductionData `json:"data"`
+	Signature []byte                 `json:"signature"`
+}
+
+// ReceivedIntroduction is an introduction as kept by its recipient.
+type ReceivedIntroduction struct {
+	IntroId          string    `json:"intro_id"`
+	IntroducerPeerId string    `json:"introducer_id"`
+	PeerId           string    `json:"peer_id"`
+	Addrs            []string  `json:"addrs"`
+	DisplayName      string    `json:"display_name"`
+	Message          string    `json:"message,omitempty"`
+	Status           string    `json:"status"`
+	ReceivedAt       time.Time `json:"received_at"`
+}
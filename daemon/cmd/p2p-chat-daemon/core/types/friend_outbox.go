@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+const (
+	FriendOutboxRequest  = "request"
+	FriendOutboxResponse = "response"
+	FriendOutboxRemoval  = "removal"
+	FriendOutboxCancel   = "cancel"
+
+	// FriendOutboxIntroduction is queued once per introduction and recipient, keyed by the
+	// introduction ID.
+	FriendOutboxIntroduction = "introduction"
+)
+
+// FriendOutboxEntry is a friend message waiting to reach its peer. The message itself is
+// rebuilt on every attempt, so it is always freshly signed: from the relationship, or from
+// Payload for messages whose relationship is gone, such as the nonce of a cancelled request.
+type FriendOutboxEntry struct {
+	PeerId        string
+	Kind          string
+	Attempts      int
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	Payload       string
+}
@@ -0,0 +1,13 @@
+package types
+
+// FriendRemovalData tells a peer that the sender ended the friendship.
+type FriendRemovalData struct {
+	SenderPeerID   string `json:"sender_id"`
+	ReceiverPeerID string `json:"receiver_id"`
+	Timestamp      string `json:"timestamp"` // RFC3339
+}
+
+type FriendRemoval struct {
+	Data            FriendRemovalData `json:"data"`
+	SenderSignature []byte            `json:"signature"`
+}
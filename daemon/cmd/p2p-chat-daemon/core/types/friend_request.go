@@ -1,11 +1,28 @@
 package types
 
 type FriendRequestData struct {
-	SenderPeerID string `json:"requester_id"`
-	Timestamp    string `json:"timestamp"`
+	SenderPeerID   string `json:"requester_id"`
+	ReceiverPeerID string `json:"receiver_id"`
+	Timestamp      string `json:"timestamp"`
+	ExpiresAt      string `json:"expires_at"`
+	Nonce          string `json:"nonce"`
+	Message        string `json:"message,omitempty"`
 }
 
 type FriendRequest struct {
 	Data            FriendRequestData `json:"data"`
 	SenderSignature []byte            `json:"signature"`
 }
+
+// FriendRequestCancellationData withdraws the pending request carrying Nonce.
+type FriendRequestCancellationData struct {
+	SenderPeerID   string `json:"sender_id"`
+	ReceiverPeerID string `json:"receiver_id"`
+	Nonce          string `json:"nonce"`
+	Timestamp      string `json:"timestamp"`
+}
+
+type FriendRequestCancellation struct {
+	Data            FriendRequestCancellationData `json:"data"`
+	SenderSignature []byte                        `json:"signature"`
+}
@@ -4,6 +4,8 @@ type FriendResponseData struct {
 	ResponderPeerID string `json:"responder_id"`
 	IsApproved      bool   `json:"is_approved"`
 	Timestamp       string `json:"timestamp"`
+	// Nonce is the nonce of the request being answered.
+	Nonce string `json:"nonce"`
 }
 
 type FriendResponse struct {
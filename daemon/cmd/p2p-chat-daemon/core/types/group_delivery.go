@@ -0,0 +1,12 @@
+package types
+
+// GroupDeliveryReport tells which members content sent to a group reached. Members in
+// the topic mesh are reached by gossip; small groups also get a direct stream to every
+// member outside the mesh.
+type GroupDeliveryReport struct {
+	GroupId   string   `json:"group_id"`
+	MessageId string   `json:"message_id,omitempty"`
+	Gossip    []string `json:"gossip"`
+	Direct    []string `json:"direct"`
+	Unreached []string `json:"unreached"`
+}
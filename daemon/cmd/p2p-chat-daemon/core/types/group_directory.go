@@ -0,0 +1,56 @@
+package types
+
+import "time"
+
+// GroupDirectoryDescriptorData advertises a public group. Any member that published the
+// group signs it; it is served to peers browsing the directory.
+type GroupDirectoryDescriptorData struct {
+	GroupId         string `json:"group_id"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	Topic           string `json:"topic"`
+	MemberCount     int    `json:"member_count"`
+	PublisherPeerId string `json:"publisher_peer_id"`
+	PublishedAt     string `json:"published_at"`
+}
+
+type GroupDirectoryDescriptor struct {
+	Data      GroupDirectoryDescriptorData `json:"data"`
+	Signature []byte                       `json:"signature"`
+}
+
+// GroupDirectoryListing is a public group found while browsing the directory.
+type GroupDirectoryListing struct {
+	GroupId         string    `json:"group_id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	Topic           string    `json:"topic"`
+	MemberCount     int       `json:"member_count"`
+	PublisherPeerId string    `json:"publisher_peer_id"`
+	SeenAt          time.Time `json:"seen_at"`
+	Descriptor      []byte    `json:"-"`
+}
+
+type GroupJoinRequestData struct {
+	GroupId         string          `json:"group_id"`
+	RequesterPeerId string          `json:"requester_peer_id"`
+	Message         string          `json:"message"`
+	KeyPackage      GroupKeyPackage `json:"key_package"`
+	Timestamp       string          `json:"timestamp"`
+}
+
+// SignedGroupJoinRequest asks a member of a public group to add the requester.
+type SignedGroupJoinRequest struct {
+	Data      GroupJoinRequestData `json:"data"`
+	Signature []byte               `json:"signature"`
+}
+
+// GroupJoinRequest is a request to join one of our public groups, held until the user decides.
+type GroupJoinRequest struct {
+	GroupId    string    `json:"group_id"`
+	PeerId     string    `json:"peer_id"`
+	Message    string    `json:"message"`
+	Status     string    `json:"status"`
+	ReceivedAt time.Time `json:"received_at"`
+	KeyPackage []byte    `json:"-"`
+}
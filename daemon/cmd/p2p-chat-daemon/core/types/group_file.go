@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+// GroupFileAnnouncementData adds a file to a group's library. The file is split into
+// chunks, each encrypted with Key; the announcement itself travels sealed with the group
+// key, so only members can read the chunks. FileId is the hash of the chunk hashes.
+type GroupFileAnnouncementData struct {
+	FileId         string   `json:"file_id"`
+	GroupId        string   `json:"group_id"`
+	Name           string   `json:"name"`
+	MimeType       string   `json:"mime_type"`
+	Size           int64    `json:"size"`
+	ChunkSize      int      `json:"chunk_size"`
+	ChunkHashes    []string `json:"chunk_hashes"`
+	ContentHash    string   `json:"content_hash"`
+	Key            []byte   `json:"key"`
+	UploaderPeerId string   `json:"uploader_peer_id"`
+	UploadedAt     string   `json:"uploaded_at"`
+}
+
+type GroupFileAnnouncement struct {
+	Data      GroupFileAnnouncementData `json:"data"`
+	Signature []byte                    `json:"signature"`
+}
+
+// GroupFile is a file of a group's library, with how much of it we hold locally.
+type GroupFile struct {
+	FileId          string    `json:"file_id"`
+	GroupId         string    `json:"group_id"`
+	Name            string    `json:"name"`
+	MimeType        string    `json:"mime_type"`
+	Size            int64     `json:"size"`
+	ChunkCount      int       `json:"chunk_count"`
+	AvailableChunks int       `json:"available_chunks"`
+	Complete        bool      `json:"complete"`
+	ContentHash     string    `json:"content_hash"`
+	UploaderPeerId  string    `json:"uploader_peer_id"`
+	UploadedAt      time.Time `json:"uploaded_at"`
+}
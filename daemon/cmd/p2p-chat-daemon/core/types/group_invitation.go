@@ -0,0 +1,32 @@
+package types
+
+import "time"
+
+const (
+	GroupInvitationPending  = "pending"
+	GroupInvitationAccepted = "accepted"
+	GroupInvitationDeclined = "declined"
+)
+
+// GroupInvitation is a group creation request received from a friend, held until the user decides.
+type GroupInvitation struct {
+	GroupId       string    `json:"group_id"`
+	InviterPeerId string    `json:"inviter_peer_id"`
+	Name          string    `json:"name"`
+	MemberPeers   []string  `json:"member_peers"`
+	Status        string    `json:"status"`
+	ReceivedAt    time.Time `json:"received_at"`
+}
+
+type GroupInvitationResponseData struct {
+	GroupId         string           `json:"group_id"`
+	ResponderPeerID string           `json:"responder_id"`
+	IsAccepted      bool             `json:"is_accepted"`
+	Timestamp       string           `json:"timestamp"`
+	KeyPackage      *GroupKeyPackage `json:"key_package,omitempty"` // set when accepted, used to add us to the key tree
+}
+
+type GroupInvitationResponse struct {
+	Data            GroupInvitationResponseData `json:"data"`
+	SenderSignature []byte                      `json:"signature"`
+}
@@ -0,0 +1,53 @@
+package types
+
+import "time"
+
+const GroupInviteURIPrefix = "p2pchat://invite?token="
+
+// GroupInviteTokenData is a capability to join a group. Anyone holding the signed token can
+// present it to a group admin until it expires or is revoked; single-use tokens are only
+// honoured by their issuer, who can tell whether they were used.
+type GroupInviteTokenData struct {
+	TokenId      string   `json:"token_id"`
+	GroupId      string   `json:"group_id"`
+	GroupName    string   `json:"group_name"`
+	IssuerPeerId string   `json:"issuer_peer_id"`
+	IssuerAddrs  []string `json:"issuer_addrs"`
+	SingleUse    bool     `json:"single_use"`
+	IssuedAt     string   `json:"issued_at"`
+	ExpiresAt    string   `json:"expires_at"`
+}
+
+type GroupInviteToken struct {
+	Data      GroupInviteTokenData `json:"data"`
+	Signature []byte               `json:"signature"`
+}
+
+// GroupInviteRevocationData withdraws a token. It is published on the group topic so every
+// admin stops honouring it.
+type GroupInviteRevocationData struct {
+	TokenId       string `json:"token_id"`
+	GroupId       string `json:"group_id"`
+	RevokerPeerId string `json:"revoker_peer_id"`
+	RevokedAt     string `json:"revoked_at"`
+}
+
+type GroupInviteRevocation struct {
+	Data      GroupInviteRevocationData `json:"data"`
+	Signature []byte                    `json:"signature"`
+}
+
+// GroupInvite is a token as tracked by an admin. Token is empty for tokens we only know
+// were revoked.
+type GroupInvite struct {
+	TokenId      string    `json:"token_id"`
+	GroupId      string    `json:"group_id"`
+	IssuerPeerId string    `json:"issuer_peer_id"`
+	SingleUse    bool      `json:"single_use"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Uses         int       `json:"uses"`
+	Revoked      bool      `json:"revoked"`
+	CreatedAt    time.Time `json:"created_at"`
+	URI          string    `json:"uri,omitempty"`
+	Token        []byte    `json:"-"`
+}
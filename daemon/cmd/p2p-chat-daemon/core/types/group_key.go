@@ -0,0 +1,117 @@
+package types
+
+import "time"
+
+const (
+	GroupContentApplication = "application"
+	GroupContentCommit      = "commit"
+	GroupContentMetadata    = "metadata"
+	GroupContentRevocation  = "invite_revocation"
+	GroupContentFile        = "file"
+)
+
+// GroupEpoch is the persisted key agreement state of a group for one epoch.
+type GroupEpoch struct {
+	GroupId    string
+	Epoch      int64
+	CommitHash string
+	State      []byte // encrypted with the db key
+	CreatedAt  time.Time
+}
+
+// GroupCommitRecord is a commit we applied, kept so members that were offline can catch up.
+type GroupCommitRecord struct {
+	GroupId    string
+	Epoch      int64
+	CommitHash string
+	Commit     []byte
+	CreatedAt  time.Time
+}
+
+// GroupStoredKeyPackage holds the private half of a key package until its welcome arrives.
+type GroupStoredKeyPackage struct {
+	GroupId    string
+	PublicKey  []byte
+	PrivateKey []byte // encrypted with the db key
+	CreatedAt  time.Time
+}
+
+// GroupKeyPackageData announces the key a peer wants to join a group with.
+type GroupKeyPackageData struct {
+	GroupId   string `json:"group_id"`
+	PeerId    string `json:"peer_id"`
+	PublicKey []byte `json:"public_key"`
+}
+
+type GroupKeyPackage struct {
+	Data      GroupKeyPackageData `json:"data"`
+	Signature []byte              `json:"signature"`
+}
+
+// GroupTreeNode is the public part of a ratchet tree node. Blank nodes have no public key,
+// only leaves carry a peer ID. Admin leaves may add and remove members; the group's
+// creator is its first admin.
+type GroupTreeNode struct {
+	PublicKey []byte `json:"public_key,omitempty"`
+	PeerId    string `json:"peer_id,omitempty"`
+	Admin     bool   `json:"admin,omitempty"`
+}
+
+// GroupSealedSecret is a secret encrypted to a tree node's public key.
+type GroupSealedSecret struct {
+	Node       int    `json:"node"`
+	KemOutput  []byte `json:"kem_output"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// GroupUpdatePathNode carries the new public key of a node on the committer's direct path
+// and its path secret sealed to every subtree below the other child.
+type GroupUpdatePathNode struct {
+	PublicKey []byte              `json:"public_key"`
+	Secrets   []GroupSealedSecret `json:"secrets"`
+}
+
+type GroupCommitData struct {
+	GroupId         string                `json:"group_id"`
+	Epoch           int64                 `json:"epoch"`
+	CommitterPeerId string                `json:"committer_peer_id"`
+	Adds            []GroupKeyPackage     `json:"adds"`
+	Removes         []string              `json:"removes"`
+	LeafPublicKey   []byte                `json:"leaf_public_key"`
+	Path            []GroupUpdatePathNode `json:"path"`
+}
+
+// GroupCommit moves a group from Epoch to Epoch+1, adding or removing members and
+// refreshing the committer's path of the tree.
+type GroupCommit struct {
+	Data      GroupCommitData `json:"data"`
+	Signature []byte          `json:"signature"`
+}
+
+type GroupWelcomeData struct {
+	GroupId         string            `json:"group_id"`
+	Name            string            `json:"name"`
+	Epoch           int64             `json:"epoch"`
+	CommitHash      string            `json:"commit_hash"`
+	CommitterPeerId string            `json:"committer_peer_id"`
+	Tree            []GroupTreeNode   `json:"tree"`
+	Secrets         GroupSealedSecret `json:"secrets"`
+}
+
+// GroupWelcome lets a newly added member join the epoch created by the commit that added them.
+type GroupWelcome struct {
+	Data      GroupWelcomeData `json:"data"`
+	Signature []byte           `json:"signature"`
+}
+
+// GroupCiphertext is the framing of everything published on a group topic.
+type GroupCiphertext struct {
+	GroupId     string `json:"group_id"`
+	Epoch       int64  `json:"epoch"`
+	KeyId       string `json:"key_id"`
+	ContentType string `json:"content_type"`
+	Ciphertext  []byte `json:"ciphertext"`
+	// MessageHash is the hash of the signed chat message inside, so that the same message
+	// sealed again keeps its identity. Control content leaves it empty.
+	MessageHash string `json:"message_hash,omitempty"`
+}
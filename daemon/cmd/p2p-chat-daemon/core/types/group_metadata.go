@@ -0,0 +1,33 @@
+package types
+
+import "time"
+
+// GroupMetadataData is the shared identity of a group. Version grows with every update so
+// members converge on the same metadata whatever order updates arrive in.
+type GroupMetadataData struct {
+	GroupId     string `json:"group_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	AvatarHash  string `json:"avatar_hash"` // hex SHA-256 of the avatar image
+	Version     int64  `json:"version"`
+	UpdatedBy   string `json:"updated_by"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type GroupMetadataUpdate struct {
+	Data      GroupMetadataData `json:"data"`
+	Signature []byte            `json:"signature"`
+}
+
+// GroupMetadata is the metadata of a group as stored locally.
+type GroupMetadata struct {
+	GroupId     string    `json:"group_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	AvatarHash  string    `json:"avatar_hash"`
+	Version     int64     `json:"version"`
+	UpdatedBy   string    `json:"updated_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Hash        string    `json:"-"`
+	Update      []byte    `json:"-"` // signed update, relayed to members catching up
+}
@@ -0,0 +1,58 @@
+package types
+
+import "time"
+
+const (
+	HandleStatusPending   = "pending"
+	HandleStatusPublished = "published"
+	HandleStatusConflict  = "conflict"
+)
+
+// HandleRecordData binds a handle to the peer claiming it. Records are published on the
+// DHT and expire unless their owner republishes them. ClaimedAt stays fixed across
+// republishing so resolution stays stable, but it is the claimant's own word: it orders
+// competing claims, it does not prove who came first.
+type HandleRecordData struct {
+	Handle    string `json:"handle"`
+	PeerId    string `json:"peer_id"`
+	ClaimedAt string `json:"claimed_at"`
+	IssuedAt  string `json:"issued_at"`
+	ExpiresAt string `json:"expires_at"`
+	Released  bool   `json:"released,omitempty"`
+}
+
+type HandleRecord struct {
+	Data      HandleRecordData `json:"data"`
+	Signature []byte           `json:"signature"`
+}
+
+// HandleClaim is a handle we claimed, with the outcome of its last publication.
+type HandleClaim struct {
+	Handle      string    `json:"handle"`
+	ClaimedAt   time.Time `json:"claimed_at"`
+	Status      string    `json:"status"`
+	OwnerPeerId string    `json:"owner_peer_id,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ResolvedHandle is the record a handle resolves to. Conflict is set when other peers'
+// claims to the handle were seen while resolving it. OwnerChanged is set when the handle
+// now resolves to someone other than PinnedPeerId, the peer it resolved to the first time.
+type ResolvedHandle struct {
+	Handle       string    `json:"handle"`
+	PeerId       string    `json:"peer_id"`
+	ClaimedAt    time.Time `json:"claimed_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Conflict     bool      `json:"conflict"`
+	Contenders   []string  `json:"contenders,omitempty"`
+	PinnedPeerId string    `json:"pinned_peer_id,omitempty"`
+	OwnerChanged bool      `json:"owner_changed"`
+}
+
+// HandlePin is the peer we trust a handle to belong to: the first peer it resolved to, or
+// the one the user accepted since.
+type HandlePin struct {
+	Handle   string    `json:"handle"`
+	PeerId   string    `json:"peer_id"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// NearbyAnnouncementData tells a peer on the local network who we are.
+type NearbyAnnouncementData struct {
+	PeerId      string `json:"peer_id"`
+	DisplayName string `json:"display_name"`
+	Timestamp   string `json:"timestamp"`
+}
+
+type NearbyAnnouncement struct {
+	Data      NearbyAnnouncementData `json:"data"`
+	Signature []byte                 `json:"signature"`
+}
+
+// NearbyPeer is a peer found on the local network over mDNS. DisplayName is the name it
+// announced, and FriendStatus our relationship with it.
+type NearbyPeer struct {
+	PeerId       string       `json:"peer_id"`
+	DisplayName  string       `json:"display_name"`
+	Addrs        []string     `json:"addrs"`
+	FriendStatus FriendStatus `json:"friend_status"`
+	FirstSeen    time.Time    `json:"first_seen"`
+	LastSeen     time.Time    `json:"last_seen"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+const (
+	PresenceAvailable = "available"
+	PresenceAway      = "away"
+	PresenceBusy      = "busy"
+	// PresenceInvisible is only ever set locally: friends are told we are offline.
+	PresenceInvisible = "invisible"
+	PresenceOffline   = "offline"
+
+	PresenceRuleFriend = "friend"
+	PresenceRuleCircle = "circle"
+)
+
+// PresenceUpdate is what we push to a friend: our status and, unless hidden from them,
+// that we are around as of Timestamp.
+type PresenceUpdate struct {
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	LastSeen  string `json:"last_seen,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Presence is a friend's status as they last told us, or ours.
+type Presence struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PresenceRule hides our presence, our last seen time or both from a friend or from every
+// friend in a circle.
+type PresenceRule struct {
+	SubjectType  string    `json:"subject_type"`
+	SubjectId    string    `json:"subject_id"`
+	HidePresence bool      `json:"hide_presence"`
+	HideLastSeen bool      `json:"hide_last_seen"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
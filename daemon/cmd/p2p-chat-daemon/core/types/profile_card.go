@@ -0,0 +1,59 @@
+package types
+
+import "time"
+
+const (
+	ProfileCardRequestGet     = "get"
+	ProfileCardRequestAvatar  = "avatar"
+	ProfileCardRequestChanged = "changed"
+)
+
+// ProfileCardData is how a peer presents itself to its friends. Version grows with every
+// update so friends can tell a newer card from one they already have.
+type ProfileCardData struct {
+	PeerId      string `json:"peer_id"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarHash  string `json:"avatar_hash"` // hex SHA-256 of the avatar image
+	Version     int64  `json:"version"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type SignedProfileCard struct {
+	Data      ProfileCardData `json:"data"`
+	Signature []byte          `json:"signature"`
+}
+
+// ProfileCard is a profile card as stored locally, ours or a friend's.
+type ProfileCard struct {
+	PeerId      string    `json:"peer_id"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarHash  string    `json:"avatar_hash"`
+	Version     int64     `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Card        []byte    `json:"-"` // signed card, served to friends
+}
+
+// Avatar is an image stored under the hex SHA-256 of its content.
+type Avatar struct {
+	Hash     string
+	MimeType string
+	Content  []byte
+}
+
+// ProfileCardRequest asks a friend for their card if it is newer than KnownVersion, or for
+// the avatar with AvatarHash. A "changed" request tells a friend our card changed.
+type ProfileCardRequest struct {
+	Type         string `json:"type"`
+	KnownVersion int64  `json:"known_version,omitempty"`
+	AvatarHash   string `json:"avatar_hash,omitempty"`
+}
+
+// ProfileCardResponse carries the requested card, left out if the asker has the latest
+// one, or avatar.
+type ProfileCardResponse struct {
+	Card           *SignedProfileCard `json:"card,omitempty"`
+	AvatarMimeType string             `json:"avatar_mime_type,omitempty"`
+	Avatar         []byte             `json:"avatar,omitempty"`
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/discovery"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -26,10 +27,14 @@ type DHTDiscovery struct {
 	dht       *dht.IpfsDHT
 	cfg       *config.P2PConfig
 	discovery *routing.RoutingDiscovery
+
+	namespacesMu sync.Mutex
+	namespaces   map[string]struct{}
 }
 
-// NewDHTDiscovery creates a new DHT discovery manager.
-func NewDHTDiscovery(ctx context.Context, cfg *config.P2PConfig, host *host.Host) (*DHTDiscovery, error) {
+// NewDHTDiscovery creates a new DHT discovery manager. Extra options are applied after the
+// defaults, e.g. to register validators for the records services store on the DHT.
+func NewDHTDiscovery(ctx context.Context, cfg *config.P2PConfig, host *host.Host, extraOpts ...dhtopts.Option) (*DHTDiscovery, error) {
 	if host == nil || cfg == nil {
 		log.Println("P2P DHT Discovery: ERROR - Cannot initialize with nil host, DHT, or config.")
 		return nil, fmt.Errorf("p2P DHT Discovery: ERROR - Cannot initialize with nil host, DHT, or config")
@@ -45,6 +50,7 @@ func NewDHTDiscovery(ctx context.Context, cfg *config.P2PConfig, host *host.Host
 	if !cfg.UsePublicBootstraps {
 		opts = append(opts, dht.ProtocolPrefix(protocol.ID(cfg.DHTProtocolID)))
 	}
+	opts = append(opts, extraOpts...)
 
 	kadDHT, err := dht.New(ctx, *host, opts...)
 	if err != nil {
@@ -56,11 +62,12 @@ func NewDHTDiscovery(ctx context.Context, cfg *config.P2PConfig, host *host.Host
 	}
 
 	return &DHTDiscovery{
-		ctx:       ctx,
-		host:      host,
-		dht:       kadDHT,
-		cfg:       cfg,
-		discovery: routing.NewRoutingDiscovery(kadDHT),
+		ctx:        ctx,
+		host:       host,
+		dht:        kadDHT,
+		cfg:        cfg,
+		discovery:  routing.NewRoutingDiscovery(kadDHT),
+		namespaces: make(map[string]struct{}),
 	}, nil
 }
 
@@ -120,6 +127,7 @@ func (d *DHTDiscovery) Run() {
 	}
 
 	go d.advertise(d.ctx)
+	go d.advertiseNamespaces(d.ctx)
 	go d.findPeers(d.ctx)
 
 	ticker := time.NewTicker(1 * time.Minute)
@@ -132,6 +140,7 @@ func (d *DHTDiscovery) Run() {
 			return
 		case <-ticker.C:
 			d.advertise(d.ctx)
+			d.advertiseNamespaces(d.ctx)
 			d.findPeers(d.ctx)
 		}
 	}
@@ -164,6 +173,83 @@ func (d *DHTDiscovery) advertise(ctx context.Context) {
 	}
 }
 
+// Context returns the context the DHT runs under. It is cancelled on shutdown.
+func (d *DHTDiscovery) Context() context.Context {
+	return d.ctx
+}
+
+// AddNamespace advertises an additional namespace on the DHT, now and on every round of
+// the background loop, until it is removed.
+func (d *DHTDiscovery) AddNamespace(ns string) {
+	d.namespacesMu.Lock()
+	_, exists := d.namespaces[ns]
+	d.namespaces[ns] = struct{}{}
+	d.namespacesMu.Unlock()
+
+	if !exists {
+		go d.advertiseNamespace(d.ctx, ns)
+	}
+}
+
+// RemoveNamespace stops advertising a namespace. Records already on the DHT expire on
+// their own.
+func (d *DHTDiscovery) RemoveNamespace(ns string) {
+	d.namespacesMu.Lock()
+	delete(d.namespaces, ns)
+	d.namespacesMu.Unlock()
+}
+
+// FindProviders returns up to limit peers advertising a namespace, excluding ourselves.
+func (d *DHTDiscovery) FindProviders(ctx context.Context, ns string, limit int) ([]peer.AddrInfo, error) {
+	peerChan, err := d.discovery.FindPeers(ctx, ns, discovery.Limit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find providers of %s: %w", ns, err)
+	}
+
+	providers := []peer.AddrInfo{}
+	for pi := range peerChan {
+		if pi.ID == (*d.host).ID() {
+			continue
+		}
+		providers = append(providers, pi)
+	}
+	return providers, nil
+}
+
+// PutValue stores a record on the DHT. Its key's namespace needs a registered validator.
+func (d *DHTDiscovery) PutValue(ctx context.Context, key string, value []byte) error {
+	return d.dht.PutValue(ctx, key, value)
+}
+
+// SearchValue looks a record up on the DHT, sending every record found that is better than
+// the ones before it, as judged by the validator of the key's namespace.
+func (d *DHTDiscovery) SearchValue(ctx context.Context, key string) (<-chan []byte, error) {
+	return d.dht.SearchValue(ctx, key)
+}
+
+func (d *DHTDiscovery) advertiseNamespaces(ctx context.Context) {
+	d.namespacesMu.Lock()
+	namespaces := make([]string, 0, len(d.namespaces))
+	for ns := range d.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	d.namespacesMu.Unlock()
+
+	for _, ns := range namespaces {
+		d.advertiseNamespace(ctx, ns)
+	}
+}
+
+func (d *DHTDiscovery) advertiseNamespace(ctx context.Context, ns string) {
+	if d.dht.RoutingTable().Size() == 0 {
+		return
+	}
+	_, err := d.discovery.Advertise(ctx, ns)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("P2P DHT Discovery: Error advertising namespace '%s': %v", ns, err)
+	}
+}
+
 // findPeers looks for peers and attempts connections.
 func (d *DHTDiscovery) findPeers(ctx context.Context) {
 	log.Printf("P2P DHT Discovery: Finding peers for service '%s'...", d.cfg.DiscoveryServiceID)
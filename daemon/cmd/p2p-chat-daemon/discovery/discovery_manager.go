@@ -3,6 +3,7 @@ package discovery
 import (
 	"context"
 	"fmt"
+	dhtopts "github.com/libp2p/go-libp2p-kad-dht/opts"
 	"github.com/libp2p/go-libp2p/core/host"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/config"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
@@ -20,14 +21,14 @@ type Manager struct {
 }
 
 // NewDiscoveryManager creates a new discovery manager*
-func NewDiscoveryManager(ctx context.Context, node *host.Host, cfg *config.Config, bus *bus.EventBus) (*Manager, error) {
-	dhtDisc, err := NewDHTDiscovery(ctx, &cfg.P2P, node)
+func NewDiscoveryManager(ctx context.Context, node *host.Host, cfg *config.Config, bus *bus.EventBus, dhtOpts ...dhtopts.Option) (*Manager, error) {
+	dhtDisc, err := NewDHTDiscovery(ctx, &cfg.P2P, node, dhtOpts...)
 
 	if err != nil {
 		return nil, fmt.Errorf("dht set up failed")
 	}
 
-	mdnsDiscovery := NewMDNSDiscovery(ctx, &cfg.P2P, node)
+	mdnsDiscovery := NewMDNSDiscovery(ctx, &cfg.P2P, node, bus)
 
 	return &Manager{
 		ctx:           ctx,
@@ -49,3 +50,8 @@ func (dm *Manager) Initialize() error {
 
 	return nil
 }
+
+// DHT returns the DHT discovery mechanism, for services advertising their own namespaces.
+func (dm *Manager) DHT() *DHTDiscovery {
+	return dm.dhtDiscovery
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"log"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/config"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
 	"strings"
 	"sync"
 	"time"
@@ -21,26 +23,29 @@ type MDNSDiscovery struct {
 	cfg     *config.P2PConfig
 	service mdns.Service
 	ctx     context.Context
+	bus     *bus.EventBus
 }
 
 // discoveryNotifee handles mDNS peer found events.
 type discoveryNotifee struct {
 	h                  host.Host
 	ctx                context.Context
+	bus                *bus.EventBus
 	connectionAttempts map[peer.ID]time.Time
 	mutex              sync.Mutex
 }
 
-func newDiscoveryNotifee(ctx context.Context, h host.Host) *discoveryNotifee {
+func newDiscoveryNotifee(ctx context.Context, h host.Host, bus *bus.EventBus) *discoveryNotifee {
 	return &discoveryNotifee{
 		h:                  h,
 		ctx:                ctx,
+		bus:                bus,
 		connectionAttempts: make(map[peer.ID]time.Time),
 	}
 }
 
 // NewMDNSDiscovery creates a new mDNS discovery manager.
-func NewMDNSDiscovery(ctx context.Context, cfg *config.P2PConfig, host *host.Host) *MDNSDiscovery {
+func NewMDNSDiscovery(ctx context.Context, cfg *config.P2PConfig, host *host.Host, bus *bus.EventBus) *MDNSDiscovery {
 	if host == nil || cfg == nil {
 		return nil
 	}
@@ -48,6 +53,7 @@ func NewMDNSDiscovery(ctx context.Context, cfg *config.P2PConfig, host *host.Hos
 		ctx:  ctx,
 		host: *host,
 		cfg:  cfg,
+		bus:  bus,
 	}
 }
 
@@ -59,7 +65,7 @@ func (m *MDNSDiscovery) Run() error {
 	}
 
 	log.Println("P2P mDNS Discovery: Setting up...")
-	notifee := newDiscoveryNotifee(m.ctx, m.host)
+	notifee := newDiscoveryNotifee(m.ctx, m.host, m.bus)
 	svc := mdns.NewMdnsService(m.host, m.cfg.MDNSServiceTag, notifee)
 	m.service = svc
 
@@ -99,6 +105,7 @@ func (n *discoveryNotifee) HandlePeerFound(pi peer.AddrInfo) {
 		return
 	}
 	log.Printf("P2P mDNS Discovery: Found peer %s, addrs: %v", pi.ID.ShortString(), pi.Addrs)
+	n.bus.PublishAsync(events.NearbyPeerDiscoveredEvent{Peer: pi})
 
 	if !n.shouldConnect(pi.ID) {
 		log.Printf("P2P mDNS Discovery: Skipping connection to %s (waiting)", pi.ID.ShortString())
@@ -0,0 +1,171 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"regexp"
+	"strings"
+	"time"
+
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Namespace is the DHT namespace handle records are stored under, as /p2pchat-handle/<handle>.
+const Namespace = "p2pchat-handle"
+
+const (
+	handleRecordMaxSize = 2 * 1024
+	// handleRecordLifetime is how long a published record stays valid without being
+	// republished.
+	handleRecordLifetime = 24 * time.Hour
+	handleRecordMaxSkew  = 10 * time.Minute
+)
+
+var (
+	ErrInvalidHandle = errors.New("invalid handle")
+
+	handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,32}$`)
+)
+
+// NormalizeHandle turns user input such as "@Alice" into the canonical handle "alice".
+func NormalizeHandle(handle string) (string, error) {
+	normalized := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+	if !handlePattern.MatchString(normalized) {
+		return "", fmt.Errorf("%w: %q must be 3 to 32 letters, digits or underscores", ErrInvalidHandle, handle)
+	}
+	return normalized, nil
+}
+
+func recordKey(handle string) string {
+	return "/" + Namespace + "/" + handle
+}
+
+// DHTOption registers the handle record validator with the DHT, so records under
+// Namespace can be stored and resolved. Only peers running this daemon accept them.
+func DHTOption() dht.Option {
+	return dht.NamespacedValidator(Namespace, Validator{})
+}
+
+func signRecord(privKey crypto.PrivKey, data types.HandleRecordData) ([]byte, error) {
+	signature, err := identity.SignPayload(privKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	recordBytes, err := json.Marshal(types.HandleRecord{Data: data, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal handle record: %w", err)
+	}
+	return recordBytes, nil
+}
+
+// handleRecord is a verified record with its timestamps parsed.
+type handleRecord struct {
+	data      types.HandleRecordData
+	claimedAt time.Time
+	issuedAt  time.Time
+	expiresAt time.Time
+}
+
+// parseRecord verifies a record stored under key: it must name the handle in the key, be
+// signed by the peer it binds the handle to, and be unexpired.
+func parseRecord(key string, value []byte) (handleRecord, error) {
+	if len(value) > handleRecordMaxSize {
+		return handleRecord{}, fmt.Errorf("handle record of %d bytes is too large", len(value))
+	}
+
+	var record types.HandleRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return handleRecord{}, fmt.Errorf("failed to deserialize handle record: %w", err)
+	}
+	data := record.Data
+
+	if normalized, err := NormalizeHandle(data.Handle); err != nil || normalized != data.Handle {
+		return handleRecord{}, fmt.Errorf("%w: %q", ErrInvalidHandle, data.Handle)
+	}
+	if key != recordKey(data.Handle) {
+		return handleRecord{}, fmt.Errorf("record for %s stored under %s", data.Handle, key)
+	}
+	if _, err := peer.Decode(data.PeerId); err != nil {
+		return handleRecord{}, fmt.Errorf("invalid peer ID: %w", err)
+	}
+
+	parsed := handleRecord{data: data}
+	var err error
+	if parsed.claimedAt, err = time.Parse(time.RFC3339, data.ClaimedAt); err != nil {
+		return handleRecord{}, fmt.Errorf("invalid claim time: %w", err)
+	}
+	if parsed.issuedAt, err = time.Parse(time.RFC3339, data.IssuedAt); err != nil {
+		return handleRecord{}, fmt.Errorf("invalid issue time: %w", err)
+	}
+	if parsed.expiresAt, err = time.Parse(time.RFC3339, data.ExpiresAt); err != nil {
+		return handleRecord{}, fmt.Errorf("invalid expiry: %w", err)
+	}
+
+	now := time.Now()
+	if parsed.claimedAt.After(parsed.issuedAt) || parsed.issuedAt.Sub(now) > handleRecordMaxSkew {
+		return handleRecord{}, fmt.Errorf("record issued at %s claims the future", data.IssuedAt)
+	}
+	if !parsed.expiresAt.After(now) {
+		return handleRecord{}, fmt.Errorf("record expired at %s", data.ExpiresAt)
+	}
+	if parsed.expiresAt.Sub(parsed.issuedAt) > handleRecordLifetime {
+		return handleRecord{}, fmt.Errorf("record expiry %s is too far out", data.ExpiresAt)
+	}
+
+	if err := identity.VerifyPayload(data.PeerId, data, record.Signature); err != nil {
+		return handleRecord{}, err
+	}
+
+	return parsed, nil
+}
+
+// betterThan reports whether r should replace other. A peer's newer record replaces its
+// older one; between peers a live claim beats a released one, then the earlier stated claim
+// time and the lower peer ID. Claim times are self-signed and can be backdated, so this
+// only makes every node pick the same record; resolvers pin the owner they first see.
+func (r handleRecord) betterThan(other handleRecord) bool {
+	if r.data.PeerId == other.data.PeerId {
+		return r.issuedAt.After(other.issuedAt)
+	}
+	if r.data.Released != other.data.Released {
+		return !r.data.Released
+	}
+	if !r.claimedAt.Equal(other.claimedAt) {
+		return r.claimedAt.Before(other.claimedAt)
+	}
+	return r.data.PeerId < other.data.PeerId
+}
+
+// Validator is the DHT record validator for Namespace.
+type Validator struct{}
+
+func (Validator) Validate(key string, value []byte) error {
+	_, err := parseRecord(key, value)
+	return err
+}
+
+func (Validator) Select(key string, values [][]byte) (int, error) {
+	best := -1
+	var bestRecord handleRecord
+
+	for i, value := range values {
+		record, err := parseRecord(key, value)
+		if err != nil {
+			continue
+		}
+		if best == -1 || record.betterThan(bestRecord) {
+			best, bestRecord = i, record
+		}
+	}
+
+	if best == -1 {
+		return 0, errors.New("no valid handle record")
+	}
+	return best, nil
+}
@@ -0,0 +1,384 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/discovery"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// handleRepublishInterval keeps our records alive well within handleRecordLifetime.
+	handleRepublishInterval = 6 * time.Hour
+	handleRetryInterval     = time.Minute
+	handleLookupTimeout     = 30 * time.Second
+)
+
+var (
+	ErrHandleTaken         = errors.New("handle is taken")
+	ErrHandleNotFound      = errors.New("handle not found")
+	ErrRegistryUnavailable = errors.New("handle registry is not available")
+)
+
+// Service claims handles for us on the DHT and resolves other peers' handles. Claim times
+// are self-reported, so the registry cannot tell who really claimed a handle first; instead
+// each handle is pinned to the peer it first resolved to, and a later change of owner is
+// flagged rather than trusted.
+type Service struct {
+	ctx          context.Context
+	appState     *core.AppState
+	bus          *bus.EventBus
+	repo         storage.HandleClaimRepository
+	mu           sync.Mutex
+	dhtDiscovery *discovery.DHTDiscovery
+}
+
+func NewHandleService(ctx context.Context, appState *core.AppState, bus *bus.EventBus, repo storage.HandleClaimRepository) *Service {
+	return &Service{
+		ctx:      ctx,
+		appState: appState,
+		bus:      bus,
+		repo:     repo,
+	}
+}
+
+// SetDHTDiscovery hands the service the DHT once the node is up, and starts republishing
+// our claims.
+func (s *Service) SetDHTDiscovery(dhtDiscovery *discovery.DHTDiscovery) {
+	s.mu.Lock()
+	s.dhtDiscovery = dhtDiscovery
+	s.mu.Unlock()
+
+	if dhtDiscovery == nil {
+		return
+	}
+
+	go s.runRepublish(dhtDiscovery.Context())
+}
+
+// Claim claims a handle for us. It fails with ErrHandleTaken if another peer holds it.
+func (s *Service) Claim(handle string) (types.HandleClaim, error) {
+	normalized, err := NormalizeHandle(handle)
+	if err != nil {
+		return types.HandleClaim{}, err
+	}
+
+	dhtDiscovery, err := s.registry()
+	if err != nil {
+		return types.HandleClaim{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 2*handleLookupTimeout)
+	defer cancel()
+
+	claim, err := s.repo.GetByHandle(ctx, normalized)
+	if errors.Is(err, sql.ErrNoRows) {
+		claim = types.HandleClaim{
+			Handle:    normalized,
+			ClaimedAt: time.Now().UTC().Truncate(time.Second),
+			Status:    types.HandleStatusPending,
+		}
+
+		resolved, err := s.resolve(ctx, dhtDiscovery, normalized)
+		switch {
+		case errors.Is(err, ErrHandleNotFound):
+		case err != nil:
+			return types.HandleClaim{}, err
+		case resolved.PeerId != s.selfId():
+			return types.HandleClaim{}, fmt.Errorf("%w: @%s belongs to %s", ErrHandleTaken, normalized, resolved.PeerId)
+		default:
+			// Our own record from before the claim was lost locally keeps its place.
+			claim.ClaimedAt = resolved.ClaimedAt
+		}
+
+		if err := s.repo.Save(ctx, claim); err != nil {
+			return types.HandleClaim{}, err
+		}
+	} else if err != nil {
+		return types.HandleClaim{}, err
+	}
+
+	return s.publishClaim(ctx, dhtDiscovery, claim), nil
+}
+
+// Release gives up a handle we claimed. It returns sql.ErrNoRows if we never claimed it.
+func (s *Service) Release(handle string) error {
+	normalized, err := NormalizeHandle(handle)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, handleLookupTimeout)
+	defer cancel()
+
+	claim, err := s.repo.GetByHandle(ctx, normalized)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, normalized); err != nil {
+		return err
+	}
+
+	// Records cannot be removed from the DHT; a released record replaces ours until it expires.
+	dhtDiscovery, err := s.registry()
+	if err != nil {
+		log.Printf("Handle Registry: Released @%s locally only: %v", normalized, err)
+		return nil
+	}
+	if err := s.putRecord(ctx, dhtDiscovery, claim, true); err != nil {
+		log.Printf("Handle Registry: Error publishing release of @%s: %v", normalized, err)
+	}
+
+	log.Printf("Handle Registry: Released @%s", normalized)
+	return nil
+}
+
+// Resolve looks up the peer a handle belongs to. The first peer a handle resolves to is
+// pinned; if it later resolves to another peer, the result is flagged as OwnerChanged until
+// the user trusts the new owner.
+func (s *Service) Resolve(handle string) (types.ResolvedHandle, error) {
+	normalized, err := NormalizeHandle(handle)
+	if err != nil {
+		return types.ResolvedHandle{}, err
+	}
+
+	dhtDiscovery, err := s.registry()
+	if err != nil {
+		return types.ResolvedHandle{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, handleLookupTimeout)
+	defer cancel()
+
+	resolved, err := s.resolve(ctx, dhtDiscovery, normalized)
+	if err != nil {
+		return types.ResolvedHandle{}, err
+	}
+
+	pin, err := s.repo.GetPin(ctx, normalized)
+	if errors.Is(err, sql.ErrNoRows) {
+		pin = types.HandlePin{Handle: normalized, PeerId: resolved.PeerId, PinnedAt: time.Now()}
+		if err := s.repo.SavePin(ctx, pin); err != nil {
+			return types.ResolvedHandle{}, err
+		}
+	} else if err != nil {
+		return types.ResolvedHandle{}, err
+	}
+
+	resolved.PinnedPeerId = pin.PeerId
+	if pin.PeerId != resolved.PeerId {
+		log.Printf("Handle Registry: WARNING - @%s now resolves to %s, but was pinned to %s", normalized, resolved.PeerId, pin.PeerId)
+		resolved.OwnerChanged = true
+	}
+
+	return resolved, nil
+}
+
+// Trust pins a handle to a peer the user confirmed, accepting a change of owner.
+func (s *Service) Trust(handle string, peerId string) (types.HandlePin, error) {
+	normalized, err := NormalizeHandle(handle)
+	if err != nil {
+		return types.HandlePin{}, err
+	}
+	if _, err := peer.Decode(peerId); err != nil {
+		return types.HandlePin{}, fmt.Errorf("%w: invalid peer ID %s: %v", ErrInvalidHandle, peerId, err)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	pin := types.HandlePin{Handle: normalized, PeerId: peerId, PinnedAt: time.Now()}
+	if err := s.repo.SavePin(ctx, pin); err != nil {
+		return types.HandlePin{}, err
+	}
+	return pin, nil
+}
+
+// GetClaims returns the handles we claimed.
+func (s *Service) GetClaims() ([]types.HandleClaim, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.repo.GetAll(ctx)
+}
+
+func (s *Service) registry() (*discovery.DHTDiscovery, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("%w: node is not ready (state: %s)", ErrRegistryUnavailable, s.appState.State)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dhtDiscovery == nil {
+		return nil, fmt.Errorf("%w: DHT is not set up yet", ErrRegistryUnavailable)
+	}
+	return s.dhtDiscovery, nil
+}
+
+func (s *Service) selfId() string {
+	return (*s.appState.Node).ID().String()
+}
+
+// resolve searches the DHT for a handle's records. Claims by other peers seen along the
+// way are reported as contenders.
+func (s *Service) resolve(ctx context.Context, dhtDiscovery *discovery.DHTDiscovery, handle string) (types.ResolvedHandle, error) {
+	key := recordKey(handle)
+
+	values, err := dhtDiscovery.SearchValue(ctx, key)
+	if err != nil {
+		return types.ResolvedHandle{}, fmt.Errorf("failed to look up @%s: %w", handle, err)
+	}
+
+	var best *handleRecord
+	claimants := map[string]struct{}{}
+	for value := range values {
+		record, err := parseRecord(key, value)
+		if err != nil {
+			continue
+		}
+		if !record.data.Released {
+			claimants[record.data.PeerId] = struct{}{}
+		}
+		if best == nil || record.betterThan(*best) {
+			best = &record
+		}
+	}
+
+	if best == nil || best.data.Released {
+		return types.ResolvedHandle{}, fmt.Errorf("%w: @%s", ErrHandleNotFound, handle)
+	}
+
+	resolved := types.ResolvedHandle{
+		Handle:    handle,
+		PeerId:    best.data.PeerId,
+		ClaimedAt: best.claimedAt,
+		ExpiresAt: best.expiresAt,
+	}
+	for claimant := range claimants {
+		if claimant != best.data.PeerId {
+			resolved.Contenders = append(resolved.Contenders, claimant)
+		}
+	}
+	resolved.Conflict = len(resolved.Contenders) > 0
+
+	return resolved, nil
+}
+
+func (s *Service) putRecord(ctx context.Context, dhtDiscovery *discovery.DHTDiscovery, claim types.HandleClaim, released bool) error {
+	now := time.Now().UTC()
+	data := types.HandleRecordData{
+		Handle:    claim.Handle,
+		PeerId:    s.selfId(),
+		ClaimedAt: claim.ClaimedAt.UTC().Format(time.RFC3339),
+		IssuedAt:  now.Format(time.RFC3339),
+		ExpiresAt: now.Add(handleRecordLifetime).Format(time.RFC3339),
+		Released:  released,
+	}
+
+	recordBytes, err := signRecord(s.appState.PrivKey, data)
+	if err != nil {
+		return err
+	}
+
+	return dhtDiscovery.PutValue(ctx, recordKey(claim.Handle), recordBytes)
+}
+
+// publishClaim publishes our record for a claim and checks who the handle resolves to
+// afterwards. The claim's status is updated, and losing the handle to a competing claim is
+// announced.
+func (s *Service) publishClaim(ctx context.Context, dhtDiscovery *discovery.DHTDiscovery, claim types.HandleClaim) types.HandleClaim {
+	previousStatus := claim.Status
+
+	putErr := s.putRecord(ctx, dhtDiscovery, claim, false)
+	resolved, err := s.resolve(ctx, dhtDiscovery, claim.Handle)
+
+	switch {
+	case err == nil && resolved.PeerId != s.selfId():
+		claim.Status = types.HandleStatusConflict
+		claim.OwnerPeerId = resolved.PeerId
+	case putErr != nil:
+		log.Printf("Handle Registry: Error publishing @%s: %v", claim.Handle, putErr)
+		claim.Status = types.HandleStatusPending
+		claim.OwnerPeerId = ""
+	case err == nil:
+		claim.Status = types.HandleStatusPublished
+		claim.OwnerPeerId = ""
+	default:
+		// Published, but not found again yet.
+		claim.Status = types.HandleStatusPending
+		claim.OwnerPeerId = ""
+	}
+
+	if err := s.repo.UpdateStatus(ctx, claim.Handle, claim.Status, claim.OwnerPeerId); err != nil {
+		log.Printf("Handle Registry: Error updating claim on @%s: %v", claim.Handle, err)
+	}
+	claim.UpdatedAt = time.Now()
+
+	if claim.Status == types.HandleStatusConflict && previousStatus != types.HandleStatusConflict {
+		log.Printf("Handle Registry: @%s resolves to %s, whose claim the registry prefers", claim.Handle, claim.OwnerPeerId)
+		s.bus.PublishAsync(events.HandleConflictEvent{Handle: claim.Handle, OwnerPeerId: claim.OwnerPeerId})
+	}
+
+	return claim
+}
+
+// runRepublish republishes our claims before their records expire, retrying soon while
+// any of them could not be published.
+func (s *Service) runRepublish(ctx context.Context) {
+	timer := time.NewTimer(handleRetryInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Handle Registry: Stopped")
+			return
+		case <-timer.C:
+		}
+
+		if s.republishClaims() {
+			timer.Reset(handleRepublishInterval)
+		} else {
+			timer.Reset(handleRetryInterval)
+		}
+	}
+}
+
+// republishClaims reports whether every claim could be published.
+func (s *Service) republishClaims() bool {
+	dhtDiscovery, err := s.registry()
+	if err != nil {
+		return false
+	}
+
+	claims, err := s.GetClaims()
+	if err != nil {
+		log.Printf("Handle Registry: Error loading claims: %v", err)
+		return false
+	}
+
+	published := true
+	for _, claim := range claims {
+		ctx, cancel := context.WithTimeout(s.ctx, 2*handleLookupTimeout)
+		claim = s.publishClaim(ctx, dhtDiscovery, claim)
+		cancel()
+
+		if claim.Status == types.HandleStatusPending {
+			published = false
+		}
+	}
+
+	return published
+}
@@ -0,0 +1,688 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/crypto_utils"
+)
+
+// welcomeSecrets is sealed to a new member's key package inside a welcome.
+type welcomeSecrets struct {
+	EpochSecret []byte
+	PathSecret  []byte
+	PathNode    int
+}
+
+// CommitAdd adds members holding the given key packages. It returns the commit sealed for the
+// group topic and a welcome for every new member.
+func (s *GroupKeyStore) CommitAdd(groupID string, keyPackages []types.GroupKeyPackage) ([]byte, []types.GroupWelcome, error) {
+	for _, kp := range keyPackages {
+		if kp.Data.GroupId != groupID {
+			return nil, nil, fmt.Errorf("key package of %s is for group %s", kp.Data.PeerId, kp.Data.GroupId)
+		}
+		if err := VerifyKeyPackage(kp); err != nil {
+			return nil, nil, err
+		}
+	}
+	return s.commit(groupID, keyPackages, nil)
+}
+
+// CommitRemove removes members from the group. They cannot derive any later epoch.
+func (s *GroupKeyStore) CommitRemove(groupID string, peerIDs []string) ([]byte, error) {
+	sealed, _, err := s.commit(groupID, nil, peerIDs)
+	return sealed, err
+}
+
+// CommitUpdate refreshes our path of the tree without changing membership, healing the
+// group after a possible compromise of our keys.
+func (s *GroupKeyStore) CommitUpdate(groupID string) ([]byte, error) {
+	sealed, _, err := s.commit(groupID, nil, nil)
+	return sealed, err
+}
+
+func (s *GroupKeyStore) commit(groupID string, adds []types.GroupKeyPackage, removes []string) ([]byte, []types.GroupWelcome, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	selfID, err := s.selfID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state, err := s.loadState(groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, removed := range removes {
+		if removed == selfID {
+			return nil, nil, errors.New("cannot remove ourselves from a group with a commit")
+		}
+	}
+
+	if err := checkCommitRights(state.Tree, state.OwnLeaf, adds, removes); err != nil {
+		return nil, nil, err
+	}
+
+	tree, added, err := applyMembershipChanges(state.Tree, adds, removes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leaves := treeLeaves(len(tree))
+	directPath := treeDirectPath(state.OwnLeaf, leaves)
+	copath := treeCopath(state.OwnLeaf, leaves)
+
+	leafSecret, err := randomSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+	leafKey, err := deriveNodeKey(leafSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tree[state.OwnLeaf] = types.GroupTreeNode{PublicKey: leafKey.PublicKey().Bytes(), PeerId: selfID, Admin: tree[state.OwnLeaf].Admin}
+	privateKeys := map[int][]byte{state.OwnLeaf: leafKey.Bytes()}
+
+	excluded := make(map[int]bool, len(added))
+	for _, leaf := range added {
+		excluded[leaf] = true
+	}
+
+	aad := commitAAD(groupID, state.Epoch)
+	pathSecrets := make([][]byte, len(directPath))
+	path := make([]types.GroupUpdatePathNode, len(directPath))
+	pathSecret := leafSecret
+
+	for i, node := range directPath {
+		pathSecret, err = nextPathSecret(pathSecret)
+		if err != nil {
+			return nil, nil, err
+		}
+		pathSecrets[i] = pathSecret
+
+		nodeKey, err := deriveNodeKey(pathSecret)
+		if err != nil {
+			return nil, nil, err
+		}
+		tree[node] = types.GroupTreeNode{PublicKey: nodeKey.PublicKey().Bytes()}
+		privateKeys[node] = nodeKey.Bytes()
+		path[i].PublicKey = nodeKey.PublicKey().Bytes()
+
+		for _, recipient := range treeResolution(tree, copath[i], excluded) {
+			sealed, err := sealToNode(recipient, tree[recipient].PublicKey, pathSecret, aad)
+			if err != nil {
+				return nil, nil, err
+			}
+			path[i].Secrets = append(path[i].Secrets, sealed)
+		}
+	}
+
+	commitSecret, err := nextPathSecret(pathSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := types.GroupCommitData{
+		GroupId:         groupID,
+		Epoch:           state.Epoch,
+		CommitterPeerId: selfID,
+		Adds:            adds,
+		Removes:         removes,
+		LeafPublicKey:   leafKey.PublicKey().Bytes(),
+		Path:            path,
+	}
+
+	signature, err := SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	commit := types.GroupCommit{Data: data, Signature: signature}
+
+	commitHash, err := HashPayload(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commitBytes, err := json.Marshal(commit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal commit: %w", err)
+	}
+
+	sealedCommit, err := state.seal(types.GroupContentCommit, "", commitBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next, epochSecret, err := state.advance(tree, privateKeys, commitSecret, commitHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var welcomes []types.GroupWelcome
+	for _, leaf := range added {
+		welcome, err := s.welcome(next, leaf, directPath, pathSecrets, epochSecret)
+		if err != nil {
+			return nil, nil, err
+		}
+		welcomes = append(welcomes, *welcome)
+	}
+
+	if err := s.persistCommit(next, state.Epoch, commitHash, commitBytes); err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("GroupKeyStore: Committed epoch %d of group %s (%d added, %d removed)", next.Epoch, groupID, len(adds), len(removes))
+	return sealedCommit, welcomes, nil
+}
+
+// welcome hands a new leaf the epoch secret and the path secret of the lowest node it
+// shares with the committer, from which it derives the rest of its path.
+func (s *GroupKeyStore) welcome(next *groupEpochState, leaf int, directPath []int, pathSecrets [][]byte, epochSecret []byte) (*types.GroupWelcome, error) {
+	pathIndex := -1
+	for i, node := range directPath {
+		if treeIsAncestor(node, leaf) {
+			pathIndex = i
+			break
+		}
+	}
+	if pathIndex < 0 {
+		return nil, fmt.Errorf("leaf %d shares no path with the committer", leaf)
+	}
+
+	secretBytes, err := json.Marshal(welcomeSecrets{
+		EpochSecret: epochSecret,
+		PathSecret:  pathSecrets[pathIndex],
+		PathNode:    directPath[pathIndex],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal welcome secrets: %w", err)
+	}
+
+	sealed, err := sealToNode(leaf, next.Tree[leaf].PublicKey, secretBytes, welcomeAAD(next.GroupId, next.Epoch))
+	if err != nil {
+		return nil, err
+	}
+
+	data := types.GroupWelcomeData{
+		GroupId:         next.GroupId,
+		Name:            next.Name,
+		Epoch:           next.Epoch,
+		CommitHash:      next.CommitHash,
+		CommitterPeerId: next.Tree[next.OwnLeaf].PeerId,
+		Tree:            next.Tree,
+		Secrets:         sealed,
+	}
+
+	signature, err := SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.GroupWelcome{Data: data, Signature: signature}, nil
+}
+
+// ApplyCommit moves the group to the epoch created by a commit from another member.
+// Concurrent commits for the same epoch are settled by keeping the one with the lowest hash.
+// Returns nil when the commit was already applied or lost to a concurrent one.
+func (s *GroupKeyStore) ApplyCommit(groupID string, commit types.GroupCommit) (*GroupEpochChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := commit.Data
+	if data.GroupId != groupID {
+		return nil, fmt.Errorf("commit for group %s arrived on group %s", data.GroupId, groupID)
+	}
+
+	if err := VerifyPayload(data.CommitterPeerId, data, commit.Signature); err != nil {
+		return nil, err
+	}
+
+	commitHash, err := HashPayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.loadState(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	base := current
+	switch {
+	case data.Epoch > current.Epoch:
+		return nil, ErrGroupEpochAhead
+	case data.Epoch == current.Epoch:
+	case data.Epoch == current.Epoch-1 && commitHash < current.CommitHash:
+		base, err = s.loadEpochRecord(s.keyRepo.GetEpoch(s.ctx, groupID, data.Epoch))
+		if err != nil {
+			return nil, fmt.Errorf("cannot settle concurrent commit for epoch %d: %w", data.Epoch, err)
+		}
+		log.Printf("GroupKeyStore: Commit %s wins epoch %d of group %s over %s", commitHash, data.Epoch+1, groupID, current.CommitHash)
+	default:
+		return nil, nil
+	}
+
+	next, change, err := s.processCommit(base, data, commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if change.RemovedSelf {
+		if err := s.keyRepo.DeleteEpochs(s.ctx, groupID); err != nil {
+			return nil, err
+		}
+		log.Printf("GroupKeyStore: We were removed from group %s", groupID)
+		return change, nil
+	}
+
+	if base != current {
+		next.retain(current.ApplicationKey, current.Epoch)
+	}
+
+	commitBytes, err := json.Marshal(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal commit: %w", err)
+	}
+
+	if err := s.persistCommit(next, data.Epoch, commitHash, commitBytes); err != nil {
+		return nil, err
+	}
+
+	log.Printf("GroupKeyStore: Group %s moved to epoch %d by %s", groupID, next.Epoch, data.CommitterPeerId)
+	return change, nil
+}
+
+func (s *GroupKeyStore) processCommit(base *groupEpochState, data types.GroupCommitData, commitHash string) (*groupEpochState, *GroupEpochChange, error) {
+	selfID, err := s.selfID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	committerLeaf := findLeaf(base.Tree, data.CommitterPeerId)
+	if committerLeaf < 0 {
+		return nil, nil, fmt.Errorf("committer %s is not a member of group %s", data.CommitterPeerId, base.GroupId)
+	}
+
+	for _, kp := range data.Adds {
+		if kp.Data.GroupId != base.GroupId {
+			return nil, nil, fmt.Errorf("key package of %s is for group %s", kp.Data.PeerId, kp.Data.GroupId)
+		}
+		if err := VerifyKeyPackage(kp); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, removed := range data.Removes {
+		if removed == data.CommitterPeerId {
+			return nil, nil, errors.New("committer cannot remove itself")
+		}
+	}
+
+	if err := checkCommitRights(base.Tree, committerLeaf, data.Adds, data.Removes); err != nil {
+		return nil, nil, err
+	}
+
+	tree, added, err := applyMembershipChanges(base.Tree, data.Adds, data.Removes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	change := &GroupEpochChange{
+		GroupId:   base.GroupId,
+		Epoch:     base.Epoch + 1,
+		Committer: data.CommitterPeerId,
+		Removed:   data.Removes,
+	}
+	for _, kp := range data.Adds {
+		change.Added = append(change.Added, kp.Data.PeerId)
+	}
+
+	if tree[base.OwnLeaf].PeerId != selfID {
+		change.RemovedSelf = true
+		return nil, change, nil
+	}
+
+	leaves := treeLeaves(len(tree))
+	directPath := treeDirectPath(committerLeaf, leaves)
+	copath := treeCopath(committerLeaf, leaves)
+
+	if len(data.Path) != len(directPath) {
+		return nil, nil, fmt.Errorf("commit path has %d nodes, expected %d", len(data.Path), len(directPath))
+	}
+	if _, err := ecdh.X25519().NewPublicKey(data.LeafPublicKey); err != nil {
+		return nil, nil, fmt.Errorf("invalid committer leaf key: %w", err)
+	}
+
+	tree[committerLeaf] = types.GroupTreeNode{PublicKey: data.LeafPublicKey, PeerId: data.CommitterPeerId, Admin: tree[committerLeaf].Admin}
+	for i, node := range directPath {
+		tree[node] = types.GroupTreeNode{PublicKey: data.Path[i].PublicKey}
+	}
+
+	privateKeys := keepValidKeys(tree, base.PrivateKeys)
+
+	excluded := make(map[int]bool, len(added))
+	for _, leaf := range added {
+		excluded[leaf] = true
+	}
+
+	pathIndex := -1
+	for i, node := range directPath {
+		if treeIsAncestor(node, base.OwnLeaf) {
+			pathIndex = i
+			break
+		}
+	}
+	if pathIndex < 0 {
+		return nil, nil, errors.New("commit path does not cover our leaf")
+	}
+
+	pathSecret, err := openPathSecret(tree, copath[pathIndex], excluded, privateKeys, data.Path[pathIndex].Secrets, commitAAD(base.GroupId, base.Epoch))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := pathIndex; i < len(directPath); i++ {
+		if i > pathIndex {
+			pathSecret, err = nextPathSecret(pathSecret)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		nodeKey, err := deriveNodeKey(pathSecret)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !bytes.Equal(nodeKey.PublicKey().Bytes(), tree[directPath[i]].PublicKey) {
+			return nil, nil, fmt.Errorf("path secret does not match public key of node %d", directPath[i])
+		}
+		privateKeys[directPath[i]] = nodeKey.Bytes()
+	}
+
+	commitSecret, err := nextPathSecret(pathSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next, _, err := base.advance(tree, privateKeys, commitSecret, commitHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return next, change, nil
+}
+
+// JoinFromWelcome sets up our state for a group from the welcome sent by the member that added us.
+func (s *GroupKeyStore) JoinFromWelcome(welcome types.GroupWelcome) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := welcome.Data
+	if err := VerifyPayload(data.CommitterPeerId, data, welcome.Signature); err != nil {
+		return err
+	}
+
+	if _, err := s.loadState(data.GroupId); err == nil {
+		return fmt.Errorf("already a member of group %s", data.GroupId)
+	}
+
+	selfID, err := s.selfID()
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.keyRepo.GetKeyPackage(s.ctx, data.GroupId)
+	if err != nil {
+		return fmt.Errorf("no key package waiting for group %s: %w", data.GroupId, err)
+	}
+
+	privateKeyBytes, err := crypto_utils.DecryptDataWithKey(s.appState.DbKey, stored.PrivateKey, core.DefaultCryptoConfig)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt key package: %w", err)
+	}
+	leafKey, err := ecdh.X25519().NewPrivateKey(privateKeyBytes)
+	if err != nil {
+		return fmt.Errorf("invalid key package: %w", err)
+	}
+
+	committerLeaf := findLeaf(data.Tree, data.CommitterPeerId)
+	if len(data.Tree)%2 == 0 || committerLeaf < 0 {
+		return errors.New("welcome carries an invalid tree")
+	}
+	if !data.Tree[committerLeaf].Admin {
+		return fmt.Errorf("%w: welcome from %s", ErrNotGroupAdmin, data.CommitterPeerId)
+	}
+
+	ownLeaf := findLeaf(data.Tree, selfID)
+	if ownLeaf < 0 || ownLeaf != data.Secrets.Node || !bytes.Equal(data.Tree[ownLeaf].PublicKey, stored.PublicKey) {
+		return errors.New("welcome is not addressed to our key package")
+	}
+
+	secretBytes, err := openFromNode(leafKey, data.Secrets, welcomeAAD(data.GroupId, data.Epoch))
+	if err != nil {
+		return fmt.Errorf("failed to open welcome: %w", err)
+	}
+
+	var secrets welcomeSecrets
+	if err := json.Unmarshal(secretBytes, &secrets); err != nil {
+		return fmt.Errorf("failed to parse welcome secrets: %w", err)
+	}
+
+	directPath := treeDirectPath(ownLeaf, treeLeaves(len(data.Tree)))
+	privateKeys := map[int][]byte{ownLeaf: leafKey.Bytes()}
+
+	pathSecret := secrets.PathSecret
+	started := false
+	for _, node := range directPath {
+		if node == secrets.PathNode {
+			started = true
+		} else if !started {
+			continue
+		} else if pathSecret, err = nextPathSecret(pathSecret); err != nil {
+			return err
+		}
+
+		nodeKey, err := deriveNodeKey(pathSecret)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(nodeKey.PublicKey().Bytes(), data.Tree[node].PublicKey) {
+			return fmt.Errorf("welcome path secret does not match public key of node %d", node)
+		}
+		privateKeys[node] = nodeKey.Bytes()
+	}
+	if !started {
+		return errors.New("welcome path secret is not on our path")
+	}
+
+	state := &groupEpochState{
+		GroupId:     data.GroupId,
+		Name:        data.Name,
+		Epoch:       data.Epoch,
+		CommitHash:  data.CommitHash,
+		Tree:        data.Tree,
+		OwnLeaf:     ownLeaf,
+		PrivateKeys: privateKeys,
+	}
+	if err := state.setEpochSecret(secrets.EpochSecret); err != nil {
+		return err
+	}
+
+	if err := s.storeGroupRecord(data.GroupId, data.Name); err != nil {
+		return err
+	}
+	if err := s.storeState(state); err != nil {
+		return err
+	}
+
+	log.Printf("GroupKeyStore: Joined group %s at epoch %d", data.GroupId, data.Epoch)
+	return s.keyRepo.DeleteKeyPackage(s.ctx, data.GroupId)
+}
+
+// advance builds the state of the next epoch from the updated tree and the commit secret.
+func (st *groupEpochState) advance(tree []types.GroupTreeNode, privateKeys map[int][]byte, commitSecret []byte, commitHash string) (*groupEpochState, []byte, error) {
+	epochSecret, err := st.nextEpochSecret(commitSecret, commitHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := &groupEpochState{
+		GroupId:      st.GroupId,
+		Name:         st.Name,
+		Epoch:        st.Epoch + 1,
+		CommitHash:   commitHash,
+		Tree:         tree,
+		OwnLeaf:      st.OwnLeaf,
+		PrivateKeys:  privateKeys,
+		RetainedKeys: append([]retainedKey{}, st.RetainedKeys...),
+	}
+	next.retain(st.ApplicationKey, st.Epoch)
+
+	if err := next.setEpochSecret(epochSecret); err != nil {
+		return nil, nil, err
+	}
+
+	return next, epochSecret, nil
+}
+
+func (s *GroupKeyStore) persistCommit(next *groupEpochState, epoch int64, commitHash string, commitBytes []byte) error {
+	err := s.keyRepo.StoreCommit(s.ctx, types.GroupCommitRecord{
+		GroupId:    next.GroupId,
+		Epoch:      epoch,
+		CommitHash: commitHash,
+		Commit:     commitBytes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.storeState(next)
+}
+
+// applyMembershipChanges returns a copy of the tree with members removed and added, and the
+// leaves the new members took. Paths above changed leaves are blanked.
+func applyMembershipChanges(tree []types.GroupTreeNode, adds []types.GroupKeyPackage, removes []string) ([]types.GroupTreeNode, []int, error) {
+	updated := append([]types.GroupTreeNode{}, tree...)
+
+	for _, removed := range removes {
+		leaf := findLeaf(updated, removed)
+		if leaf < 0 {
+			return nil, nil, fmt.Errorf("%s is not a member of the group", removed)
+		}
+		updated[leaf] = types.GroupTreeNode{}
+		blankPath(updated, leaf)
+	}
+
+	var added []int
+	for _, kp := range adds {
+		if findLeaf(updated, kp.Data.PeerId) >= 0 {
+			return nil, nil, fmt.Errorf("%s is already a member of the group", kp.Data.PeerId)
+		}
+
+		leaf := -1
+		for i := 0; i < len(updated); i += 2 {
+			if updated[i].PeerId == "" && len(updated[i].PublicKey) == 0 {
+				leaf = i
+				break
+			}
+		}
+		if leaf < 0 {
+			leaf = len(updated) + 1
+			updated = append(updated, make([]types.GroupTreeNode, treeWidth(2*treeLeaves(len(updated)))-len(updated))...)
+		}
+
+		updated[leaf] = types.GroupTreeNode{PublicKey: kp.Data.PublicKey, PeerId: kp.Data.PeerId}
+		blankPath(updated, leaf)
+		added = append(added, leaf)
+	}
+
+	return updated, added, nil
+}
+
+// checkCommitRights checks that the member at committerLeaf may make a commit with these
+// membership changes. Anyone may refresh their own path; only admins add or remove members,
+// and admins cannot be removed.
+func checkCommitRights(tree []types.GroupTreeNode, committerLeaf int, adds []types.GroupKeyPackage, removes []string) error {
+	if len(adds) == 0 && len(removes) == 0 {
+		return nil
+	}
+	if !tree[committerLeaf].Admin {
+		return fmt.Errorf("%w: %s cannot change its members", ErrNotGroupAdmin, tree[committerLeaf].PeerId)
+	}
+	for _, removed := range removes {
+		if leaf := findLeaf(tree, removed); leaf >= 0 && tree[leaf].Admin {
+			return fmt.Errorf("admin %s cannot be removed from the group", removed)
+		}
+	}
+	return nil
+}
+
+func blankPath(tree []types.GroupTreeNode, leaf int) {
+	for _, node := range treeDirectPath(leaf, treeLeaves(len(tree))) {
+		tree[node] = types.GroupTreeNode{}
+	}
+}
+
+func findLeaf(tree []types.GroupTreeNode, peerID string) int {
+	for i := 0; i < len(tree); i += 2 {
+		if tree[i].PeerId == peerID && len(tree[i].PublicKey) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// keepValidKeys drops private keys of nodes whose public key changed or was blanked.
+func keepValidKeys(tree []types.GroupTreeNode, privateKeys map[int][]byte) map[int][]byte {
+	valid := make(map[int][]byte, len(privateKeys))
+	for node, keyBytes := range privateKeys {
+		if node >= len(tree) {
+			continue
+		}
+		key, err := ecdh.X25519().NewPrivateKey(keyBytes)
+		if err != nil || !bytes.Equal(key.PublicKey().Bytes(), tree[node].PublicKey) {
+			continue
+		}
+		valid[node] = keyBytes
+	}
+	return valid
+}
+
+// openPathSecret finds the secret sealed to a node of the copath subtree we hold the key of.
+func openPathSecret(tree []types.GroupTreeNode, copathNode int, excluded map[int]bool, privateKeys map[int][]byte, secrets []types.GroupSealedSecret, aad []byte) ([]byte, error) {
+	for _, recipient := range treeResolution(tree, copathNode, excluded) {
+		keyBytes, ok := privateKeys[recipient]
+		if !ok {
+			continue
+		}
+
+		for _, sealed := range secrets {
+			if sealed.Node != recipient {
+				continue
+			}
+
+			key, err := ecdh.X25519().NewPrivateKey(keyBytes)
+			if err != nil {
+				return nil, err
+			}
+			return openFromNode(key, sealed, aad)
+		}
+	}
+	return nil, errors.New("no path secret sealed to a node we hold")
+}
+
+func commitAAD(groupID string, epoch int64) []byte {
+	return []byte(fmt.Sprintf("%s|%d|commit", groupID, epoch))
+}
+
+func welcomeAAD(groupID string, epoch int64) []byte {
+	return []byte(fmt.Sprintf("%s|%d|welcome", groupID, epoch))
+}
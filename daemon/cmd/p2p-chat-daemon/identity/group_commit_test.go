@@ -0,0 +1,330 @@
+package identity
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/config"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"path/filepath"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const testGroupID = "test-group"
+
+type testMember struct {
+	id    string
+	store *GroupKeyStore
+}
+
+func newTestMember(t *testing.T, name string) *testMember {
+	t.Helper()
+
+	privKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	id, err := peer.IDFromPrivateKey(privKey)
+	if err != nil {
+		t.Fatalf("peer ID: %v", err)
+	}
+
+	db, err := storage.NewDB(&config.Config{P2P: config.P2PConfig{DbPath: filepath.Join(t.TempDir(), name+".db")}})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	keyRepo, err := storage.NewSQLiteKeyRepository(db)
+	if err != nil {
+		t.Fatalf("key repository: %v", err)
+	}
+
+	appState := core.NewAppState("")
+	appState.PrivKey = privKey
+	appState.DbKey = make([]byte, core.DefaultCryptoConfig.ArgonKeyLen)
+	if _, err := rand.Read(appState.DbKey); err != nil {
+		t.Fatalf("db key: %v", err)
+	}
+
+	return &testMember{id: id.String(), store: NewGroupKeyStore(keyRepo, appState, context.Background())}
+}
+
+func (m *testMember) state(t *testing.T) *groupEpochState {
+	t.Helper()
+	state, err := m.store.loadState(testGroupID)
+	if err != nil {
+		t.Fatalf("%s: load state: %v", m.id, err)
+	}
+	return state
+}
+
+// openCommit reads a commit published on the group topic, as a member still in its epoch would.
+func openCommit(t *testing.T, reader *testMember, sealed []byte) types.GroupCommit {
+	t.Helper()
+	contentType, plaintext, err := reader.store.Open(testGroupID, sealed)
+	if err != nil {
+		t.Fatalf("open commit: %v", err)
+	}
+	if contentType != types.GroupContentCommit {
+		t.Fatalf("content type = %q, want %q", contentType, types.GroupContentCommit)
+	}
+	var commit types.GroupCommit
+	if err := json.Unmarshal(plaintext, &commit); err != nil {
+		t.Fatalf("parse commit: %v", err)
+	}
+	return commit
+}
+
+// newTestGroup creates a group of size members, the first being its admin, all in the same epoch.
+func newTestGroup(t *testing.T, size int) []*testMember {
+	t.Helper()
+
+	admin := newTestMember(t, "member0")
+	if err := admin.store.CreateGroup(testGroupID, "Test"); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+
+	members := []*testMember{admin}
+	for i := 1; i < size; i++ {
+		members = append(members, addTestMember(t, members, newTestMember(t, fmt.Sprintf("member%d", i))))
+	}
+	return members
+}
+
+// addTestMember has the admin add newcomer and every other member apply the commit.
+func addTestMember(t *testing.T, members []*testMember, newcomer *testMember) *testMember {
+	t.Helper()
+
+	keyPackage, err := newcomer.store.NewKeyPackage(testGroupID)
+	if err != nil {
+		t.Fatalf("key package: %v", err)
+	}
+
+	sealed, welcomes, err := members[0].store.CommitAdd(testGroupID, []types.GroupKeyPackage{*keyPackage})
+	if err != nil {
+		t.Fatalf("commit add: %v", err)
+	}
+	if len(welcomes) != 1 {
+		t.Fatalf("got %d welcomes, want 1", len(welcomes))
+	}
+
+	for _, m := range members[1:] {
+		if _, err := m.store.ApplyCommit(testGroupID, openCommit(t, m, sealed)); err != nil {
+			t.Fatalf("%s: apply add: %v", m.id, err)
+		}
+	}
+
+	if err := newcomer.store.JoinFromWelcome(welcomes[0]); err != nil {
+		t.Fatalf("join from welcome: %v", err)
+	}
+	return newcomer
+}
+
+func requireSameEpoch(t *testing.T, members []*testMember) {
+	t.Helper()
+	want := members[0].state(t)
+	for _, m := range members[1:] {
+		got := m.state(t)
+		if got.Epoch != want.Epoch || got.CommitHash != want.CommitHash {
+			t.Fatalf("%s is at epoch %d (%s), want %d (%s)", m.id, got.Epoch, got.CommitHash, want.Epoch, want.CommitHash)
+		}
+		if !bytes.Equal(got.ApplicationKey, want.ApplicationKey) || !bytes.Equal(got.InitSecret, want.InitSecret) {
+			t.Fatalf("%s derived a different secret for epoch %d", m.id, got.Epoch)
+		}
+	}
+}
+
+func TestJoinFromWelcomeDerivesEpochSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		members int
+	}{
+		{"join a group of one", 1},
+		{"join a group of two", 2},
+		{"join a group of three", 3},
+		{"join a full tree of four", 4},
+		{"join a group of five", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := newTestGroup(t, tt.members)
+			newcomer := addTestMember(t, members, newTestMember(t, "newcomer"))
+			members = append(members, newcomer)
+
+			requireSameEpoch(t, members)
+			if got := newcomer.state(t).Epoch; got != int64(tt.members) {
+				t.Fatalf("newcomer is at epoch %d, want %d", got, tt.members)
+			}
+
+			sealed, err := members[0].store.Encrypt(testGroupID, "", []byte("hello"))
+			if err != nil {
+				t.Fatalf("encrypt: %v", err)
+			}
+			_, plaintext, err := newcomer.store.Open(testGroupID, sealed)
+			if err != nil || string(plaintext) != "hello" {
+				t.Fatalf("newcomer opened %q, %v", plaintext, err)
+			}
+		})
+	}
+}
+
+func TestRemovedMemberCannotDeriveNextEpoch(t *testing.T) {
+	tests := []struct {
+		name    string
+		members int
+		removed int
+	}{
+		{"remove from a group of two", 2, 1},
+		{"remove the middle of three", 3, 1},
+		{"remove the last of three", 3, 2},
+		{"remove from a full tree of four", 4, 3},
+		{"remove the sibling of the admin", 5, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := newTestGroup(t, tt.members)
+			removed := members[tt.removed]
+			before := removed.state(t)
+
+			sealed, err := members[0].store.CommitRemove(testGroupID, []string{removed.id})
+			if err != nil {
+				t.Fatalf("commit remove: %v", err)
+			}
+			commit := openCommit(t, removed, sealed)
+
+			var remaining []*testMember
+			for i, m := range members {
+				if i != tt.removed {
+					remaining = append(remaining, m)
+				}
+			}
+			for _, m := range remaining[1:] {
+				if _, err := m.store.ApplyCommit(testGroupID, commit); err != nil {
+					t.Fatalf("%s: apply remove: %v", m.id, err)
+				}
+			}
+			requireSameEpoch(t, remaining)
+
+			// None of the keys the removed member held opens a path secret of the commit.
+			for _, node := range commit.Data.Path {
+				for _, secret := range node.Secrets {
+					if _, ok := before.PrivateKeys[secret.Node]; ok {
+						t.Fatalf("path secret was sealed to node %d, which the removed member holds", secret.Node)
+					}
+				}
+			}
+
+			change, err := removed.store.ApplyCommit(testGroupID, commit)
+			if err != nil {
+				t.Fatalf("removed member: apply remove: %v", err)
+			}
+			if change == nil || !change.RemovedSelf {
+				t.Fatalf("removed member was not told it was removed: %+v", change)
+			}
+			if _, err := removed.store.loadState(testGroupID); !errors.Is(err, ErrNoGroupState) {
+				t.Fatalf("removed member kept its state: %v", err)
+			}
+
+			message, err := members[0].store.Encrypt(testGroupID, "", []byte("after"))
+			if err != nil {
+				t.Fatalf("encrypt: %v", err)
+			}
+			if _, _, err := removed.store.Open(testGroupID, message); err == nil {
+				t.Fatal("removed member opened a message of the next epoch")
+			}
+		})
+	}
+}
+
+func TestRemoveRequiresAdmin(t *testing.T) {
+	members := newTestGroup(t, 3)
+
+	if _, err := members[1].store.CommitRemove(testGroupID, []string{members[2].id}); !errors.Is(err, ErrNotGroupAdmin) {
+		t.Fatalf("member removed another member: %v", err)
+	}
+	if _, err := members[1].store.CommitRemove(testGroupID, []string{members[0].id}); !errors.Is(err, ErrNotGroupAdmin) {
+		t.Fatalf("member removed the admin: %v", err)
+	}
+}
+
+func TestConcurrentCommitsSettleOnOneWinner(t *testing.T) {
+	tests := []struct {
+		name        string
+		members     int
+		committers  [2]int
+		secondFirst bool // the bystanders see the second commit first
+	}{
+		{"two members update at once", 3, [2]int{1, 2}, false},
+		{"two members update at once, seen in reverse", 3, [2]int{1, 2}, true},
+		{"admin and member update at once", 4, [2]int{0, 3}, false},
+		{"admin and member update at once, seen in reverse", 4, [2]int{0, 3}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := newTestGroup(t, tt.members)
+			first, second := members[tt.committers[0]], members[tt.committers[1]]
+
+			var commits [2]types.GroupCommit
+			for i, committer := range []*testMember{first, second} {
+				reader := members[tt.committers[1-i]]
+				sealed, err := committer.store.CommitUpdate(testGroupID)
+				if err != nil {
+					t.Fatalf("%s: commit update: %v", committer.id, err)
+				}
+				commits[i] = openCommit(t, reader, sealed)
+			}
+
+			hashes := [2]string{}
+			for i := range commits {
+				hash, err := HashPayload(commits[i].Data)
+				if err != nil {
+					t.Fatalf("hash commit: %v", err)
+				}
+				hashes[i] = hash
+			}
+			winner := hashes[0]
+			if hashes[1] < winner {
+				winner = hashes[1]
+			}
+
+			if _, err := first.store.ApplyCommit(testGroupID, commits[1]); err != nil {
+				t.Fatalf("first committer: apply concurrent commit: %v", err)
+			}
+			if _, err := second.store.ApplyCommit(testGroupID, commits[0]); err != nil {
+				t.Fatalf("second committer: apply concurrent commit: %v", err)
+			}
+
+			order := []types.GroupCommit{commits[0], commits[1]}
+			if tt.secondFirst {
+				order[0], order[1] = order[1], order[0]
+			}
+			for i, m := range members {
+				if i == tt.committers[0] || i == tt.committers[1] {
+					continue
+				}
+				for _, commit := range order {
+					if _, err := m.store.ApplyCommit(testGroupID, commit); err != nil {
+						t.Fatalf("%s: apply commit: %v", m.id, err)
+					}
+				}
+			}
+
+			requireSameEpoch(t, members)
+			if got := members[0].state(t).CommitHash; got != winner {
+				t.Fatalf("members settled on %s, want the lowest hash %s", got, winner)
+			}
+		})
+	}
+}
@@ -1,70 +1,641 @@
 package identity
 
 import (
+	"bytes"
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
 	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/crypto_utils"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
 	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
 )
 
 const (
-	groupKeySize   = 32 // AES-256
 	groupNonceSize = 12 // AES-GCM standard nonce size
+
+	// groupRetainedEpochs is how many past application keys are kept for messages still in
+	// flight: the previous epoch's and, after a concurrent commit, the losing one's.
+	groupRetainedEpochs = 2
+
+	// groupRetainedKeyLifetime bounds how long a past application key opens late messages.
+	groupRetainedKeyLifetime = 10 * time.Minute
+
+	// groupSettleWindow is how long the full state of a superseded epoch is kept to settle a
+	// concurrent commit. After that its tree keys and init secret are wiped.
+	groupSettleWindow = 5 * time.Minute
+
+	groupKeyPruneInterval = time.Minute
+
+	// staticKeyId marks framed content encrypted with the static key of an older group.
+	staticKeyId = "static"
+)
+
+var (
+	// ErrGroupEpochAhead is returned for content from an epoch we have not reached yet.
+	ErrGroupEpochAhead = errors.New("group content is from a newer epoch")
+	ErrNoGroupState    = errors.New("no key state for group")
+	ErrGroupKeyExpired = errors.New("group content is from an epoch whose key is no longer kept")
+	ErrNotGroupAdmin   = errors.New("not an admin of the group")
 )
 
-// GroupKeyStore manages symmetric keys for groups.
+// GroupKeyStore manages the key agreement state of groups. Each group moves through
+// epochs; a commit adds or removes members and replaces the committer's path of the
+// ratchet tree with fresh secrets, so past epochs stay secret (forward secrecy) and a
+// leaked state stops working after the next commit (post-compromise security).
+// Groups created before epochs existed keep their static key.
 type GroupKeyStore struct {
-	mu      sync.RWMutex
-	keyRepo storage.KeyRepository
-	ctx     context.Context
+	mu       sync.RWMutex
+	keyRepo  storage.KeyRepository
+	appState *core.AppState
+	ctx      context.Context
+}
+
+// groupEpochState is what a member knows about the group in one epoch.
+type groupEpochState struct {
+	GroupId        string
+	Name           string
+	Epoch          int64
+	CommitHash     string
+	Tree           []types.GroupTreeNode
+	OwnLeaf        int
+	PrivateKeys    map[int][]byte // own leaf and the ancestors whose secrets we know
+	InitSecret     []byte
+	ApplicationKey []byte
+	RetainedKeys   []retainedKey
+}
+
+type retainedKey struct {
+	Epoch     int64
+	KeyId     string
+	Key       []byte
+	ExpiresAt time.Time
+}
+
+// GroupEpochChange describes what a commit changed.
+type GroupEpochChange struct {
+	GroupId     string
+	Epoch       int64
+	Committer   string
+	Added       []string
+	Removed     []string
+	RemovedSelf bool
 }
 
 // NewGroupKeyStore creates a new GroupKeyStore.
-func NewGroupKeyStore(keyRepo storage.KeyRepository, ctx context.Context) *GroupKeyStore {
+func NewGroupKeyStore(keyRepo storage.KeyRepository, appState *core.AppState, ctx context.Context) *GroupKeyStore {
 	return &GroupKeyStore{
-		keyRepo: keyRepo,
-		ctx:     ctx,
+		keyRepo:  keyRepo,
+		appState: appState,
+		ctx:      ctx,
 	}
 }
 
-// GenerateNewKey creates a new symmetric key for a group and stores it.
-func (s *GroupKeyStore) GenerateNewKey(groupID string, groupName string) ([]byte, error) {
-	key := make([]byte, groupKeySize)
-	if _, err := io.ReadFull(rand.Reader, key); err != nil {
-		return nil, fmt.Errorf("failed to generate group key for %s: %w", groupID, err)
+// CreateGroup starts epoch 0 of a new group with ourselves as the only leaf.
+func (s *GroupKeyStore) CreateGroup(groupID string, groupName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	selfID, err := s.selfID()
+	if err != nil {
+		return err
 	}
 
-	s.keyRepo.Store(s.ctx, types.GroupKey{
-		Key:     key,
-		GroupId: groupID,
-		Name:    groupName,
-	})
+	leafSecret, err := randomSecret()
+	if err != nil {
+		return err
+	}
+	leafKey, err := deriveNodeKey(leafSecret)
+	if err != nil {
+		return err
+	}
+
+	epochSecret, err := randomSecret()
+	if err != nil {
+		return err
+	}
 
-	return key, nil
+	state := &groupEpochState{
+		GroupId:     groupID,
+		Name:        groupName,
+		Epoch:       0,
+		Tree:        []types.GroupTreeNode{{PublicKey: leafKey.PublicKey().Bytes(), PeerId: selfID, Admin: true}},
+		OwnLeaf:     0,
+		PrivateKeys: map[int][]byte{0: leafKey.Bytes()},
+	}
+	if err := state.setEpochSecret(epochSecret); err != nil {
+		return err
+	}
+
+	if err := s.storeGroupRecord(groupID, groupName); err != nil {
+		return err
+	}
+
+	return s.storeState(state)
+}
+
+// HasEpochState reports whether the group uses epoch keys rather than a static key.
+func (s *GroupKeyStore) HasEpochState(groupID string) bool {
+	_, err := s.keyRepo.GetLatestEpoch(s.ctx, groupID)
+	return err == nil
+}
+
+// HasGroupKeys reports whether we hold any key of the group, epoch based or static.
+func (s *GroupKeyStore) HasGroupKeys(groupID string) bool {
+	if s.HasEpochState(groupID) {
+		return true
+	}
+	_, ok := s.GetKey(groupID)
+	return ok
+}
+
+// CurrentEpoch returns the epoch we are in for the group.
+func (s *GroupKeyStore) CurrentEpoch(groupID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, err := s.loadState(groupID)
+	if err != nil {
+		return 0, err
+	}
+	return state.Epoch, nil
 }
 
-// GetKey retrieves the symmetric encryption key for a given groupID.
+// TreeMembers returns the peers holding a leaf in the group's current epoch.
+func (s *GroupKeyStore) TreeMembers(groupID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, err := s.loadState(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []string
+	for i := 0; i < len(state.Tree); i += 2 {
+		if state.Tree[i].PeerId != "" {
+			members = append(members, state.Tree[i].PeerId)
+		}
+	}
+	return members, nil
+}
+
+// IsAdmin reports whether a peer may add and remove members of the group in its current epoch.
+func (s *GroupKeyStore) IsAdmin(groupID string, peerID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, err := s.loadState(groupID)
+	if err != nil {
+		return false, err
+	}
+
+	leaf := findLeaf(state.Tree, peerID)
+	return leaf >= 0 && state.Tree[leaf].Admin, nil
+}
+
+// GetKey retrieves the static symmetric key of a group created before epoch keys.
 // It returns the key and a boolean indicating if the key was found.
 func (s *GroupKeyStore) GetKey(groupID string) ([]byte, bool) {
 
 	key, err := s.keyRepo.GetKey(s.ctx, groupID)
 
-	if err != nil {
+	if err != nil || len(key.Key) == 0 {
 		return nil, false
 	}
 
 	return key.Key, true
 }
 
-// Encrypt encrypts plaintext using the group's key.
+// RendezvousTags returns the tags members of a group meet under on the DHT: one derived
+// from the current application key and one from each retained key, so members a few epochs
+// behind still find the others. Outsiders cannot compute them, and removed members lose
+// track once the keys they knew are no longer retained.
+func (s *GroupKeyStore) RendezvousTags(groupID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys [][]byte
+	state, err := s.loadState(groupID)
+	switch {
+	case err == nil:
+		keys = append(keys, state.ApplicationKey)
+		now := time.Now()
+		for i := len(state.RetainedKeys) - 1; i >= 0; i-- {
+			if state.RetainedKeys[i].live(now) {
+				keys = append(keys, state.RetainedKeys[i].Key)
+			}
+		}
+	case errors.Is(err, ErrNoGroupState):
+		key, ok := s.GetKey(groupID)
+		if !ok {
+			return nil, err
+		}
+		keys = append(keys, key)
+	default:
+		return nil, err
+	}
+
+	tags := make([]string, 0, len(keys))
+	for _, key := range keys {
+		secret, err := treeExpand(key, "rendezvous "+groupID)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, hex.EncodeToString(secret[:16]))
+	}
+	return tags, nil
+}
+
+// Encrypt encrypts an application message with the group's current epoch key. messageHash
+// is the hash of the signed message, carried in the frame.
+func (s *GroupKeyStore) Encrypt(groupID string, messageHash string, plaintext []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, err := s.loadState(groupID)
+	if errors.Is(err, ErrNoGroupState) {
+		return s.encryptStatic(groupID, plaintext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return state.seal(types.GroupContentApplication, messageHash, plaintext)
+}
+
+// Seal encrypts control content of the given type for the group topic.
+func (s *GroupKeyStore) Seal(groupID string, contentType string, plaintext []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, err := s.loadState(groupID)
+	if err == nil {
+		return state.seal(contentType, "", plaintext)
+	}
+	if !errors.Is(err, ErrNoGroupState) {
+		return nil, err
+	}
+
+	ciphertext, err := s.encryptStatic(groupID, append([]byte(contentType+"|"), plaintext...))
+	if err != nil {
+		return nil, err
+	}
+
+	framed, err := json.Marshal(types.GroupCiphertext{
+		GroupId:     groupID,
+		KeyId:       staticKeyId,
+		ContentType: contentType,
+		Ciphertext:  ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal group ciphertext: %w", err)
+	}
+	return framed, nil
+}
+
+// Open decrypts content published on a group topic and returns its content type.
+func (s *GroupKeyStore) Open(groupID string, data []byte) (string, []byte, error) {
+	var framed types.GroupCiphertext
+	if err := json.Unmarshal(data, &framed); err != nil || framed.KeyId == "" {
+		plaintext, err := s.decryptStatic(groupID, data)
+		return types.GroupContentApplication, plaintext, err
+	}
+
+	if framed.GroupId != groupID {
+		return "", nil, fmt.Errorf("content for group %s arrived on group %s", framed.GroupId, groupID)
+	}
+
+	if framed.KeyId == staticKeyId {
+		return s.openStatic(groupID, framed)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, err := s.loadState(groupID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := state.findKey(framed.KeyId)
+	if key == nil {
+		if framed.Epoch > state.Epoch {
+			return "", nil, ErrGroupEpochAhead
+		}
+		return "", nil, fmt.Errorf("%w: epoch %d of group %s", ErrGroupKeyExpired, framed.Epoch, groupID)
+	}
+
+	plaintext, err := aeadOpen(key, framed.Ciphertext, contentAAD(groupID, framed.Epoch, framed.ContentType))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open content of group %s: %w", groupID, err)
+	}
+
+	return framed.ContentType, plaintext, nil
+}
+
+// NewKeyPackage creates the key we join a group with. Its private half waits in storage
+// until the welcome from the member adding us arrives.
+func (s *GroupKeyStore) NewKeyPackage(groupID string) (*types.GroupKeyPackage, error) {
+	selfID, err := s.selfID()
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key package for group %s: %w", groupID, err)
+	}
+
+	encryptedKey, err := crypto_utils.EncryptDataWithKey(s.appState.DbKey, privateKey.Bytes(), core.DefaultCryptoConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key package: %w", err)
+	}
+
+	err = s.keyRepo.StoreKeyPackage(s.ctx, types.GroupStoredKeyPackage{
+		GroupId:    groupID,
+		PublicKey:  privateKey.PublicKey().Bytes(),
+		PrivateKey: encryptedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := types.GroupKeyPackageData{
+		GroupId:   groupID,
+		PeerId:    selfID,
+		PublicKey: privateKey.PublicKey().Bytes(),
+	}
+
+	signature, err := SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.GroupKeyPackage{Data: data, Signature: signature}, nil
+}
+
+// VerifyKeyPackage checks that a key package is well formed and signed by the peer it names.
+func VerifyKeyPackage(keyPackage types.GroupKeyPackage) error {
+	if _, err := ecdh.X25519().NewPublicKey(keyPackage.Data.PublicKey); err != nil {
+		return fmt.Errorf("invalid key package of %s: %w", keyPackage.Data.PeerId, err)
+	}
+	return VerifyPayload(keyPackage.Data.PeerId, keyPackage.Data, keyPackage.Signature)
+}
+
+func (s *GroupKeyStore) selfID() (string, error) {
+	if s.appState.PrivKey == nil {
+		return "", errors.New("private key is not loaded")
+	}
+	id, err := peer.IDFromPrivateKey(s.appState.PrivKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive own peer ID: %w", err)
+	}
+	return id.String(), nil
+}
+
+// storeGroupRecord keeps the group's name in group_keys. Groups with epoch keys have no static key.
+func (s *GroupKeyStore) storeGroupRecord(groupID string, groupName string) error {
+	return s.keyRepo.Store(s.ctx, types.GroupKey{
+		Key:     []byte{},
+		GroupId: groupID,
+		Name:    groupName,
+	})
+}
+
+func (s *GroupKeyStore) loadState(groupID string) (*groupEpochState, error) {
+	return s.loadEpochRecord(s.keyRepo.GetLatestEpoch(s.ctx, groupID))
+}
+
+func (s *GroupKeyStore) loadEpochRecord(record *types.GroupEpoch, err error) (*groupEpochState, error) {
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoGroupState
+		}
+		return nil, err
+	}
+
+	stateBytes, err := crypto_utils.DecryptDataWithKey(s.appState.DbKey, record.State, core.DefaultCryptoConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt epoch %d of group %s: %w", record.Epoch, record.GroupId, err)
+	}
+
+	var state groupEpochState
+	if err := json.Unmarshal(stateBytes, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse epoch %d of group %s: %w", record.Epoch, record.GroupId, err)
+	}
+
+	return &state, nil
+}
+
+// storeState persists a new epoch and drops everything older than the one before it.
+// The previous epoch is kept to settle concurrent commits, until PruneExpiredKeys wipes it
+// once groupSettleWindow has passed.
+func (s *GroupKeyStore) storeState(state *groupEpochState) error {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal epoch state: %w", err)
+	}
+
+	encryptedState, err := crypto_utils.EncryptDataWithKey(s.appState.DbKey, stateBytes, core.DefaultCryptoConfig)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt epoch state: %w", err)
+	}
+
+	err = s.keyRepo.StoreEpoch(s.ctx, types.GroupEpoch{
+		GroupId:    state.GroupId,
+		Epoch:      state.Epoch,
+		CommitHash: state.CommitHash,
+		State:      encryptedState,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.keyRepo.DeleteEpochsBefore(s.ctx, state.GroupId, state.Epoch-1)
+}
+
+// Start wipes expired key material every groupKeyPruneInterval until the context ends.
+func (s *GroupKeyStore) Start() {
+	ticker := time.NewTicker(groupKeyPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.PruneExpiredKeys(); err != nil {
+				log.Printf("GroupKeyStore: Error pruning expired keys: %v", err)
+			}
+		}
+	}
+}
+
+// PruneExpiredKeys deletes superseded epochs once their settle window is over and drops past
+// application keys whose lifetime ended, so a later compromise of our storage cannot open
+// the epochs before.
+func (s *GroupKeyStore) PruneExpiredKeys() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if err := s.keyRepo.DeleteSupersededEpochs(s.ctx, now.Add(-groupSettleWindow)); err != nil {
+		return err
+	}
+
+	records, err := s.keyRepo.GetLatestEpochs(s.ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range records {
+		state, err := s.loadEpochRecord(&records[i], nil)
+		if err != nil {
+			log.Printf("GroupKeyStore: Error loading epoch %d of group %s: %v", records[i].Epoch, records[i].GroupId, err)
+			continue
+		}
+		if !state.dropExpiredKeys(now) {
+			continue
+		}
+		if err := s.storeState(state); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setEpochSecret derives the keys of the epoch from its secret.
+func (st *groupEpochState) setEpochSecret(epochSecret []byte) error {
+	applicationKey, err := treeExpand(epochSecret, "application")
+	if err != nil {
+		return err
+	}
+	initSecret, err := treeExpand(epochSecret, "init")
+	if err != nil {
+		return err
+	}
+
+	st.ApplicationKey = applicationKey
+	st.InitSecret = initSecret
+	return nil
+}
+
+// nextEpochSecret mixes the commit secret into the previous epoch's init secret.
+func (st *groupEpochState) nextEpochSecret(commitSecret []byte, commitHash string) ([]byte, error) {
+	prk, err := hkdf.Extract(sha256.New, commitSecret, st.InitSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract epoch secret: %w", err)
+	}
+	return treeExpand(prk, fmt.Sprintf("epoch %s %d %s", st.GroupId, st.Epoch+1, commitHash))
+}
+
+// retain keeps the current application key around for late messages, for
+// groupRetainedKeyLifetime at most.
+func (st *groupEpochState) retain(key []byte, epoch int64) {
+	st.dropExpiredKeys(time.Now())
+	st.RetainedKeys = append(st.RetainedKeys, retainedKey{
+		Epoch:     epoch,
+		KeyId:     keyId(key),
+		Key:       key,
+		ExpiresAt: time.Now().Add(groupRetainedKeyLifetime),
+	})
+	if len(st.RetainedKeys) > groupRetainedEpochs {
+		st.RetainedKeys = st.RetainedKeys[len(st.RetainedKeys)-groupRetainedEpochs:]
+	}
+}
+
+// dropExpiredKeys forgets past application keys whose lifetime is over. It reports whether
+// any was dropped.
+func (st *groupEpochState) dropExpiredKeys(now time.Time) bool {
+	live := st.RetainedKeys[:0]
+	for _, k := range st.RetainedKeys {
+		if k.live(now) {
+			live = append(live, k)
+		}
+	}
+	dropped := len(live) != len(st.RetainedKeys)
+	st.RetainedKeys = live
+	return dropped
+}
+
+func (k retainedKey) live(now time.Time) bool {
+	return now.Before(k.ExpiresAt)
+}
+
+func (st *groupEpochState) findKey(id string) []byte {
+	if keyId(st.ApplicationKey) == id {
+		return st.ApplicationKey
+	}
+	now := time.Now()
+	for _, k := range st.RetainedKeys {
+		if k.KeyId == id && k.live(now) {
+			return k.Key
+		}
+	}
+	return nil
+}
+
+// seal encrypts content with the epoch's application key and frames it for the group topic.
+func (st *groupEpochState) seal(contentType string, messageHash string, plaintext []byte) ([]byte, error) {
+	ciphertext, err := aeadSeal(st.ApplicationKey, plaintext, contentAAD(st.GroupId, st.Epoch, contentType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt content for group %s: %w", st.GroupId, err)
+	}
+
+	framed, err := json.Marshal(types.GroupCiphertext{
+		GroupId:     st.GroupId,
+		Epoch:       st.Epoch,
+		KeyId:       keyId(st.ApplicationKey),
+		ContentType: contentType,
+		Ciphertext:  ciphertext,
+		MessageHash: messageHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal group ciphertext: %w", err)
+	}
+	return framed, nil
+}
+
+func contentAAD(groupID string, epoch int64, contentType string) []byte {
+	return []byte(fmt.Sprintf("%s|%d|%s", groupID, epoch, contentType))
+}
+
+// openStatic decrypts framed control content of a group that still uses a static key.
+// The content type is bound by prefixing it to the plaintext.
+func (s *GroupKeyStore) openStatic(groupID string, framed types.GroupCiphertext) (string, []byte, error) {
+	plaintext, err := s.decryptStatic(groupID, framed.Ciphertext)
+	if err != nil {
+		return "", nil, err
+	}
+
+	prefix := []byte(framed.ContentType + "|")
+	if !bytes.HasPrefix(plaintext, prefix) {
+		return "", nil, fmt.Errorf("content type of group %s content does not match its frame", groupID)
+	}
+
+	return framed.ContentType, plaintext[len(prefix):], nil
+}
+
+// encryptStatic encrypts plaintext using the static key of a group created before epoch keys.
 // Returns ciphertext (nonce prefixed).
-func (s *GroupKeyStore) Encrypt(groupID string, plaintext []byte) ([]byte, error) {
+func (s *GroupKeyStore) encryptStatic(groupID string, plaintext []byte) ([]byte, error) {
 	key, ok := s.GetKey(groupID)
 	if !ok {
 		return nil, fmt.Errorf("no key found for group %s to encrypt", groupID)
@@ -88,8 +659,8 @@ func (s *GroupKeyStore) Encrypt(groupID string, plaintext []byte) ([]byte, error
 	return append(nonce, ciphertext...), nil
 }
 
-// Decrypt decrypts ciphertext (nonce prefixed) using the group's key.
-func (s *GroupKeyStore) Decrypt(groupID string, ciphertextWithNonce []byte) ([]byte, error) {
+// decryptStatic decrypts ciphertext (nonce prefixed) using the group's static key.
+func (s *GroupKeyStore) decryptStatic(groupID string, ciphertextWithNonce []byte) ([]byte, error) {
 	key, ok := s.GetKey(groupID)
 	if !ok {
 		return nil, fmt.Errorf("no key found for group %s to decrypt", groupID)
@@ -0,0 +1,72 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/gibson042/canonicaljson-go"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// SignPayload signs the canonical JSON form of data with the given private key.
+func SignPayload(privKey crypto.PrivKey, data interface{}) ([]byte, error) {
+	if privKey == nil {
+		return nil, errors.New("private key is not loaded")
+	}
+
+	bytesToSign, err := canonicaljson.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal canonical json for signing: %w", err)
+	}
+
+	signature, err := privKey.Sign(bytesToSign)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign payload: %w", err)
+	}
+
+	return signature, nil
+}
+
+// VerifyPayload checks that signature over the canonical JSON form of data
+// was produced by the key embedded in signerPeerId.
+func VerifyPayload(signerPeerId string, data interface{}, signature []byte) error {
+	signerPID, err := peer.Decode(signerPeerId)
+	if err != nil {
+		return fmt.Errorf("invalid signer peer ID %s: %w", signerPeerId, err)
+	}
+
+	pubKey, err := signerPID.ExtractPublicKey()
+	if err != nil {
+		return fmt.Errorf("failed to extract public key of %s: %w", signerPeerId, err)
+	}
+
+	bytesToVerify, err := canonicaljson.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal canonical json for verification: %w", err)
+	}
+
+	isValid, err := pubKey.Verify(bytesToVerify, signature)
+	if err != nil {
+		return fmt.Errorf("signature verification error for %s: %w", signerPeerId, err)
+	}
+
+	if !isValid {
+		return fmt.Errorf("invalid signature from %s", signerPeerId)
+	}
+
+	return nil
+}
+
+// HashPayload returns the hex encoded SHA-256 digest of the canonical JSON form of data.
+func HashPayload(data interface{}) (string, error) {
+	payloadBytes, err := canonicaljson.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal canonical json for hashing: %w", err)
+	}
+
+	digest := sha256.Sum256(payloadBytes)
+	return hex.EncodeToString(digest[:]), nil
+}
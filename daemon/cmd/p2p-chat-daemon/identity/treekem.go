@@ -0,0 +1,248 @@
+package identity
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+)
+
+// The ratchet tree follows the array layout of RFC 9420 (MLS): leaves sit at even
+// indexes, parents at odd ones, and the number of leaves is always a power of two.
+// Every member knows the private keys of the nodes on the path from its leaf to the
+// root, and a commit replaces the committer's path with keys derived from a fresh secret.
+
+const (
+	treeSecretSize = 32
+	treeNonceSize  = 12
+)
+
+func treeLevel(x int) int {
+	return bits.TrailingZeros(^uint(x))
+}
+
+func treeWidth(leaves int) int {
+	if leaves == 0 {
+		return 0
+	}
+	return 2*(leaves-1) + 1
+}
+
+func treeLeaves(width int) int {
+	return (width + 1) / 2
+}
+
+func treeRoot(leaves int) int {
+	w := treeWidth(leaves)
+	return (1 << (bits.Len(uint(w)) - 1)) - 1
+}
+
+func treeLeft(x int) int {
+	k := treeLevel(x)
+	return x ^ (1 << (k - 1))
+}
+
+func treeRight(x int) int {
+	k := treeLevel(x)
+	return x ^ (3 << (k - 1))
+}
+
+func treeParent(x int) int {
+	k := treeLevel(x)
+	b := (x >> (k + 1)) & 1
+	return (x | (1 << k)) ^ (b << (k + 1))
+}
+
+func treeSibling(x int) int {
+	p := treeParent(x)
+	if x < p {
+		return treeRight(p)
+	}
+	return treeLeft(p)
+}
+
+// treeDirectPath returns the ancestors of x, from its parent up to the root.
+func treeDirectPath(x int, leaves int) []int {
+	root := treeRoot(leaves)
+	var path []int
+	for x != root {
+		x = treeParent(x)
+		path = append(path, x)
+	}
+	return path
+}
+
+// treeCopath returns the siblings of x and of its ancestors below the root.
+func treeCopath(x int, leaves int) []int {
+	root := treeRoot(leaves)
+	var copath []int
+	for x != root {
+		copath = append(copath, treeSibling(x))
+		x = treeParent(x)
+	}
+	return copath
+}
+
+// treeIsAncestor reports whether node a covers node x.
+func treeIsAncestor(a int, x int) bool {
+	span := (1 << treeLevel(a)) - 1
+	return x >= a-span && x <= a+span
+}
+
+// treeResolution returns the non-blank nodes that together cover the subtree of x.
+// Leaves in exclude are skipped, they get their secrets another way.
+func treeResolution(tree []types.GroupTreeNode, x int, exclude map[int]bool) []int {
+	if len(tree[x].PublicKey) > 0 {
+		if exclude[x] {
+			return nil
+		}
+		return []int{x}
+	}
+	if treeLevel(x) == 0 {
+		return nil
+	}
+	return append(treeResolution(tree, treeLeft(x), exclude), treeResolution(tree, treeRight(x), exclude)...)
+}
+
+func treeExpand(secret []byte, label string) ([]byte, error) {
+	out, err := hkdf.Expand(sha256.New, secret, "p2p-chat "+label, treeSecretSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive %s secret: %w", label, err)
+	}
+	return out, nil
+}
+
+func nextPathSecret(pathSecret []byte) ([]byte, error) {
+	return treeExpand(pathSecret, "path")
+}
+
+// deriveNodeKey turns a path secret into the X25519 key pair of a tree node.
+func deriveNodeKey(pathSecret []byte) (*ecdh.PrivateKey, error) {
+	nodeSecret, err := treeExpand(pathSecret, "node")
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.X25519().NewPrivateKey(nodeSecret)
+}
+
+func randomSecret() ([]byte, error) {
+	secret := make([]byte, treeSecretSize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return secret, nil
+}
+
+// sealToNode encrypts a secret to a node's public key with an ephemeral X25519 exchange.
+func sealToNode(node int, publicKey []byte, secret []byte, aad []byte) (types.GroupSealedSecret, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return types.GroupSealedSecret{}, fmt.Errorf("invalid public key of node %d: %w", node, err)
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return types.GroupSealedSecret{}, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return types.GroupSealedSecret{}, fmt.Errorf("failed to agree on shared secret: %w", err)
+	}
+
+	kemOutput := ephemeral.PublicKey().Bytes()
+	key, err := sealKey(shared, kemOutput, publicKey)
+	if err != nil {
+		return types.GroupSealedSecret{}, err
+	}
+
+	ciphertext, err := aeadSeal(key, secret, aad)
+	if err != nil {
+		return types.GroupSealedSecret{}, err
+	}
+
+	return types.GroupSealedSecret{Node: node, KemOutput: kemOutput, Ciphertext: ciphertext}, nil
+}
+
+// openFromNode decrypts a secret sealed to the node whose private key is given.
+func openFromNode(privateKey *ecdh.PrivateKey, sealed types.GroupSealedSecret, aad []byte) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().NewPublicKey(sealed.KemOutput)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	shared, err := privateKey.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to agree on shared secret: %w", err)
+	}
+
+	key, err := sealKey(shared, sealed.KemOutput, privateKey.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return aeadOpen(key, sealed.Ciphertext, aad)
+}
+
+func sealKey(shared []byte, kemOutput []byte, recipient []byte) ([]byte, error) {
+	salt := append(append([]byte{}, kemOutput...), recipient...)
+	key, err := hkdf.Key(sha256.New, shared, salt, "p2p-chat treekem seal", treeSecretSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive sealing key: %w", err)
+	}
+	return key, nil
+}
+
+// aeadSeal encrypts with AES-256-GCM and returns (nonce || ciphertext).
+func aeadSeal(key []byte, plaintext []byte, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES-GCM: %w", err)
+	}
+
+	nonce := make([]byte, treeNonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return append(nonce, aesgcm.Seal(nil, nonce, plaintext, aad)...), nil
+}
+
+func aeadOpen(key []byte, ciphertextWithNonce []byte, aad []byte) ([]byte, error) {
+	if len(ciphertextWithNonce) < treeNonceSize {
+		return nil, errors.New("ciphertext too short to contain nonce")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES-GCM: %w", err)
+	}
+
+	plaintext, err := aesgcm.Open(nil, ciphertextWithNonce[:treeNonceSize], ciphertextWithNonce[treeNonceSize:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt/authenticate: %w", err)
+	}
+	return plaintext, nil
+}
+
+// keyId names an application key without revealing it.
+func keyId(key []byte) string {
+	digest := sha256.Sum256(append([]byte("p2p-chat key id "), key...))
+	return hex.EncodeToString(digest[:8])
+}
@@ -5,6 +5,7 @@ import (
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
 	"time"
 )
@@ -64,10 +65,98 @@ type GroupChatMessageSentEvent struct {
 
 type GroupChatMessage struct {
 	GroupId      string
+	MessageId    string
+	Hash         string
+	Parents      []string
 	SenderPeerId string
 	Message      string
 	Time         time.Time
+	Envelope     []byte
 }
+type GroupInvitationReceivedEvent struct {
+	Invitation types.GroupInvitation
+}
+
+type GroupInvitationResponseReceivedEvent struct {
+	GroupId         string
+	ResponderPeerId string
+	IsAccepted      bool
+}
+
+// GroupMessageDeliveredEvent reports which members a group message we sent reached.
+type GroupMessageDeliveredEvent struct {
+	Report types.GroupDeliveryReport
+}
+
+// GroupEpochChangedEvent is published when a commit moves a group to a new key epoch.
+type GroupEpochChangedEvent struct {
+	GroupId     string
+	Epoch       int64
+	Added       []string
+	Removed     []string
+	RemovedSelf bool
+}
+
+// GroupEpochBehindEvent is published when a group topic carries content from an epoch we have not reached.
+type GroupEpochBehindEvent struct {
+	GroupId string
+}
+
+// GroupMetadataReceivedEvent carries a signed metadata update published on a group topic.
+type GroupMetadataReceivedEvent struct {
+	GroupId      string
+	SenderPeerId string
+	Update       []byte
+}
+
+// GroupInviteRevocationReceivedEvent carries a signed invite revocation published on a group topic.
+type GroupInviteRevocationReceivedEvent struct {
+	GroupId      string
+	SenderPeerId string
+	Revocation   []byte
+}
+
+// GroupFileAnnouncedEvent carries a signed file announcement published on a group topic.
+type GroupFileAnnouncedEvent struct {
+	GroupId      string
+	SenderPeerId string
+	Announcement []byte
+}
+
+// GroupFileUpdatedEvent is published when a file is added to a group library or more of it
+// becomes available locally.
+type GroupFileUpdatedEvent struct {
+	File types.GroupFile
+}
+
+type GroupMetadataUpdatedEvent struct {
+	Metadata types.GroupMetadata
+}
+
+// GroupJoinRequestReceivedEvent is published when someone asks to join one of our public groups.
+type GroupJoinRequestReceivedEvent struct {
+	Request types.GroupJoinRequest
+}
+
+// ChannelContentReceivedEvent carries validated content published on a channel topic.
+type ChannelContentReceivedEvent struct {
+	ChannelId   string
+	ContentType string
+	Payload     []byte
+}
+
+type ChannelUpdatedEvent struct {
+	Channel types.Channel
+}
+
+type ChannelPostAddedEvent struct {
+	Post types.ChannelPost
+}
+
+type ChannelReactionAddedEvent struct {
+	Reaction types.ChannelReaction
+}
+
 type FriendRequestReceived struct {
 	FriendRequest types.FriendRequestData
 }
@@ -76,11 +165,63 @@ type FriendResponseReceivedEvent struct {
 	SenderPeerId string
 	Status       types.FriendStatus
 	Timestamp    string
+	Nonce        string
 }
 
 type FriendRequestSentEvent struct {
 	ReceiverPeerId string
 	Timestamp      time.Time
+	ExpiresAt      time.Time
+	Nonce          string
+	IntroMessage   string
+}
+
+// FriendIntroductionReceivedEvent is published when a friend introduces us to someone.
+type FriendIntroductionReceivedEvent struct {
+	Introduction types.ReceivedIntroduction
+}
+
+// NearbyPeerDiscoveredEvent is published when mDNS finds a peer on the local network.
+type NearbyPeerDiscoveredEvent struct {
+	Peer peer.AddrInfo
+}
+
+// NearbyPeerUpdatedEvent is published when a nearby peer is first listed or announces a
+// new display name.
+type NearbyPeerUpdatedEvent struct {
+	Peer types.NearbyPeer
+}
+
+// NearbyPeerLostEvent is published when a nearby peer left the local network.
+type NearbyPeerLostEvent struct {
+	PeerId string
+}
+
+// HandleConflictEvent is published when a handle we claimed resolves to another peer,
+// whose claim the registry prefers.
+type HandleConflictEvent struct {
+	Handle      string
+	OwnerPeerId string
+}
+
+// ProfileCardUpdatedEvent is published when a friend's profile card, or ours, changed.
+type ProfileCardUpdatedEvent struct {
+	Card types.ProfileCard
+}
+
+// FriendPresenceChangedEvent is published when a friend tells us their status, or stops
+// answering. LastSeen is zero unless they share it with us.
+type FriendPresenceChangedEvent struct {
+	PeerId   string
+	Presence types.Presence
+	LastSeen time.Time
+}
+
+// FriendRequestCancelledEvent is published when we withdraw a pending friend request or
+// its sender withdraws one sent to us.
+type FriendRequestCancelledEvent struct {
+	PeerId    string
+	Initiated bool
 }
 
 type FriendResponseSentEvent struct {
@@ -88,6 +229,13 @@ type FriendResponseSentEvent struct {
 	IsAccepted bool
 }
 
+// FriendRemovedEvent is published when we end a friendship or a friend ends it with us.
+type FriendRemovedEvent struct {
+	PeerId       string
+	Initiated    bool // we ended it
+	PurgeHistory bool
+}
+
 type FriendOnlineStatusChangedEvent struct {
 	PeerID   string
 	IsOnline bool
@@ -13,16 +13,33 @@ import (
 type DaemonState int
 
 const (
-	GroupChatProtocolID          = "/p2p-chat-daemon/group-chat/1.0.0"
-	ChatProtocolID               = "/p2p-chat-daemon/chat/1.0.0"
-	FriendRequestProtocolID      = "/p2p-chat-daemon/friends-request/1.0.0"
-	FriendResponseProtocolID     = "/p2p-chat-daemon/friends-response/1.0.0"
-	FriendResponsePollProtocolId = "/p2p-chat-daemon/friends-response-poll/1.0.0"
-	OnlineAnnouncementTopic      = "p2p-chat/online-announcements"
+	GroupChatProtocolID               = "/p2p-chat-daemon/group-chat/1.0.0"
+	GroupHistoryProtocolID            = "/p2p-chat-daemon/group-history/1.0.0"
+	GroupInvitationResponseProtocolID = "/p2p-chat-daemon/group-invitation-response/1.0.0"
+	GroupWelcomeProtocolID            = "/p2p-chat-daemon/group-welcome/1.0.0"
+	GroupEpochsProtocolID             = "/p2p-chat-daemon/group-epochs/1.0.0"
+	GroupInviteProtocolID             = "/p2p-chat-daemon/group-invite/1.0.0"
+	GroupDirectoryProtocolID          = "/p2p-chat-daemon/group-directory/1.0.0"
+	GroupJoinRequestProtocolID        = "/p2p-chat-daemon/group-join-request/1.0.0"
+	GroupFileProtocolID               = "/p2p-chat-daemon/group-file/1.0.0"
+	GroupDeliveryProtocolID           = "/p2p-chat-daemon/group-delivery/1.0.0"
+	ChannelInfoProtocolID             = "/p2p-chat-daemon/channel-info/1.0.0"
+	ChatProtocolID                    = "/p2p-chat-daemon/chat/1.0.0"
+	FriendRequestProtocolID           = "/p2p-chat-daemon/friends-request/1.0.0"
+	FriendResponseProtocolID          = "/p2p-chat-daemon/friends-response/1.0.0"
+	FriendResponsePollProtocolId      = "/p2p-chat-daemon/friends-response-poll/1.0.0"
+	FriendRemovalProtocolID           = "/p2p-chat-daemon/friends-removal/1.0.0"
+	FriendRequestCancelProtocolID     = "/p2p-chat-daemon/friends-request-cancel/1.0.0"
+	FriendIntroductionProtocolID      = "/p2p-chat-daemon/friends-introduction/1.0.0"
+	NearbyAnnounceProtocolID          = "/p2p-chat-daemon/nearby-announce/1.0.0"
+	ProfileCardProtocolID             = "/p2p-chat-daemon/profile-card/1.0.0"
+	PresenceProtocolID                = "/p2p-chat-daemon/presence/1.0.0"
+	OnlineAnnouncementTopic           = "p2p-chat/online-announcements"
 )
 
 const (
 	GroupChatTopic = "/p2p-chat-daemon/group-chat/1.0.0/"
+	ChannelTopic   = "/p2p-chat-daemon/channel/1.0.0/"
 )
 
 const (
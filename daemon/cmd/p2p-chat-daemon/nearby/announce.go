@@ -0,0 +1,217 @@
+package nearby
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+const (
+	announcementMaxSize = 4 * 1024
+	announcementMaxAge  = 10 * time.Minute
+)
+
+// announceIfNeeded starts an exchange of announcements with a nearby peer we are connected
+// to and have not exchanged them with yet.
+func (s *Service) announceIfNeeded(id peer.ID) {
+	if (*s.appState.Node).Network().Connectedness(id) != network.Connected {
+		return
+	}
+
+	s.mu.Lock()
+	entry, exists := s.peers[id]
+	if !exists || entry.announced || entry.announcing {
+		s.mu.Unlock()
+		return
+	}
+	entry.announcing = true
+	s.mu.Unlock()
+
+	go func() {
+		err := s.announce(id)
+
+		s.mu.Lock()
+		if entry, exists := s.peers[id]; exists {
+			entry.announcing = false
+			entry.announced = entry.announced || err == nil
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			log.Printf("Nearby: Error exchanging announcements with %s: %v", id.ShortString(), err)
+		}
+	}()
+}
+
+// announce sends our announcement to a nearby peer and reads theirs in return.
+func (s *Service) announce(id peer.ID) error {
+	announcementBytes, err := s.newAnnouncement()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	stream, err := (*s.appState.Node).NewStream(ctx, id, core.NearbyAnnounceProtocolID)
+	if err != nil {
+		return fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(announcementBytes); err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to write announcement: %w", err)
+	}
+	if err := stream.CloseWrite(); err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to close announcement: %w", err)
+	}
+
+	stream.SetReadDeadline(time.Now().Add(10 * time.Second))
+	replyBytes, err := io.ReadAll(io.LimitReader(stream, announcementMaxSize))
+	if err != nil {
+		stream.Reset()
+		return fmt.Errorf("failed to read announcement: %w", err)
+	}
+
+	return s.recordAnnouncement(id, replyBytes)
+}
+
+// handleAnnounceStream answers an announcement from a peer on the local network with ours.
+// Peers reaching us from outside the local network learn nothing.
+func (s *Service) handleAnnounceStream(stream network.Stream) {
+	remotePeerId := stream.Conn().RemotePeer()
+	defer stream.Close()
+
+	if !isLocalAddr(stream) {
+		log.Printf("Nearby: Ignoring announcement from %s, which is not on the local network", remotePeerId.ShortString())
+		stream.Reset()
+		return
+	}
+
+	stream.SetReadDeadline(time.Now().Add(10 * time.Second))
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, announcementMaxSize))
+	if err != nil {
+		log.Printf("Nearby: Error reading announcement from %s: %v", remotePeerId.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	if err := s.recordAnnouncement(remotePeerId, receivedBytes); err != nil {
+		log.Printf("Nearby: Rejecting announcement from %s: %v", remotePeerId.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	announcementBytes, err := s.newAnnouncement()
+	if err != nil {
+		log.Printf("Nearby: Error creating announcement: %v", err)
+		stream.Reset()
+		return
+	}
+	if _, err := stream.Write(announcementBytes); err != nil {
+		log.Printf("Nearby: Error answering %s: %v", remotePeerId.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	s.mu.Lock()
+	if entry, exists := s.peers[remotePeerId]; exists {
+		entry.announced = true
+	}
+	s.mu.Unlock()
+}
+
+func (s *Service) newAnnouncement() ([]byte, error) {
+	name, err := s.GetDisplayName()
+	if err != nil {
+		return nil, err
+	}
+
+	data := types.NearbyAnnouncementData{
+		PeerId:      (*s.appState.Node).ID().String(),
+		DisplayName: name,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	announcementBytes, err := json.Marshal(types.NearbyAnnouncement{Data: data, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal announcement: %w", err)
+	}
+	return announcementBytes, nil
+}
+
+// recordAnnouncement verifies a peer's announcement and lists the peer under the name it
+// announced.
+func (s *Service) recordAnnouncement(sender peer.ID, announcementBytes []byte) error {
+	var announcement types.NearbyAnnouncement
+	if err := json.Unmarshal(announcementBytes, &announcement); err != nil {
+		return fmt.Errorf("failed to deserialize announcement: %w", err)
+	}
+	data := announcement.Data
+
+	if data.PeerId != sender.String() {
+		return fmt.Errorf("announcement of %s was sent by %s", data.PeerId, sender)
+	}
+	if len([]rune(data.DisplayName)) > displayNameMaxLength {
+		return fmt.Errorf("display name longer than %d characters", displayNameMaxLength)
+	}
+
+	sentAt, err := time.Parse(time.RFC3339, data.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	if age := time.Since(sentAt); age > announcementMaxAge || age < -announcementMaxAge {
+		return fmt.Errorf("announcement sent at %s is stale", data.Timestamp)
+	}
+
+	if err := identity.VerifyPayload(data.PeerId, data, announcement.Signature); err != nil {
+		return err
+	}
+
+	if s.gater.IsBlocked(sender) {
+		return fmt.Errorf("peer is blocked")
+	}
+
+	s.mu.Lock()
+	entry, exists := s.peers[sender]
+	if !exists {
+		entry = &nearbyPeer{peer: types.NearbyPeer{PeerId: sender.String(), FirstSeen: time.Now()}}
+		for _, addr := range (*s.appState.Node).Peerstore().Addrs(sender) {
+			entry.peer.Addrs = append(entry.peer.Addrs, addr.String())
+		}
+		s.peers[sender] = entry
+	}
+	changed := !exists || entry.peer.DisplayName != data.DisplayName
+	entry.peer.DisplayName = data.DisplayName
+	entry.peer.LastSeen = time.Now()
+	updated := entry.peer
+	s.mu.Unlock()
+
+	if changed {
+		log.Printf("Nearby: %s announced itself as %q", sender.ShortString(), data.DisplayName)
+		s.publishPeer(updated)
+	}
+	return nil
+}
+
+func isLocalAddr(stream network.Stream) bool {
+	addr := stream.Conn().RemoteMultiaddr()
+	return manet.IsPrivateAddr(addr) || manet.IsIPLoopback(addr)
+}
@@ -0,0 +1,53 @@
+package nearby
+
+import (
+	"context"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+)
+
+type Consumer struct {
+	bus           *bus.EventBus
+	ctx           context.Context
+	nearbyService *Service
+	eventsChan    chan interface{}
+}
+
+func NewConsumer(eventBus *bus.EventBus, nearbyService *Service, ctx context.Context) *Consumer {
+	return &Consumer{
+		bus:           eventBus,
+		ctx:           ctx,
+		nearbyService: nearbyService,
+		eventsChan:    make(chan interface{}),
+	}
+}
+
+func (c *Consumer) Start() {
+	log.Println("nearby consumer started")
+	c.bus.Subscribe(c.eventsChan, events.NearbyPeerDiscoveredEvent{})
+
+	go c.listen()
+}
+
+func (c *Consumer) listen() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			log.Println("nearby consumer stopped")
+			return
+
+		case event := <-c.eventsChan:
+			c.handleEvent(event)
+		}
+	}
+}
+
+func (c *Consumer) handleEvent(event interface{}) {
+	switch event := event.(type) {
+
+	case events.NearbyPeerDiscoveredEvent:
+		c.nearbyService.PeerFound(event.Peer)
+		return
+	}
+}
@@ -0,0 +1,236 @@
+package nearby
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// nearbyPeerTTL is how long a peer stays listed after we last saw it over mDNS or
+	// were last connected to it.
+	nearbyPeerTTL        = 5 * time.Minute
+	nearbyPruneInterval  = time.Minute
+	displayNameMaxLength = 64
+
+	displayNameSetting = "nearby.display_name"
+)
+
+var ErrInvalidDisplayName = errors.New("invalid display name")
+
+// Gater tells which peers are blocked.
+type Gater interface {
+	IsBlocked(id peer.ID) bool
+}
+
+// nearbyPeer is a registry entry. announced is set once we exchanged announcements with
+// the peer, announcing while an exchange is under way.
+type nearbyPeer struct {
+	peer       types.NearbyPeer
+	announced  bool
+	announcing bool
+}
+
+// Service keeps the registry of peers found on the local network, and exchanges display
+// names with them so the user can tell who they are.
+type Service struct {
+	ctx              context.Context
+	appState         *core.AppState
+	bus              *bus.EventBus
+	relationshipRepo storage.RelationshipRepository
+	settingsRepo     storage.SettingsRepository
+	gater            Gater
+	mu               sync.Mutex
+	peers            map[peer.ID]*nearbyPeer
+}
+
+func NewNearbyService(
+	ctx context.Context,
+	appState *core.AppState,
+	bus *bus.EventBus,
+	relationshipRepo storage.RelationshipRepository,
+	settingsRepo storage.SettingsRepository,
+	gater Gater,
+) *Service {
+	return &Service{
+		ctx:              ctx,
+		appState:         appState,
+		bus:              bus,
+		relationshipRepo: relationshipRepo,
+		settingsRepo:     settingsRepo,
+		gater:            gater,
+		peers:            make(map[peer.ID]*nearbyPeer),
+	}
+}
+
+func (s *Service) Register() {
+	(*s.appState.Node).SetStreamHandler(core.NearbyAnnounceProtocolID, s.handleAnnounceStream)
+
+	notifiee := &network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			s.announceIfNeeded(conn.RemotePeer())
+		},
+	}
+	(*s.appState.Node).Network().Notify(notifiee)
+
+	go s.prune(notifiee)
+}
+
+// PeerFound records a peer found over mDNS and announces ourselves to it once connected.
+func (s *Service) PeerFound(pi peer.AddrInfo) {
+	if s.appState.Node == nil || pi.ID == (*s.appState.Node).ID() || s.gater.IsBlocked(pi.ID) {
+		return
+	}
+
+	addrs := make([]string, 0, len(pi.Addrs))
+	for _, addr := range pi.Addrs {
+		addrs = append(addrs, addr.String())
+	}
+
+	s.mu.Lock()
+	entry, exists := s.peers[pi.ID]
+	if !exists {
+		entry = &nearbyPeer{peer: types.NearbyPeer{PeerId: pi.ID.String(), FirstSeen: time.Now()}}
+		s.peers[pi.ID] = entry
+	}
+	entry.peer.Addrs = addrs
+	entry.peer.LastSeen = time.Now()
+	found := entry.peer
+	s.mu.Unlock()
+
+	if !exists {
+		log.Printf("Nearby: Found %s on the local network", pi.ID.ShortString())
+		s.publishPeer(found)
+	}
+
+	s.announceIfNeeded(pi.ID)
+}
+
+// GetNearby lists the peers currently on the local network.
+func (s *Service) GetNearby() []types.NearbyPeer {
+	s.mu.Lock()
+	nearby := make([]types.NearbyPeer, 0, len(s.peers))
+	for _, entry := range s.peers {
+		nearby = append(nearby, entry.peer)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(nearby, func(i, j int) bool {
+		return nearby[i].FirstSeen.Before(nearby[j].FirstSeen)
+	})
+
+	for i := range nearby {
+		nearby[i].FriendStatus = s.friendStatus(nearby[i].PeerId)
+	}
+	return nearby
+}
+
+// GetDisplayName returns the name we announce to nearby peers.
+func (s *Service) GetDisplayName() (string, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	name, err := s.settingsRepo.Get(ctx, displayNameSetting)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return name, err
+}
+
+// SetDisplayName sets the name we announce to nearby peers, and announces it again to the
+// ones we are connected to.
+func (s *Service) SetDisplayName(name string) error {
+	name = strings.TrimSpace(name)
+	if len([]rune(name)) > displayNameMaxLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidDisplayName, displayNameMaxLength)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.settingsRepo.Set(ctx, displayNameSetting, name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	ids := make([]peer.ID, 0, len(s.peers))
+	for id, entry := range s.peers {
+		entry.announced = false
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		s.announceIfNeeded(id)
+	}
+	return nil
+}
+
+func (s *Service) friendStatus(peerId string) types.FriendStatus {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, peerId)
+	if err != nil {
+		return types.FriendStatusNone
+	}
+	return relationship.Status
+}
+
+func (s *Service) publishPeer(nearbyPeer types.NearbyPeer) {
+	nearbyPeer.FriendStatus = s.friendStatus(nearbyPeer.PeerId)
+	s.bus.PublishAsync(events.NearbyPeerUpdatedEvent{Peer: nearbyPeer})
+}
+
+// prune drops peers that left the local network: those we are no longer connected to and
+// have not seen for nearbyPeerTTL.
+func (s *Service) prune(notifiee *network.NotifyBundle) {
+	defer (*s.appState.Node).Network().StopNotify(notifiee)
+
+	ticker := time.NewTicker(nearbyPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			log.Println("Nearby: Stopped")
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		var lost []peer.ID
+
+		s.mu.Lock()
+		for id, entry := range s.peers {
+			if (*s.appState.Node).Network().Connectedness(id) == network.Connected {
+				entry.peer.LastSeen = now
+				continue
+			}
+			if now.Sub(entry.peer.LastSeen) > nearbyPeerTTL || s.gater.IsBlocked(id) {
+				delete(s.peers, id)
+				lost = append(lost, id)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, id := range lost {
+			log.Printf("Nearby: %s left the local network", id.ShortString())
+			s.bus.PublishAsync(events.NearbyPeerLostEvent{PeerId: id.String()})
+		}
+	}
+}
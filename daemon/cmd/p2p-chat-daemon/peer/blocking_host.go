@@ -0,0 +1,45 @@
+package peer
+
+import (
+	"log"
+
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// PeerGater decides which peers the node talks to.
+type PeerGater interface {
+	connmgr.ConnectionGater
+	IsBlocked(id peer.ID) bool
+}
+
+// blockingHost drops streams opened by blocked peers before they reach a protocol handler.
+// The connection gater already refuses new connections; this covers connections that were
+// open when the peer got blocked and streams racing the disconnect.
+type blockingHost struct {
+	host.Host
+	gater PeerGater
+}
+
+func (h *blockingHost) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
+	h.Host.SetStreamHandler(pid, h.guard(handler))
+}
+
+func (h *blockingHost) SetStreamHandlerMatch(pid protocol.ID, match func(protocol.ID) bool, handler network.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, match, h.guard(handler))
+}
+
+func (h *blockingHost) guard(handler network.StreamHandler) network.StreamHandler {
+	return func(stream network.Stream) {
+		remotePeer := stream.Conn().RemotePeer()
+		if h.gater.IsBlocked(remotePeer) {
+			log.Printf("Dropping %s stream from blocked peer %s", stream.Protocol(), remotePeer.ShortString())
+			stream.Reset()
+			return
+		}
+		handler(stream)
+	}
+}
@@ -25,15 +25,17 @@ type NodeManager struct {
 	ctx      context.Context
 	appState *core.AppState
 	cfg      *config.P2PConfig
+	gater    PeerGater
 	node     *host.Host
 }
 
 // NewNodeManager creates a new NodeManager
-func NewNodeManager(ctx context.Context, appState *core.AppState, cfg *config.P2PConfig) *NodeManager {
+func NewNodeManager(ctx context.Context, appState *core.AppState, cfg *config.P2PConfig, gater PeerGater) *NodeManager {
 	return &NodeManager{
 		ctx:      ctx,
 		appState: appState,
 		cfg:      cfg,
+		gater:    gater,
 	}
 }
 
@@ -76,19 +78,20 @@ func (nm *NodeManager) Initialize() (*host.Host, error) {
 		libp2p.EnableRelayService(),
 		libp2p.EnableAutoRelayWithPeerSource(peerSource, autorelay.WithMinCandidates(len(nm.cfg.BootstrapPeers))),
 		libp2p.EnableAutoNATv2(),
+		libp2p.ConnectionGater(nm.gater),
 	}
 
-	node, err := libp2p.New(opts...)
+	libp2pNode, err := libp2p.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create libp2p host: %w", err)
+	}
 
-	addrs := node.Addrs()
+	addrs := libp2pNode.Addrs()
 	for _, addr := range addrs {
 		fmt.Printf("Listening on: %s\n", addr.String())
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to create libp2p host: %w", err)
-	}
-
+	var node host.Host = &blockingHost{Host: libp2pNode, gater: nm.gater}
 	nm.node = &node
 	return &node, nil
 }
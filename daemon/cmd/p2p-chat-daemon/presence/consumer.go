@@ -0,0 +1,86 @@
+package presence
+
+import (
+	"context"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+type Consumer struct {
+	bus             *bus.EventBus
+	ctx             context.Context
+	presenceService *Service
+	eventsChan      chan interface{}
+}
+
+func NewConsumer(eventBus *bus.EventBus, presenceService *Service, ctx context.Context) *Consumer {
+	return &Consumer{
+		bus:             eventBus,
+		ctx:             ctx,
+		presenceService: presenceService,
+		eventsChan:      make(chan interface{}),
+	}
+}
+
+func (c *Consumer) Start() {
+	log.Println("presence consumer started")
+	c.bus.Subscribe(c.eventsChan, events.FriendOnlineStatusChangedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendRemovedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendResponseReceivedEvent{})
+	c.bus.Subscribe(c.eventsChan, events.FriendResponseSentEvent{})
+
+	go c.listen()
+}
+
+func (c *Consumer) listen() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			log.Println("presence consumer stopped")
+			return
+
+		case event := <-c.eventsChan:
+			c.handleEvent(event)
+		}
+	}
+}
+
+func (c *Consumer) handleEvent(event interface{}) {
+	switch event := event.(type) {
+
+	case events.FriendOnlineStatusChangedEvent:
+		if !event.IsOnline {
+			c.presenceService.FriendOffline(event.PeerID, event.LastSeen)
+		}
+		return
+
+	case events.FriendRemovedEvent:
+		c.presenceService.Forget(event.PeerId)
+		return
+
+	// A new friend, who may already be connected to us, learns our presence right away.
+	case events.FriendResponseReceivedEvent:
+		if event.Status == types.FriendStatusApproved {
+			c.pushTo(event.SenderPeerId)
+		}
+		return
+
+	case events.FriendResponseSentEvent:
+		if event.IsAccepted {
+			c.pushTo(event.PeerId)
+		}
+		return
+	}
+}
+
+func (c *Consumer) pushTo(peerId string) {
+	id, err := peer.Decode(peerId)
+	if err != nil {
+		return
+	}
+	go c.presenceService.pushTo(id)
+}
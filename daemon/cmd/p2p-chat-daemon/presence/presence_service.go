@@ -0,0 +1,298 @@
+package presence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	presenceMessageMaxLength = 140
+	// presenceRefreshInterval keeps the last seen time friends have of us current while we
+	// stay connected.
+	presenceRefreshInterval = 5 * time.Minute
+
+	presenceStatusSetting  = "presence.status"
+	presenceMessageSetting = "presence.message"
+)
+
+var ErrInvalidPresence = errors.New("invalid presence")
+
+// Service tells our friends how we are doing and keeps track of what they tell us. Privacy
+// rules hide our status, our last seen time or both from chosen friends and circles.
+type Service struct {
+	ctx              context.Context
+	appState         *core.AppState
+	bus              *bus.EventBus
+	relationshipRepo storage.RelationshipRepository
+	settingsRepo     storage.SettingsRepository
+	ruleRepo         storage.PresenceRuleRepository
+	profileService   *profile.Service
+	mu               sync.Mutex
+	friends          map[string]friendPresence
+}
+
+// friendPresence is what a friend last told us. sharesLastSeen is set while they let us
+// see when they were last around.
+type friendPresence struct {
+	presence       types.Presence
+	sharesLastSeen bool
+}
+
+func NewPresenceService(
+	ctx context.Context,
+	appState *core.AppState,
+	bus *bus.EventBus,
+	relationshipRepo storage.RelationshipRepository,
+	settingsRepo storage.SettingsRepository,
+	ruleRepo storage.PresenceRuleRepository,
+	profileService *profile.Service,
+) *Service {
+	return &Service{
+		ctx:              ctx,
+		appState:         appState,
+		bus:              bus,
+		relationshipRepo: relationshipRepo,
+		settingsRepo:     settingsRepo,
+		ruleRepo:         ruleRepo,
+		profileService:   profileService,
+		friends:          make(map[string]friendPresence),
+	}
+}
+
+func (s *Service) Register() {
+	(*s.appState.Node).SetStreamHandler(core.PresenceProtocolID, s.handlePresenceStream)
+
+	notifiee := &network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			go s.pushTo(conn.RemotePeer())
+		},
+	}
+	(*s.appState.Node).Network().Notify(notifiee)
+
+	go s.refresh(notifiee)
+}
+
+// GetOwnPresence returns the status we set. Until we set one we are available.
+func (s *Service) GetOwnPresence() (types.Presence, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	presence := types.Presence{Status: types.PresenceAvailable}
+
+	status, err := s.settingsRepo.Get(ctx, presenceStatusSetting)
+	if errors.Is(err, sql.ErrNoRows) {
+		return presence, nil
+	}
+	if err != nil {
+		return presence, err
+	}
+	presence.Status = status
+
+	message, err := s.settingsRepo.Get(ctx, presenceMessageSetting)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return presence, err
+	}
+	presence.Message = message
+
+	return presence, nil
+}
+
+// SetOwnPresence sets our status and message, and tells the friends we are connected to.
+func (s *Service) SetOwnPresence(status string, message string) (types.Presence, error) {
+	message = strings.TrimSpace(message)
+
+	switch status {
+	case types.PresenceAvailable, types.PresenceAway, types.PresenceBusy, types.PresenceInvisible:
+	default:
+		return types.Presence{}, fmt.Errorf("%w: unknown status %q", ErrInvalidPresence, status)
+	}
+	if utf8.RuneCountInString(message) > presenceMessageMaxLength {
+		return types.Presence{}, fmt.Errorf("%w: message longer than %d characters", ErrInvalidPresence, presenceMessageMaxLength)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.settingsRepo.Set(ctx, presenceStatusSetting, status); err != nil {
+		return types.Presence{}, err
+	}
+	if err := s.settingsRepo.Set(ctx, presenceMessageSetting, message); err != nil {
+		return types.Presence{}, err
+	}
+
+	go s.pushAll()
+
+	return types.Presence{Status: status, Message: message, UpdatedAt: time.Now()}, nil
+}
+
+// GetRules returns our presence privacy rules.
+func (s *Service) GetRules() ([]types.PresenceRule, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.ruleRepo.GetAll(ctx)
+}
+
+// SetRule hides our presence, last seen time or both from a friend or a circle. A rule
+// hiding neither is removed.
+func (s *Service) SetRule(subjectType string, subjectId string, hidePresence bool, hideLastSeen bool) (types.PresenceRule, error) {
+	switch subjectType {
+	case types.PresenceRuleFriend:
+		if _, err := peer.Decode(subjectId); err != nil {
+			return types.PresenceRule{}, fmt.Errorf("%w: invalid peer ID: %v", ErrInvalidPresence, err)
+		}
+	case types.PresenceRuleCircle:
+		if _, err := s.profileService.CircleMembers([]string{subjectId}); err != nil {
+			if errors.Is(err, profile.ErrInvalidLabel) {
+				return types.PresenceRule{}, fmt.Errorf("%w: %v", ErrInvalidPresence, err)
+			}
+			return types.PresenceRule{}, err
+		}
+	default:
+		return types.PresenceRule{}, fmt.Errorf("%w: rules apply to a %q or a %q", ErrInvalidPresence, types.PresenceRuleFriend, types.PresenceRuleCircle)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	rule := types.PresenceRule{
+		SubjectType:  subjectType,
+		SubjectId:    subjectId,
+		HidePresence: hidePresence,
+		HideLastSeen: hideLastSeen,
+		UpdatedAt:    time.Now(),
+	}
+
+	if !hidePresence && !hideLastSeen {
+		if err := s.ruleRepo.Delete(ctx, subjectType, subjectId); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return types.PresenceRule{}, err
+		}
+	} else if err := s.ruleRepo.Save(ctx, rule); err != nil {
+		return types.PresenceRule{}, err
+	}
+
+	go s.pushAll()
+
+	return rule, nil
+}
+
+// FriendPresence returns what a friend last told us about their status, if anything.
+func (s *Service) FriendPresence(peerId string) (types.Presence, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	friend, ok := s.friends[peerId]
+	return friend.presence, ok
+}
+
+// FriendOffline records that a friend stopped answering pings. If they share their last
+// seen time with us, it is the time we lost them.
+func (s *Service) FriendOffline(peerId string, at time.Time) {
+	s.mu.Lock()
+	friend, ok := s.friends[peerId]
+	if !ok || friend.presence.Status == types.PresenceOffline {
+		s.mu.Unlock()
+		return
+	}
+	friend.presence = types.Presence{Status: types.PresenceOffline, UpdatedAt: at}
+	s.friends[peerId] = friend
+	s.mu.Unlock()
+
+	event := events.FriendPresenceChangedEvent{PeerId: peerId, Presence: friend.presence}
+	if friend.sharesLastSeen {
+		s.recordLastSeen(peerId, at)
+		event.LastSeen = at
+	}
+	s.bus.PublishAsync(event)
+}
+
+// Forget drops what we know of a peer's presence once they are no longer a friend.
+func (s *Service) Forget(peerId string) {
+	s.mu.Lock()
+	delete(s.friends, peerId)
+	s.mu.Unlock()
+}
+
+func (s *Service) recordLastSeen(peerId string, at time.Time) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.relationshipRepo.UpdateLastSeen(ctx, peerId, at); err != nil {
+		log.Printf("Presence: Error recording last seen of %s: %v", peerId, err)
+	}
+}
+
+// visibility tells whether our presence and last seen time are hidden from a friend,
+// either directly or through a circle they are in.
+func (s *Service) visibility(ctx context.Context, peerId string) (hidePresence bool, hideLastSeen bool, err error) {
+	rules, err := s.ruleRepo.GetAll(ctx)
+	if err != nil {
+		return false, false, err
+	}
+
+	for _, rule := range rules {
+		applies := false
+		switch rule.SubjectType {
+		case types.PresenceRuleFriend:
+			applies = rule.SubjectId == peerId
+		case types.PresenceRuleCircle:
+			members, err := s.profileService.CircleMembers([]string{rule.SubjectId})
+			if err != nil {
+				// Rules outlive the circles they name; a deleted circle hides nothing.
+				continue
+			}
+			for _, member := range members {
+				if member == peerId {
+					applies = true
+					break
+				}
+			}
+		}
+
+		if applies {
+			hidePresence = hidePresence || rule.HidePresence
+			hideLastSeen = hideLastSeen || rule.HideLastSeen
+		}
+	}
+
+	return hidePresence, hideLastSeen, nil
+}
+
+func (s *Service) isFriend(ctx context.Context, id peer.ID) bool {
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, id.String())
+	return err == nil && relationship.Status == types.FriendStatusApproved
+}
+
+// refresh pushes our presence to connected friends every presenceRefreshInterval.
+func (s *Service) refresh(notifiee *network.NotifyBundle) {
+	defer (*s.appState.Node).Network().StopNotify(notifiee)
+
+	ticker := time.NewTicker(presenceRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			log.Println("Presence: Stopped")
+			return
+		case <-ticker.C:
+			s.pushAll()
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	presenceUpdateMaxSize = 2 * 1024
+	// presenceUpdateMaxAge drops updates that took too long to arrive, or whose sender's
+	// clock is badly off.
+	presenceUpdateMaxAge = 10 * time.Minute
+	presenceTimeout      = 10 * time.Second
+)
+
+// handlePresenceStream takes a presence update from a friend. Other peers are ignored.
+func (s *Service) handlePresenceStream(stream network.Stream) {
+	remotePeerId := stream.Conn().RemotePeer()
+	defer stream.Close()
+
+	stream.SetReadDeadline(time.Now().Add(presenceTimeout))
+	updateBytes, err := io.ReadAll(io.LimitReader(stream, presenceUpdateMaxSize))
+	if err != nil {
+		log.Printf("Presence: Error reading update from %s: %v", remotePeerId.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	var update types.PresenceUpdate
+	if err := json.Unmarshal(updateBytes, &update); err != nil {
+		log.Printf("Presence: Error deserializing update from %s: %v", remotePeerId.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, presenceTimeout)
+	defer cancel()
+
+	if !s.isFriend(ctx, remotePeerId) {
+		log.Printf("Presence: Ignoring update from %s, who is not a friend", remotePeerId.ShortString())
+		stream.Reset()
+		return
+	}
+
+	switch update.Status {
+	case types.PresenceAvailable, types.PresenceAway, types.PresenceBusy, types.PresenceOffline:
+	default:
+		log.Printf("Presence: Ignoring unknown status %q from %s", update.Status, remotePeerId.ShortString())
+		return
+	}
+	if utf8.RuneCountInString(update.Message) > presenceMessageMaxLength {
+		log.Printf("Presence: Ignoring update from %s with an overlong message", remotePeerId.ShortString())
+		return
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, update.Timestamp)
+	if err != nil || time.Since(timestamp).Abs() > presenceUpdateMaxAge {
+		log.Printf("Presence: Ignoring stale update from %s", remotePeerId.ShortString())
+		return
+	}
+
+	var lastSeen time.Time
+	if update.LastSeen != "" {
+		lastSeen, err = time.Parse(time.RFC3339, update.LastSeen)
+		if err != nil || lastSeen.After(time.Now()) {
+			// Whatever they claim, we saw them just now.
+			lastSeen = time.Now()
+		}
+	}
+
+	presence := types.Presence{Status: update.Status, Message: update.Message, UpdatedAt: timestamp}
+
+	peerId := remotePeerId.String()
+	s.mu.Lock()
+	previous := s.friends[peerId].presence
+	s.friends[peerId] = friendPresence{presence: presence, sharesLastSeen: !lastSeen.IsZero()}
+	s.mu.Unlock()
+
+	if !lastSeen.IsZero() {
+		s.recordLastSeen(peerId, lastSeen)
+	}
+
+	if previous.Status != presence.Status || previous.Message != presence.Message {
+		log.Printf("Presence: %s is now %s", remotePeerId.ShortString(), presence.Status)
+	}
+	s.bus.PublishAsync(events.FriendPresenceChangedEvent{PeerId: peerId, Presence: presence, LastSeen: lastSeen})
+}
+
+// updateFor builds the update a friend gets from us, as our privacy rules allow. Friends
+// we hide from, or everyone while we are invisible, are told we are offline.
+func (s *Service) updateFor(ctx context.Context, id peer.ID, own types.Presence) (types.PresenceUpdate, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	hidePresence, hideLastSeen, err := s.visibility(ctx, id.String())
+	if err != nil {
+		return types.PresenceUpdate{}, err
+	}
+
+	if own.Status == types.PresenceInvisible || hidePresence {
+		return types.PresenceUpdate{Status: types.PresenceOffline, Timestamp: now}, nil
+	}
+
+	update := types.PresenceUpdate{Status: own.Status, Message: own.Message, Timestamp: now}
+	if !hideLastSeen {
+		update.LastSeen = now
+	}
+	return update, nil
+}
+
+// pushTo sends our presence to a peer if they are a friend.
+func (s *Service) pushTo(id peer.ID) {
+	ctx, cancel := context.WithTimeout(s.ctx, presenceTimeout)
+	defer cancel()
+
+	if !s.isFriend(ctx, id) {
+		return
+	}
+
+	own, err := s.GetOwnPresence()
+	if err != nil {
+		log.Printf("Presence: Error loading our presence: %v", err)
+		return
+	}
+
+	if err := s.send(ctx, id, own); err != nil {
+		log.Printf("Presence: Could not update %s: %v", id.ShortString(), err)
+	}
+}
+
+// pushAll sends our presence to the friends we are connected to.
+func (s *Service) pushAll() {
+	ctx, cancel := context.WithTimeout(s.ctx, presenceTimeout)
+	defer cancel()
+
+	own, err := s.GetOwnPresence()
+	if err != nil {
+		log.Printf("Presence: Error loading our presence: %v", err)
+		return
+	}
+
+	friends, err := s.relationshipRepo.GetAcceptedRelations(ctx)
+	if err != nil {
+		log.Printf("Presence: Error loading friends: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, friend := range friends {
+		id, err := peer.Decode(friend.PeerID)
+		if err != nil || (*s.appState.Node).Network().Connectedness(id) != network.Connected {
+			continue
+		}
+
+		wg.Add(1)
+		go func(id peer.ID) {
+			defer wg.Done()
+
+			if err := s.send(ctx, id, own); err != nil {
+				log.Printf("Presence: Could not update %s: %v", id.ShortString(), err)
+			}
+		}(id)
+	}
+	wg.Wait()
+}
+
+func (s *Service) send(ctx context.Context, id peer.ID, own types.Presence) error {
+	update, err := s.updateFor(ctx, id, own)
+	if err != nil {
+		return err
+	}
+
+	updateBytes, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	stream, err := (*s.appState.Node).NewStream(ctx, id, core.PresenceProtocolID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if _, err := stream.Write(updateBytes); err != nil {
+		stream.Reset()
+		return err
+	}
+	return nil
+}
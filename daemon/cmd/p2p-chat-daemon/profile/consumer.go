@@ -3,14 +3,12 @@ package profile
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
-	"strings"
 	"time"
 )
 
@@ -95,28 +93,53 @@ func (c *Consumer) handleFriendRequestReceived(request types.FriendRequestData)
 	storeCtx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
 	defer cancel()
 
-	layout := "2006-01-02 15:04:05.999999 -0700 MST"
-
-	cleanTimestamp := request.Timestamp
-	if idx := strings.Index(request.Timestamp, " m=+"); idx > 0 {
-		cleanTimestamp = request.Timestamp[:idx]
+	t, err := time.Parse(time.RFC3339, request.Timestamp)
+	if err != nil {
+		log.Printf("Profile Consumer: ERROR - Failed to parse timestamp of friends request from %s: %v", request.SenderPeerID, err)
+		return
 	}
 
-	t, err := time.Parse(layout, cleanTimestamp)
+	expiresAt, err := time.Parse(time.RFC3339, request.ExpiresAt)
 	if err != nil {
-		fmt.Println("Error parsing timestamp:", err)
+		log.Printf("Profile Consumer: ERROR - Failed to parse expiry of friends request from %s: %v", request.SenderPeerID, err)
 		return
 	}
 
 	entity := types.FriendRelationship{
-		PeerID:      request.SenderPeerID,
-		Status:      types.FriendStatusPending,
-		RequestedAt: t,
+		PeerID:       request.SenderPeerID,
+		Status:       types.FriendStatusPending,
+		RequestedAt:  t,
+		IntroMessage: request.Message,
+		ExpiresAt:    expiresAt,
+		RequestNonce: request.Nonce,
 	}
 
 	curRel, err := c.relationshipRepo.GetRelationByPeerId(storeCtx, request.SenderPeerID)
 
-	if curRel.PeerID != "" {
+	if curRel.Status == types.FriendStatusSent {
+		// We asked them too, e.g. both accepted an introduction; the requests settle it. Their
+		// request takes the place of ours, so our answer names their nonce.
+		log.Printf("Profile Consumer: Friend requests with %s crossed, accepting theirs", request.SenderPeerID)
+		if err := c.relationshipRepo.Delete(storeCtx, request.SenderPeerID); err != nil {
+			log.Printf("Profile Consumer: ERROR - Failed to clear crossed friends request to %s: %v", request.SenderPeerID, err)
+			return
+		}
+		if err := c.relationshipRepo.Store(storeCtx, entity); err != nil {
+			log.Printf("Profile Consumer: ERROR - Failed to store crossed friends request from %s: %v", request.SenderPeerID, err)
+			return
+		}
+		if err := c.profileService.RespondToFriendRequest(request.SenderPeerID, true); err != nil {
+			log.Printf("Profile Consumer: ERROR - Failed to accept crossed friends request from %s: %v", request.SenderPeerID, err)
+		}
+		return
+	}
+
+	if curRel.Status == types.FriendStatusRemoved {
+		if err := c.relationshipRepo.Delete(storeCtx, request.SenderPeerID); err != nil {
+			log.Printf("Profile Consumer: ERROR - Failed to clear ended friendship with %s: %v", request.SenderPeerID, err)
+			return
+		}
+	} else if curRel.PeerID != "" {
 		log.Printf("Profile Consumer: Received duplicate friends request from %s", request.SenderPeerID)
 		return
 	}
@@ -124,51 +147,46 @@ func (c *Consumer) handleFriendRequestReceived(request types.FriendRequestData)
 	err = c.relationshipRepo.Store(storeCtx, entity)
 	if err != nil {
 		log.Printf("Profile Consumer: ERROR - Failed to store friends request from %s: %v", request.SenderPeerID, err)
-	} else {
-		log.Printf("Profile Consumer: Successfully stored friends request from %s", request.SenderPeerID)
+		return
 	}
+
+	log.Printf("Profile Consumer: Successfully stored friends request from %s", request.SenderPeerID)
+	c.profileService.autoAcceptIntroduced(request.SenderPeerID)
 }
 
 func (c *Consumer) handleFriendRequestSent(event events.FriendRequestSentEvent) {
 	storeCtx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
 	defer cancel()
 
-	layout := "2006-01-02 15:04:05.999999 -0700 MST"
-	cleanTimestamp := event.Timestamp.String()
-	if idx := strings.Index(event.Timestamp.String(), " m=+"); idx > 0 {
-		cleanTimestamp = event.Timestamp.String()[:idx]
-	}
-
-	t, err := time.Parse(layout, cleanTimestamp)
-	if err != nil {
-		fmt.Println("Error parsing timestamp:", err)
-		return
+	entity := types.FriendRelationship{
+		PeerID:       event.ReceiverPeerId,
+		Status:       types.FriendStatusSent,
+		RequestedAt:  event.Timestamp,
+		IntroMessage: event.IntroMessage,
+		ExpiresAt:    event.ExpiresAt,
+		RequestNonce: event.Nonce,
 	}
 
-	entity := types.FriendRelationship{
-		PeerID:      event.ReceiverPeerId,
-		Status:      types.FriendStatusSent,
-		RequestedAt: t,
+	if curRel, err := c.relationshipRepo.GetRelationByPeerId(storeCtx, event.ReceiverPeerId); err == nil && curRel.Status == types.FriendStatusRemoved {
+		if err := c.relationshipRepo.Delete(storeCtx, event.ReceiverPeerId); err != nil {
+			log.Printf("Profile Consumer: ERROR - Failed to clear ended friendship with %s: %v", event.ReceiverPeerId, err)
+			return
+		}
 	}
 
-	err = c.relationshipRepo.Store(storeCtx, entity)
+	err := c.relationshipRepo.Store(storeCtx, entity)
 	if err != nil {
 		log.Printf("Profile Consumer: ERROR - Failed to store friends request sent to %s: %v", event.ReceiverPeerId, err)
-	} else {
-		log.Printf("Profile Consumer: Successfully stored friends request sent to %s", event.ReceiverPeerId)
+		return
 	}
+
+	log.Printf("Profile Consumer: Successfully stored friends request sent to %s", event.ReceiverPeerId)
+	c.profileService.queueFriendMessage(event.ReceiverPeerId, types.FriendOutboxRequest)
 }
 
 func (c *Consumer) handleFriendResponseSentEvent(event events.FriendResponseSentEvent) {
-	err := c.profileService.SendFriendResponse(
-		event.PeerId,
-		event.IsAccepted,
-	)
-	if err != nil {
-		log.Printf("Profile Consumer: ERROR - Failed to send friends response to %s: %v", event.PeerId, err)
-		return
-	}
-	log.Printf("Profile Consumer: Successfully sent friends response to %s", event.PeerId)
+	c.profileService.queueFriendMessage(event.PeerId, types.FriendOutboxResponse)
+	log.Printf("Profile Consumer: Queued friends response to %s", event.PeerId)
 }
 
 func (c *Consumer) handleFriendResponseReceivedEvent(event events.FriendResponseReceivedEvent) {
@@ -177,29 +195,23 @@ func (c *Consumer) handleFriendResponseReceivedEvent(event events.FriendResponse
 
 	status := event.Status
 
-	layout := "2006-01-02 15:04:05.999999 -0700 MST"
-
-	cleanTimestamp := event.Timestamp
-	if idx := strings.Index(event.Timestamp, " m=+"); idx > 0 {
-		cleanTimestamp = event.Timestamp[:idx]
-	}
-
-	t, err := time.Parse(layout, cleanTimestamp)
+	t, err := time.Parse(time.RFC3339, event.Timestamp)
 	if err != nil {
-		fmt.Println("Error parsing timestamp:", err)
+		log.Printf("Profile Consumer: ERROR - Failed to parse timestamp of friends response from %s: %v", event.SenderPeerId, err)
 		return
 	}
 
-	entity := types.FriendRelationship{
-		PeerID:     event.SenderPeerId,
-		Status:     status,
-		ApprovedAt: t,
-	}
-
-	err = c.relationshipRepo.UpdateStatus(storeCtx, entity)
+	// Only an answer to the request we are still waiting on counts; anything else, such as a
+	// peer we unfriended replaying an old approval, leaves the relationship alone.
+	resolved, err := c.relationshipRepo.ResolveSentRequest(storeCtx, event.SenderPeerId, event.Nonce, status, t)
 	if err != nil {
 		log.Printf("Profile Consumer: ERROR - Failed to store friends response from %s: %v", event.SenderPeerId, err)
-	} else {
-		log.Printf("Profile Consumer: Successfully stored friends response from %s", event.SenderPeerId)
+		return
+	}
+	if !resolved {
+		log.Printf("Profile Consumer: Ignoring friends response from %s that answers no pending request", event.SenderPeerId)
+		return
 	}
+
+	log.Printf("Profile Consumer: Successfully stored friends response from %s", event.SenderPeerId)
 }
@@ -0,0 +1,450 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+const (
+	contactExportMaxContacts = 10000
+	contactExportMaxAddrs    = 16
+	contactReconnectTimeout  = 30 * time.Second
+	contactReconnectWorkers  = 8
+)
+
+var ErrInvalidContactExport = errors.New("invalid contact list export")
+
+// ExportContacts returns our friends and pending friend requests, with the addresses,
+// names and labels we know them by, signed by our identity.
+func (s *Service) ExportContacts() (*types.ContactExport, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 30*time.Second)
+	defer cancel()
+
+	relationships, err := s.relationshipRepo.GetAcceptedRelations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.relationshipRepo.GetPendingRelations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	relationships = append(relationships, pending...)
+
+	if err := s.labelFriends(ctx, relationships); err != nil {
+		return nil, err
+	}
+
+	data := types.ContactExportData{
+		PeerId:     (*s.appState.Node).ID().String(),
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Contacts:   make([]types.ExportedContact, 0, len(relationships)),
+	}
+
+	for _, relationship := range relationships {
+		contact := types.ExportedContact{
+			PeerId:  relationship.PeerID,
+			Addrs:   []string{},
+			Note:    relationship.Note,
+			Tags:    relationship.Tags,
+			Circles: relationship.Circles,
+			Status:  relationship.Status.String(),
+		}
+		if !relationship.RequestedAt.IsZero() {
+			contact.RequestedAt = relationship.RequestedAt.UTC().Format(time.RFC3339)
+		}
+		if !relationship.ApprovedAt.IsZero() {
+			contact.ApprovedAt = relationship.ApprovedAt.UTC().Format(time.RFC3339)
+		}
+
+		if name, err := s.displayNameRepo.GetByEntity(ctx, relationship.PeerID, friendEntityType); err == nil {
+			contact.DisplayName = name.DisplayName
+		}
+
+		if pid, err := peer.Decode(relationship.PeerID); err == nil {
+			for _, addr := range (*s.appState.Node).Peerstore().Addrs(pid) {
+				if len(contact.Addrs) == contactExportMaxAddrs {
+					break
+				}
+				if !manet.IsIPLoopback(addr) {
+					contact.Addrs = append(contact.Addrs, addr.String())
+				}
+			}
+		}
+
+		data.Contacts = append(data.Contacts, contact)
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ContactExport{Data: data, Signature: signature}, nil
+}
+
+// ImportContacts merges a contact list export into ours and reconnects to the contacts.
+//
+// An export signed by our own identity carries its friendships over as they are. One
+// signed by another identity, such as our old one, cannot: its friends get a friend
+// request instead. Friendships we ended or declined are never revived, and where a name or
+// note differs from ours, onConflict tells whether ours is kept or replaced. Tags and
+// circles are merged.
+func (s *Service) ImportContacts(export types.ContactExport, onConflict string) (*types.ContactImportReport, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	switch onConflict {
+	case "":
+		onConflict = types.ContactImportKeep
+	case types.ContactImportKeep, types.ContactImportReplace:
+	default:
+		return nil, fmt.Errorf("%w: unknown conflict resolution %q", ErrInvalidContactExport, onConflict)
+	}
+
+	data := export.Data
+	if len(data.Contacts) > contactExportMaxContacts {
+		return nil, fmt.Errorf("%w: more than %d contacts", ErrInvalidContactExport, contactExportMaxContacts)
+	}
+	if err := identity.VerifyPayload(data.PeerId, data, export.Signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContactExport, err)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Minute)
+	defer cancel()
+
+	labels, err := s.labelRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	circles, err := s.circleRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.ContactImportReport{
+		ExportedBy:   data.PeerId,
+		SameIdentity: data.PeerId == (*s.appState.Node).ID().String(),
+		Results:      make([]types.ContactImportResult, 0, len(data.Contacts)),
+	}
+
+	var reconnect []peer.ID
+	for _, contact := range data.Contacts {
+		result, pid := s.importContact(ctx, contact, report.SameIdentity, onConflict, labels, &circles)
+		report.Results = append(report.Results, result)
+
+		if result.Outcome != types.ContactImportSkipped && pid != "" {
+			reconnect = append(reconnect, pid)
+		}
+	}
+
+	log.Printf("Contact import: Imported %d contact(s) exported by %s", len(data.Contacts), data.PeerId)
+
+	go s.reconnectContacts(reconnect)
+
+	return report, nil
+}
+
+func (s *Service) importContact(
+	ctx context.Context,
+	contact types.ExportedContact,
+	sameIdentity bool,
+	onConflict string,
+	labels map[string]*types.ContactLabels,
+	circles *[]types.Circle,
+) (types.ContactImportResult, peer.ID) {
+	result := types.ContactImportResult{PeerId: contact.PeerId}
+	skip := func(reason string) (types.ContactImportResult, peer.ID) {
+		result.Outcome = types.ContactImportSkipped
+		result.Reason = reason
+		return result, ""
+	}
+
+	pid, err := peer.Decode(contact.PeerId)
+	if err != nil {
+		return skip("invalid peer ID")
+	}
+	if pid == (*s.appState.Node).ID() {
+		return skip("this is us")
+	}
+	if len([]rune(contact.DisplayName)) > contactNameMaxLength || len([]rune(contact.Note)) > contactNoteMaxLength {
+		return skip("name or note is too long")
+	}
+
+	imported := parseExportedStatus(contact.Status)
+	if imported == types.FriendStatusNone {
+		return skip(fmt.Sprintf("unsupported status %q", contact.Status))
+	}
+	if imported == types.FriendStatusPending && !sameIdentity {
+		return skip("the friend request was sent to the exporting identity")
+	}
+
+	for i, a := range contact.Addrs {
+		if i == contactExportMaxAddrs {
+			break
+		}
+		if addr, err := multiaddr.NewMultiaddr(a); err == nil {
+			(*s.appState.Node).Peerstore().AddAddr(pid, addr, peerstore.AddressTTL)
+		}
+	}
+
+	local, err := s.relationshipRepo.GetRelationByPeerId(ctx, contact.PeerId)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		result.Outcome, err = s.importRelationship(ctx, contact, imported, sameIdentity)
+		if err != nil {
+			log.Printf("Contact import: Error importing %s: %v", contact.PeerId, err)
+			return skip(err.Error())
+		}
+	case err != nil:
+		log.Printf("Contact import: Error loading relationship with %s: %v", contact.PeerId, err)
+		return skip("failed to load our relationship")
+	default:
+		result.Outcome = types.ContactImportMerged
+		if conflict, ok := s.mergeRelationship(ctx, local, imported, sameIdentity); ok {
+			result.Conflicts = append(result.Conflicts, conflict)
+		}
+	}
+
+	status, _ := s.friendStatus(ctx, contact.PeerId)
+
+	current := labels[contact.PeerId]
+	if current == nil {
+		current = &types.ContactLabels{}
+	}
+
+	if conflict, ok := s.mergeDisplayName(ctx, contact, onConflict); ok {
+		result.Conflicts = append(result.Conflicts, conflict)
+	}
+
+	if contact.Note != "" && contact.Note != current.Note {
+		conflict := types.ContactImportConflict{Field: "note", Local: current.Note, Imported: contact.Note, Kept: types.ContactImportKeep}
+		if current.Note == "" || onConflict == types.ContactImportReplace {
+			if err := s.labelRepo.SetNote(ctx, contact.PeerId, contact.Note); err != nil {
+				log.Printf("Contact import: Error storing note on %s: %v", contact.PeerId, err)
+			} else {
+				conflict.Kept = types.ContactImportReplace
+			}
+		}
+		if current.Note != "" {
+			result.Conflicts = append(result.Conflicts, conflict)
+		}
+	}
+
+	for _, tag := range contact.Tags {
+		if err := s.AddContactTag(contact.PeerId, tag); err != nil {
+			log.Printf("Contact import: Skipping tag %q on %s: %v", tag, contact.PeerId, err)
+		}
+	}
+
+	if status == types.FriendStatusApproved {
+		for _, name := range contact.Circles {
+			if err := s.importCircleMember(ctx, circles, name, contact.PeerId); err != nil {
+				log.Printf("Contact import: Error adding %s to circle %q: %v", contact.PeerId, name, err)
+			}
+		}
+	}
+
+	return result, pid
+}
+
+// importRelationship takes over a relationship we had none of.
+func (s *Service) importRelationship(ctx context.Context, contact types.ExportedContact, imported types.FriendStatus, sameIdentity bool) (string, error) {
+	if !sameIdentity || imported == types.FriendStatusSent {
+		if err := s.SendFriendRequest(contact.PeerId, ""); err != nil {
+			return "", err
+		}
+		return types.ContactImportRequested, nil
+	}
+
+	relationship := types.FriendRelationship{
+		PeerID:      contact.PeerId,
+		Status:      imported,
+		RequestedAt: parseExportedTime(contact.RequestedAt),
+		ApprovedAt:  parseExportedTime(contact.ApprovedAt),
+	}
+	if imported == types.FriendStatusPending {
+		relationship.ExpiresAt = time.Now().Add(friendRequestLifetime)
+	}
+
+	if err := s.relationshipRepo.Store(ctx, relationship); err != nil {
+		return "", err
+	}
+	return types.ContactImportAdded, nil
+}
+
+// mergeRelationship reconciles an imported relationship with ours. A friendship in the
+// import completes a request still open on our side; otherwise ours is kept.
+func (s *Service) mergeRelationship(ctx context.Context, local types.FriendRelationship, imported types.FriendStatus, sameIdentity bool) (types.ContactImportConflict, bool) {
+	if local.Status == imported {
+		return types.ContactImportConflict{}, false
+	}
+
+	conflict := types.ContactImportConflict{
+		Field:    "status",
+		Local:    local.Status.String(),
+		Imported: imported.String(),
+		Kept:     types.ContactImportKeep,
+	}
+
+	open := local.Status == types.FriendStatusSent || local.Status == types.FriendStatusPending
+	if sameIdentity && open && imported == types.FriendStatusApproved {
+		local.Status = types.FriendStatusApproved
+		local.ApprovedAt = time.Now()
+		if err := s.relationshipRepo.UpdateStatus(ctx, local); err != nil {
+			log.Printf("Contact import: Error updating relationship with %s: %v", local.PeerID, err)
+		} else {
+			conflict.Kept = types.ContactImportReplace
+		}
+	}
+
+	return conflict, true
+}
+
+func (s *Service) mergeDisplayName(ctx context.Context, contact types.ExportedContact, onConflict string) (types.ContactImportConflict, bool) {
+	if contact.DisplayName == "" {
+		return types.ContactImportConflict{}, false
+	}
+
+	local, err := s.displayNameRepo.GetByEntity(ctx, contact.PeerId, friendEntityType)
+	if errors.Is(err, sql.ErrNoRows) {
+		err := s.displayNameRepo.Store(ctx, storage.DisplayName{
+			EntityID:    contact.PeerId,
+			EntityType:  friendEntityType,
+			DisplayName: contact.DisplayName,
+		})
+		if err != nil {
+			log.Printf("Contact import: Error storing display name for %s: %v", contact.PeerId, err)
+		}
+		return types.ContactImportConflict{}, false
+	}
+	if err != nil {
+		log.Printf("Contact import: Error loading display name for %s: %v", contact.PeerId, err)
+		return types.ContactImportConflict{}, false
+	}
+	if local.DisplayName == contact.DisplayName {
+		return types.ContactImportConflict{}, false
+	}
+
+	conflict := types.ContactImportConflict{
+		Field:    "display_name",
+		Local:    local.DisplayName,
+		Imported: contact.DisplayName,
+		Kept:     types.ContactImportKeep,
+	}
+	if onConflict == types.ContactImportReplace {
+		if err := s.displayNameRepo.Update(ctx, contact.PeerId, friendEntityType, contact.DisplayName); err != nil {
+			log.Printf("Contact import: Error updating display name for %s: %v", contact.PeerId, err)
+		} else {
+			conflict.Kept = types.ContactImportReplace
+		}
+	}
+	return conflict, true
+}
+
+// importCircleMember adds a friend to the circle with the given name, creating it if we
+// have none by that name.
+func (s *Service) importCircleMember(ctx context.Context, circles *[]types.Circle, name string, peerId string) error {
+	name, err := normalizeCircleName(name)
+	if err != nil {
+		return err
+	}
+
+	for _, circle := range *circles {
+		if strings.EqualFold(circle.Name, name) {
+			return s.circleRepo.AddMember(ctx, circle.CircleId, peerId)
+		}
+	}
+
+	circle, err := s.CreateCircle(name, []string{peerId})
+	if err != nil {
+		return err
+	}
+	*circles = append(*circles, circle)
+	return nil
+}
+
+func (s *Service) friendStatus(ctx context.Context, peerId string) (types.FriendStatus, error) {
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, peerId)
+	if err != nil {
+		return types.FriendStatusNone, err
+	}
+	return relationship.Status, nil
+}
+
+// reconnectContacts dials imported contacts at the addresses the import taught us, so
+// queued friend messages go out and their status shows up without waiting. A few workers
+// share the dials, since an import may hold thousands of contacts.
+func (s *Service) reconnectContacts(ids []peer.ID) {
+	pending := make(chan peer.AddrInfo)
+
+	var wg sync.WaitGroup
+	for i := 0; i < contactReconnectWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addrInfo := range pending {
+				ctx, cancel := context.WithTimeout(s.ctx, contactReconnectTimeout)
+				if err := (*s.appState.Node).Connect(ctx, addrInfo); err != nil {
+					log.Printf("Contact import: Could not reconnect to %s: %v", addrInfo.ID.ShortString(), err)
+				}
+				cancel()
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		if s.ctx.Err() != nil {
+			break
+		}
+		if (*s.appState.Node).Network().Connectedness(id) == network.Connected {
+			continue
+		}
+
+		addrInfo := (*s.appState.Node).Peerstore().PeerInfo(id)
+		if len(addrInfo.Addrs) == 0 {
+			continue
+		}
+		pending <- addrInfo
+	}
+	close(pending)
+
+	wg.Wait()
+}
+
+func parseExportedStatus(status string) types.FriendStatus {
+	for _, known := range []types.FriendStatus{types.FriendStatusSent, types.FriendStatusPending, types.FriendStatusApproved} {
+		if status == known.String() {
+			return known
+		}
+	}
+	return types.FriendStatusNone
+}
+
+func parseExportedTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,272 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	contactNoteMaxLength = 1000
+	contactTagMaxLength  = 32
+	contactTagsMax       = 20
+	circleNameMaxLength  = 64
+)
+
+var ErrInvalidLabel = errors.New("invalid contact label")
+
+// SetContactNote replaces our private note on a contact. An empty note removes it.
+func (s *Service) SetContactNote(peerId string, note string) error {
+	if _, err := peer.Decode(peerId); err != nil {
+		return fmt.Errorf("%w: invalid peer ID: %v", ErrInvalidLabel, err)
+	}
+
+	note = strings.TrimSpace(note)
+	if len([]rune(note)) > contactNoteMaxLength {
+		return fmt.Errorf("%w: note longer than %d characters", ErrInvalidLabel, contactNoteMaxLength)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.labelRepo.SetNote(ctx, peerId, note)
+}
+
+// AddContactTag tags a contact. Tags are compared case-insensitively and stored in lower case.
+func (s *Service) AddContactTag(peerId string, tag string) error {
+	if _, err := peer.Decode(peerId); err != nil {
+		return fmt.Errorf("%w: invalid peer ID: %v", ErrInvalidLabel, err)
+	}
+
+	tag, err := normalizeContactTag(tag)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	count, err := s.labelRepo.CountTags(ctx, peerId)
+	if err != nil {
+		return err
+	}
+	if count >= contactTagsMax {
+		return fmt.Errorf("%w: contacts have at most %d tags", ErrInvalidLabel, contactTagsMax)
+	}
+
+	return s.labelRepo.AddTag(ctx, peerId, tag)
+}
+
+// RemoveContactTag removes a tag from a contact. It returns sql.ErrNoRows if the contact
+// did not have it.
+func (s *Service) RemoveContactTag(peerId string, tag string) error {
+	tag, err := normalizeContactTag(tag)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.labelRepo.RemoveTag(ctx, peerId, tag)
+}
+
+func normalizeContactTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len([]rune(tag)) > contactTagMaxLength {
+		return "", fmt.Errorf("%w: tags must be 1 to %d characters", ErrInvalidLabel, contactTagMaxLength)
+	}
+	return tag, nil
+}
+
+// GetCircles returns every circle with its members.
+func (s *Service) GetCircles() ([]types.Circle, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.circleRepo.GetAll(ctx)
+}
+
+// CreateCircle creates a named circle of friends.
+func (s *Service) CreateCircle(name string, members []string) (types.Circle, error) {
+	name, err := normalizeCircleName(name)
+	if err != nil {
+		return types.Circle{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	for _, member := range members {
+		if err := s.requireFriend(ctx, member); err != nil {
+			return types.Circle{}, err
+		}
+	}
+
+	circle := types.Circle{
+		CircleId:  uuid.New().String(),
+		Name:      name,
+		Members:   members,
+		CreatedAt: time.Now(),
+	}
+	if circle.Members == nil {
+		circle.Members = []string{}
+	}
+
+	if err := s.requireUniqueCircleName(ctx, "", name); err != nil {
+		return types.Circle{}, err
+	}
+	if err := s.circleRepo.Create(ctx, circle); err != nil {
+		return types.Circle{}, err
+	}
+
+	return circle, nil
+}
+
+// RenameCircle renames a circle. It returns sql.ErrNoRows if the circle does not exist.
+func (s *Service) RenameCircle(circleId string, name string) error {
+	name, err := normalizeCircleName(name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.requireUniqueCircleName(ctx, circleId, name); err != nil {
+		return err
+	}
+	return s.circleRepo.Rename(ctx, circleId, name)
+}
+
+// DeleteCircle deletes a circle. It returns sql.ErrNoRows if the circle does not exist.
+func (s *Service) DeleteCircle(circleId string) error {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.circleRepo.Delete(ctx, circleId)
+}
+
+// AddCircleMember adds a friend to a circle.
+func (s *Service) AddCircleMember(circleId string, peerId string) error {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.circleRepo.GetById(ctx, circleId); err != nil {
+		return err
+	}
+	if err := s.requireFriend(ctx, peerId); err != nil {
+		return err
+	}
+
+	return s.circleRepo.AddMember(ctx, circleId, peerId)
+}
+
+// RemoveCircleMember removes a peer from a circle. It returns sql.ErrNoRows if the peer
+// was not in it.
+func (s *Service) RemoveCircleMember(circleId string, peerId string) error {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.circleRepo.RemoveMember(ctx, circleId, peerId)
+}
+
+// CircleMembers expands circles into the friends in them, without duplicates. Members we
+// are no longer friends with are left out, so circles can serve as recipient lists.
+func (s *Service) CircleMembers(circleIds []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	seen := map[string]struct{}{}
+	members := []string{}
+
+	for _, circleId := range circleIds {
+		circle, err := s.circleRepo.GetById(ctx, circleId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("%w: circle %s does not exist", ErrInvalidLabel, circleId)
+			}
+			return nil, err
+		}
+
+		for _, member := range circle.Members {
+			if _, dup := seen[member]; dup {
+				continue
+			}
+			seen[member] = struct{}{}
+
+			if s.requireFriend(ctx, member) != nil {
+				continue
+			}
+			members = append(members, member)
+		}
+	}
+
+	return members, nil
+}
+
+// labelFriends fills in our notes, tags and circles on each friend.
+func (s *Service) labelFriends(ctx context.Context, friends []types.FriendRelationship) error {
+	labels, err := s.labelRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	circles, err := s.circleRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, circle := range circles {
+		for _, member := range circle.Members {
+			if labels[member] == nil {
+				labels[member] = &types.ContactLabels{}
+			}
+			labels[member].Circles = append(labels[member].Circles, circle.Name)
+		}
+	}
+
+	for i := range friends {
+		if contact := labels[friends[i].PeerID]; contact != nil {
+			friends[i].Note = contact.Note
+			friends[i].Tags = contact.Tags
+			friends[i].Circles = contact.Circles
+		}
+	}
+	return nil
+}
+
+func (s *Service) requireFriend(ctx context.Context, peerId string) error {
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, peerId)
+	if err != nil || relationship.Status != types.FriendStatusApproved {
+		return fmt.Errorf("%w: %s", ErrNotFriend, peerId)
+	}
+	return nil
+}
+
+func (s *Service) requireUniqueCircleName(ctx context.Context, circleId string, name string) error {
+	circles, err := s.circleRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, circle := range circles {
+		if circle.CircleId != circleId && strings.EqualFold(circle.Name, name) {
+			return fmt.Errorf("%w: a circle named %q already exists", ErrInvalidLabel, circle.Name)
+		}
+	}
+	return nil
+}
+
+func normalizeCircleName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > circleNameMaxLength {
+		return "", fmt.Errorf("%w: circle names must be 1 to %d characters", ErrInvalidLabel, circleNameMaxLength)
+	}
+	return name, nil
+}
@@ -0,0 +1,159 @@
+package profile
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+const (
+	// contactURIMaxAddrs keeps URIs small enough for a readable QR code.
+	contactURIMaxAddrs   = 8
+	contactNameMaxLength = 64
+)
+
+var ErrInvalidContact = errors.New("invalid contact")
+
+// ContactURI returns a signed contact URI for our node, suggesting name to whoever
+// imports it.
+func (s *Service) ContactURI(name string) (string, error) {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return "", fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	name = strings.TrimSpace(name)
+	if len([]rune(name)) > contactNameMaxLength {
+		return "", fmt.Errorf("%w: name longer than %d characters", ErrInvalidContact, contactNameMaxLength)
+	}
+
+	data := types.ContactCardData{
+		PeerId: (*s.appState.Node).ID().String(),
+		Addrs:  s.contactAddrs(),
+		Name:   name,
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeContactURI(data, signature), nil
+}
+
+// contactAddrs lists the addresses worth sharing, public ones first. Loopback addresses
+// only help on the same machine and are left out.
+func (s *Service) contactAddrs() []string {
+	var public, private []string
+	for _, addr := range (*s.appState.Node).Addrs() {
+		switch {
+		case manet.IsIPLoopback(addr):
+			continue
+		case manet.IsPublicAddr(addr):
+			public = append(public, addr.String())
+		default:
+			private = append(private, addr.String())
+		}
+	}
+
+	addrs := append(public, private...)
+	if len(addrs) > contactURIMaxAddrs {
+		addrs = addrs[:contactURIMaxAddrs]
+	}
+	return addrs
+}
+
+// ImportContactURI verifies a contact URI, remembers the addresses it carries and sends the
+// peer a friend request.
+func (s *Service) ImportContactURI(uri string, introMessage string) (*types.ContactCardData, error) {
+	data, signature, err := parseContactURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	pid, err := peer.Decode(data.PeerId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid peer ID: %v", ErrInvalidContact, err)
+	}
+
+	if len([]rune(data.Name)) > contactNameMaxLength {
+		return nil, fmt.Errorf("%w: name longer than %d characters", ErrInvalidContact, contactNameMaxLength)
+	}
+
+	if err := identity.VerifyPayload(data.PeerId, data, signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContact, err)
+	}
+
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return nil, fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	for _, a := range data.Addrs {
+		if addr, err := multiaddr.NewMultiaddr(a); err == nil {
+			(*s.appState.Node).Peerstore().AddAddr(pid, addr, peerstore.AddressTTL)
+		}
+	}
+
+	if err := s.SendFriendRequest(data.PeerId, introMessage); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func encodeContactURI(data types.ContactCardData, signature []byte) string {
+	query := url.Values{}
+	query.Set("peer", data.PeerId)
+	if len(data.Addrs) > 0 {
+		query.Set("addrs", strings.Join(data.Addrs, ","))
+	}
+	if data.Name != "" {
+		query.Set("name", data.Name)
+	}
+	query.Set("sig", base64.RawURLEncoding.EncodeToString(signature))
+
+	return types.ContactURIPrefix + query.Encode()
+}
+
+func parseContactURI(uri string) (*types.ContactCardData, []byte, error) {
+	rawQuery, ok := strings.CutPrefix(strings.TrimSpace(uri), types.ContactURIPrefix)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: not a contact link", ErrInvalidContact)
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidContact, err)
+	}
+
+	data := &types.ContactCardData{
+		PeerId: query.Get("peer"),
+		Name:   query.Get("name"),
+	}
+	if addrs := query.Get("addrs"); addrs != "" {
+		data.Addrs = strings.Split(addrs, ",")
+	}
+
+	if data.PeerId == "" {
+		return nil, nil, fmt.Errorf("%w: missing peer", ErrInvalidContact)
+	}
+	if len(data.Addrs) > contactURIMaxAddrs {
+		return nil, nil, fmt.Errorf("%w: more than %d addresses", ErrInvalidContact, contactURIMaxAddrs)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(query.Get("sig"))
+	if err != nil || len(signature) == 0 {
+		return nil, nil, fmt.Errorf("%w: missing or malformed signature", ErrInvalidContact)
+	}
+
+	return data, signature, nil
+}
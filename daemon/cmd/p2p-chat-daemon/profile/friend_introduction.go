@@ -0,0 +1,395 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	IntroductionActionAccept  = "accept"
+	IntroductionActionAuto    = "auto_accept"
+	IntroductionActionDismiss = "dismiss"
+)
+
+const (
+	friendIntroductionMaxSize = 8 * 1024
+	introductionMaxAddrs      = 8
+	introductionNameMaxLength = 64
+	// friendEntityType is the display name entity type of friends.
+	friendEntityType = "friend"
+)
+
+var ErrInvalidIntroduction = errors.New("invalid introduction")
+
+// IntroduceFriends introduces two of our friends to each other. Each receives a signed
+// introduction naming the other, with the addresses and name we know them by. Introductions
+// wait in the outbox until each friend is reachable.
+func (s *Service) IntroduceFriends(peerA string, peerB string, message string) error {
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	pidA, err := peer.Decode(peerA)
+	if err != nil {
+		return fmt.Errorf("%w: invalid peer ID %s: %v", ErrInvalidIntroduction, peerA, err)
+	}
+	pidB, err := peer.Decode(peerB)
+	if err != nil {
+		return fmt.Errorf("%w: invalid peer ID %s: %v", ErrInvalidIntroduction, peerB, err)
+	}
+	if pidA == pidB {
+		return fmt.Errorf("%w: cannot introduce a peer to themselves", ErrInvalidIntroduction)
+	}
+	if len([]rune(message)) > friendRequestMessageMaxLength {
+		return fmt.Errorf("%w: message longer than %d characters", ErrInvalidIntroduction, friendRequestMessageMaxLength)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	for _, peerId := range []string{peerA, peerB} {
+		relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, peerId)
+		if err != nil || relationship.Status != types.FriendStatusApproved {
+			return fmt.Errorf("%w: %s", ErrNotFriend, peerId)
+		}
+	}
+
+	payloads := map[string]queuedIntroduction{
+		peerA: {PeerId: peerB, Message: message},
+		peerB: {PeerId: peerA, Message: message},
+	}
+
+	introId := uuid.NewString()
+	for recipient, queued := range payloads {
+		payload, err := json.Marshal(queued)
+		if err != nil {
+			return fmt.Errorf("failed to marshal introduction: %w", err)
+		}
+		s.QueueOutboxMessage(recipient, types.FriendOutboxIntroduction, introId, string(payload))
+	}
+
+	return nil
+}
+
+// queuedIntroduction is what the outbox keeps of an introduction. The rest is rebuilt on
+// every attempt, so the recipient gets fresh addresses and a fresh timestamp.
+type queuedIntroduction struct {
+	PeerId  string `json:"peer_id"`
+	Message string `json:"message"`
+}
+
+// sendQueuedIntroduction sends an introduction from the outbox while both peers are still
+// our friends.
+func (s *Service) sendQueuedIntroduction(ctx context.Context, recipient peer.ID, introId string, entry types.FriendOutboxEntry) error {
+	if time.Since(entry.CreatedAt) > friendRequestLifetime {
+		return ErrOutboxObsolete
+	}
+
+	var queued queuedIntroduction
+	if err := json.Unmarshal([]byte(entry.Payload), &queued); err != nil {
+		return ErrOutboxObsolete
+	}
+	introduced, err := peer.Decode(queued.PeerId)
+	if err != nil {
+		return ErrOutboxObsolete
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	for _, peerId := range []string{recipient.String(), queued.PeerId} {
+		relationship, err := s.relationshipRepo.GetRelationByPeerId(lookupCtx, peerId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOutboxObsolete
+		}
+		if err != nil {
+			return err
+		}
+		if relationship.Status != types.FriendStatusApproved {
+			return ErrOutboxObsolete
+		}
+	}
+
+	data := s.introductionOf(lookupCtx, introId, recipient, introduced, queued.Message)
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return err
+	}
+
+	introBytes, err := json.Marshal(types.FriendIntroduction{Data: data, Signature: signature})
+	if err != nil {
+		return fmt.Errorf("failed to marshal introduction: %w", err)
+	}
+
+	return s.writeFriendStream(ctx, recipient, core.FriendIntroductionProtocolID, introBytes)
+}
+
+func (s *Service) introductionOf(ctx context.Context, introId string, recipient peer.ID, introduced peer.ID, message string) types.FriendIntroductionData {
+	var addrs []string
+	for _, addr := range (*s.appState.Node).Peerstore().Addrs(introduced) {
+		if len(addrs) == introductionMaxAddrs {
+			break
+		}
+		addrs = append(addrs, addr.String())
+	}
+
+	var displayName string
+	if name, err := s.displayNameRepo.GetByEntity(ctx, introduced.String(), friendEntityType); err == nil {
+		displayName = name.DisplayName
+	}
+
+	return types.FriendIntroductionData{
+		IntroId:          introId,
+		IntroducerPeerId: (*s.appState.Node).ID().String(),
+		RecipientPeerId:  recipient.String(),
+		PeerId:           introduced.String(),
+		Addrs:            addrs,
+		DisplayName:      displayName,
+		Message:          message,
+		Timestamp:        time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// handleFriendIntroductionStream keeps an introduction from a friend for the user to act on.
+func (s *Service) handleFriendIntroductionStream(stream network.Stream) {
+	remotePeerId := stream.Conn().RemotePeer()
+	defer stream.Close()
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, friendIntroductionMaxSize))
+	if err != nil {
+		log.Printf("Friend Introduction Handler: Error reading introduction from %s: %v", remotePeerId.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var introduction types.FriendIntroduction
+	if err := json.Unmarshal(receivedBytes, &introduction); err != nil {
+		log.Printf("Friend Introduction Handler: Error deserializing introduction from %s: %v", remotePeerId.String(), err)
+		stream.Reset()
+		return
+	}
+
+	data := introduction.Data
+	if err := s.validateFriendIntroduction(remotePeerId, introduction); err != nil {
+		log.Printf("Friend Introduction Handler: Rejecting introduction from %s: %v", remotePeerId.String(), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, data.PeerId); err == nil &&
+		(relationship.Status == types.FriendStatusApproved || relationship.Status == types.FriendStatusSent) {
+		log.Printf("Friend Introduction Handler: Already friends with or waiting on %s", data.PeerId)
+		return
+	}
+
+	introducedPID, _ := peer.Decode(data.PeerId)
+	for _, a := range data.Addrs {
+		if addr, err := multiaddr.NewMultiaddr(a); err == nil {
+			(*s.appState.Node).Peerstore().AddAddr(introducedPID, addr, peerstore.AddressTTL)
+		}
+	}
+
+	received := types.ReceivedIntroduction{
+		IntroId:          data.IntroId,
+		IntroducerPeerId: data.IntroducerPeerId,
+		PeerId:           data.PeerId,
+		Addrs:            data.Addrs,
+		DisplayName:      data.DisplayName,
+		Message:          data.Message,
+		Status:           types.IntroductionStatusPending,
+		ReceivedAt:       time.Now(),
+	}
+
+	if err := s.introductionRepo.Store(ctx, received); err != nil {
+		log.Printf("Friend Introduction Handler: Error storing introduction from %s: %v", remotePeerId.String(), err)
+		return
+	}
+
+	s.bus.PublishAsync(events.FriendIntroductionReceivedEvent{Introduction: received})
+
+	log.Printf("Friend Introduction Handler: %s introduced us to %s", remotePeerId.String(), data.PeerId)
+}
+
+// validateFriendIntroduction accepts fresh introductions, addressed to us, from a friend.
+func (s *Service) validateFriendIntroduction(sender peer.ID, introduction types.FriendIntroduction) error {
+	data := introduction.Data
+
+	if data.IntroducerPeerId != sender.String() {
+		return fmt.Errorf("introduction by %s was sent by %s", data.IntroducerPeerId, sender)
+	}
+	if data.RecipientPeerId != (*s.appState.Node).ID().String() {
+		return fmt.Errorf("introduction addressed to %s", data.RecipientPeerId)
+	}
+	if data.IntroId == "" {
+		return errors.New("introduction has no ID")
+	}
+
+	if _, err := peer.Decode(data.PeerId); err != nil {
+		return fmt.Errorf("invalid introduced peer: %w", err)
+	}
+	if data.PeerId == data.RecipientPeerId || data.PeerId == data.IntroducerPeerId {
+		return fmt.Errorf("introduction of %s to themselves", data.PeerId)
+	}
+
+	if len(data.Addrs) > introductionMaxAddrs {
+		return fmt.Errorf("more than %d addresses", introductionMaxAddrs)
+	}
+	if len([]rune(data.DisplayName)) > introductionNameMaxLength {
+		return fmt.Errorf("display name longer than %d characters", introductionNameMaxLength)
+	}
+	if len([]rune(data.Message)) > friendRequestMessageMaxLength {
+		return fmt.Errorf("message longer than %d characters", friendRequestMessageMaxLength)
+	}
+
+	sentAt, err := time.Parse(time.RFC3339, data.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	if age := time.Since(sentAt); age > friendRequestMaxAge || age < -friendRequestMaxAge {
+		return fmt.Errorf("introduction sent at %s is stale", data.Timestamp)
+	}
+
+	if err := identity.VerifyPayload(data.IntroducerPeerId, data, introduction.Signature); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, data.IntroducerPeerId)
+	if err != nil || relationship.Status != types.FriendStatusApproved {
+		return fmt.Errorf("%w: %s", ErrNotFriend, data.IntroducerPeerId)
+	}
+
+	return nil
+}
+
+// GetIntroductions returns introductions we have not acted on yet.
+func (s *Service) GetIntroductions() ([]types.ReceivedIntroduction, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	return s.introductionRepo.GetOpen(ctx)
+}
+
+// RespondToIntroduction acts on an introduction. Accepting sends the introduced peer a
+// friend request; auto-accept instead waits and approves their request when it arrives.
+// Either way a request they already sent us is approved at once.
+func (s *Service) RespondToIntroduction(introId string, action string) error {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	intro, err := s.introductionRepo.GetById(ctx, introId)
+	if err != nil {
+		return err
+	}
+	if intro.Status != types.IntroductionStatusPending && intro.Status != types.IntroductionStatusAutoAccept {
+		return fmt.Errorf("%w: introduction %s is %s", ErrInvalidIntroduction, introId, intro.Status)
+	}
+
+	if action == IntroductionActionDismiss {
+		return s.introductionRepo.UpdateStatus(ctx, introId, types.IntroductionStatusDismissed)
+	}
+	if action != IntroductionActionAccept && action != IntroductionActionAuto {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidIntroduction, action)
+	}
+
+	s.adoptIntroducedName(ctx, intro)
+
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, intro.PeerId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	switch {
+	case relationship.Status == types.FriendStatusPending:
+		if err := s.RespondToFriendRequest(intro.PeerId, true); err != nil {
+			return err
+		}
+	case relationship.Status == types.FriendStatusApproved || relationship.Status == types.FriendStatusSent:
+	case action == IntroductionActionAuto:
+		return s.introductionRepo.UpdateStatus(ctx, introId, types.IntroductionStatusAutoAccept)
+	default:
+		if err := s.SendFriendRequest(intro.PeerId, s.introductionRequestMessage(ctx, intro)); err != nil {
+			return err
+		}
+	}
+
+	return s.introductionRepo.UpdateStatus(ctx, introId, types.IntroductionStatusAccepted)
+}
+
+// autoAcceptIntroduced approves a friend request from a peer we chose to auto-accept when
+// they were introduced to us.
+func (s *Service) autoAcceptIntroduced(peerId string) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	intros, err := s.introductionRepo.GetOpenForPeer(ctx, peerId)
+	if err != nil {
+		log.Printf("Friend introductions: Error loading introductions of %s: %v", peerId, err)
+		return
+	}
+
+	for _, intro := range intros {
+		if intro.Status != types.IntroductionStatusAutoAccept {
+			continue
+		}
+
+		if err := s.RespondToFriendRequest(peerId, true); err != nil {
+			log.Printf("Friend introductions: Error auto-accepting %s: %v", peerId, err)
+			return
+		}
+		if err := s.introductionRepo.UpdateStatus(ctx, intro.IntroId, types.IntroductionStatusAccepted); err != nil {
+			log.Printf("Friend introductions: Error updating introduction %s: %v", intro.IntroId, err)
+		}
+
+		log.Printf("Friend introductions: Auto-accepted friend request from %s", peerId)
+		return
+	}
+}
+
+// adoptIntroducedName uses the introducer's name for the peer unless we already have one.
+func (s *Service) adoptIntroducedName(ctx context.Context, intro types.ReceivedIntroduction) {
+	if intro.DisplayName == "" {
+		return
+	}
+	if _, err := s.displayNameRepo.GetByEntity(ctx, intro.PeerId, friendEntityType); err == nil {
+		return
+	}
+
+	err := s.displayNameRepo.Store(ctx, storage.DisplayName{
+		EntityID:    intro.PeerId,
+		EntityType:  friendEntityType,
+		DisplayName: intro.DisplayName,
+	})
+	if err != nil {
+		log.Printf("Friend introductions: Error storing display name for %s: %v", intro.PeerId, err)
+	}
+}
+
+func (s *Service) introductionRequestMessage(ctx context.Context, intro types.ReceivedIntroduction) string {
+	introducer := intro.IntroducerPeerId
+	if name, err := s.displayNameRepo.GetByEntity(ctx, intro.IntroducerPeerId, friendEntityType); err == nil {
+		introducer = name.DisplayName
+	}
+	return fmt.Sprintf("Introduced by %s", introducer)
+}
@@ -0,0 +1,293 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	friendOutboxBaseBackoff = 30 * time.Second
+	friendOutboxMaxBackoff  = time.Hour
+	// friendOutboxIdleInterval is how long the outbox sleeps when nothing is waiting; new
+	// messages and connections wake it earlier.
+	friendOutboxIdleInterval = 10 * time.Minute
+	friendOutboxMinInterval  = time.Second
+)
+
+// ErrOutboxObsolete marks a queued message that no longer needs delivering, because the
+// request was answered, cancelled or expired in the meantime.
+var ErrOutboxObsolete = errors.New("outbox entry is obsolete")
+
+// OutboxSender rebuilds and sends a message that another service queued in the outbox. key
+// tells apart several messages of the same kind waiting for one peer.
+type OutboxSender func(ctx context.Context, target peer.ID, key string, entry types.FriendOutboxEntry) error
+
+// RegisterOutboxSender lets another service deliver its own kind of message through the
+// outbox. Messages of a kind nobody registered yet are retried rather than dropped.
+func (s *Service) RegisterOutboxSender(kind string, send OutboxSender) {
+	s.outboxMu.Lock()
+	s.outboxSenders[kind] = send
+	s.outboxMu.Unlock()
+
+	s.wakeFriendOutbox()
+}
+
+// QueueOutboxMessage persists a message of a registered kind for delivery to a peer. Queueing
+// the same kind and key again replaces the waiting message.
+func (s *Service) QueueOutboxMessage(peerId string, kind string, key string, payload string) {
+	s.queueFriendPayload(peerId, kind+"/"+key, payload)
+}
+
+// queueFriendMessage persists a friend message for delivery and wakes the outbox.
+func (s *Service) queueFriendMessage(peerId string, kind string) {
+	s.queueFriendPayload(peerId, kind, "")
+}
+
+// queueFriendPayload queues a friend message that is rebuilt from payload rather than from
+// the relationship.
+func (s *Service) queueFriendPayload(peerId string, kind string, payload string) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.outboxRepo.Enqueue(ctx, peerId, kind, payload); err != nil {
+		log.Printf("Friend outbox: Error queueing %s for %s: %v", kind, peerId, err)
+		return
+	}
+
+	s.wakeFriendOutbox()
+}
+
+func (s *Service) wakeFriendOutbox() {
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// runFriendOutbox delivers queued friend messages. A message goes out as soon as a
+// connection to its peer comes up; until then it is retried with exponential backoff.
+func (s *Service) runFriendOutbox() {
+	s.queueSentRequests()
+
+	notifiee := &network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			go s.flushFriendOutboxForPeer(conn.RemotePeer())
+		},
+	}
+	(*s.appState.Node).Network().Notify(notifiee)
+	defer (*s.appState.Node).Network().StopNotify(notifiee)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			log.Println("Friend outbox: Stopped")
+			return
+		case <-s.outboxWake:
+		case <-timer.C:
+		}
+
+		timer.Reset(s.flushDueFriendOutbox())
+	}
+}
+
+// queueSentRequests queues requests that were pending before the outbox existed, and gives
+// every waiting request a fresh attempt on startup.
+func (s *Service) queueSentRequests() {
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	pending, err := s.relationshipRepo.GetPendingRelations(ctx)
+	if err != nil {
+		log.Printf("Friend outbox: Error loading pending requests: %v", err)
+		return
+	}
+
+	for _, relationship := range pending {
+		if relationship.Status != types.FriendStatusSent {
+			continue
+		}
+		if err := s.outboxRepo.Enqueue(ctx, relationship.PeerID, types.FriendOutboxRequest, ""); err != nil {
+			log.Printf("Friend outbox: Error queueing request for %s: %v", relationship.PeerID, err)
+		}
+	}
+}
+
+// flushDueFriendOutbox starts delivery of every message whose backoff has passed and returns
+// how long to wait until the next one is due.
+func (s *Service) flushDueFriendOutbox() time.Duration {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	entries, err := s.outboxRepo.GetAll(ctx)
+	if err != nil {
+		log.Printf("Friend outbox: Error loading queued messages: %v", err)
+		return friendOutboxIdleInterval
+	}
+
+	now := time.Now()
+	wait := friendOutboxIdleInterval
+
+	for _, entry := range entries {
+		if !entry.NextAttemptAt.After(now) {
+			go s.deliverFriendOutboxEntry(entry)
+			continue
+		}
+		if until := entry.NextAttemptAt.Sub(now); until < wait {
+			wait = until
+		}
+	}
+
+	return max(wait, friendOutboxMinInterval)
+}
+
+// flushFriendOutboxForPeer delivers everything queued for a peer that just connected,
+// regardless of backoff.
+func (s *Service) flushFriendOutboxForPeer(pid peer.ID) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	entries, err := s.outboxRepo.GetByPeer(ctx, pid.String())
+	if err != nil {
+		log.Printf("Friend outbox: Error loading queued messages for %s: %v", pid.ShortString(), err)
+		return
+	}
+
+	for _, entry := range entries {
+		s.deliverFriendOutboxEntry(entry)
+	}
+}
+
+func (s *Service) deliverFriendOutboxEntry(entry types.FriendOutboxEntry) {
+	key := entry.PeerId + "/" + entry.Kind
+
+	s.outboxMu.Lock()
+	if _, busy := s.outboxInFlight[key]; busy {
+		s.outboxMu.Unlock()
+		return
+	}
+	s.outboxInFlight[key] = struct{}{}
+	s.outboxMu.Unlock()
+
+	defer func() {
+		s.outboxMu.Lock()
+		delete(s.outboxInFlight, key)
+		s.outboxMu.Unlock()
+	}()
+
+	err := s.sendFriendOutboxEntry(entry)
+	if s.ctx.Err() != nil {
+		// Shutting down: leave the entry as it was for the next start.
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	if err == nil || errors.Is(err, ErrOutboxObsolete) {
+		if err == nil {
+			log.Printf("Friend outbox: Delivered %s to %s", entry.Kind, entry.PeerId)
+		}
+		if err := s.outboxRepo.Delete(ctx, entry.PeerId, entry.Kind); err != nil {
+			log.Printf("Friend outbox: Error removing delivered %s for %s: %v", entry.Kind, entry.PeerId, err)
+		}
+		return
+	}
+
+	attempts := entry.Attempts + 1
+	nextAttemptAt := time.Now().Add(friendOutboxBackoff(attempts))
+	log.Printf("Friend outbox: Attempt %d to deliver %s to %s failed, retrying at %s: %v",
+		attempts, entry.Kind, entry.PeerId, nextAttemptAt.Format(time.RFC3339), err)
+
+	if err := s.outboxRepo.RecordFailure(ctx, entry.PeerId, entry.Kind, attempts, nextAttemptAt); err != nil {
+		log.Printf("Friend outbox: Error recording failed attempt for %s: %v", entry.PeerId, err)
+	}
+	s.wakeFriendOutbox()
+}
+
+// sendFriendOutboxEntry rebuilds a queued message from the relationship, or hands it to the
+// service that queued it, and sends it.
+func (s *Service) sendFriendOutboxEntry(entry types.FriendOutboxEntry) error {
+	targetPID, err := peer.Decode(entry.PeerId)
+	if err != nil {
+		return ErrOutboxObsolete
+	}
+
+	if kind, key, found := strings.Cut(entry.Kind, "/"); found {
+		s.outboxMu.Lock()
+		send, ok := s.outboxSenders[kind]
+		s.outboxMu.Unlock()
+		if !ok {
+			return fmt.Errorf("no sender registered for %s messages", kind)
+		}
+		return send(s.ctx, targetPID, key, entry)
+	}
+
+	// The request a cancellation withdraws is deleted already; the entry carries its nonce.
+	if entry.Kind == types.FriendOutboxCancel {
+		if time.Since(entry.CreatedAt) > friendRequestLifetime {
+			return ErrOutboxObsolete
+		}
+		return s.sendFriendRequestCancellation(s.ctx, targetPID, entry.Payload)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, entry.PeerId)
+	cancel()
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrOutboxObsolete
+	}
+	if err != nil {
+		return err
+	}
+
+	switch entry.Kind {
+	case types.FriendOutboxRequest:
+		// Requests stored before they carried an expiry expire relative to when they were made.
+		if relationship.ExpiresAt.IsZero() {
+			relationship.ExpiresAt = relationship.RequestedAt.Add(friendRequestLifetime)
+		}
+		if relationship.Status != types.FriendStatusSent || !relationship.ExpiresAt.After(time.Now()) {
+			return ErrOutboxObsolete
+		}
+		return s.sendFriendRequest(s.ctx, targetPID, relationship)
+
+	case types.FriendOutboxResponse:
+		if relationship.Status != types.FriendStatusApproved && relationship.Status != types.FriendStatusRejected {
+			return ErrOutboxObsolete
+		}
+		if time.Since(entry.CreatedAt) > friendRequestLifetime {
+			return ErrOutboxObsolete
+		}
+		return s.sendFriendResponse(s.ctx, targetPID, relationship)
+
+	case types.FriendOutboxRemoval:
+		// A removal is retried until the peer hears of it, unless we became friends again.
+		if relationship.Status != types.FriendStatusRemoved {
+			return ErrOutboxObsolete
+		}
+		return s.sendFriendRemoval(s.ctx, targetPID)
+
+	default:
+		return ErrOutboxObsolete
+	}
+}
+
+func friendOutboxBackoff(attempts int) time.Duration {
+	backoff := friendOutboxBaseBackoff
+	for i := 1; i < attempts && backoff < friendOutboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, friendOutboxMaxBackoff)
+}
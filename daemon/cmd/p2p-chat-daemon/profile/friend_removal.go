@@ -0,0 +1,154 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	friendRemovalMaxSize = 4 * 1024
+	// friendRemovalMaxAge bounds how old a removal notice may be, so a captured notice
+	// cannot end a later friendship.
+	friendRemovalMaxAge = 10 * time.Minute
+)
+
+var ErrNotFriend = errors.New("peer is not a friend")
+
+// Unfriend ends the friendship with a peer. The peer is told with a signed notice, queued
+// until it can be reached; meanwhile it stops being pinged and its messages are refused. With
+// purgeHistory set, our conversation with the peer is deleted as well.
+func (s *Service) Unfriend(peerId string, purgeHistory bool) error {
+	targetPID, err := peer.Decode(peerId)
+	if err != nil {
+		return fmt.Errorf("invalid target PeerID format: %w", err)
+	}
+
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, peerId)
+	if err != nil {
+		return err
+	}
+	if relationship.Status != types.FriendStatusApproved {
+		return fmt.Errorf("%w: %s", ErrNotFriend, peerId)
+	}
+
+	relationship.Status = types.FriendStatusRemoved
+	if err := s.relationshipRepo.UpdateStatus(ctx, relationship); err != nil {
+		return fmt.Errorf("failed to update friends relationship status: %w", err)
+	}
+
+	s.connectionService.Forget(targetPID)
+	s.bus.PublishAsync(events.FriendRemovedEvent{PeerId: peerId, Initiated: true, PurgeHistory: purgeHistory})
+
+	s.queueFriendMessage(peerId, types.FriendOutboxRemoval)
+
+	return nil
+}
+
+// sendFriendRemoval signs a removal notice with a fresh timestamp and sends it.
+func (s *Service) sendFriendRemoval(ctx context.Context, targetPID peer.ID) error {
+	data := types.FriendRemovalData{
+		SenderPeerID:   (*s.appState.Node).ID().String(),
+		ReceiverPeerID: targetPID.String(),
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return err
+	}
+
+	removalBytes, err := json.Marshal(types.FriendRemoval{Data: data, SenderSignature: signature})
+	if err != nil {
+		return fmt.Errorf("failed to marshal friend removal: %w", err)
+	}
+
+	return s.writeFriendStream(ctx, targetPID, core.FriendRemovalProtocolID, removalBytes)
+}
+
+// handleFriendRemovalStream ends the friendship when a friend tells us they removed us.
+func (s *Service) handleFriendRemovalStream(stream network.Stream) {
+	remotePeerId := stream.Conn().RemotePeer()
+	defer stream.Close()
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, friendRemovalMaxSize))
+	if err != nil {
+		log.Printf("Friend Removal Handler: Error reading removal from %s: %v", remotePeerId.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var removal types.FriendRemoval
+	if err := json.Unmarshal(receivedBytes, &removal); err != nil {
+		log.Printf("Friend Removal Handler: Error deserializing removal from %s: %v", remotePeerId.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if err := s.validateFriendRemoval(remotePeerId, removal); err != nil {
+		log.Printf("Friend Removal Handler: Rejecting removal from %s: %v", remotePeerId.String(), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, remotePeerId.String())
+	if err != nil {
+		log.Printf("Friend Removal Handler: No relationship with %s: %v", remotePeerId.String(), err)
+		return
+	}
+	if relationship.Status == types.FriendStatusRemoved {
+		return
+	}
+
+	relationship.Status = types.FriendStatusRemoved
+	if err := s.relationshipRepo.UpdateStatus(ctx, relationship); err != nil {
+		log.Printf("Friend Removal Handler: Error updating relationship with %s: %v", remotePeerId.String(), err)
+		return
+	}
+
+	s.connectionService.Forget(remotePeerId)
+	s.bus.PublishAsync(events.FriendRemovedEvent{PeerId: remotePeerId.String()})
+
+	log.Printf("Friend Removal Handler: %s ended the friendship", remotePeerId.String())
+}
+
+func (s *Service) validateFriendRemoval(sender peer.ID, removal types.FriendRemoval) error {
+	data := removal.Data
+
+	if data.SenderPeerID != sender.String() {
+		return fmt.Errorf("removal by %s was sent by %s", data.SenderPeerID, sender)
+	}
+	if data.ReceiverPeerID != (*s.appState.Node).ID().String() {
+		return fmt.Errorf("removal addressed to %s", data.ReceiverPeerID)
+	}
+
+	sentAt, err := time.Parse(time.RFC3339, data.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	if age := time.Since(sentAt); age > friendRemovalMaxAge || age < -friendRemovalMaxAge {
+		return fmt.Errorf("removal sent at %s is stale", data.Timestamp)
+	}
+
+	return identity.VerifyPayload(data.SenderPeerID, data, removal.SenderSignature)
+}
@@ -0,0 +1,155 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const friendRequestCancelMaxSize = 4 * 1024
+
+var ErrNoPendingRequest = errors.New("no pending friend request")
+
+// CancelFriendRequest withdraws a friend request we sent. The receiver is told with a
+// signed cancellation naming the request's nonce, so it drops exactly that request. The
+// cancellation is queued in the outbox until the receiver can be reached.
+func (s *Service) CancelFriendRequest(peerId string) error {
+	if _, err := peer.Decode(peerId); err != nil {
+		return fmt.Errorf("invalid target PeerID format: %w", err)
+	}
+
+	if s.appState.State != core.StateRunning || s.appState.Node == nil {
+		return fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, peerId)
+	if err != nil {
+		return err
+	}
+	if relationship.Status != types.FriendStatusSent {
+		return fmt.Errorf("%w: %s", ErrNoPendingRequest, peerId)
+	}
+
+	if err := s.relationshipRepo.Delete(ctx, peerId); err != nil {
+		return fmt.Errorf("failed to delete friend request: %w", err)
+	}
+	if err := s.outboxRepo.Delete(ctx, peerId, types.FriendOutboxRequest); err != nil {
+		log.Printf("Friend request cancel API: Error dropping queued request for %s: %v", peerId, err)
+	}
+
+	s.bus.PublishAsync(events.FriendRequestCancelledEvent{PeerId: peerId, Initiated: true})
+
+	s.queueFriendPayload(peerId, types.FriendOutboxCancel, relationship.RequestNonce)
+
+	return nil
+}
+
+// sendFriendRequestCancellation signs a cancellation of the request with the given nonce
+// with a fresh timestamp and sends it.
+func (s *Service) sendFriendRequestCancellation(ctx context.Context, targetPID peer.ID, nonce string) error {
+	data := types.FriendRequestCancellationData{
+		SenderPeerID:   (*s.appState.Node).ID().String(),
+		ReceiverPeerID: targetPID.String(),
+		Nonce:          nonce,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signature, err := identity.SignPayload(s.appState.PrivKey, data)
+	if err != nil {
+		return err
+	}
+
+	cancellationBytes, err := json.Marshal(types.FriendRequestCancellation{Data: data, SenderSignature: signature})
+	if err != nil {
+		return fmt.Errorf("failed to marshal friend request cancellation: %w", err)
+	}
+
+	return s.writeFriendStream(ctx, targetPID, core.FriendRequestCancelProtocolID, cancellationBytes)
+}
+
+// handleFriendRequestCancelStream drops a pending request when its sender withdraws it.
+func (s *Service) handleFriendRequestCancelStream(stream network.Stream) {
+	remotePeerId := stream.Conn().RemotePeer()
+	defer stream.Close()
+
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, friendRequestCancelMaxSize))
+	if err != nil {
+		log.Printf("Friend Request Cancel Handler: Error reading cancellation from %s: %v", remotePeerId.String(), err)
+		stream.Reset()
+		return
+	}
+
+	var cancellation types.FriendRequestCancellation
+	if err := json.Unmarshal(receivedBytes, &cancellation); err != nil {
+		log.Printf("Friend Request Cancel Handler: Error deserializing cancellation from %s: %v", remotePeerId.String(), err)
+		stream.Reset()
+		return
+	}
+
+	if err := s.validateFriendRequestCancellation(remotePeerId, cancellation); err != nil {
+		log.Printf("Friend Request Cancel Handler: Rejecting cancellation from %s: %v", remotePeerId.String(), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, remotePeerId.String())
+	if err != nil {
+		log.Printf("Friend Request Cancel Handler: No request from %s: %v", remotePeerId.String(), err)
+		return
+	}
+	if relationship.Status != types.FriendStatusPending {
+		log.Printf("Friend Request Cancel Handler: Request from %s is no longer pending", remotePeerId.String())
+		return
+	}
+	// Requests recorded from a poll carry no nonce; any cancellation from the sender ends them.
+	if relationship.RequestNonce != "" && relationship.RequestNonce != cancellation.Data.Nonce {
+		log.Printf("Friend Request Cancel Handler: Cancellation from %s names another request", remotePeerId.String())
+		return
+	}
+
+	if err := s.relationshipRepo.Delete(ctx, remotePeerId.String()); err != nil {
+		log.Printf("Friend Request Cancel Handler: Error deleting request from %s: %v", remotePeerId.String(), err)
+		return
+	}
+
+	s.bus.PublishAsync(events.FriendRequestCancelledEvent{PeerId: remotePeerId.String()})
+
+	log.Printf("Friend Request Cancel Handler: %s withdrew their friend request", remotePeerId.String())
+}
+
+func (s *Service) validateFriendRequestCancellation(sender peer.ID, cancellation types.FriendRequestCancellation) error {
+	data := cancellation.Data
+
+	if data.SenderPeerID != sender.String() {
+		return fmt.Errorf("cancellation by %s was sent by %s", data.SenderPeerID, sender)
+	}
+	if data.ReceiverPeerID != (*s.appState.Node).ID().String() {
+		return fmt.Errorf("cancellation addressed to %s", data.ReceiverPeerID)
+	}
+
+	sentAt, err := time.Parse(time.RFC3339, data.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	if age := time.Since(sentAt); age > friendRequestMaxAge || age < -friendRequestMaxAge {
+		return fmt.Errorf("cancellation sent at %s is stale", data.Timestamp)
+	}
+
+	return identity.VerifyPayload(data.SenderPeerID, data, cancellation.SenderSignature)
+}
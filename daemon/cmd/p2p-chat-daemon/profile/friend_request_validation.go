@@ -0,0 +1,120 @@
+package profile
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	friendRequestMaxSize = 8 * 1024
+	// friendRequestMaxAge bounds how old a signed request may be when it arrives. Nonces are
+	// remembered for at least this long, so a captured request cannot be replayed.
+	friendRequestMaxAge = 10 * time.Minute
+	// friendRequestLifetime is how long a request stays pending before it expires.
+	friendRequestLifetime       = 14 * 24 * time.Hour
+	friendRequestExpiryInterval = 10 * time.Minute
+
+	friendRequestNonceSize        = 16
+	friendRequestMessageMaxLength = 500
+)
+
+func newFriendRequestNonce() (string, error) {
+	nonce := make([]byte, friendRequestNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate friend request nonce: %w", err)
+	}
+	return hex.EncodeToString(nonce), nil
+}
+
+// validateFriendRequest checks that a signed request is meant for us, fresh, unexpired and
+// not a replay. The nonce is only recorded once everything else checks out.
+func (s *Service) validateFriendRequest(sender peer.ID, data types.FriendRequestData) error {
+	if data.SenderPeerID != sender.String() {
+		return fmt.Errorf("request by %s was sent by %s", data.SenderPeerID, sender)
+	}
+	if data.ReceiverPeerID != (*s.appState.Node).ID().String() {
+		return fmt.Errorf("request addressed to %s", data.ReceiverPeerID)
+	}
+
+	sentAt, err := time.Parse(time.RFC3339, data.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	if age := time.Since(sentAt); age > friendRequestMaxAge || age < -friendRequestMaxAge {
+		return fmt.Errorf("request sent at %s is stale", data.Timestamp)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, data.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("invalid expiry: %w", err)
+	}
+	if !expiresAt.After(time.Now()) {
+		return fmt.Errorf("request expired at %s", data.ExpiresAt)
+	}
+	if expiresAt.Sub(sentAt) > friendRequestLifetime {
+		return fmt.Errorf("request expiry %s is too far out", data.ExpiresAt)
+	}
+
+	if nonce, err := hex.DecodeString(data.Nonce); err != nil || len(nonce) != friendRequestNonceSize {
+		return fmt.Errorf("invalid nonce %q", data.Nonce)
+	}
+
+	if len([]rune(data.Message)) > friendRequestMessageMaxLength {
+		return fmt.Errorf("intro message longer than %d characters", friendRequestMessageMaxLength)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	fresh, err := s.relationshipRepo.RememberRequestNonce(ctx, data.SenderPeerID, data.Nonce)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return fmt.Errorf("nonce %s was already used", data.Nonce)
+	}
+
+	return nil
+}
+
+// expireFriendRequests periodically drops expired requests and the nonces that are too old
+// to matter for replay detection.
+func (s *Service) expireFriendRequests() {
+	ticker := time.NewTicker(friendRequestExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sweepFriendRequests()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) sweepFriendRequests() {
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	expired, err := s.relationshipRepo.DeleteExpiredRequests(ctx, now)
+	if err != nil {
+		log.Printf("Friend requests: Error deleting expired requests: %v", err)
+	} else if expired > 0 {
+		log.Printf("Friend requests: Deleted %d expired requests", expired)
+	}
+
+	if err := s.relationshipRepo.PruneRequestNonces(ctx, now.Add(-2*friendRequestMaxAge)); err != nil {
+		log.Printf("Friend requests: Error pruning request nonces: %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/gibson042/canonicaljson-go"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
 	"io"
 	"log"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/connection"
@@ -18,15 +19,26 @@ import (
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"sync"
 	"time"
 )
 
 type Service struct {
 	relationshipRepo  storage.RelationshipRepository
+	outboxRepo        storage.FriendOutboxRepository
+	introductionRepo  storage.FriendIntroductionRepository
+	displayNameRepo   storage.DisplayNameRepository
+	labelRepo         storage.ContactLabelRepository
+	circleRepo        storage.CircleRepository
 	connectionService *connection.Service
 	ctx               context.Context
 	appState          *core.AppState
 	bus               *bus.EventBus
+
+	outboxMu       sync.Mutex
+	outboxInFlight map[string]struct{}
+	outboxSenders  map[string]OutboxSender
+	outboxWake     chan struct{}
 }
 
 func NewProtocolHandler(
@@ -34,6 +46,11 @@ func NewProtocolHandler(
 	bus *bus.EventBus,
 	ctx context.Context,
 	repo storage.RelationshipRepository,
+	outboxRepo storage.FriendOutboxRepository,
+	introductionRepo storage.FriendIntroductionRepository,
+	displayNameRepo storage.DisplayNameRepository,
+	labelRepo storage.ContactLabelRepository,
+	circleRepo storage.CircleRepository,
 	connSvc *connection.Service,
 ) *Service {
 	return &Service{
@@ -41,7 +58,15 @@ func NewProtocolHandler(
 		bus:               bus,
 		ctx:               ctx,
 		relationshipRepo:  repo,
+		outboxRepo:        outboxRepo,
+		introductionRepo:  introductionRepo,
+		displayNameRepo:   displayNameRepo,
+		labelRepo:         labelRepo,
+		circleRepo:        circleRepo,
 		connectionService: connSvc,
+		outboxInFlight:    make(map[string]struct{}),
+		outboxSenders:     make(map[string]OutboxSender),
+		outboxWake:        make(chan struct{}, 1),
 	}
 }
 
@@ -50,8 +75,14 @@ func (s *Service) Register() {
 	(*s.appState.Node).SetStreamHandler(core.FriendRequestProtocolID, s.handleFriendRequestStream)
 	(*s.appState.Node).SetStreamHandler(core.FriendResponseProtocolID, s.handleFriendResponseStream)
 	(*s.appState.Node).SetStreamHandler(core.FriendResponsePollProtocolId, s.handleFriendResponsePollStream)
+	(*s.appState.Node).SetStreamHandler(core.FriendRemovalProtocolID, s.handleFriendRemovalStream)
+	(*s.appState.Node).SetStreamHandler(core.FriendRequestCancelProtocolID, s.handleFriendRequestCancelStream)
+	(*s.appState.Node).SetStreamHandler(core.FriendIntroductionProtocolID, s.handleFriendIntroductionStream)
+
+	s.RegisterOutboxSender(types.FriendOutboxIntroduction, s.sendQueuedIntroduction)
 
-	go s.PollForFriendResponse()
+	go s.runFriendOutbox()
+	go s.expireFriendRequests()
 }
 
 func (s *Service) handleFriendRequestStream(stream network.Stream) {
@@ -59,7 +90,7 @@ func (s *Service) handleFriendRequestStream(stream network.Stream) {
 
 	log.Printf("Friend Request: Received friends request from %s", remotePeerId.String())
 
-	receivedBytes, err := io.ReadAll(stream)
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, friendRequestMaxSize))
 
 	if err != nil {
 		log.Printf("Friend Request Handler: Error reading message content from %s: %v", remotePeerId.String(), err)
@@ -101,6 +132,11 @@ func (s *Service) handleFriendRequestStream(stream network.Stream) {
 
 	log.Printf("Friend Request Handler: Signature verified successfully from %s", remotePeerId.String())
 
+	if err := s.validateFriendRequest(remotePeerId, request.Data); err != nil {
+		log.Printf("Friend Request Handler: Rejecting request from %s: %v", remotePeerId.String(), err)
+		return
+	}
+
 	s.bus.PublishAsync(events.FriendRequestReceived{FriendRequest: request.Data})
 }
 
@@ -109,7 +145,7 @@ func (s *Service) handleFriendResponseStream(stream network.Stream) {
 
 	log.Printf("Friend Response: Received friends response from %s", remotePeerId.String())
 
-	receivedBytes, err := io.ReadAll(stream)
+	receivedBytes, err := io.ReadAll(io.LimitReader(stream, friendRequestMaxSize))
 
 	if err != nil {
 		log.Printf("Friend Response Handler: Error reading message content from %s: %v", remotePeerId.String(), err)
@@ -151,6 +187,23 @@ func (s *Service) handleFriendResponseStream(stream network.Stream) {
 
 	log.Printf("Friend Response Handler: Signature verified successfully from %s", remotePeerId.String())
 
+	if request.Data.ResponderPeerID != remotePeerId.String() {
+		log.Printf("Friend Response Handler: Rejecting response by %s sent by %s", request.Data.ResponderPeerID, remotePeerId.String())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, remotePeerId.String())
+	cancel()
+	if err != nil || relationship.Status != types.FriendStatusSent {
+		log.Printf("Friend Response Handler: Rejecting response from %s, who we are not waiting on", remotePeerId.String())
+		return
+	}
+	if request.Data.Nonce == "" || request.Data.Nonce != relationship.RequestNonce {
+		log.Printf("Friend Response Handler: Rejecting response from %s to another request", remotePeerId.String())
+		return
+	}
+
 	var status types.FriendStatus
 
 	if request.Data.IsApproved {
@@ -163,118 +216,101 @@ func (s *Service) handleFriendResponseStream(stream network.Stream) {
 		SenderPeerId: request.Data.ResponderPeerID,
 		Status:       status,
 		Timestamp:    request.Data.Timestamp,
+		Nonce:        request.Data.Nonce,
 	})
 }
 
-func (s *Service) SendFriendRequest(receiverPeerId string) error {
+// SendFriendRequest queues a signed friend request, optionally carrying a short intro
+// message. The request expires if it is not answered within friendRequestLifetime.
+func (s *Service) SendFriendRequest(receiverPeerId string, introMessage string) error {
 	targetPID, err := peer.Decode(receiverPeerId)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Invalid target PeerID format: %v", err))
 	}
 
-	log.Printf("%v", s.appState.State)
-	log.Printf("%v", s.appState.Node)
 	if s.appState.State != core.StateRunning || s.appState.Node == nil {
-		return errors.New(fmt.Sprintf(fmt.Sprintf("Node is not ready (state: %s)", s.appState.State)))
+		return fmt.Errorf("Node is not ready (state: %s)", s.appState.State)
 	}
 
 	if targetPID == (*s.appState.Node).ID() {
 		return errors.New(fmt.Sprintf("Cannot send friends request to self"))
 	}
 
-	data := types.FriendRequestData{
-		SenderPeerID: (*s.appState.Node).ID().String(),
-		Timestamp:    time.Now().String(),
+	if len([]rune(introMessage)) > friendRequestMessageMaxLength {
+		return fmt.Errorf("intro message longer than %d characters", friendRequestMessageMaxLength)
 	}
 
-	bytesToSign, err := canonicaljson.Marshal(data)
+	nonce, err := newFriendRequestNonce()
 	if err != nil {
-		return fmt.Errorf("failed to marshal canonical json for signing: %w", err)
+		return err
 	}
 
-	senderSignature, err := s.appState.PrivKey.Sign(bytesToSign)
+	now := time.Now().UTC()
 
-	request := types.FriendRequest{
-		Data:            data,
-		SenderSignature: senderSignature,
-	}
-
-	requestBytes, err := json.Marshal(request)
-
-	s.bus.PublishAsync(events.FriendRequestSentEvent{ReceiverPeerId: receiverPeerId, Timestamp: time.Now()})
-
-	go s.SendFriendRequestAsync(receiverPeerId, targetPID, err, requestBytes)
+	s.bus.PublishAsync(events.FriendRequestSentEvent{
+		ReceiverPeerId: receiverPeerId,
+		Timestamp:      now,
+		ExpiresAt:      now.Add(friendRequestLifetime),
+		Nonce:          nonce,
+		IntroMessage:   introMessage,
+	})
 
 	return nil
 }
 
-func (s *Service) SendFriendRequestAsync(receiverPeerId string, targetPID peer.ID, err error, requestBytes []byte) error {
-	log.Printf("Friend request API: Checking connectedness to %s", targetPID.ShortString())
-	connectedness := (*s.appState.Node).Network().Connectedness(targetPID)
-
-	if connectedness != network.Connected {
-		log.Printf("Friend request API: Not connected to %s (State: %s). Attempting connection...", targetPID.ShortString(), connectedness)
-
-		addrInfo := (*s.appState.Node).Peerstore().PeerInfo(targetPID)
-		if len(addrInfo.Addrs) == 0 {
-			log.Printf("Friend request API: No addresses found in Peerstore for %s. Cannot connect.", targetPID.ShortString())
-			return nil
-		}
-
-		connectCtx, connectCancel := context.WithTimeout(context.Background(), 60*time.Second)
-		defer connectCancel()
-
-		err = (*s.appState.Node).Connect(connectCtx, addrInfo)
+// sendFriendRequest signs the request stored for a peer with a fresh timestamp and sends it.
+// The nonce stays the same across attempts, so a receiver that got an earlier attempt treats
+// the retry as a replay rather than a second request.
+func (s *Service) sendFriendRequest(ctx context.Context, targetPID peer.ID, relationship types.FriendRelationship) error {
+	if relationship.RequestNonce == "" {
+		nonce, err := newFriendRequestNonce()
 		if err != nil {
-			log.Printf("Friend request API: Failed to connect to %s: %v", targetPID.ShortString(), err)
-			return nil
+			return err
 		}
-		log.Printf("Friend request API: Successfully connected to %s.", targetPID.ShortString())
-	} else {
-		log.Printf("Friend request API: Already connected to %s.", targetPID.ShortString())
+		if err := s.relationshipRepo.SetRequestNonce(ctx, targetPID.String(), nonce); err != nil {
+			return err
+		}
+		relationship.RequestNonce = nonce
 	}
 
-	log.Printf("Friend request API: Attempting to open stream to %s for protocol %s", targetPID.ShortString(), core.FriendRequestProtocolID)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	ctx = network.WithAllowLimitedConn(ctx, "mito")
-	defer cancel()
+	data := types.FriendRequestData{
+		SenderPeerID:   (*s.appState.Node).ID().String(),
+		ReceiverPeerID: targetPID.String(),
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+		ExpiresAt:      relationship.ExpiresAt.UTC().Format(time.RFC3339),
+		Nonce:          relationship.RequestNonce,
+		Message:        relationship.IntroMessage,
+	}
 
-	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.FriendRequestProtocolID)
+	bytesToSign, err := canonicaljson.Marshal(data)
 	if err != nil {
-		log.Printf("Friend request API: Failed to open stream to %s: %v", targetPID.ShortString(), err)
-		return nil
+		return fmt.Errorf("failed to marshal canonical json for signing: %w", err)
 	}
-	log.Printf("Friend request API: Stream opened successfully to %s", targetPID.ShortString())
 
-	writer := bufio.NewWriter(stream)
-	_, err = writer.Write(requestBytes)
-
-	if err == nil {
-		err = writer.Flush()
-		stream.CloseWrite()
+	senderSignature, err := s.appState.PrivKey.Sign(bytesToSign)
+	if err != nil {
+		return fmt.Errorf("failed to sign friends request: %w", err)
 	}
 
+	requestBytes, err := json.Marshal(types.FriendRequest{
+		Data:            data,
+		SenderSignature: senderSignature,
+	})
 	if err != nil {
-		log.Printf("Error writing/closing friends request stream to %s: %v", receiverPeerId, err)
-		stream.Reset()
-		return nil
+		return fmt.Errorf("failed to marshal friends request: %w", err)
 	}
 
-	log.Printf("Friend request sent successfully to %s", receiverPeerId)
-	return nil
+	return s.writeFriendStream(ctx, targetPID, core.FriendRequestProtocolID, requestBytes)
 }
 
-func (s *Service) SendFriendResponse(receiverPeerId string, isApproved bool) error {
-	targetPID, err := peer.Decode(receiverPeerId)
-	if err != nil {
-		return errors.New(fmt.Sprintf("Invalid target PeerID format: %v", err))
-	}
-
+// sendFriendResponse sends our answer to the request stored for a peer, naming the request
+// by its nonce so the answer cannot be replayed against a later request.
+func (s *Service) sendFriendResponse(ctx context.Context, targetPID peer.ID, relationship types.FriendRelationship) error {
 	data := types.FriendResponseData{
 		ResponderPeerID: (*s.appState.Node).ID().String(),
-		IsApproved:      isApproved,
-		Timestamp:       time.Now().String(),
+		IsApproved:      relationship.Status == types.FriendStatusApproved,
+		Timestamp:       time.Now().UTC().Format(time.RFC3339),
+		Nonce:           relationship.RequestNonce,
 	}
 
 	bytesToSign, err := canonicaljson.Marshal(data)
@@ -283,68 +319,61 @@ func (s *Service) SendFriendResponse(receiverPeerId string, isApproved bool) err
 	}
 
 	senderSignature, err := s.appState.PrivKey.Sign(bytesToSign)
+	if err != nil {
+		return fmt.Errorf("failed to sign friends response: %w", err)
+	}
 
-	request := types.FriendResponse{
+	responseBytes, err := json.Marshal(types.FriendResponse{
 		Data:            data,
 		SenderSignature: senderSignature,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal friends response: %w", err)
 	}
 
-	requestBytes, err := json.Marshal(request)
+	return s.writeFriendStream(ctx, targetPID, core.FriendResponseProtocolID, responseBytes)
+}
 
-	log.Printf("Friend response API: Checking connectedness to %s", targetPID.ShortString())
+// writeFriendStream connects to a peer if needed and writes a single message on a new stream.
+func (s *Service) writeFriendStream(ctx context.Context, targetPID peer.ID, protocolID protocol.ID, payload []byte) error {
 	connectedness := (*s.appState.Node).Network().Connectedness(targetPID)
 
 	if connectedness != network.Connected {
-		log.Printf("Friend response API: Not connected to %s (State: %s). Attempting connection...", targetPID.ShortString(), connectedness)
-
 		addrInfo := (*s.appState.Node).Peerstore().PeerInfo(targetPID)
 		if len(addrInfo.Addrs) == 0 {
-			log.Printf("Friend response API: No addresses found in Peerstore for %s. Cannot connect.", targetPID.ShortString())
-			return errors.New(fmt.Sprintf("Cannot connect to peer %s: No known addresses", targetPID.ShortString()))
+			return fmt.Errorf("cannot connect to peer %s: no known addresses", targetPID.ShortString())
 		}
 
-		connectCtx, connectCancel := context.WithTimeout(context.Background(), 60*time.Second)
+		connectCtx, connectCancel := context.WithTimeout(ctx, 60*time.Second)
 		defer connectCancel()
 
-		err = (*s.appState.Node).Connect(connectCtx, addrInfo)
-		if err != nil {
-			log.Printf("Friend response API: Failed to connect to %s: %v", targetPID.ShortString(), err)
-			return errors.New(fmt.Sprintf("Failed to establish connection with peer %s: %v", targetPID.ShortString(), err))
+		if err := (*s.appState.Node).Connect(connectCtx, addrInfo); err != nil {
+			return fmt.Errorf("failed to establish connection with peer %s: %w", targetPID.ShortString(), err)
 		}
-		log.Printf("Friend response API: Successfully connected to %s.", targetPID.ShortString())
-	} else {
-		log.Printf("Friend response API: Already connected to %s.", targetPID.ShortString())
 	}
 
-	log.Printf("Friend response API: Attempting to open stream to %s for protocol %s", targetPID.ShortString(), core.FriendRequestProtocolID)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	ctx = network.WithAllowLimitedConn(ctx, "mito")
+	streamCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	streamCtx = network.WithAllowLimitedConn(streamCtx, "mito")
 	defer cancel()
 
-	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.FriendResponseProtocolID)
+	stream, err := (*s.appState.Node).NewStream(streamCtx, targetPID, protocolID)
 	if err != nil {
-		log.Printf("Friend response API: Failed to open stream to %s: %v", targetPID.ShortString(), err)
-		return errors.New(fmt.Sprintf("Failed to connect/open stream to peer %s: %v", targetPID.ShortString(), err))
+		return fmt.Errorf("failed to open stream to peer %s: %w", targetPID.ShortString(), err)
 	}
-	log.Printf("Friend response API: Stream opened successfully to %s", targetPID.ShortString())
 
 	writer := bufio.NewWriter(stream)
-	_, err = writer.Write(requestBytes)
+	_, err = writer.Write(payload)
 
 	if err == nil {
 		err = writer.Flush()
-		stream.CloseWrite()
 	}
 
 	if err != nil {
-		log.Printf("Error writing/closing friends response stream to %s: %v", receiverPeerId, err)
 		stream.Reset()
-		return fmt.Errorf("failed to send/close friends response: %w", err)
+		return fmt.Errorf("failed to write %s stream: %w", protocolID, err)
 	}
 
-	log.Printf("Friend response sent successfully to %s", receiverPeerId)
-	return nil
+	return stream.CloseWrite()
 }
 
 func (s *Service) RespondToFriendRequest(receiverPeerId string, isAccepted bool) error {
@@ -395,6 +424,10 @@ func (s *Service) GetFriends() ([]types.FriendRelationship, error) {
 		return nil, err
 	}
 
+	if err := s.labelFriends(s.ctx, r); err != nil {
+		log.Printf("Error labelling friends: %v", err)
+	}
+
 	return r, nil
 }
 
@@ -409,7 +442,8 @@ func (s *Service) GetFriendRequests() ([]types.FriendRelationship, error) {
 	return r, nil
 }
 
-// handleFriendResponsePollStream processes incoming friend response poll requests
+// handleFriendResponsePollStream answers peers on older versions that still poll for the
+// outcome of their friend requests.
 func (s *Service) handleFriendResponsePollStream(stream network.Stream) {
 	peerID := stream.Conn().RemotePeer()
 	log.Printf("FriendResponsePoll: Received new stream from %s", peerID.ShortString())
@@ -420,15 +454,10 @@ func (s *Service) handleFriendResponsePollStream(stream network.Stream) {
 	relationship, err := s.relationshipRepo.GetRelationByPeerId(ctx, peerID.String())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			s.bus.PublishAsync(events.FriendRequestReceived{
-				FriendRequest: types.FriendRequestData{
-					SenderPeerID: peerID.String(),
-					Timestamp:    time.Now().String(),
-				},
-			})
-
+			// A poll is not a request: only a signed request on the friend request protocol
+			// makes a peer pending.
 			relationship = types.FriendRelationship{
-				Status: types.FriendStatusPending,
+				Status: types.FriendStatusNone,
 			}
 		} else {
 			log.Printf("FriendResponsePoll Handler: Error fetching relationship for %s: %v", peerID.String(), err)
@@ -464,114 +493,3 @@ func (s *Service) handleFriendResponsePollStream(stream network.Stream) {
 
 	stream.Close()
 }
-
-func (s *Service) PollForFriendResponse() {
-	for {
-		log.Printf("=============== Polling =================")
-		pendingFriendRequests, err := s.GetFriendRequests()
-
-		log.Printf("Pending friend requests: %v", pendingFriendRequests)
-
-		if err != nil {
-			log.Printf("Error polling for friend requests: %v", err)
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		for _, request := range pendingFriendRequests {
-			if request.Status != types.FriendStatusSent {
-				continue
-			}
-
-			log.Printf("Asking %s", request.PeerID)
-			err := s.AskForFriendRequestResponse(request.PeerID)
-
-			if err != nil {
-				log.Printf("Error polling for friend requests: %v", err)
-				continue
-			}
-		}
-
-		time.Sleep(10 * time.Second)
-	}
-}
-
-func (s *Service) AskForFriendRequestResponse(peerId string) error {
-	targetPID, err := peer.Decode(peerId)
-	if err != nil {
-		log.Printf("Invalid target PeerID format: %v", err)
-		return fmt.Errorf("invalid peer ID format: %w", err)
-	}
-
-	connectedness := (*s.appState.Node).Network().Connectedness(targetPID)
-	if connectedness != network.Connected {
-		log.Printf("Not connected to %s (State: %s). Attempting connection...", targetPID.ShortString(), connectedness)
-
-		addrInfo := (*s.appState.Node).Peerstore().PeerInfo(targetPID)
-		if len(addrInfo.Addrs) == 0 {
-			log.Printf("No addresses found in Peerstore for %s. Cannot connect.", targetPID.ShortString())
-			return fmt.Errorf("cannot connect to peer %s: no known addresses", targetPID.ShortString())
-		}
-
-		connectCtx, connectCancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer connectCancel()
-
-		err = (*s.appState.Node).Connect(connectCtx, addrInfo)
-		if err != nil {
-			log.Printf("Failed to connect to %s: %v", targetPID.ShortString(), err)
-			return fmt.Errorf("failed to establish connection with peer %s: %w", targetPID.ShortString(), err)
-		}
-		log.Printf("Successfully connected to %s", targetPID.ShortString())
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	log.Printf("AskForFriendRequestResponse: Opening stream to %s for protocol %s", targetPID.ShortString(), core.FriendResponsePollProtocolId)
-	stream, err := (*s.appState.Node).NewStream(ctx, targetPID, core.FriendResponsePollProtocolId)
-	if err != nil {
-		log.Printf("Failed to open stream to %s: %v", targetPID.ShortString(), err)
-		return fmt.Errorf("failed to open stream to peer %s: %w", targetPID.ShortString(), err)
-	}
-	defer stream.Close()
-
-	myPeerID := (*s.appState.Node).ID().String()
-	_, err = stream.Write([]byte(myPeerID))
-	if err != nil {
-		log.Printf("AskForFriendRequestResponse: Failed to write to stream: %v", err)
-		stream.Reset()
-		return fmt.Errorf("AskForFriendRequestResponse :failed to write to stream: %w", err)
-	}
-
-	reader := bufio.NewReader(stream)
-	responseBytes, err := io.ReadAll(reader)
-	if err != nil {
-		log.Printf("AskForFriendRequestResponse: Failed to read from stream: %v", err)
-		stream.Reset()
-		return fmt.Errorf("AskForFriendRequestResponse :failed to read from stream: %w", err)
-	}
-
-	if len(responseBytes) == 0 {
-		return nil
-	}
-
-	var relationship types.FriendRelationship
-	err = json.Unmarshal(responseBytes, &relationship)
-	if err != nil {
-		log.Printf("AskForFriendRequestResponse: Failed to unmarshal response: %v", err)
-		return fmt.Errorf("AskForFriendRequestResponse: failed to parse response: %w", err)
-	}
-
-	if relationship.Status == types.FriendStatusPending {
-		log.Printf("AskForFriendRequestResponse: Received friend request response with pending status from %s", targetPID.ShortString())
-		return nil
-	}
-
-	s.bus.PublishAsync(events.FriendResponseReceivedEvent{
-		SenderPeerId: targetPID.String(),
-		Status:       relationship.Status,
-		Timestamp:    relationship.ApprovedAt.String(),
-	})
-	log.Printf("AskForFriendRequestResponse: Received friend request response from %s: %s", targetPID.ShortString(), relationship.Status)
-	return nil
-}
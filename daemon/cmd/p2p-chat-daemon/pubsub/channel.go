@@ -0,0 +1,205 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"time"
+
+	"github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	channelMessageMaxSize = 64 * 1024
+
+	// Subscribers may only react, so they get a tighter budget than group members.
+	channelSenderRateLimit  = 20
+	channelSenderRateWindow = 10 * time.Second
+)
+
+// JoinChannelTopic subscribes to a broadcast channel. Its validator only lets posts from
+// the channel's authors, descriptor updates from its owner and reactions from anyone through.
+func (s *Service) JoinChannelTopic(channelId string) error {
+	topicName := core.ChannelTopic + channelId
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limiter := newSenderRateLimiter(channelSenderRateLimit, channelSenderRateWindow)
+
+	validator := func(ctx context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+		return s.validateChannelMessage(ctx, channelId, limiter, msg)
+	}
+
+	if err := s.pubsub.RegisterTopicValidator(topicName, validator); err != nil {
+		return fmt.Errorf("failed to register validator for topic %s: %w", topicName, err)
+	}
+
+	topic, err := s.pubsub.Join(topicName, pubsub.WithTopicMessageIdFn(channelMessageId))
+	if err != nil {
+		s.pubsub.UnregisterTopicValidator(topicName)
+		return fmt.Errorf("failed to join channel topic: %w", err)
+	}
+	s.topics[topicName] = topic
+	s.setTopicScore(topic, s.cfg.ChannelTopicWeight)
+
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to channel topic: %w", err)
+	}
+	s.subs[topicName] = sub
+
+	go s.handleChannelMessages(sub, channelId)
+
+	return nil
+}
+
+func (s *Service) validateChannelMessage(ctx context.Context, channelId string, limiter *senderRateLimiter, msg *pubsub.Message) pubsub.ValidationResult {
+	sender := msg.GetFrom()
+
+	if sender == (*s.appState.Node).ID() {
+		return pubsub.ValidationAccept
+	}
+
+	if len(msg.Data) > channelMessageMaxSize {
+		log.Printf("Validator: Rejecting %d byte message from %s on channel %s", len(msg.Data), sender.ShortString(), channelId)
+		return pubsub.ValidationReject
+	}
+
+	// Content is signed by its publisher, as checked below, so this covers relayed posts too.
+	if s.gater.IsBlocked(sender) {
+		return pubsub.ValidationIgnore
+	}
+
+	if !limiter.allow(sender) {
+		log.Printf("Validator: Ignoring message from %s on channel %s, rate limit exceeded", sender.ShortString(), channelId)
+		return pubsub.ValidationIgnore
+	}
+
+	var envelope types.ChannelEnvelope
+	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+		log.Printf("Validator: Rejecting malformed message from %s on channel %s: %v", sender.ShortString(), channelId, err)
+		return pubsub.ValidationReject
+	}
+
+	signer, err := VerifyChannelContent(channelId, envelope)
+	if err != nil {
+		log.Printf("Validator: Rejecting message from %s on channel %s: %v", sender.ShortString(), channelId, err)
+		return pubsub.ValidationReject
+	}
+
+	if signer != sender.String() {
+		log.Printf("Validator: Rejecting content signed by %s but published by %s on channel %s", signer, sender.ShortString(), channelId)
+		return pubsub.ValidationReject
+	}
+
+	channel, err := s.channelRepo.GetChannel(ctx, channelId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pubsub.ValidationIgnore
+		}
+		log.Printf("Validator: Error loading channel %s: %v", channelId, err)
+		return pubsub.ValidationIgnore
+	}
+
+	switch envelope.ContentType {
+	case types.ChannelContentDescriptor:
+		if signer != channel.OwnerPeerId {
+			log.Printf("Validator: Rejecting descriptor of channel %s from non-owner %s", channelId, sender.ShortString())
+			return pubsub.ValidationReject
+		}
+
+	case types.ChannelContentPost:
+		isAuthor, err := s.channelRepo.IsAuthor(ctx, channelId, signer)
+		if err != nil {
+			return pubsub.ValidationIgnore
+		}
+		if !isAuthor {
+			log.Printf("Validator: Rejecting post on channel %s from non-author %s", channelId, sender.ShortString())
+			return pubsub.ValidationReject
+		}
+	}
+
+	return pubsub.ValidationAccept
+}
+
+// VerifyChannelContent checks that content belongs to the channel and carries a valid
+// signature, and returns the peer that signed it. Whether that peer may publish the
+// content is left to the caller.
+func VerifyChannelContent(channelId string, envelope types.ChannelEnvelope) (string, error) {
+	switch envelope.ContentType {
+	case types.ChannelContentDescriptor:
+		var descriptor types.SignedChannelDescriptor
+		if err := json.Unmarshal(envelope.Payload, &descriptor); err != nil {
+			return "", fmt.Errorf("malformed descriptor: %w", err)
+		}
+		if descriptor.Data.ChannelId != channelId {
+			return "", fmt.Errorf("descriptor of channel %s", descriptor.Data.ChannelId)
+		}
+		if err := identity.VerifyPayload(descriptor.Data.OwnerPeerId, descriptor.Data, descriptor.Signature); err != nil {
+			return "", err
+		}
+		return descriptor.Data.OwnerPeerId, nil
+
+	case types.ChannelContentPost:
+		var post types.SignedChannelPost
+		if err := json.Unmarshal(envelope.Payload, &post); err != nil {
+			return "", fmt.Errorf("malformed post: %w", err)
+		}
+		if post.Data.ChannelId != channelId {
+			return "", fmt.Errorf("post %s addressed to channel %s", post.Data.Id, post.Data.ChannelId)
+		}
+		if err := identity.VerifyPayload(post.Data.AuthorPeerId, post.Data, post.Signature); err != nil {
+			return "", err
+		}
+		return post.Data.AuthorPeerId, nil
+
+	case types.ChannelContentReaction:
+		var reaction types.SignedChannelReaction
+		if err := json.Unmarshal(envelope.Payload, &reaction); err != nil {
+			return "", fmt.Errorf("malformed reaction: %w", err)
+		}
+		if reaction.Data.ChannelId != channelId {
+			return "", fmt.Errorf("reaction addressed to channel %s", reaction.Data.ChannelId)
+		}
+		if err := identity.VerifyPayload(reaction.Data.ReactorPeerId, reaction.Data, reaction.Signature); err != nil {
+			return "", err
+		}
+		return reaction.Data.ReactorPeerId, nil
+	}
+
+	return "", fmt.Errorf("unknown content type %q", envelope.ContentType)
+}
+
+func (s *Service) handleChannelMessages(sub *pubsub.Subscription, channelId string) {
+	for {
+		msg, err := sub.Next(s.ctx)
+		if err != nil {
+			log.Printf("Error receiving channel message: %v", err)
+			return
+		}
+
+		if msg.ReceivedFrom == (*s.appState.Node).ID() {
+			continue
+		}
+
+		var envelope types.ChannelEnvelope
+		if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+			continue
+		}
+
+		s.eventBus.PublishAsync(events.ChannelContentReceivedEvent{
+			ChannelId:   channelId,
+			ContentType: envelope.ContentType,
+			Payload:     envelope.Payload,
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// groupContentSeenTTL is how long the ID of dispatched group content is remembered. Gossip
+// and direct copies of the same content arrive well within it.
+const groupContentSeenTTL = 10 * time.Minute
+
+var ErrGroupContentRejected = errors.New("group content rejected")
+
+// seenCache remembers IDs for a while, so content delivered twice is handled once.
+type seenCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]time.Time
+}
+
+func newSeenCache(ttl time.Duration) *seenCache {
+	return &seenCache{ttl: ttl, entries: make(map[string]time.Time)}
+}
+
+// markSeen records id and reports whether it was new.
+func (c *seenCache) markSeen(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if seenAt, ok := c.entries[id]; ok && now.Sub(seenAt) < c.ttl {
+		return false
+	}
+	c.entries[id] = now
+
+	if len(c.entries) > 1024 {
+		for key, seenAt := range c.entries {
+			if now.Sub(seenAt) >= c.ttl {
+				delete(c.entries, key)
+			}
+		}
+	}
+
+	return true
+}
+
+// groupContentId identifies content independently of the path it came by: chat messages
+// by their message ID, everything else by a hash of the plaintext.
+func groupContentId(groupId string, content *validatedGroupContent) string {
+	if content.Message != nil {
+		return groupId + "/" + content.Message.Data.Id
+	}
+	hash := sha256.Sum256(content.Plaintext)
+	return groupId + "/" + content.ContentType + "/" + hex.EncodeToString(hash[:])
+}
+
+// TopicPeers returns the peers we know to be subscribed to a topic.
+func (s *Service) TopicPeers(topicName string) []peer.ID {
+	s.mu.RLock()
+	topic, ok := s.topics[topicName]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return topic.ListPeers()
+}
+
+// DeliverGroupContent accepts group content that a member sent us over a direct stream
+// instead of the topic. It is validated exactly like gossip and dispatched unless the
+// gossip copy already was.
+func (s *Service) DeliverGroupContent(groupId string, sender peer.ID, data []byte) error {
+	ctx, cancel := context.WithTimeout(s.ctx, groupValidatorTimeout)
+	defer cancel()
+
+	content, result := s.checkGroupContent(ctx, groupId, sender, data, s.directLimiter)
+	if result != pubsub.ValidationAccept {
+		return fmt.Errorf("%w from %s on group %s", ErrGroupContentRejected, sender.ShortString(), groupId)
+	}
+
+	s.dispatchGroupContent(groupId, sender, content)
+	return nil
+}
@@ -3,15 +3,20 @@ package pubsub
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/config"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
+	"sync"
 	"time"
 
 	"github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
 )
 
@@ -32,11 +37,23 @@ type OnlineAnnouncement struct {
 type Service struct {
 	ctx                  context.Context
 	appState             *core.AppState
+	cfg                  *config.PubSubConfig
 	pubsub               *pubsub.PubSub
+	mu                   sync.RWMutex // guards topics and subs
 	topics               map[string]*pubsub.Topic
 	subs                 map[string]*pubsub.Subscription
 	groupKeyStoreService *identity.GroupKeyStore
+	groupMemberRepo      storage.GroupMemberRepository
+	channelRepo          storage.ChannelRepository
 	eventBus             *bus.EventBus
+	seenGroupContent     *seenCache
+	directLimiter        *senderRateLimiter
+	gater                Gater
+}
+
+// Gater tells which peers are blocked.
+type Gater interface {
+	IsBlocked(id peer.ID) bool
 }
 
 // NewPubSubService creates a new pubsub service
@@ -44,27 +61,40 @@ func NewPubSubService(
 	bus *bus.EventBus,
 	ctx context.Context,
 	appState *core.AppState,
+	cfg *config.PubSubConfig,
 	groupKeyStoreService *identity.GroupKeyStore,
+	groupMemberRepo storage.GroupMemberRepository,
+	channelRepo storage.ChannelRepository,
+	gater Gater,
 ) (*Service, error) {
 	return &Service{
 		ctx:                  ctx,
 		appState:             appState,
+		cfg:                  cfg,
 		topics:               make(map[string]*pubsub.Topic),
 		subs:                 make(map[string]*pubsub.Subscription),
 		groupKeyStoreService: groupKeyStoreService,
+		groupMemberRepo:      groupMemberRepo,
+		channelRepo:          channelRepo,
 		eventBus:             bus,
+		seenGroupContent:     newSeenCache(groupContentSeenTTL),
+		directLimiter:        newSenderRateLimiter(groupSenderRateLimit, groupSenderRateWindow),
+		gater:                gater,
 	}, nil
 }
 
 // Start initializes the pubsub topics and subscriptions
 func (s *Service) Start() error {
-	pubsubService, err := pubsub.NewGossipSub(s.ctx, *s.appState.Node)
+	pubsubService, err := pubsub.NewGossipSub(s.ctx, *s.appState.Node, s.routerOptions()...)
 	if err != nil {
 		return fmt.Errorf("failed to create pubsub service: %w", err)
 	}
 
 	s.pubsub = pubsubService
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	onlineTopic, err := s.pubsub.Join(OnlineAnnouncementTopic)
 	if err != nil {
 		return fmt.Errorf("failed to join online announcement topic: %w", err)
@@ -84,24 +114,61 @@ func (s *Service) Start() error {
 }
 
 func (s *Service) JoinTopic(topicName string, groupId string) error {
-	topic, err := s.pubsub.Join(topicName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.registerGroupValidator(topicName, groupId); err != nil {
+		return err
+	}
+
+	topic, err := s.pubsub.Join(topicName, pubsub.WithTopicMessageIdFn(groupMessageId))
 	if err != nil {
+		s.pubsub.UnregisterTopicValidator(topicName)
 		return fmt.Errorf("failed to join online announcement topicName: %w", err)
 	}
 
 	s.topics[topicName] = topic
+	s.setTopicScore(topic, s.cfg.GroupTopicWeight)
 
 	sub, err := topic.Subscribe()
 	if err != nil {
 		log.Printf("Error subscribing to online announcement topicName: %v", err)
 		return fmt.Errorf("failed to subscribe to online announcement topicName: %w", err)
 	}
+	s.subs[topicName] = sub
 
 	go s.handleIncomingMessages(sub, groupId)
 
 	return nil
 }
 
+// LeaveTopic stops listening to a topic and leaves it
+func (s *Service) LeaveTopic(topicName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub, ok := s.subs[topicName]; ok {
+		sub.Cancel()
+		delete(s.subs, topicName)
+	}
+
+	if err := s.pubsub.UnregisterTopicValidator(topicName); err != nil {
+		log.Printf("Error unregistering validator for topic %s: %v", topicName, err)
+	}
+
+	topic, ok := s.topics[topicName]
+	if !ok {
+		return nil
+	}
+	delete(s.topics, topicName)
+
+	if err := topic.Close(); err != nil {
+		return fmt.Errorf("failed to leave topic: %w", err)
+	}
+
+	return nil
+}
+
 func (s *Service) handleIncomingMessages(sub *pubsub.Subscription, groupId string) {
 	for {
 		msg, err := sub.Next(s.ctx)
@@ -115,52 +182,112 @@ func (s *Service) handleIncomingMessages(sub *pubsub.Subscription, groupId strin
 			continue
 		}
 
-		bytes, _ := s.groupKeyStoreService.Decrypt(groupId, msg.Data)
-
-		var message types.GroupChatMessage
-		if err := json.Unmarshal(bytes, &message); err != nil {
-			log.Printf("Error unmarshalling message: %v", err)
+		// Group messages reach us only after the topic validator decrypted and checked them.
+		content, ok := msg.ValidatorData.(*validatedGroupContent)
+		if !ok {
 			continue
 		}
 
-		sender := msg.GetFrom()
+		s.dispatchGroupContent(groupId, msg.GetFrom(), content)
+	}
+}
 
-		if sender.String() != message.SenderPeerId {
-			log.Printf("Matyvilebs vigac: %s != %s", message.SenderPeerId, sender.String())
-			continue
-		}
+// dispatchGroupContent hands validated group content to its consumers. Content that
+// already arrived by the other delivery path is dropped.
+func (s *Service) dispatchGroupContent(groupId string, sender peer.ID, content *validatedGroupContent) {
+	if !s.seenGroupContent.markSeen(groupContentId(groupId, content)) {
+		return
+	}
 
-		log.Printf("📢📢📢📢📢 MOVIDA: message - %s, dro - %s) 📢📢📢📢📢", message.Message, message.Time)
+	switch content.ContentType {
+	case types.GroupContentCommit:
+		s.handleCommit(groupId, content.Plaintext)
+		return
 
-		mes := events.GroupChatMessage{
+	case types.GroupContentMetadata:
+		s.eventBus.PublishAsync(events.GroupMetadataReceivedEvent{
 			GroupId:      groupId,
-			Message:      message.Message,
 			SenderPeerId: sender.String(),
-			Time:         time.Now(),
-		}
-		s.eventBus.PublishAsync(events.GroupChatMessageReceivedEvent{Message: mes})
+			Update:       content.Plaintext,
+		})
+		return
+
+	case types.GroupContentRevocation:
+		s.eventBus.PublishAsync(events.GroupInviteRevocationReceivedEvent{
+			GroupId:      groupId,
+			SenderPeerId: sender.String(),
+			Revocation:   content.Plaintext,
+		})
+		return
+
+	case types.GroupContentFile:
+		s.eventBus.PublishAsync(events.GroupFileAnnouncedEvent{
+			GroupId:      groupId,
+			SenderPeerId: sender.String(),
+			Announcement: content.Plaintext,
+		})
+		return
+	}
+
+	message := content.Message.Data
+
+	hash, err := identity.HashPayload(message)
+	if err != nil {
+		log.Printf("Error hashing group message %s: %v", message.Id, err)
+		return
+	}
+
+	log.Printf("📢📢📢📢📢 MOVIDA: message - %s, dro - %s) 📢📢📢📢📢", message.Message, message.Time)
+
+	mes := events.GroupChatMessage{
+		GroupId:      groupId,
+		MessageId:    message.Id,
+		Hash:         hash,
+		Parents:      message.Parents,
+		Message:      message.Message,
+		SenderPeerId: sender.String(),
+		Time:         message.Time,
+		Envelope:     content.Plaintext,
 	}
+	s.eventBus.PublishAsync(events.GroupChatMessageReceivedEvent{Message: mes})
 }
 
-// Publish publishes to topic
-func (s *Service) Publish(message []byte, topicName string) error {
-	for {
-		dht := s.appState.Dht
-		peerCount := len(dht.RoutingTable().ListPeers())
+// handleCommit applies a key commit published on a group topic
+func (s *Service) handleCommit(groupId string, commitBytes []byte) {
+	var commit types.GroupCommit
+	if err := json.Unmarshal(commitBytes, &commit); err != nil {
+		log.Printf("Error unmarshalling commit: %v", err)
+		return
+	}
 
-		_, ok := s.topics[topicName]
-		if !ok {
-			log.Printf("online announcement topicName not joined")
-			continue
+	change, err := s.groupKeyStoreService.ApplyCommit(groupId, commit)
+	if err != nil {
+		if errors.Is(err, identity.ErrGroupEpochAhead) {
+			s.eventBus.PublishAsync(events.GroupEpochBehindEvent{GroupId: groupId})
 		}
+		log.Printf("Error applying commit for group %s: %v", groupId, err)
+		return
+	}
 
-		if peerCount > 1 {
-			break
-		}
-		time.Sleep(1 * time.Second)
+	if change == nil {
+		return
 	}
 
+	s.eventBus.PublishAsync(events.GroupEpochChangedEvent{
+		GroupId:     change.GroupId,
+		Epoch:       change.Epoch,
+		Added:       change.Added,
+		Removed:     change.Removed,
+		RemovedSelf: change.RemovedSelf,
+	})
+}
+
+// Publish publishes to topic. Peers that are not in the topic mesh yet do not get the
+// message; see TopicPeers.
+func (s *Service) Publish(message []byte, topicName string) error {
+	s.mu.RLock()
 	topic, ok := s.topics[topicName]
+	s.mu.RUnlock()
 	if !ok {
 		return fmt.Errorf("topicName not joined")
 	}
@@ -177,6 +304,9 @@ func (s *Service) Publish(message []byte, topicName string) error {
 
 // Stop cleans up pubsub resources
 func (s *Service) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	for name, sub := range s.subs {
 		sub.Cancel()
 		delete(s.subs, name)
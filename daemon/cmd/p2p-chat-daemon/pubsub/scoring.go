@@ -0,0 +1,126 @@
+package pubsub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+
+	"github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// Peers need a record of useful deliveries before we accept their peer exchange or
+	// graft them opportunistically.
+	acceptPXThreshold           = 10
+	opportunisticGraftThreshold = 3
+
+	scoreDecayToZero = 0.01
+	scoreRetention   = 10 * time.Minute
+)
+
+// routerOptions builds the gossipsub options from the configuration.
+func (s *Service) routerOptions() []pubsub.Option {
+	opts := []pubsub.Option{
+		pubsub.WithFloodPublish(s.cfg.FloodPublish),
+	}
+
+	if !s.cfg.EnablePeerScoring {
+		return opts
+	}
+
+	params := &pubsub.PeerScoreParams{
+		Topics:                    make(map[string]*pubsub.TopicScoreParams),
+		AppSpecificScore:          func(peer.ID) float64 { return 0 },
+		AppSpecificWeight:         1,
+		BehaviourPenaltyWeight:    -10,
+		BehaviourPenaltyThreshold: 6,
+		BehaviourPenaltyDecay:     pubsub.ScoreParameterDecay(10 * time.Minute),
+		DecayInterval:             pubsub.DefaultDecayInterval,
+		DecayToZero:               scoreDecayToZero,
+		RetainScore:               scoreRetention,
+	}
+
+	thresholds := &pubsub.PeerScoreThresholds{
+		GossipThreshold:             s.cfg.GossipThreshold,
+		PublishThreshold:            s.cfg.PublishThreshold,
+		GraylistThreshold:           s.cfg.GraylistThreshold,
+		AcceptPXThreshold:           acceptPXThreshold,
+		OpportunisticGraftThreshold: opportunisticGraftThreshold,
+	}
+
+	return append(opts, pubsub.WithPeerScore(params, thresholds))
+}
+
+// topicScoreParams rewards time in the mesh and first deliveries, and punishes invalid
+// messages hard. Mesh delivery rates are not scored: groups are small and quiet, and
+// honest members would be penalised for silence.
+func (s *Service) topicScoreParams(weight float64) *pubsub.TopicScoreParams {
+	return &pubsub.TopicScoreParams{
+		SkipAtomicValidation:           true,
+		TopicWeight:                    weight,
+		TimeInMeshWeight:               1.0 / 3600,
+		TimeInMeshQuantum:              time.Second,
+		TimeInMeshCap:                  3600,
+		FirstMessageDeliveriesWeight:   1,
+		FirstMessageDeliveriesDecay:    pubsub.ScoreParameterDecay(time.Hour),
+		FirstMessageDeliveriesCap:      20,
+		InvalidMessageDeliveriesWeight: s.cfg.InvalidMessageWeight,
+		InvalidMessageDeliveriesDecay:  pubsub.ScoreParameterDecay(time.Hour),
+	}
+}
+
+// setTopicScore applies score weights to a joined topic when scoring is enabled.
+func (s *Service) setTopicScore(topic *pubsub.Topic, weight float64) {
+	if !s.cfg.EnablePeerScoring {
+		return
+	}
+	if err := topic.SetScoreParams(s.topicScoreParams(weight)); err != nil {
+		log.Printf("Error setting score parameters for topic %s: %v", topic.String(), err)
+	}
+}
+
+// groupMessageId identifies chat messages by the hash of the signed message in their frame,
+// so a message sealed again, say under a later epoch, is still suppressed by the router.
+// The publisher is part of the ID, and the validator checks the hash, so a forged frame
+// carrying a real message's hash cannot shadow it. Control content and frames without a
+// hash are identified by their ciphertext.
+func groupMessageId(msg *pb.Message) string {
+	var framed types.GroupCiphertext
+	if err := json.Unmarshal(msg.Data, &framed); err != nil || framed.KeyId == "" {
+		return contentMessageId("group", msg.Data)
+	}
+	if framed.MessageHash != "" {
+		return fmt.Sprintf("group/%s/message/%s/%s", framed.GroupId, peer.ID(msg.From), framed.MessageHash)
+	}
+	return contentMessageId("group/"+framed.GroupId+"/"+framed.KeyId, framed.Ciphertext)
+}
+
+// channelMessageId identifies channel posts by their UUID, so a post republished by its
+// author is suppressed by the router. The signature is part of the ID; otherwise a forged
+// copy carrying a real post's UUID would shadow it.
+func channelMessageId(msg *pb.Message) string {
+	var envelope types.ChannelEnvelope
+	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+		return contentMessageId("channel", msg.Data)
+	}
+
+	if envelope.ContentType == types.ChannelContentPost {
+		var post types.SignedChannelPost
+		if err := json.Unmarshal(envelope.Payload, &post); err == nil && post.Data.Id != "" {
+			return contentMessageId(fmt.Sprintf("channel/%s/post/%s", post.Data.ChannelId, post.Data.Id), post.Signature)
+		}
+	}
+
+	return contentMessageId("channel/"+envelope.ContentType, envelope.Payload)
+}
+
+func contentMessageId(prefix string, data []byte) string {
+	hash := sha256.Sum256(data)
+	return prefix + "/" + hex.EncodeToString(hash[:16])
+}
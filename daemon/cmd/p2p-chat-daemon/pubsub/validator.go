@@ -0,0 +1,296 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// groupMessageMaxSize bounds everything published on a group topic. Commits of large
+	// groups are the biggest content we send.
+	groupMessageMaxSize = 512 * 1024
+
+	// groupSenderRateLimit messages per groupSenderRateWindow are accepted from one member.
+	groupSenderRateLimit  = 30
+	groupSenderRateWindow = 10 * time.Second
+
+	// groupValidatorTimeout bounds how long a message from the next epoch waits for the
+	// commit that gets us there.
+	groupValidatorTimeout   = 3 * time.Second
+	groupEpochRetryInterval = 100 * time.Millisecond
+)
+
+// validatedGroupContent is attached to accepted messages so the subscriber does not
+// have to decrypt and parse them a second time.
+type validatedGroupContent struct {
+	ContentType string
+	Plaintext   []byte
+	Message     *types.SignedGroupChatMessage
+}
+
+// senderRate counts the messages of one sender in the current window.
+type senderRate struct {
+	windowStart time.Time
+	count       int
+}
+
+// senderRateLimiter tracks how many messages each sender published to a topic recently.
+// Senders whose window ran out are swept once per window, so rotating peer IDs cannot grow
+// it without bound.
+type senderRateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	senders   map[peer.ID]*senderRate
+	lastSweep time.Time
+}
+
+func newSenderRateLimiter(limit int, window time.Duration) *senderRateLimiter {
+	return &senderRateLimiter{limit: limit, window: window, senders: make(map[peer.ID]*senderRate)}
+}
+
+// allow records a message from sender and reports whether it is within the limit.
+func (l *senderRateLimiter) allow(sender peer.ID) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > l.window {
+		for id, rate := range l.senders {
+			if now.Sub(rate.windowStart) > l.window {
+				delete(l.senders, id)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	rate, ok := l.senders[sender]
+	if !ok || now.Sub(rate.windowStart) > l.window {
+		l.senders[sender] = &senderRate{windowStart: now, count: 1}
+		return true
+	}
+
+	rate.count++
+	return rate.count <= l.limit
+}
+
+// registerGroupValidator rejects messages on a group topic at the gossip layer unless they
+// come from a member, decrypt under a group key, are well formed and respect the size and
+// rate limits. Rejected messages are never relayed to the rest of the mesh.
+func (s *Service) registerGroupValidator(topicName string, groupId string) error {
+	limiter := newSenderRateLimiter(groupSenderRateLimit, groupSenderRateWindow)
+
+	validator := func(ctx context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+		return s.validateGroupMessage(ctx, groupId, limiter, msg)
+	}
+
+	err := s.pubsub.RegisterTopicValidator(topicName, validator, pubsub.WithValidatorTimeout(groupValidatorTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to register validator for topic %s: %w", topicName, err)
+	}
+
+	return nil
+}
+
+func (s *Service) validateGroupMessage(ctx context.Context, groupId string, limiter *senderRateLimiter, msg *pubsub.Message) pubsub.ValidationResult {
+	sender := msg.GetFrom()
+
+	// Our own messages are validated on publish too.
+	if sender == (*s.appState.Node).ID() {
+		return pubsub.ValidationAccept
+	}
+
+	content, result := s.checkGroupContent(ctx, groupId, sender, msg.Data, limiter)
+	if result == pubsub.ValidationAccept {
+		msg.ValidatorData = content
+	}
+	return result
+}
+
+// checkGroupContent applies the group topic rules to content from sender, whether it came
+// through gossip or over a direct stream.
+func (s *Service) checkGroupContent(ctx context.Context, groupId string, sender peer.ID, data []byte, limiter *senderRateLimiter) (*validatedGroupContent, pubsub.ValidationResult) {
+	if len(data) > groupMessageMaxSize {
+		log.Printf("Validator: Rejecting %d byte message from %s on group %s", len(data), sender.ShortString(), groupId)
+		return nil, pubsub.ValidationReject
+	}
+
+	// Everything on a group topic is authored by the peer that published it, which
+	// validateGroupContent enforces, so this also drops content relayed for a blocked author.
+	if s.gater.IsBlocked(sender) {
+		return nil, pubsub.ValidationIgnore
+	}
+
+	if !s.isGroupMember(ctx, groupId, sender.String()) {
+		log.Printf("Validator: Rejecting message from non-member %s on group %s", sender.ShortString(), groupId)
+		return nil, pubsub.ValidationReject
+	}
+
+	if !limiter.allow(sender) {
+		log.Printf("Validator: Ignoring message from %s on group %s, rate limit exceeded", sender.ShortString(), groupId)
+		return nil, pubsub.ValidationIgnore
+	}
+
+	contentType, plaintext, err := s.openGroupMessage(ctx, groupId, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, identity.ErrGroupEpochAhead):
+			// We missed a commit; history sync brings us and this message up to date.
+			s.eventBus.PublishAsync(events.GroupEpochBehindEvent{GroupId: groupId})
+			return nil, pubsub.ValidationIgnore
+		case errors.Is(err, identity.ErrGroupKeyExpired):
+			return nil, pubsub.ValidationIgnore
+		}
+		log.Printf("Validator: Rejecting undecryptable message from %s on group %s: %v", sender.ShortString(), groupId, err)
+		return nil, pubsub.ValidationReject
+	}
+
+	content := &validatedGroupContent{ContentType: contentType, Plaintext: plaintext}
+
+	if err := validateGroupContent(groupId, sender.String(), content); err != nil {
+		log.Printf("Validator: Rejecting malformed message from %s on group %s: %v", sender.ShortString(), groupId, err)
+		return nil, pubsub.ValidationReject
+	}
+
+	if err := checkFramedMessageHash(data, content); err != nil {
+		log.Printf("Validator: Rejecting message from %s on group %s: %v", sender.ShortString(), groupId, err)
+		return nil, pubsub.ValidationReject
+	}
+
+	return content, pubsub.ValidationAccept
+}
+
+// checkFramedMessageHash makes sure the message hash in the frame, which the router
+// identifies the message by, is that of the message inside. Frames of older senders and
+// of control content carry none.
+func checkFramedMessageHash(data []byte, content *validatedGroupContent) error {
+	var framed types.GroupCiphertext
+	if err := json.Unmarshal(data, &framed); err != nil || framed.MessageHash == "" {
+		return nil
+	}
+	if content.Message == nil {
+		return fmt.Errorf("%s content carries a message hash", content.ContentType)
+	}
+
+	hash, err := identity.HashPayload(content.Message.Data)
+	if err != nil {
+		return err
+	}
+	if hash != framed.MessageHash {
+		return fmt.Errorf("frame of message %s carries hash %s", content.Message.Data.Id, framed.MessageHash)
+	}
+	return nil
+}
+
+// isGroupMember checks the member list and, for members added by a commit we applied but
+// have not stored yet, the ratchet tree.
+func (s *Service) isGroupMember(ctx context.Context, groupId string, peerId string) bool {
+	isMember, err := s.groupMemberRepo.IsMember(ctx, groupId, peerId)
+	if err == nil && isMember {
+		return true
+	}
+
+	members, err := s.groupKeyStoreService.TreeMembers(groupId)
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(members, peerId)
+}
+
+// openGroupMessage decrypts a message, giving a commit that is still being validated or
+// delivered a moment to move us into the epoch the message was sent in.
+func (s *Service) openGroupMessage(ctx context.Context, groupId string, data []byte) (string, []byte, error) {
+	for {
+		contentType, plaintext, err := s.groupKeyStoreService.Open(groupId, data)
+		if !errors.Is(err, identity.ErrGroupEpochAhead) {
+			return contentType, plaintext, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", nil, err
+		case <-time.After(groupEpochRetryInterval):
+		}
+	}
+}
+
+// validateGroupContent checks that decrypted content is well formed and was authored by
+// the peer that published it.
+func validateGroupContent(groupId string, sender string, content *validatedGroupContent) error {
+	switch content.ContentType {
+	case types.GroupContentCommit:
+		var commit types.GroupCommit
+		if err := json.Unmarshal(content.Plaintext, &commit); err != nil {
+			return fmt.Errorf("malformed commit: %w", err)
+		}
+		if commit.Data.CommitterPeerId != sender {
+			return fmt.Errorf("commit signed by %s was published by %s", commit.Data.CommitterPeerId, sender)
+		}
+		return nil
+
+	case types.GroupContentMetadata:
+		var update types.GroupMetadataUpdate
+		if err := json.Unmarshal(content.Plaintext, &update); err != nil {
+			return fmt.Errorf("malformed metadata update: %w", err)
+		}
+		if update.Data.UpdatedBy != sender {
+			return fmt.Errorf("metadata update by %s was published by %s", update.Data.UpdatedBy, sender)
+		}
+		return nil
+
+	case types.GroupContentRevocation:
+		var revocation types.GroupInviteRevocation
+		if err := json.Unmarshal(content.Plaintext, &revocation); err != nil {
+			return fmt.Errorf("malformed invite revocation: %w", err)
+		}
+		if revocation.Data.RevokerPeerId != sender {
+			return fmt.Errorf("invite revocation by %s was published by %s", revocation.Data.RevokerPeerId, sender)
+		}
+		return nil
+
+	case types.GroupContentFile:
+		var announcement types.GroupFileAnnouncement
+		if err := json.Unmarshal(content.Plaintext, &announcement); err != nil {
+			return fmt.Errorf("malformed file announcement: %w", err)
+		}
+		if announcement.Data.UploaderPeerId != sender {
+			return fmt.Errorf("file announced by %s was published by %s", announcement.Data.UploaderPeerId, sender)
+		}
+		return nil
+
+	case types.GroupContentApplication:
+		var signed types.SignedGroupChatMessage
+		if err := json.Unmarshal(content.Plaintext, &signed); err != nil {
+			return fmt.Errorf("malformed message: %w", err)
+		}
+		message := signed.Data
+
+		if message.SenderPeerId != sender {
+			return fmt.Errorf("message from %s was published by %s", message.SenderPeerId, sender)
+		}
+		if message.GroupId != groupId {
+			return fmt.Errorf("message %s was addressed to group %s", message.Id, message.GroupId)
+		}
+		if err := identity.VerifyPayload(message.SenderPeerId, message, signed.SenderSignature); err != nil {
+			return fmt.Errorf("invalid signature on message %s: %w", message.Id, err)
+		}
+
+		content.Message = &signed
+		return nil
+	}
+
+	return fmt.Errorf("unknown content type %q", content.ContentType)
+}
@@ -9,15 +9,21 @@ import (
 	"os/signal"
 	uiapi "p2p-chat-daemon/cmd/p2p-chat-daemon/api"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/appstate"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/blocklist"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/card"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/channel"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/chat"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/config"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/connection"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/discovery"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/handle"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/identity"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/bus"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/internal/core/events"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/nearby"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/peer"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/presence"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/profile"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/pubsub"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/storage"
@@ -31,9 +37,16 @@ type Application struct {
 	config            *config.Config
 	appstate          *core.AppState
 	chatService       *chat.Service
+	channelService    *channel.Service
 	profileService    *profile.Service
 	connectionService *connection.Service
 	pubsubService     *pubsub.Service
+	keyService        *identity.GroupKeyStore
+	blocklistService  *blocklist.Service
+	handleService     *handle.Service
+	nearbyService     *nearby.Service
+	cardService       *card.Service
+	presenceService   *presence.Service
 	cancel            context.CancelFunc
 	server            *http.Server
 	messageRepo       storage.MessageRepository
@@ -94,16 +107,156 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 		return nil, fmt.Errorf("failed to create display name repository: %w", err)
 	}
 
-	keyService := identity.NewGroupKeyStore(keyRepo, ctx)
+	groupInvitationRepo, err := storage.NewSQLiteGroupInvitationRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create group invitation repository: %w", err)
+	}
+
+	groupMetadataRepo, err := storage.NewSQLiteGroupMetadataRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create group metadata repository: %w", err)
+	}
+
+	groupInviteRepo, err := storage.NewSQLiteGroupInviteRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create group invite repository: %w", err)
+	}
+
+	groupDirectoryRepo, err := storage.NewSQLiteGroupDirectoryRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create group directory repository: %w", err)
+	}
+
+	groupFileRepo, err := storage.NewSQLiteGroupFileRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create group file repository: %w", err)
+	}
+
+	channelRepo, err := storage.NewSQLiteChannelRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create channel repository: %w", err)
+	}
+
+	friendOutboxRepo, err := storage.NewSQLiteFriendOutboxRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create friend outbox repository: %w", err)
+	}
+
+	friendIntroductionRepo, err := storage.NewSQLiteFriendIntroductionRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create friend introduction repository: %w", err)
+	}
 
-	pubsubService, err := pubsub.NewPubSubService(eventbus, ctx, appState, keyService)
+	contactLabelRepo, err := storage.NewSQLiteContactLabelRepository(db)
 	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create contact label repository: %w", err)
+	}
+
+	circleRepo, err := storage.NewSQLiteCircleRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create circle repository: %w", err)
+	}
+
+	blockedPeerRepo, err := storage.NewSQLiteBlockedPeerRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create blocked peer repository: %w", err)
+	}
+
+	blocklistService, err := blocklist.NewBlocklistService(ctx, appState, blockedPeerRepo)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create blocklist service: %w", err)
+	}
+
+	handleClaimRepo, err := storage.NewSQLiteHandleClaimRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create handle claim repository: %w", err)
+	}
+
+	handleService := handle.NewHandleService(ctx, appState, eventbus, handleClaimRepo)
+
+	settingsRepo, err := storage.NewSQLiteSettingsRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create settings repository: %w", err)
+	}
+
+	nearbyService := nearby.NewNearbyService(ctx, appState, eventbus, relationshipRepo, settingsRepo, blocklistService)
+
+	profileCardRepo, err := storage.NewSQLiteProfileCardRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create profile card repository: %w", err)
+	}
+
+	avatarRepo, err := storage.NewSQLiteAvatarRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create avatar repository: %w", err)
+	}
+
+	cardService := card.NewProfileCardService(ctx, appState, eventbus, profileCardRepo, avatarRepo, relationshipRepo)
+
+	presenceRuleRepo, err := storage.NewSQLitePresenceRuleRepository(db)
+	if err != nil {
+		db.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create presence rule repository: %w", err)
+	}
+
+	keyService := identity.NewGroupKeyStore(keyRepo, appState, ctx)
+
+	pubsubService, err := pubsub.NewPubSubService(eventbus, ctx, appState, &cfg.PubSub, keyService, groupMemberRepo, channelRepo, blocklistService)
+	if err != nil {
+		db.Close()
+		cancel()
 		return nil, fmt.Errorf("failed to create pubsub service: %w", err)
 	}
 
 	connectionService := connection.NewConnectionService(ctx, appState, relationshipRepo, eventbus)
 
-	profileHandle := profile.NewProtocolHandler(appState, eventbus, ctx, relationshipRepo, connectionService)
+	profileHandle := profile.NewProtocolHandler(
+		appState,
+		eventbus,
+		ctx,
+		relationshipRepo,
+		friendOutboxRepo,
+		friendIntroductionRepo,
+		displayNameRepo,
+		contactLabelRepo,
+		circleRepo,
+		connectionService,
+	)
+
+	presenceService := presence.NewPresenceService(ctx, appState, eventbus, relationshipRepo, settingsRepo, presenceRuleRepo, profileHandle)
 
 	chatHandler := chat.NewProtocolHandler(
 		appState,
@@ -114,17 +267,31 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 		keyRepo,
 		pubsubService,
 		msgRepo,
+		groupInvitationRepo,
+		groupMetadataRepo,
+		groupInviteRepo,
+		groupDirectoryRepo,
+		groupFileRepo,
+		blocklistService,
 	)
 
+	channelHandler := channel.NewChannelService(appState, eventbus, pubsubService, channelRepo, blocklistService)
+
 	_, server, handler, err := uiapi.StartAPIServer(
 		ctx,
 		cfg.API.ListenAddr,
 		appState,
 		eventbus,
 		chatHandler,
+		channelHandler,
 		profileHandle,
 		connectionService,
 		displayNameRepo,
+		blocklistService,
+		handleService,
+		nearbyService,
+		cardService,
+		presenceService,
 	)
 	eventbus.PublishAsync(events.ApiStartedEvent{})
 
@@ -142,6 +309,7 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 		appstate:          appState,
 		eventBus:          eventbus,
 		chatService:       chatHandler,
+		channelService:    channelHandler,
 		profileService:    profileHandle,
 		connectionService: connectionService,
 		cancel:            cancel,
@@ -149,6 +317,12 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 		messageRepo:       msgRepo,
 		relationshipRepo:  relationshipRepo,
 		pubsubService:     pubsubService,
+		keyService:        keyService,
+		blocklistService:  blocklistService,
+		handleService:     handleService,
+		nearbyService:     nearbyService,
+		cardService:       cardService,
+		presenceService:   presenceService,
 	}
 
 	return app, nil
@@ -172,7 +346,7 @@ func (app *Application) Start() error {
 		log.Println("P2P Initializer: Shutdown signal received before key was ready.")
 	}
 
-	nodeManager := peer.NewNodeManager(app.ctx, app.appstate, &app.config.P2P)
+	nodeManager := peer.NewNodeManager(app.ctx, app.appstate, &app.config.P2P, app.blocklistService)
 	host, err := nodeManager.Initialize()
 	if err != nil {
 		return err
@@ -184,16 +358,27 @@ func (app *Application) Start() error {
 	if err != nil {
 		return err
 	}
-	discoveryManager, err := discovery.NewDiscoveryManager(app.ctx, host, app.config, app.eventBus)
+	// Subscribed before discovery starts, so no peer found over mDNS is missed.
+	nearby.NewConsumer(app.eventBus, app.nearbyService, app.ctx).Start()
+
+	discoveryManager, err := discovery.NewDiscoveryManager(app.ctx, host, app.config, app.eventBus, handle.DHTOption())
 	err = discoveryManager.Initialize()
+	app.chatService.SetDHTDiscovery(discoveryManager.DHT())
+	app.handleService.SetDHTDiscovery(discoveryManager.DHT())
 	app.eventBus.PublishAsync(events.SetupCompletedEvent{})
 
-	chatCons, err := chat.NewConsumer(app.appstate, app.eventBus, app.messageRepo, app.ctx)
+	chatCons, err := chat.NewConsumer(app.appstate, app.eventBus, app.messageRepo, app.chatService, app.ctx)
 	if err != nil {
 		log.Println("Failed to create chat consumer")
 		return err
 	}
 
+	channelCons, err := channel.NewConsumer(app.appstate, app.eventBus, app.channelService, app.ctx)
+	if err != nil {
+		log.Println("Failed to create channel consumer")
+		return err
+	}
+
 	profileCons, err := profile.NewConsumer(app.appstate, app.eventBus, app.relationshipRepo, app.profileService, app.ctx)
 	if err != nil {
 		log.Println("Failed to create chat consumer")
@@ -206,10 +391,18 @@ func (app *Application) Start() error {
 	}
 
 	go app.chatService.Register()
+	go app.channelService.Register()
 	go app.profileService.Register()
+	go app.nearbyService.Register()
+	go app.cardService.Register()
+	go app.presenceService.Register()
 	go chatCons.Start()
+	go channelCons.Start()
 	go profileCons.Start()
+	go card.NewConsumer(app.eventBus, app.cardService, app.ctx).Start()
+	go presence.NewConsumer(app.eventBus, app.presenceService, app.ctx).Start()
 	go app.connectionService.Start()
+	go app.keyService.Start()
 
 	return nil
 }
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+// AvatarRepository keeps avatar images by the hash of their content.
+type AvatarRepository interface {
+	Store(ctx context.Context, avatar types.Avatar) error
+	Get(ctx context.Context, hash string) (*types.Avatar, error)
+	Has(ctx context.Context, hash string) (bool, error)
+	DeleteUnused(ctx context.Context, before time.Time) (int64, error)
+}
+
+type sqliteAvatarRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAvatarRepository(database *DB) (AvatarRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for avatar repository")
+	}
+	return &sqliteAvatarRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqliteAvatarRepository) Store(ctx context.Context, avatar types.Avatar) error {
+	sqlStmt := `INSERT OR IGNORE INTO avatars (hash, mime_type, content, created_at) VALUES (?, ?, ?, ?);`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, avatar.Hash, avatar.MimeType, avatar.Content, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to store avatar %s: %w", avatar.Hash, err)
+	}
+	return nil
+}
+
+// Get returns sql.ErrNoRows if we do not have the avatar.
+func (r *sqliteAvatarRepository) Get(ctx context.Context, hash string) (*types.Avatar, error) {
+	avatar := types.Avatar{Hash: hash}
+
+	err := r.db.QueryRowContext(ctx, `SELECT mime_type, content FROM avatars WHERE hash = ?;`, hash).Scan(
+		&avatar.MimeType,
+		&avatar.Content,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get avatar %s: %w", hash, err)
+	}
+
+	return &avatar, nil
+}
+
+func (r *sqliteAvatarRepository) Has(ctx context.Context, hash string) (bool, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM avatars WHERE hash = ?;`, hash).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up avatar %s: %w", hash, err)
+	}
+	return count > 0, nil
+}
+
+// DeleteUnused deletes avatars stored before the given time that no profile card uses.
+func (r *sqliteAvatarRepository) DeleteUnused(ctx context.Context, before time.Time) (int64, error) {
+	sqlStmt := `
+		DELETE FROM avatars
+		WHERE created_at < ?
+		AND hash NOT IN (SELECT avatar_hash FROM profile_cards);
+	`
+
+	res, err := r.db.ExecContext(ctx, sqlStmt, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unused avatars: %w", err)
+	}
+
+	deleted, _ := res.RowsAffected()
+	if deleted > 0 {
+		log.Printf("Storage: Deleted %d unused avatar(s)", deleted)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type BlockedPeerRepository interface {
+	Block(ctx context.Context, peerID string, reason string) error
+	Unblock(ctx context.Context, peerID string) error
+	GetBlocked(ctx context.Context) ([]types.BlockedPeer, error)
+}
+
+type sqliteBlockedPeerRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteBlockedPeerRepository(database *DB) (BlockedPeerRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for blocked peer repository")
+	}
+	return &sqliteBlockedPeerRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqliteBlockedPeerRepository) Block(ctx context.Context, peerID string, reason string) error {
+	sqlStmt := `
+		INSERT INTO blocked_peers (peer_id, reason, blocked_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(peer_id) DO UPDATE SET reason = excluded.reason;
+	`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, peerID, reason, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to block peer %s: %w", peerID, err)
+	}
+
+	log.Printf("Storage: Blocked peer %s", peerID)
+	return nil
+}
+
+func (r *sqliteBlockedPeerRepository) Unblock(ctx context.Context, peerID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM blocked_peers WHERE peer_id = ?;`, peerID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock peer %s: %w", peerID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check unblocked peer %s: %w", peerID, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	log.Printf("Storage: Unblocked peer %s", peerID)
+	return nil
+}
+
+func (r *sqliteBlockedPeerRepository) GetBlocked(ctx context.Context) ([]types.BlockedPeer, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT peer_id, reason, blocked_at FROM blocked_peers ORDER BY blocked_at;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked peers: %w", err)
+	}
+	defer rows.Close()
+
+	blocked := []types.BlockedPeer{}
+	for rows.Next() {
+		var entry types.BlockedPeer
+		var blockedAt int64
+		if err := rows.Scan(&entry.PeerId, &entry.Reason, &blockedAt); err != nil {
+			log.Printf("Storage: Error scanning blocked peer row: %v", err)
+			continue
+		}
+		entry.BlockedAt = time.Unix(blockedAt, 0)
+		blocked = append(blocked, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over blocked peers: %w", err)
+	}
+
+	return blocked, nil
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type ChannelRepository interface {
+	StoreChannel(ctx context.Context, channel types.Channel) error
+	GetChannel(ctx context.Context, channelID string) (*types.Channel, error)
+	GetChannels(ctx context.Context) ([]types.Channel, error)
+	DeleteChannel(ctx context.Context, channelID string) error
+	IsAuthor(ctx context.Context, channelID string, peerID string) (bool, error)
+	StorePost(ctx context.Context, post types.ChannelPost) (bool, error)
+	GetPosts(ctx context.Context, channelID string, limit int) ([]types.ChannelPost, error)
+	StoreReaction(ctx context.Context, reaction types.ChannelReaction) (bool, error)
+}
+
+type sqliteChannelRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteChannelRepository(database *DB) (ChannelRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for channel repository")
+	}
+	return &sqliteChannelRepository{db: database.GetDB()}, nil
+}
+
+// StoreChannel inserts or replaces a channel together with its list of authors.
+func (r *sqliteChannelRepository) StoreChannel(ctx context.Context, channel types.Channel) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for channel %s: %w", channel.ChannelId, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO channels (channel_id, name, description, owner_peer_id, version, descriptor, joined_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			version = excluded.version,
+			descriptor = excluded.descriptor;
+	`,
+		channel.ChannelId,
+		channel.Name,
+		channel.Description,
+		channel.OwnerPeerId,
+		channel.Version,
+		channel.Descriptor,
+		channel.JoinedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store channel %s: %w", channel.ChannelId, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM channel_authors WHERE channel_id = ?", channel.ChannelId); err != nil {
+		return fmt.Errorf("failed to clear authors of channel %s: %w", channel.ChannelId, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT OR IGNORE INTO channel_authors (channel_id, peer_id) VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement for authors of channel %s: %w", channel.ChannelId, err)
+	}
+	defer stmt.Close()
+
+	for _, author := range channel.Authors {
+		if _, err := stmt.ExecContext(ctx, channel.ChannelId, author); err != nil {
+			return fmt.Errorf("failed to add author %s to channel %s: %w", author, channel.ChannelId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for channel %s: %w", channel.ChannelId, err)
+	}
+
+	log.Printf("Storage: Stored version %d of channel %s with %d author(s)", channel.Version, channel.ChannelId, len(channel.Authors))
+	return nil
+}
+
+func (r *sqliteChannelRepository) GetChannel(ctx context.Context, channelID string) (*types.Channel, error) {
+	sqlStmt := `
+		SELECT channel_id, name, description, owner_peer_id, version, descriptor, joined_at
+		FROM channels
+		WHERE channel_id = ?;
+	`
+
+	channel, err := scanChannel(r.db.QueryRowContext(ctx, sqlStmt, channelID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get channel %s: %w", channelID, err)
+	}
+
+	channel.Authors, err = r.getAuthors(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return channel, nil
+}
+
+func (r *sqliteChannelRepository) GetChannels(ctx context.Context) ([]types.Channel, error) {
+	sqlStmt := `
+		SELECT channel_id, name, description, owner_peer_id, version, descriptor, joined_at
+		FROM channels
+		ORDER BY joined_at;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channels: %w", err)
+	}
+	defer rows.Close()
+
+	channels := []types.Channel{}
+	for rows.Next() {
+		channel, err := scanChannel(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning channel row: %v", err)
+			continue
+		}
+		channels = append(channels, *channel)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating channel rows: %w", err)
+	}
+	rows.Close()
+
+	for i := range channels {
+		channels[i].Authors, err = r.getAuthors(ctx, channels[i].ChannelId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return channels, nil
+}
+
+// DeleteChannel removes a channel and everything received on it.
+func (r *sqliteChannelRepository) DeleteChannel(ctx context.Context, channelID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for deleting channel %s: %w", channelID, err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"channel_reactions", "channel_posts", "channel_authors", "channels"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE channel_id = ?", channelID); err != nil {
+			return fmt.Errorf("failed to delete channel %s from %s: %w", channelID, table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for deleting channel %s: %w", channelID, err)
+	}
+
+	log.Printf("Storage: Deleted channel %s", channelID)
+	return nil
+}
+
+func (r *sqliteChannelRepository) IsAuthor(ctx context.Context, channelID string, peerID string) (bool, error) {
+	var exists int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT 1 FROM channel_authors WHERE channel_id = ? AND peer_id = ? LIMIT 1",
+		channelID, peerID,
+	).Scan(&exists)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check author %s of channel %s: %w", peerID, channelID, err)
+	}
+
+	return true, nil
+}
+
+// StorePost stores a post and reports whether it was new.
+func (r *sqliteChannelRepository) StorePost(ctx context.Context, post types.ChannelPost) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO channel_posts (post_id, channel_id, author_peer_id, message, timestamp, envelope)
+		VALUES (?, ?, ?, ?, ?, ?);
+	`,
+		post.Id,
+		post.ChannelId,
+		post.AuthorPeerId,
+		post.Message,
+		post.Time.Unix(),
+		post.Envelope,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to store post %s of channel %s: %w", post.Id, post.ChannelId, err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// GetPosts returns the latest posts of a channel, oldest first, with their reaction counts.
+func (r *sqliteChannelRepository) GetPosts(ctx context.Context, channelID string, limit int) ([]types.ChannelPost, error) {
+	sqlStmt := `
+		SELECT post_id, channel_id, author_peer_id, message, timestamp, envelope
+		FROM (
+			SELECT * FROM channel_posts
+			WHERE channel_id = ?
+			ORDER BY timestamp DESC
+			LIMIT ?
+		)
+		ORDER BY timestamp ASC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt, channelID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query posts of channel %s: %w", channelID, err)
+	}
+	defer rows.Close()
+
+	posts := []types.ChannelPost{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var post types.ChannelPost
+		var timestamp int64
+		if err := rows.Scan(&post.Id, &post.ChannelId, &post.AuthorPeerId, &post.Message, &timestamp, &post.Envelope); err != nil {
+			log.Printf("Storage: Error scanning channel post row: %v", err)
+			continue
+		}
+		post.Time = time.Unix(timestamp, 0)
+		post.Reactions = make(map[string]int)
+		index[post.Id] = len(posts)
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating channel post rows: %w", err)
+	}
+	rows.Close()
+
+	reactionRows, err := r.db.QueryContext(ctx, `
+		SELECT post_id, reaction, COUNT(*)
+		FROM channel_reactions
+		WHERE channel_id = ?
+		GROUP BY post_id, reaction;
+	`, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions of channel %s: %w", channelID, err)
+	}
+	defer reactionRows.Close()
+
+	for reactionRows.Next() {
+		var postID, reaction string
+		var count int
+		if err := reactionRows.Scan(&postID, &reaction, &count); err != nil {
+			log.Printf("Storage: Error scanning channel reaction row: %v", err)
+			continue
+		}
+		if i, ok := index[postID]; ok {
+			posts[i].Reactions[reaction] = count
+		}
+	}
+
+	if err = reactionRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating channel reaction rows: %w", err)
+	}
+
+	return posts, nil
+}
+
+// StoreReaction stores a reaction and reports whether it was new.
+func (r *sqliteChannelRepository) StoreReaction(ctx context.Context, reaction types.ChannelReaction) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO channel_reactions (channel_id, post_id, reactor_peer_id, reaction, timestamp, envelope)
+		VALUES (?, ?, ?, ?, ?, ?);
+	`,
+		reaction.ChannelId,
+		reaction.PostId,
+		reaction.ReactorPeerId,
+		reaction.Reaction,
+		reaction.Time.Unix(),
+		reaction.Envelope,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to store reaction to post %s: %w", reaction.PostId, err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+func (r *sqliteChannelRepository) getAuthors(ctx context.Context, channelID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT peer_id FROM channel_authors WHERE channel_id = ?", channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query authors of channel %s: %w", channelID, err)
+	}
+	defer rows.Close()
+
+	authors := []string{}
+	for rows.Next() {
+		var peerID string
+		if err := rows.Scan(&peerID); err != nil {
+			log.Printf("Storage: Error scanning channel author row: %v", err)
+			continue
+		}
+		authors = append(authors, peerID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating channel author rows: %w", err)
+	}
+
+	return authors, nil
+}
+
+func scanChannel(row interface{ Scan(dest ...any) error }) (*types.Channel, error) {
+	var channel types.Channel
+	var joinedAtUnix int64
+
+	err := row.Scan(
+		&channel.ChannelId,
+		&channel.Name,
+		&channel.Description,
+		&channel.OwnerPeerId,
+		&channel.Version,
+		&channel.Descriptor,
+		&joinedAtUnix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	channel.JoinedAt = time.Unix(joinedAtUnix, 0)
+
+	return &channel, nil
+}
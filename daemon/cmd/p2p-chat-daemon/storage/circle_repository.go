@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type CircleRepository interface {
+	Create(ctx context.Context, circle types.Circle) error
+	Rename(ctx context.Context, circleID string, name string) error
+	Delete(ctx context.Context, circleID string) error
+	GetById(ctx context.Context, circleID string) (types.Circle, error)
+	GetAll(ctx context.Context) ([]types.Circle, error)
+	AddMember(ctx context.Context, circleID string, peerID string) error
+	RemoveMember(ctx context.Context, circleID string, peerID string) error
+}
+
+type sqliteCircleRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteCircleRepository(database *DB) (CircleRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for circle repository")
+	}
+	return &sqliteCircleRepository{db: database.GetDB()}, nil
+}
+
+// Create stores a circle with its members. Circle names are unique.
+func (r *sqliteCircleRepository) Create(ctx context.Context, circle types.Circle) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for circle %s: %w", circle.Name, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO circles (circle_id, name, created_at) VALUES (?, ?, ?);`,
+		circle.CircleId, circle.Name, circle.CreatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to create circle %s: %w", circle.Name, err)
+	}
+
+	for _, peerID := range circle.Members {
+		_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO circle_members (circle_id, peer_id, added_at) VALUES (?, ?, ?);`,
+			circle.CircleId, peerID, circle.CreatedAt.Unix())
+		if err != nil {
+			return fmt.Errorf("failed to add %s to circle %s: %w", peerID, circle.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit circle %s: %w", circle.Name, err)
+	}
+
+	log.Printf("Storage: Created circle %s with %d members", circle.Name, len(circle.Members))
+	return nil
+}
+
+func (r *sqliteCircleRepository) Rename(ctx context.Context, circleID string, name string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE circles SET name = ? WHERE circle_id = ?;`, name, circleID)
+	if err != nil {
+		return fmt.Errorf("failed to rename circle %s: %w", circleID, err)
+	}
+	return requireAffected(result, "renamed circle "+circleID)
+}
+
+func (r *sqliteCircleRepository) Delete(ctx context.Context, circleID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for deleting circle %s: %w", circleID, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM circle_members WHERE circle_id = ?;`, circleID); err != nil {
+		return fmt.Errorf("failed to delete members of circle %s: %w", circleID, err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM circles WHERE circle_id = ?;`, circleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete circle %s: %w", circleID, err)
+	}
+	if err := requireAffected(result, "deleted circle "+circleID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deletion of circle %s: %w", circleID, err)
+	}
+
+	log.Printf("Storage: Deleted circle %s", circleID)
+	return nil
+}
+
+func (r *sqliteCircleRepository) GetById(ctx context.Context, circleID string) (types.Circle, error) {
+	var circle types.Circle
+	var createdAt int64
+
+	err := r.db.QueryRowContext(ctx, `SELECT circle_id, name, created_at FROM circles WHERE circle_id = ?;`, circleID).
+		Scan(&circle.CircleId, &circle.Name, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Circle{}, sql.ErrNoRows
+		}
+		return types.Circle{}, fmt.Errorf("failed to get circle %s: %w", circleID, err)
+	}
+	circle.CreatedAt = time.Unix(createdAt, 0)
+
+	members, err := r.getMembers(ctx, circleID)
+	if err != nil {
+		return types.Circle{}, err
+	}
+	circle.Members = members
+
+	return circle, nil
+}
+
+func (r *sqliteCircleRepository) GetAll(ctx context.Context) ([]types.Circle, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT circle_id, name, created_at FROM circles ORDER BY name;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query circles: %w", err)
+	}
+
+	circles := []types.Circle{}
+	for rows.Next() {
+		var circle types.Circle
+		var createdAt int64
+		if err := rows.Scan(&circle.CircleId, &circle.Name, &createdAt); err != nil {
+			log.Printf("Storage: Error scanning circle row: %v", err)
+			continue
+		}
+		circle.CreatedAt = time.Unix(createdAt, 0)
+		circles = append(circles, circle)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error iterating over circles: %w", err)
+	}
+
+	for i := range circles {
+		members, err := r.getMembers(ctx, circles[i].CircleId)
+		if err != nil {
+			return nil, err
+		}
+		circles[i].Members = members
+	}
+
+	return circles, nil
+}
+
+func (r *sqliteCircleRepository) AddMember(ctx context.Context, circleID string, peerID string) error {
+	sqlStmt := `INSERT OR IGNORE INTO circle_members (circle_id, peer_id, added_at) VALUES (?, ?, ?);`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, circleID, peerID, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to add %s to circle %s: %w", peerID, circleID, err)
+	}
+	return nil
+}
+
+// RemoveMember returns sql.ErrNoRows if the peer was not in the circle.
+func (r *sqliteCircleRepository) RemoveMember(ctx context.Context, circleID string, peerID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM circle_members WHERE circle_id = ? AND peer_id = ?;`, circleID, peerID)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s from circle %s: %w", peerID, circleID, err)
+	}
+	return requireAffected(result, "removed member of circle "+circleID)
+}
+
+func (r *sqliteCircleRepository) getMembers(ctx context.Context, circleID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT peer_id FROM circle_members WHERE circle_id = ? ORDER BY added_at;`, circleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query members of circle %s: %w", circleID, err)
+	}
+	defer rows.Close()
+
+	members := []string{}
+	for rows.Next() {
+		var peerID string
+		if err := rows.Scan(&peerID); err != nil {
+			log.Printf("Storage: Error scanning circle member row: %v", err)
+			continue
+		}
+		members = append(members, peerID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over members of circle %s: %w", circleID, err)
+	}
+
+	return members, nil
+}
+
+// requireAffected returns sql.ErrNoRows if a statement changed nothing.
+func requireAffected(result sql.Result, what string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", what, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
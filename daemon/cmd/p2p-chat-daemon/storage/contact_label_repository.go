@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+// ContactLabelRepository keeps our private notes and tags on contacts. Circles are kept by
+// the CircleRepository.
+type ContactLabelRepository interface {
+	SetNote(ctx context.Context, peerID string, note string) error
+	AddTag(ctx context.Context, peerID string, tag string) error
+	RemoveTag(ctx context.Context, peerID string, tag string) error
+	CountTags(ctx context.Context, peerID string) (int, error)
+	GetAll(ctx context.Context) (map[string]*types.ContactLabels, error)
+}
+
+type sqliteContactLabelRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteContactLabelRepository(database *DB) (ContactLabelRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for contact label repository")
+	}
+	return &sqliteContactLabelRepository{db: database.GetDB()}, nil
+}
+
+// SetNote replaces the note on a contact. An empty note removes it.
+func (r *sqliteContactLabelRepository) SetNote(ctx context.Context, peerID string, note string) error {
+	if note == "" {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM contact_notes WHERE peer_id = ?;`, peerID); err != nil {
+			return fmt.Errorf("failed to delete note on %s: %w", peerID, err)
+		}
+		return nil
+	}
+
+	sqlStmt := `
+		INSERT INTO contact_notes (peer_id, note, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(peer_id) DO UPDATE SET note = excluded.note, updated_at = excluded.updated_at;
+	`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, peerID, note, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to set note on %s: %w", peerID, err)
+	}
+
+	log.Printf("Storage: Set note on %s", peerID)
+	return nil
+}
+
+func (r *sqliteContactLabelRepository) AddTag(ctx context.Context, peerID string, tag string) error {
+	sqlStmt := `INSERT OR IGNORE INTO contact_tags (peer_id, tag, created_at) VALUES (?, ?, ?);`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, peerID, tag, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to tag %s with %s: %w", peerID, tag, err)
+	}
+	return nil
+}
+
+// RemoveTag returns sql.ErrNoRows if the contact did not have the tag.
+func (r *sqliteContactLabelRepository) RemoveTag(ctx context.Context, peerID string, tag string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM contact_tags WHERE peer_id = ? AND tag = ?;`, peerID, tag)
+	if err != nil {
+		return fmt.Errorf("failed to remove tag %s from %s: %w", tag, peerID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check removed tag %s from %s: %w", tag, peerID, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *sqliteContactLabelRepository) CountTags(ctx context.Context, peerID string) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM contact_tags WHERE peer_id = ?;`, peerID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tags on %s: %w", peerID, err)
+	}
+	return count, nil
+}
+
+// GetAll returns the notes and tags of every labelled contact, keyed by peer ID.
+func (r *sqliteContactLabelRepository) GetAll(ctx context.Context) (map[string]*types.ContactLabels, error) {
+	labels := map[string]*types.ContactLabels{}
+	labelsOf := func(peerID string) *types.ContactLabels {
+		if labels[peerID] == nil {
+			labels[peerID] = &types.ContactLabels{}
+		}
+		return labels[peerID]
+	}
+
+	noteRows, err := r.db.QueryContext(ctx, `SELECT peer_id, note FROM contact_notes;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact notes: %w", err)
+	}
+	defer noteRows.Close()
+
+	for noteRows.Next() {
+		var peerID, note string
+		if err := noteRows.Scan(&peerID, &note); err != nil {
+			log.Printf("Storage: Error scanning contact note row: %v", err)
+			continue
+		}
+		labelsOf(peerID).Note = note
+	}
+	if err := noteRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over contact notes: %w", err)
+	}
+
+	tagRows, err := r.db.QueryContext(ctx, `SELECT peer_id, tag FROM contact_tags ORDER BY tag;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact tags: %w", err)
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var peerID, tag string
+		if err := tagRows.Scan(&peerID, &tag); err != nil {
+			log.Printf("Storage: Error scanning contact tag row: %v", err)
+			continue
+		}
+		contact := labelsOf(peerID)
+		contact.Tags = append(contact.Tags, tag)
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over contact tags: %w", err)
+	}
+
+	return labels, nil
+}
@@ -30,7 +30,7 @@ func NewDB(config *config.Config) (*DB, error) {
 	dbPath := config.P2P.DbPath
 	log.Printf("Storage: Initializing database at %s", dbPath)
 
-	dsn := fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", dbPath)
+	dsn := fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=secure_delete(1)", dbPath)
 
 	dbHandle, err := sql.Open(dbDriverName, dsn)
 	if err != nil {
@@ -95,7 +95,17 @@ func (db *DB) ensureCreation() error {
 			group_id TEXT NOT NULL,
 			sender_peer_id TEXT NOT NULL,
 			content BLOB NOT NULL,
-			sent_at INTEGER NOT NULL
+			sent_at INTEGER NOT NULL,
+			message_id TEXT DEFAULT NULL,
+			envelope BLOB DEFAULT NULL,
+			message_hash TEXT DEFAULT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS group_message_edges (
+			group_id TEXT NOT NULL,
+			child_hash TEXT NOT NULL,
+			parent_hash TEXT NOT NULL,
+			UNIQUE(group_id, child_hash, parent_hash)
 		);
 		
 		CREATE TABLE IF NOT EXISTS display_names (
@@ -108,6 +118,252 @@ func (db *DB) ensureCreation() error {
     		UNIQUE(entity_id, entity_type)
 		);
 
+		CREATE TABLE IF NOT EXISTS group_invitations (
+			group_id TEXT PRIMARY KEY NOT NULL,
+			inviter_peer_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			members TEXT NOT NULL,             -- JSON array of peer IDs
+			status TEXT NOT NULL,              -- 'pending', 'accepted' or 'declined'
+			received_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS group_epochs (
+			group_id TEXT NOT NULL,
+			epoch INTEGER NOT NULL,
+			commit_hash TEXT NOT NULL,
+			state BLOB NOT NULL,               -- epoch secrets and tree keys, encrypted with the db key
+			created_at INTEGER NOT NULL,
+			PRIMARY KEY (group_id, epoch)
+		);
+
+		CREATE TABLE IF NOT EXISTS group_commits (
+			group_id TEXT NOT NULL,
+			epoch INTEGER NOT NULL,            -- epoch the commit was made in
+			commit_hash TEXT NOT NULL,
+			commit_data BLOB NOT NULL,         -- signed commit, path secrets are sealed to tree nodes
+			created_at INTEGER NOT NULL,
+			PRIMARY KEY (group_id, epoch)
+		);
+
+		CREATE TABLE IF NOT EXISTS group_key_packages (
+			group_id TEXT PRIMARY KEY NOT NULL,
+			public_key BLOB NOT NULL,
+			private_key BLOB NOT NULL,         -- encrypted with the db key
+			created_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS group_metadata (
+			group_id TEXT PRIMARY KEY NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			avatar_hash TEXT NOT NULL DEFAULT '',
+			version INTEGER NOT NULL,
+			updated_by TEXT NOT NULL,
+			updated_at INTEGER NOT NULL,
+			update_hash TEXT NOT NULL,
+			signed_update BLOB NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS group_invites (
+			token_id TEXT PRIMARY KEY NOT NULL,
+			group_id TEXT NOT NULL,
+			issuer_peer_id TEXT NOT NULL DEFAULT '',
+			single_use INTEGER NOT NULL DEFAULT 0,
+			expires_at INTEGER NOT NULL DEFAULT 0,
+			uses INTEGER NOT NULL DEFAULT 0,
+			revoked INTEGER NOT NULL DEFAULT 0,
+			token BLOB,                        -- signed token, NULL if we only saw its revocation
+			created_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS published_groups (
+			group_id TEXT PRIMARY KEY NOT NULL,
+			published_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS directory_groups (
+			group_id TEXT PRIMARY KEY NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			topic TEXT NOT NULL,
+			member_count INTEGER NOT NULL,
+			publisher_peer_id TEXT NOT NULL,
+			descriptor BLOB NOT NULL,
+			seen_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS group_join_requests (
+			group_id TEXT NOT NULL,
+			peer_id TEXT NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			key_package BLOB NOT NULL,
+			status TEXT NOT NULL,
+			received_at INTEGER NOT NULL,
+			PRIMARY KEY (group_id, peer_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS group_files (
+			group_id TEXT NOT NULL,
+			file_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			mime_type TEXT NOT NULL DEFAULT '',
+			size INTEGER NOT NULL,
+			chunk_count INTEGER NOT NULL,
+			content_hash TEXT NOT NULL,
+			uploader_peer_id TEXT NOT NULL,
+			announcement BLOB NOT NULL,
+			uploaded_at INTEGER NOT NULL,
+			PRIMARY KEY (group_id, file_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS group_file_chunks (
+			file_id TEXT NOT NULL,
+			chunk_index INTEGER NOT NULL,
+			data BLOB NOT NULL,
+			PRIMARY KEY (file_id, chunk_index)
+		);
+
+		CREATE TABLE IF NOT EXISTS friend_request_nonces (
+			peer_id TEXT NOT NULL,
+			nonce TEXT NOT NULL,
+			seen_at INTEGER NOT NULL,
+			PRIMARY KEY (peer_id, nonce)
+		);
+
+		CREATE TABLE IF NOT EXISTS friend_outbox (
+			peer_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			payload TEXT DEFAULT NULL,
+			PRIMARY KEY (peer_id, kind)
+		);
+
+		CREATE TABLE IF NOT EXISTS friend_introductions (
+			intro_id TEXT PRIMARY KEY NOT NULL,
+			introducer_id TEXT NOT NULL,
+			peer_id TEXT NOT NULL,
+			addrs TEXT NOT NULL DEFAULT '[]',
+			display_name TEXT NOT NULL DEFAULT '',
+			message TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			received_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS contact_notes (
+			peer_id TEXT PRIMARY KEY NOT NULL,
+			note TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS contact_tags (
+			peer_id TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			PRIMARY KEY (peer_id, tag)
+		);
+
+		CREATE TABLE IF NOT EXISTS circles (
+			circle_id TEXT PRIMARY KEY NOT NULL,
+			name TEXT NOT NULL UNIQUE,
+			created_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS circle_members (
+			circle_id TEXT NOT NULL,
+			peer_id TEXT NOT NULL,
+			added_at INTEGER NOT NULL,
+			PRIMARY KEY (circle_id, peer_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS profile_cards (
+			peer_id TEXT PRIMARY KEY NOT NULL,  -- our own card or a friend's
+			display_name TEXT NOT NULL,
+			bio TEXT NOT NULL DEFAULT '',
+			avatar_hash TEXT NOT NULL DEFAULT '',
+			version INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			signed_card BLOB NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS avatars (
+			hash TEXT PRIMARY KEY NOT NULL,     -- hex SHA-256 of content
+			mime_type TEXT NOT NULL,
+			content BLOB NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS presence_rules (
+			subject_type TEXT NOT NULL,         -- 'friend' or 'circle'
+			subject_id TEXT NOT NULL,           -- peer_id or circle_id
+			hide_presence INTEGER NOT NULL DEFAULT 0,
+			hide_last_seen INTEGER NOT NULL DEFAULT 0,
+			updated_at INTEGER NOT NULL,
+			PRIMARY KEY (subject_type, subject_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS handle_claims (
+			handle TEXT PRIMARY KEY NOT NULL,
+			claimed_at INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			owner_peer_id TEXT NOT NULL DEFAULT '',
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS handle_pins (
+			handle TEXT PRIMARY KEY NOT NULL,
+			peer_id TEXT NOT NULL,
+			pinned_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY NOT NULL,
+			value TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS blocked_peers (
+			peer_id TEXT PRIMARY KEY NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			blocked_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS channels (
+			channel_id TEXT PRIMARY KEY NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			owner_peer_id TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			descriptor BLOB NOT NULL,
+			joined_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS channel_authors (
+			channel_id TEXT NOT NULL,
+			peer_id TEXT NOT NULL,
+			PRIMARY KEY (channel_id, peer_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS channel_posts (
+			post_id TEXT PRIMARY KEY NOT NULL,
+			channel_id TEXT NOT NULL,
+			author_peer_id TEXT NOT NULL,
+			message TEXT NOT NULL,
+			timestamp INTEGER NOT NULL,
+			envelope BLOB NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS channel_reactions (
+			channel_id TEXT NOT NULL,
+			post_id TEXT NOT NULL,
+			reactor_peer_id TEXT NOT NULL,
+			reaction TEXT NOT NULL,
+			timestamp INTEGER NOT NULL,
+			envelope BLOB NOT NULL,
+			PRIMARY KEY (post_id, reactor_peer_id, reaction)
+		);
+
 		CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages (recipient_peer_id);
 		CREATE INDEX IF NOT EXISTS idx_relationships_peer_id ON relationships (peer_id);
 		CREATE INDEX IF NOT EXISTS idx_display_names_entity ON display_names (entity_id, entity_type);
@@ -119,10 +375,89 @@ func (db *DB) ensureCreation() error {
 	if err != nil {
 		return fmt.Errorf("failed to execute schema SQL: %w", err)
 	}
+
+	if err := db.ensureColumns(); err != nil {
+		return err
+	}
+
+	indexSQL := `
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_group_messages_message_id ON group_messages (group_id, message_id);
+		CREATE INDEX IF NOT EXISTS idx_group_messages_hash ON group_messages (group_id, message_hash);
+		CREATE INDEX IF NOT EXISTS idx_group_message_edges_parent ON group_message_edges (group_id, parent_hash);
+		CREATE INDEX IF NOT EXISTS idx_channel_posts_channel ON channel_posts (channel_id, timestamp);
+		CREATE INDEX IF NOT EXISTS idx_channel_reactions_channel ON channel_reactions (channel_id);
+		CREATE INDEX IF NOT EXISTS idx_friend_introductions_peer ON friend_introductions (peer_id, status);
+		CREATE INDEX IF NOT EXISTS idx_circle_members_peer ON circle_members (peer_id);
+	`
+
+	_, err = db.sqlDB.Exec(indexSQL)
+	if err != nil {
+		return fmt.Errorf("failed to execute index SQL: %w", err)
+	}
+
 	log.Println("Storage: Schema applied successfully.")
 	return nil
 }
 
+// ensureColumns adds columns introduced after the initial schema to databases
+// created by older versions, since CREATE TABLE IF NOT EXISTS leaves them untouched.
+func (db *DB) ensureColumns() error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"group_messages", "message_id", "TEXT DEFAULT NULL"},
+		{"group_messages", "envelope", "BLOB DEFAULT NULL"},
+		{"group_messages", "message_hash", "TEXT DEFAULT NULL"},
+		{"relationships", "intro_message", "TEXT DEFAULT NULL"},
+		{"relationships", "expires_at", "TEXT DEFAULT NULL"},
+		{"relationships", "request_nonce", "TEXT DEFAULT NULL"},
+		{"relationships", "last_seen", "TEXT DEFAULT NULL"},
+		{"friend_outbox", "payload", "TEXT DEFAULT NULL"},
+	}
+
+	for _, c := range columns {
+		exists, err := db.columnExists(c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		log.Printf("Storage: Adding column %s.%s", c.table, c.column)
+		_, err = db.sqlDB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", c.table, c.column, c.definition))
+		if err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.column, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) columnExists(table, column string) (bool, error) {
+	rows, err := db.sqlDB.Query(fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read table info for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan table info for %s: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
 func (db *DB) Close() error {
 	log.Println("Storage: Closing database connection pool...")
 	if db.sqlDB == nil {
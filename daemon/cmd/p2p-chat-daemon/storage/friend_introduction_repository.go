@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type FriendIntroductionRepository interface {
+	Store(ctx context.Context, intro types.ReceivedIntroduction) error
+	GetById(ctx context.Context, introId string) (types.ReceivedIntroduction, error)
+	GetOpen(ctx context.Context) ([]types.ReceivedIntroduction, error)
+	GetOpenForPeer(ctx context.Context, peerId string) ([]types.ReceivedIntroduction, error)
+	UpdateStatus(ctx context.Context, introId string, status string) error
+}
+
+type sqliteFriendIntroductionRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteFriendIntroductionRepository(database *DB) (FriendIntroductionRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for friend introduction repository")
+	}
+	return &sqliteFriendIntroductionRepository{db: database.GetDB()}, nil
+}
+
+// Store keeps an introduction. A second introduction to the same peer by the same friend
+// replaces the first while it is still open.
+func (r *sqliteFriendIntroductionRepository) Store(ctx context.Context, intro types.ReceivedIntroduction) error {
+	addrsJSON, err := json.Marshal(intro.Addrs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal introduction addresses: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM friend_introductions WHERE introducer_id = ? AND peer_id = ? AND status = ?;`,
+		intro.IntroducerPeerId, intro.PeerId, types.IntroductionStatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to replace introduction of %s: %w", intro.PeerId, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO friend_introductions (intro_id, introducer_id, peer_id, addrs, display_name, message, status, received_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`,
+		intro.IntroId,
+		intro.IntroducerPeerId,
+		intro.PeerId,
+		string(addrsJSON),
+		intro.DisplayName,
+		intro.Message,
+		intro.Status,
+		intro.ReceivedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store introduction %s: %w", intro.IntroId, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit introduction store transaction: %w", err)
+	}
+
+	log.Printf("Storage: Stored introduction of %s by %s", intro.PeerId, intro.IntroducerPeerId)
+	return nil
+}
+
+func (r *sqliteFriendIntroductionRepository) GetById(ctx context.Context, introId string) (types.ReceivedIntroduction, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT intro_id, introducer_id, peer_id, addrs, display_name, message, status, received_at
+		FROM friend_introductions WHERE intro_id = ?;`, introId)
+	if err != nil {
+		return types.ReceivedIntroduction{}, fmt.Errorf("failed to query introduction %s: %w", introId, err)
+	}
+	defer rows.Close()
+
+	intros, err := scanIntroductions(rows)
+	if err != nil {
+		return types.ReceivedIntroduction{}, err
+	}
+	if len(intros) == 0 {
+		return types.ReceivedIntroduction{}, sql.ErrNoRows
+	}
+
+	return intros[0], nil
+}
+
+// GetOpen returns introductions that were neither acted on nor dismissed, including those
+// waiting to auto-accept.
+func (r *sqliteFriendIntroductionRepository) GetOpen(ctx context.Context) ([]types.ReceivedIntroduction, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT intro_id, introducer_id, peer_id, addrs, display_name, message, status, received_at
+		FROM friend_introductions WHERE status IN (?, ?)
+		ORDER BY received_at DESC;`,
+		types.IntroductionStatusPending, types.IntroductionStatusAutoAccept,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query introductions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIntroductions(rows)
+}
+
+func (r *sqliteFriendIntroductionRepository) GetOpenForPeer(ctx context.Context, peerId string) ([]types.ReceivedIntroduction, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT intro_id, introducer_id, peer_id, addrs, display_name, message, status, received_at
+		FROM friend_introductions WHERE peer_id = ? AND status IN (?, ?)
+		ORDER BY received_at DESC;`,
+		peerId, types.IntroductionStatusPending, types.IntroductionStatusAutoAccept,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query introductions of %s: %w", peerId, err)
+	}
+	defer rows.Close()
+
+	return scanIntroductions(rows)
+}
+
+func (r *sqliteFriendIntroductionRepository) UpdateStatus(ctx context.Context, introId string, status string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE friend_introductions SET status = ? WHERE intro_id = ?;`, status, introId)
+	if err != nil {
+		return fmt.Errorf("failed to update introduction %s: %w", introId, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check updated introduction %s: %w", introId, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func scanIntroductions(rows *sql.Rows) ([]types.ReceivedIntroduction, error) {
+	intros := []types.ReceivedIntroduction{}
+	for rows.Next() {
+		var intro types.ReceivedIntroduction
+		var addrsJSON string
+		var receivedAt int64
+		if err := rows.Scan(
+			&intro.IntroId,
+			&intro.IntroducerPeerId,
+			&intro.PeerId,
+			&addrsJSON,
+			&intro.DisplayName,
+			&intro.Message,
+			&intro.Status,
+			&receivedAt,
+		); err != nil {
+			log.Printf("Storage: Error scanning introduction row: %v", err)
+			continue
+		}
+		if err := json.Unmarshal([]byte(addrsJSON), &intro.Addrs); err != nil {
+			log.Printf("Storage: Error decoding addresses of introduction %s: %v", intro.IntroId, err)
+		}
+		intro.ReceivedAt = time.Unix(receivedAt, 0)
+		intros = append(intros, intro)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over introductions: %w", err)
+	}
+
+	return intros, nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type FriendOutboxRepository interface {
+	Enqueue(ctx context.Context, peerId string, kind string, payload string) error
+	GetAll(ctx context.Context) ([]types.FriendOutboxEntry, error)
+	GetByPeer(ctx context.Context, peerId string) ([]types.FriendOutboxEntry, error)
+	RecordFailure(ctx context.Context, peerId string, kind string, attempts int, nextAttemptAt time.Time) error
+	Delete(ctx context.Context, peerId string, kind string) error
+}
+
+type sqliteFriendOutboxRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteFriendOutboxRepository(database *DB) (FriendOutboxRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for friend outbox repository")
+	}
+	return &sqliteFriendOutboxRepository{db: database.GetDB()}, nil
+}
+
+// Enqueue queues a message for a peer, due immediately. Queueing a message that is already
+// waiting starts it over, with no backoff and the new payload.
+func (r *sqliteFriendOutboxRepository) Enqueue(ctx context.Context, peerId string, kind string, payload string) error {
+	sqlStmt := `
+		INSERT INTO friend_outbox (peer_id, kind, attempts, next_attempt_at, created_at, payload)
+		VALUES (?, ?, 0, ?, ?, ?)
+		ON CONFLICT(peer_id, kind) DO UPDATE SET
+			attempts = 0,
+			next_attempt_at = excluded.next_attempt_at,
+			created_at = excluded.created_at,
+			payload = excluded.payload;
+	`
+
+	now := time.Now().Unix()
+	if _, err := r.db.ExecContext(ctx, sqlStmt, peerId, kind, now, now, nullIfEmpty(payload)); err != nil {
+		return fmt.Errorf("failed to queue friend %s for %s: %w", kind, peerId, err)
+	}
+	return nil
+}
+
+func (r *sqliteFriendOutboxRepository) GetAll(ctx context.Context) ([]types.FriendOutboxEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT peer_id, kind, attempts, next_attempt_at, created_at, payload
+		FROM friend_outbox ORDER BY next_attempt_at;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query friend outbox: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFriendOutboxEntries(rows)
+}
+
+func (r *sqliteFriendOutboxRepository) GetByPeer(ctx context.Context, peerId string) ([]types.FriendOutboxEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT peer_id, kind, attempts, next_attempt_at, created_at, payload
+		FROM friend_outbox WHERE peer_id = ?;`, peerId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query friend outbox for %s: %w", peerId, err)
+	}
+	defer rows.Close()
+
+	return scanFriendOutboxEntries(rows)
+}
+
+func (r *sqliteFriendOutboxRepository) RecordFailure(ctx context.Context, peerId string, kind string, attempts int, nextAttemptAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE friend_outbox SET attempts = ?, next_attempt_at = ? WHERE peer_id = ? AND kind = ?;`,
+		attempts, nextAttemptAt.Unix(), peerId, kind,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record friend %s attempt for %s: %w", kind, peerId, err)
+	}
+	return nil
+}
+
+func (r *sqliteFriendOutboxRepository) Delete(ctx context.Context, peerId string, kind string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM friend_outbox WHERE peer_id = ? AND kind = ?;`, peerId, kind); err != nil {
+		return fmt.Errorf("failed to delete friend %s for %s: %w", kind, peerId, err)
+	}
+	return nil
+}
+
+func scanFriendOutboxEntries(rows *sql.Rows) ([]types.FriendOutboxEntry, error) {
+	var entries []types.FriendOutboxEntry
+	for rows.Next() {
+		var entry types.FriendOutboxEntry
+		var nextAttemptAt, createdAt int64
+		var payload sql.NullString
+		if err := rows.Scan(&entry.PeerId, &entry.Kind, &entry.Attempts, &nextAttemptAt, &createdAt, &payload); err != nil {
+			log.Printf("Storage: Error scanning friend outbox row: %v", err)
+			continue
+		}
+		entry.NextAttemptAt = time.Unix(nextAttemptAt, 0)
+		entry.CreatedAt = time.Unix(createdAt, 0)
+		entry.Payload = payload.String
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over friend outbox: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"strings"
+	"time"
+)
+
+type GroupDirectoryRepository interface {
+	Publish(ctx context.Context, groupID string) error
+	Unpublish(ctx context.Context, groupID string) error
+	IsPublished(ctx context.Context, groupID string) (bool, error)
+	GetPublished(ctx context.Context) ([]string, error)
+	StoreListing(ctx context.Context, listing types.GroupDirectoryListing) error
+	GetListing(ctx context.Context, groupID string) (*types.GroupDirectoryListing, error)
+	SearchListings(ctx context.Context, query string) ([]types.GroupDirectoryListing, error)
+	StoreJoinRequest(ctx context.Context, request types.GroupJoinRequest) error
+	GetJoinRequest(ctx context.Context, groupID string, peerID string) (*types.GroupJoinRequest, error)
+	GetJoinRequests(ctx context.Context, groupID string, status string) ([]types.GroupJoinRequest, error)
+	UpdateJoinRequestStatus(ctx context.Context, groupID string, peerID string, status string) error
+}
+
+type sqliteGroupDirectoryRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteGroupDirectoryRepository(database *DB) (GroupDirectoryRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for group directory repository")
+	}
+	return &sqliteGroupDirectoryRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqliteGroupDirectoryRepository) Publish(ctx context.Context, groupID string) error {
+	sqlStmt := `INSERT OR IGNORE INTO published_groups (group_id, published_at) VALUES (?, ?);`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, groupID, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to publish group %s: %w", groupID, err)
+	}
+
+	log.Printf("Storage: Published group %s to the directory", groupID)
+	return nil
+}
+
+func (r *sqliteGroupDirectoryRepository) Unpublish(ctx context.Context, groupID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM published_groups WHERE group_id = ?;`, groupID); err != nil {
+		return fmt.Errorf("failed to unpublish group %s: %w", groupID, err)
+	}
+
+	log.Printf("Storage: Unpublished group %s from the directory", groupID)
+	return nil
+}
+
+func (r *sqliteGroupDirectoryRepository) IsPublished(ctx context.Context, groupID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM published_groups WHERE group_id = ?);`, groupID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether group %s is published: %w", groupID, err)
+	}
+	return exists, nil
+}
+
+func (r *sqliteGroupDirectoryRepository) GetPublished(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT group_id FROM published_groups ORDER BY published_at;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query published groups: %w", err)
+	}
+	defer rows.Close()
+
+	groupIDs := []string{}
+	for rows.Next() {
+		var groupID string
+		if err := rows.Scan(&groupID); err != nil {
+			log.Printf("Storage: Error scanning published group row: %v", err)
+			continue
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating published group rows: %w", err)
+	}
+
+	return groupIDs, nil
+}
+
+// StoreListing caches a descriptor found in the directory, replacing any older one.
+func (r *sqliteGroupDirectoryRepository) StoreListing(ctx context.Context, listing types.GroupDirectoryListing) error {
+	sqlStmt := `
+		INSERT INTO directory_groups (group_id, name, description, topic, member_count, publisher_peer_id, descriptor, seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (group_id) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			topic = excluded.topic,
+			member_count = excluded.member_count,
+			publisher_peer_id = excluded.publisher_peer_id,
+			descriptor = excluded.descriptor,
+			seen_at = excluded.seen_at;
+	`
+
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		listing.GroupId,
+		listing.Name,
+		listing.Description,
+		listing.Topic,
+		listing.MemberCount,
+		listing.PublisherPeerId,
+		listing.Descriptor,
+		listing.SeenAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store directory listing of group %s: %w", listing.GroupId, err)
+	}
+
+	return nil
+}
+
+func (r *sqliteGroupDirectoryRepository) GetListing(ctx context.Context, groupID string) (*types.GroupDirectoryListing, error) {
+	sqlStmt := `
+		SELECT group_id, name, description, topic, member_count, publisher_peer_id, descriptor, seen_at
+		FROM directory_groups
+		WHERE group_id = ?;
+	`
+
+	listing, err := scanGroupDirectoryListing(r.db.QueryRowContext(ctx, sqlStmt, groupID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get directory listing of group %s: %w", groupID, err)
+	}
+
+	return listing, nil
+}
+
+// SearchListings returns cached listings whose name or description contains the query,
+// most recently seen first. An empty query matches every listing.
+func (r *sqliteGroupDirectoryRepository) SearchListings(ctx context.Context, query string) ([]types.GroupDirectoryListing, error) {
+	sqlStmt := `
+		SELECT group_id, name, description, topic, member_count, publisher_peer_id, descriptor, seen_at
+		FROM directory_groups
+		WHERE name LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\'
+		ORDER BY seen_at DESC;
+	`
+
+	pattern := "%" + escapeLike(query) + "%"
+	rows, err := r.db.QueryContext(ctx, sqlStmt, pattern, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search directory listings: %w", err)
+	}
+	defer rows.Close()
+
+	listings := []types.GroupDirectoryListing{}
+	for rows.Next() {
+		listing, err := scanGroupDirectoryListing(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning directory listing row: %v", err)
+			continue
+		}
+		listings = append(listings, *listing)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating directory listing rows: %w", err)
+	}
+
+	return listings, nil
+}
+
+// StoreJoinRequest records a request to join a group, resetting an earlier one from the
+// same peer back to the request's status.
+func (r *sqliteGroupDirectoryRepository) StoreJoinRequest(ctx context.Context, request types.GroupJoinRequest) error {
+	sqlStmt := `
+		INSERT INTO group_join_requests (group_id, peer_id, message, key_package, status, received_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (group_id, peer_id) DO UPDATE SET
+			message = excluded.message,
+			key_package = excluded.key_package,
+			status = excluded.status,
+			received_at = excluded.received_at;
+	`
+
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		request.GroupId,
+		request.PeerId,
+		request.Message,
+		request.KeyPackage,
+		request.Status,
+		request.ReceivedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store join request of %s for group %s: %w", request.PeerId, request.GroupId, err)
+	}
+
+	log.Printf("Storage: Stored join request of %s for group %s", request.PeerId, request.GroupId)
+	return nil
+}
+
+func (r *sqliteGroupDirectoryRepository) GetJoinRequest(ctx context.Context, groupID string, peerID string) (*types.GroupJoinRequest, error) {
+	sqlStmt := `
+		SELECT group_id, peer_id, message, key_package, status, received_at
+		FROM group_join_requests
+		WHERE group_id = ? AND peer_id = ?;
+	`
+
+	request, err := scanGroupJoinRequest(r.db.QueryRowContext(ctx, sqlStmt, groupID, peerID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get join request of %s for group %s: %w", peerID, groupID, err)
+	}
+
+	return request, nil
+}
+
+// GetJoinRequests returns the join requests with the given status, of one group or of
+// every group if groupID is empty, oldest first.
+func (r *sqliteGroupDirectoryRepository) GetJoinRequests(ctx context.Context, groupID string, status string) ([]types.GroupJoinRequest, error) {
+	sqlStmt := `
+		SELECT group_id, peer_id, message, key_package, status, received_at
+		FROM group_join_requests
+		WHERE status = ? AND (? = '' OR group_id = ?)
+		ORDER BY received_at;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt, status, groupID, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query join requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []types.GroupJoinRequest{}
+	for rows.Next() {
+		request, err := scanGroupJoinRequest(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning join request row: %v", err)
+			continue
+		}
+		requests = append(requests, *request)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating join request rows: %w", err)
+	}
+
+	return requests, nil
+}
+
+func (r *sqliteGroupDirectoryRepository) UpdateJoinRequestStatus(ctx context.Context, groupID string, peerID string, status string) error {
+	sqlStmt := `UPDATE group_join_requests SET status = ? WHERE group_id = ? AND peer_id = ?;`
+
+	res, err := r.db.ExecContext(ctx, sqlStmt, status, groupID, peerID)
+	if err != nil {
+		return fmt.Errorf("failed to update join request of %s for group %s: %w", peerID, groupID, err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	log.Printf("Storage: Join request of %s for group %s is now %s", peerID, groupID, status)
+	return nil
+}
+
+func scanGroupDirectoryListing(row interface{ Scan(dest ...any) error }) (*types.GroupDirectoryListing, error) {
+	var listing types.GroupDirectoryListing
+	var seenAtUnix int64
+
+	err := row.Scan(
+		&listing.GroupId,
+		&listing.Name,
+		&listing.Description,
+		&listing.Topic,
+		&listing.MemberCount,
+		&listing.PublisherPeerId,
+		&listing.Descriptor,
+		&seenAtUnix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	listing.SeenAt = time.Unix(seenAtUnix, 0)
+
+	return &listing, nil
+}
+
+func scanGroupJoinRequest(row interface{ Scan(dest ...any) error }) (*types.GroupJoinRequest, error) {
+	var request types.GroupJoinRequest
+	var receivedAtUnix int64
+
+	err := row.Scan(
+		&request.GroupId,
+		&request.PeerId,
+		&request.Message,
+		&request.KeyPackage,
+		&request.Status,
+		&receivedAtUnix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	request.ReceivedAt = time.Unix(receivedAtUnix, 0)
+
+	return &request, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type GroupFileRepository interface {
+	StoreFile(ctx context.Context, file types.GroupFile, announcement []byte) (bool, error)
+	GetFile(ctx context.Context, groupID string, fileID string) (*types.GroupFile, error)
+	GetFiles(ctx context.Context, groupID string) ([]types.GroupFile, error)
+	GetAnnouncement(ctx context.Context, groupID string, fileID string) ([]byte, error)
+	StoreChunk(ctx context.Context, fileID string, index int, data []byte) error
+	GetChunk(ctx context.Context, fileID string, index int) ([]byte, error)
+	GetChunkIndexes(ctx context.Context, fileID string) ([]int, error)
+}
+
+type sqliteGroupFileRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteGroupFileRepository(database *DB) (GroupFileRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for group file repository")
+	}
+	return &sqliteGroupFileRepository{db: database.GetDB()}, nil
+}
+
+// StoreFile records an announced file. It reports false if the file was already known.
+func (r *sqliteGroupFileRepository) StoreFile(ctx context.Context, file types.GroupFile, announcement []byte) (bool, error) {
+	sqlStmt := `
+		INSERT OR IGNORE INTO group_files (group_id, file_id, name, mime_type, size, chunk_count, content_hash, uploader_peer_id, announcement, uploaded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`
+
+	res, err := r.db.ExecContext(ctx, sqlStmt,
+		file.GroupId,
+		file.FileId,
+		file.Name,
+		file.MimeType,
+		file.Size,
+		file.ChunkCount,
+		file.ContentHash,
+		file.UploaderPeerId,
+		announcement,
+		file.UploadedAt.Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to store file %s of group %s: %w", file.FileId, file.GroupId, err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected > 0 {
+		log.Printf("Storage: Stored file %s of group %s", file.FileId, file.GroupId)
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *sqliteGroupFileRepository) GetFile(ctx context.Context, groupID string, fileID string) (*types.GroupFile, error) {
+	sqlStmt := `
+		SELECT f.file_id, f.group_id, f.name, f.mime_type, f.size, f.chunk_count, f.content_hash, f.uploader_peer_id, f.uploaded_at,
+			(SELECT COUNT(*) FROM group_file_chunks c WHERE c.file_id = f.file_id)
+		FROM group_files f
+		WHERE f.group_id = ? AND f.file_id = ?;
+	`
+
+	file, err := scanGroupFile(r.db.QueryRowContext(ctx, sqlStmt, groupID, fileID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get file %s of group %s: %w", fileID, groupID, err)
+	}
+
+	return file, nil
+}
+
+// GetFiles returns the library of a group, newest first.
+func (r *sqliteGroupFileRepository) GetFiles(ctx context.Context, groupID string) ([]types.GroupFile, error) {
+	sqlStmt := `
+		SELECT f.file_id, f.group_id, f.name, f.mime_type, f.size, f.chunk_count, f.content_hash, f.uploader_peer_id, f.uploaded_at,
+			(SELECT COUNT(*) FROM group_file_chunks c WHERE c.file_id = f.file_id)
+		FROM group_files f
+		WHERE f.group_id = ?
+		ORDER BY f.uploaded_at DESC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query files of group %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	files := []types.GroupFile{}
+	for rows.Next() {
+		file, err := scanGroupFile(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning group file row: %v", err)
+			continue
+		}
+		files = append(files, *file)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group file rows: %w", err)
+	}
+
+	return files, nil
+}
+
+func (r *sqliteGroupFileRepository) GetAnnouncement(ctx context.Context, groupID string, fileID string) ([]byte, error) {
+	var announcement []byte
+	err := r.db.QueryRowContext(ctx, `SELECT announcement FROM group_files WHERE group_id = ? AND file_id = ?;`, groupID, fileID).Scan(&announcement)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get announcement of file %s: %w", fileID, err)
+	}
+	return announcement, nil
+}
+
+func (r *sqliteGroupFileRepository) StoreChunk(ctx context.Context, fileID string, index int, data []byte) error {
+	sqlStmt := `INSERT OR IGNORE INTO group_file_chunks (file_id, chunk_index, data) VALUES (?, ?, ?);`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, fileID, index, data); err != nil {
+		return fmt.Errorf("failed to store chunk %d of file %s: %w", index, fileID, err)
+	}
+	return nil
+}
+
+func (r *sqliteGroupFileRepository) GetChunk(ctx context.Context, fileID string, index int) ([]byte, error) {
+	var data []byte
+	err := r.db.QueryRowContext(ctx, `SELECT data FROM group_file_chunks WHERE file_id = ? AND chunk_index = ?;`, fileID, index).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get chunk %d of file %s: %w", index, fileID, err)
+	}
+	return data, nil
+}
+
+// GetChunkIndexes returns the indexes of the chunks of a file we hold, in order.
+func (r *sqliteGroupFileRepository) GetChunkIndexes(ctx context.Context, fileID string) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT chunk_index FROM group_file_chunks WHERE file_id = ? ORDER BY chunk_index;`, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks of file %s: %w", fileID, err)
+	}
+	defer rows.Close()
+
+	indexes := []int{}
+	for rows.Next() {
+		var index int
+		if err := rows.Scan(&index); err != nil {
+			log.Printf("Storage: Error scanning chunk index row: %v", err)
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk index rows: %w", err)
+	}
+
+	return indexes, nil
+}
+
+func scanGroupFile(row interface{ Scan(dest ...any) error }) (*types.GroupFile, error) {
+	var file types.GroupFile
+	var uploadedAtUnix int64
+
+	err := row.Scan(
+		&file.FileId,
+		&file.GroupId,
+		&file.Name,
+		&file.MimeType,
+		&file.Size,
+		&file.ChunkCount,
+		&file.ContentHash,
+		&file.UploaderPeerId,
+		&uploadedAtUnix,
+		&file.AvailableChunks,
+	)
+	if err != nil {
+		return nil, err
+	}
+	file.UploadedAt = time.Unix(uploadedAtUnix, 0)
+	file.Complete = file.AvailableChunks >= file.ChunkCount
+
+	return &file, nil
+}
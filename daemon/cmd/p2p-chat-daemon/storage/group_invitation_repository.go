@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type GroupInvitationRepository interface {
+	Store(ctx context.Context, invitation types.GroupInvitation) error
+	Replace(ctx context.Context, invitation types.GroupInvitation) error
+	GetByGroupId(ctx context.Context, groupID string) (*types.GroupInvitation, error)
+	GetPending(ctx context.Context) ([]types.GroupInvitation, error)
+	UpdateStatus(ctx context.Context, groupID string, status string) error
+}
+
+type sqliteGroupInvitationRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteGroupInvitationRepository(database *DB) (GroupInvitationRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for group invitation repository")
+	}
+	return &sqliteGroupInvitationRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqliteGroupInvitationRepository) Store(ctx context.Context, invitation types.GroupInvitation) error {
+	return r.store(ctx, "INSERT OR IGNORE", invitation)
+}
+
+// Replace stores an invitation, overwriting any earlier one to the same group.
+func (r *sqliteGroupInvitationRepository) Replace(ctx context.Context, invitation types.GroupInvitation) error {
+	return r.store(ctx, "REPLACE", invitation)
+}
+
+func (r *sqliteGroupInvitationRepository) store(ctx context.Context, verb string, invitation types.GroupInvitation) error {
+	membersBytes, err := json.Marshal(invitation.MemberPeers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal members of invitation %s: %w", invitation.GroupId, err)
+	}
+
+	receivedAt := invitation.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	sqlStmt := verb + ` INTO group_invitations (group_id, inviter_peer_id, name, members, status, received_at)
+		VALUES (?, ?, ?, ?, ?, ?);
+	`
+
+	_, err = r.db.ExecContext(ctx, sqlStmt,
+		invitation.GroupId,
+		invitation.InviterPeerId,
+		invitation.Name,
+		string(membersBytes),
+		invitation.Status,
+		receivedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store invitation to group %s from %s: %w", invitation.GroupId, invitation.InviterPeerId, err)
+	}
+
+	log.Printf("Storage: Stored invitation to group %s from %s", invitation.GroupId, invitation.InviterPeerId)
+	return nil
+}
+
+func (r *sqliteGroupInvitationRepository) GetByGroupId(ctx context.Context, groupID string) (*types.GroupInvitation, error) {
+	sqlStmt := `
+		SELECT group_id, inviter_peer_id, name, members, status, received_at
+		FROM group_invitations
+		WHERE group_id = ?;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation to group %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get invitation to group %s: %w", groupID, err)
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	invitation, err := scanGroupInvitation(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan invitation to group %s: %w", groupID, err)
+	}
+
+	return &invitation, nil
+}
+
+func (r *sqliteGroupInvitationRepository) GetPending(ctx context.Context) ([]types.GroupInvitation, error) {
+	sqlStmt := `
+		SELECT group_id, inviter_peer_id, name, members, status, received_at
+		FROM group_invitations
+		WHERE status = ?
+		ORDER BY received_at ASC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt, types.GroupInvitationPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending group invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []types.GroupInvitation
+	for rows.Next() {
+		invitation, err := scanGroupInvitation(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning group invitation row: %v", err)
+			continue
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group invitation rows: %w", err)
+	}
+
+	return invitations, nil
+}
+
+func (r *sqliteGroupInvitationRepository) UpdateStatus(ctx context.Context, groupID string, status string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE group_invitations SET status = ? WHERE group_id = ?;`, status, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to update invitation to group %s: %w", groupID, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func scanGroupInvitation(rows *sql.Rows) (types.GroupInvitation, error) {
+	var invitation types.GroupInvitation
+	var membersJSON string
+	var receivedAtUnix int64
+
+	err := rows.Scan(
+		&invitation.GroupId,
+		&invitation.InviterPeerId,
+		&invitation.Name,
+		&membersJSON,
+		&invitation.Status,
+		&receivedAtUnix,
+	)
+	if err != nil {
+		return types.GroupInvitation{}, err
+	}
+
+	if err := json.Unmarshal([]byte(membersJSON), &invitation.MemberPeers); err != nil {
+		return types.GroupInvitation{}, fmt.Errorf("failed to parse members of invitation %s: %w", invitation.GroupId, err)
+	}
+	invitation.ReceivedAt = time.Unix(receivedAtUnix, 0)
+
+	return invitation, nil
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type GroupInviteRepository interface {
+	Store(ctx context.Context, invite types.GroupInvite) error
+	Get(ctx context.Context, tokenID string) (*types.GroupInvite, error)
+	GetByGroup(ctx context.Context, groupID string) ([]types.GroupInvite, error)
+	RecordUse(ctx context.Context, invite types.GroupInvite) (bool, error)
+	Revoke(ctx context.Context, tokenID string, groupID string) error
+}
+
+type sqliteGroupInviteRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteGroupInviteRepository(database *DB) (GroupInviteRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for group invite repository")
+	}
+	return &sqliteGroupInviteRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqliteGroupInviteRepository) Store(ctx context.Context, invite types.GroupInvite) error {
+	sqlStmt := `
+		INSERT OR IGNORE INTO group_invites (token_id, group_id, issuer_peer_id, single_use, expires_at, uses, revoked, token, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`
+
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		invite.TokenId,
+		invite.GroupId,
+		invite.IssuerPeerId,
+		invite.SingleUse,
+		invite.ExpiresAt.Unix(),
+		invite.Uses,
+		invite.Revoked,
+		invite.Token,
+		time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store invite %s of group %s: %w", invite.TokenId, invite.GroupId, err)
+	}
+
+	log.Printf("Storage: Stored invite %s of group %s", invite.TokenId, invite.GroupId)
+	return nil
+}
+
+func (r *sqliteGroupInviteRepository) Get(ctx context.Context, tokenID string) (*types.GroupInvite, error) {
+	sqlStmt := `
+		SELECT token_id, group_id, issuer_peer_id, single_use, expires_at, uses, revoked, token, created_at
+		FROM group_invites
+		WHERE token_id = ?;
+	`
+
+	invite, err := scanGroupInvite(r.db.QueryRowContext(ctx, sqlStmt, tokenID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get invite %s: %w", tokenID, err)
+	}
+
+	return invite, nil
+}
+
+// GetByGroup returns every token of a group we know the contents of, newest first.
+func (r *sqliteGroupInviteRepository) GetByGroup(ctx context.Context, groupID string) ([]types.GroupInvite, error) {
+	sqlStmt := `
+		SELECT token_id, group_id, issuer_peer_id, single_use, expires_at, uses, revoked, token, created_at
+		FROM group_invites
+		WHERE group_id = ? AND token IS NOT NULL
+		ORDER BY created_at DESC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invites of group %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	invites := []types.GroupInvite{}
+	for rows.Next() {
+		invite, err := scanGroupInvite(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning group invite row: %v", err)
+			continue
+		}
+		invites = append(invites, *invite)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group invite rows: %w", err)
+	}
+
+	return invites, nil
+}
+
+// RecordUse counts a redemption of a token. It reports false, without counting, if the
+// token was revoked or is single-use and already redeemed.
+func (r *sqliteGroupInviteRepository) RecordUse(ctx context.Context, invite types.GroupInvite) (bool, error) {
+	if err := r.Store(ctx, invite); err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE group_invites
+		SET uses = uses + 1
+		WHERE token_id = ? AND revoked = 0 AND (single_use = 0 OR uses = 0);
+	`, invite.TokenId)
+	if err != nil {
+		return false, fmt.Errorf("failed to record use of invite %s: %w", invite.TokenId, err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// Revoke marks a token as revoked, remembering it even if we never saw the token itself.
+// A token of another group is left alone, so an admin of one group cannot revoke invites
+// to another.
+func (r *sqliteGroupInviteRepository) Revoke(ctx context.Context, tokenID string, groupID string) error {
+	sqlStmt := `
+		INSERT INTO group_invites (token_id, group_id, revoked, created_at)
+		VALUES (?, ?, 1, ?)
+		ON CONFLICT (token_id) DO UPDATE SET revoked = 1
+		WHERE group_invites.group_id = excluded.group_id;
+	`
+
+	res, err := r.db.ExecContext(ctx, sqlStmt, tokenID, groupID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite %s: %w", tokenID, err)
+	}
+
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("invite %s does not belong to group %s", tokenID, groupID)
+	}
+
+	log.Printf("Storage: Revoked invite %s of group %s", tokenID, groupID)
+	return nil
+}
+
+func scanGroupInvite(row interface{ Scan(dest ...any) error }) (*types.GroupInvite, error) {
+	var invite types.GroupInvite
+	var expiresAtUnix, createdAtUnix int64
+
+	err := row.Scan(
+		&invite.TokenId,
+		&invite.GroupId,
+		&invite.IssuerPeerId,
+		&invite.SingleUse,
+		&expiresAtUnix,
+		&invite.Uses,
+		&invite.Revoked,
+		&invite.Token,
+		&createdAtUnix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	invite.ExpiresAt = time.Unix(expiresAtUnix, 0)
+	invite.CreatedAt = time.Unix(createdAtUnix, 0)
+
+	return &invite, nil
+}
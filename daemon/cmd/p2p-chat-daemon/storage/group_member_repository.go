@@ -12,12 +12,17 @@ type GroupMemberRepository interface {
 	AddMembers(ctx context.Context, groupID string, peerIDs []string) error
 	GetGroupsWithMembers(ctx context.Context) (map[string][]string, error)
 	GetGroups(ctx context.Context) ([]GroupInfo, error)
+	GetMembers(ctx context.Context, groupID string) ([]string, error)
+	IsMember(ctx context.Context, groupID string, peerID string) (bool, error)
+	RemoveMember(ctx context.Context, groupID string, peerID string) error
 }
 
 type GroupInfo struct {
-	GroupID string   `json:"group_id"`
-	Members []string `json:"members"`
-	Name    string   `json:"name"`
+	GroupID     string   `json:"group_id"`
+	Members     []string `json:"members"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	AvatarHash  string   `json:"avatar_hash"`
 }
 
 type sqliteGroupMemberRepository struct {
@@ -100,6 +105,52 @@ func (r *sqliteGroupMemberRepository) GetGroupsWithMembers(ctx context.Context)
 	return result, nil
 }
 
+func (r *sqliteGroupMemberRepository) GetMembers(ctx context.Context, groupID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT DISTINCT peer_id FROM group_members WHERE group_id = ? ORDER BY peer_id", groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query members of group %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	var members []string
+	for rows.Next() {
+		var peerID string
+		if err := rows.Scan(&peerID); err != nil {
+			return nil, fmt.Errorf("failed to scan member row of group %s: %w", groupID, err)
+		}
+		members = append(members, peerID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating members of group %s: %w", groupID, err)
+	}
+
+	return members, nil
+}
+
+func (r *sqliteGroupMemberRepository) IsMember(ctx context.Context, groupID string, peerID string) (bool, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(1) FROM group_members WHERE group_id = ? AND peer_id = ?",
+		groupID, peerID,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check membership of %s in group %s: %w", peerID, groupID, err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *sqliteGroupMemberRepository) RemoveMember(ctx context.Context, groupID string, peerID string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM group_members WHERE group_id = ? AND peer_id = ?", groupID, peerID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member %s from group %s: %w", peerID, groupID, err)
+	}
+
+	log.Printf("Storage: Removed member %s from group %s", peerID, groupID)
+	return nil
+}
+
 func (r *sqliteGroupMemberRepository) GetGroups(ctx context.Context) ([]GroupInfo, error) {
 	groupsMap, err := r.GetGroupsWithMembers(ctx)
 	if err != nil {
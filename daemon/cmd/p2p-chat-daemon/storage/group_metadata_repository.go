@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type GroupMetadataRepository interface {
+	Store(ctx context.Context, metadata types.GroupMetadata) error
+	Get(ctx context.Context, groupID string) (*types.GroupMetadata, error)
+	GetAll(ctx context.Context) (map[string]types.GroupMetadata, error)
+}
+
+type sqliteGroupMetadataRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteGroupMetadataRepository(database *DB) (GroupMetadataRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for group metadata repository")
+	}
+	return &sqliteGroupMetadataRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqliteGroupMetadataRepository) Store(ctx context.Context, metadata types.GroupMetadata) error {
+	sqlStmt := `
+		REPLACE INTO group_metadata (group_id, name, description, avatar_hash, version, updated_by, updated_at, update_hash, signed_update)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`
+
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		metadata.GroupId,
+		metadata.Name,
+		metadata.Description,
+		metadata.AvatarHash,
+		metadata.Version,
+		metadata.UpdatedBy,
+		metadata.UpdatedAt.Unix(),
+		metadata.Hash,
+		metadata.Update,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store metadata of group %s: %w", metadata.GroupId, err)
+	}
+
+	log.Printf("Storage: Stored metadata version %d of group %s", metadata.Version, metadata.GroupId)
+	return nil
+}
+
+func (r *sqliteGroupMetadataRepository) Get(ctx context.Context, groupID string) (*types.GroupMetadata, error) {
+	sqlStmt := `
+		SELECT group_id, name, description, avatar_hash, version, updated_by, updated_at, update_hash, signed_update
+		FROM group_metadata
+		WHERE group_id = ?;
+	`
+
+	metadata, err := scanGroupMetadata(r.db.QueryRowContext(ctx, sqlStmt, groupID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get metadata of group %s: %w", groupID, err)
+	}
+
+	return metadata, nil
+}
+
+func (r *sqliteGroupMetadataRepository) GetAll(ctx context.Context) (map[string]types.GroupMetadata, error) {
+	sqlStmt := `
+		SELECT group_id, name, description, avatar_hash, version, updated_by, updated_at, update_hash, signed_update
+		FROM group_metadata;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group metadata: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]types.GroupMetadata)
+	for rows.Next() {
+		metadata, err := scanGroupMetadata(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning group metadata row: %v", err)
+			continue
+		}
+		result[metadata.GroupId] = *metadata
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group metadata rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func scanGroupMetadata(row interface{ Scan(dest ...any) error }) (*types.GroupMetadata, error) {
+	var metadata types.GroupMetadata
+	var updatedAtUnix int64
+
+	err := row.Scan(
+		&metadata.GroupId,
+		&metadata.Name,
+		&metadata.Description,
+		&metadata.AvatarHash,
+		&metadata.Version,
+		&metadata.UpdatedBy,
+		&updatedAtUnix,
+		&metadata.Hash,
+		&metadata.Update,
+	)
+	if err != nil {
+		return nil, err
+	}
+	metadata.UpdatedAt = time.Unix(updatedAtUnix, 0)
+
+	return &metadata, nil
+}
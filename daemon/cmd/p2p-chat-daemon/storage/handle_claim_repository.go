@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type HandleClaimRepository interface {
+	Save(ctx context.Context, claim types.HandleClaim) error
+	UpdateStatus(ctx context.Context, handle string, status string, ownerPeerId string) error
+	Delete(ctx context.Context, handle string) error
+	GetByHandle(ctx context.Context, handle string) (types.HandleClaim, error)
+	GetAll(ctx context.Context) ([]types.HandleClaim, error)
+	GetPin(ctx context.Context, handle string) (types.HandlePin, error)
+	SavePin(ctx context.Context, pin types.HandlePin) error
+}
+
+type sqliteHandleClaimRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteHandleClaimRepository(database *DB) (HandleClaimRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for handle claim repository")
+	}
+	return &sqliteHandleClaimRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqliteHandleClaimRepository) Save(ctx context.Context, claim types.HandleClaim) error {
+	sqlStmt := `
+		INSERT INTO handle_claims (handle, claimed_at, status, owner_peer_id, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(handle) DO UPDATE SET
+			status = excluded.status,
+			owner_peer_id = excluded.owner_peer_id,
+			updated_at = excluded.updated_at;
+	`
+
+	_, err := r.db.ExecContext(ctx, sqlStmt, claim.Handle, claim.ClaimedAt.Unix(), claim.Status, claim.OwnerPeerId, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save handle claim %s: %w", claim.Handle, err)
+	}
+
+	log.Printf("Storage: Saved claim on handle %s", claim.Handle)
+	return nil
+}
+
+func (r *sqliteHandleClaimRepository) UpdateStatus(ctx context.Context, handle string, status string, ownerPeerId string) error {
+	sqlStmt := `UPDATE handle_claims SET status = ?, owner_peer_id = ?, updated_at = ? WHERE handle = ?;`
+
+	result, err := r.db.ExecContext(ctx, sqlStmt, status, ownerPeerId, time.Now().Unix(), handle)
+	if err != nil {
+		return fmt.Errorf("failed to update claim on handle %s: %w", handle, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check updated claim on handle %s: %w", handle, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *sqliteHandleClaimRepository) Delete(ctx context.Context, handle string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM handle_claims WHERE handle = ?;`, handle)
+	if err != nil {
+		return fmt.Errorf("failed to delete claim on handle %s: %w", handle, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check deleted claim on handle %s: %w", handle, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	log.Printf("Storage: Deleted claim on handle %s", handle)
+	return nil
+}
+
+func (r *sqliteHandleClaimRepository) GetByHandle(ctx context.Context, handle string) (types.HandleClaim, error) {
+	sqlStmt := `SELECT handle, claimed_at, status, owner_peer_id, updated_at FROM handle_claims WHERE handle = ?;`
+
+	claim, err := scanHandleClaim(r.db.QueryRowContext(ctx, sqlStmt, handle))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.HandleClaim{}, sql.ErrNoRows
+		}
+		return types.HandleClaim{}, fmt.Errorf("failed to get claim on handle %s: %w", handle, err)
+	}
+
+	return claim, nil
+}
+
+func (r *sqliteHandleClaimRepository) GetAll(ctx context.Context) ([]types.HandleClaim, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT handle, claimed_at, status, owner_peer_id, updated_at FROM handle_claims ORDER BY claimed_at;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query handle claims: %w", err)
+	}
+	defer rows.Close()
+
+	claims := []types.HandleClaim{}
+	for rows.Next() {
+		claim, err := scanHandleClaim(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning handle claim row: %v", err)
+			continue
+		}
+		claims = append(claims, claim)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over handle claims: %w", err)
+	}
+
+	return claims, nil
+}
+
+// GetPin returns the peer we trust a handle to belong to. It returns sql.ErrNoRows if the
+// handle was never resolved.
+func (r *sqliteHandleClaimRepository) GetPin(ctx context.Context, handle string) (types.HandlePin, error) {
+	var pin types.HandlePin
+	var pinnedAt int64
+
+	err := r.db.QueryRowContext(ctx, `SELECT handle, peer_id, pinned_at FROM handle_pins WHERE handle = ?;`, handle).
+		Scan(&pin.Handle, &pin.PeerId, &pinnedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.HandlePin{}, sql.ErrNoRows
+		}
+		return types.HandlePin{}, fmt.Errorf("failed to get pin of handle %s: %w", handle, err)
+	}
+
+	pin.PinnedAt = time.Unix(pinnedAt, 0)
+	return pin, nil
+}
+
+func (r *sqliteHandleClaimRepository) SavePin(ctx context.Context, pin types.HandlePin) error {
+	sqlStmt := `
+		INSERT INTO handle_pins (handle, peer_id, pinned_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(handle) DO UPDATE SET
+			peer_id = excluded.peer_id,
+			pinned_at = excluded.pinned_at;
+	`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, pin.Handle, pin.PeerId, pin.PinnedAt.Unix()); err != nil {
+		return fmt.Errorf("failed to pin handle %s: %w", pin.Handle, err)
+	}
+
+	log.Printf("Storage: Pinned handle %s to %s", pin.Handle, pin.PeerId)
+	return nil
+}
+
+func scanHandleClaim(row interface{ Scan(dest ...any) error }) (types.HandleClaim, error) {
+	var claim types.HandleClaim
+	var claimedAt, updatedAt int64
+
+	if err := row.Scan(&claim.Handle, &claimedAt, &claim.Status, &claim.OwnerPeerId, &updatedAt); err != nil {
+		return types.HandleClaim{}, err
+	}
+
+	claim.ClaimedAt = time.Unix(claimedAt, 0)
+	claim.UpdatedAt = time.Unix(updatedAt, 0)
+	return claim, nil
+}
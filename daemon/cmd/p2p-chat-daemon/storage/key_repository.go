@@ -14,6 +14,30 @@ type KeyRepository interface {
 	Store(ctx context.Context, key types.GroupKey) error
 
 	GetKey(ctx context.Context, groupID string) (*types.GroupKey, error)
+
+	StoreEpoch(ctx context.Context, epoch types.GroupEpoch) error
+
+	GetLatestEpoch(ctx context.Context, groupID string) (*types.GroupEpoch, error)
+
+	GetEpoch(ctx context.Context, groupID string, epoch int64) (*types.GroupEpoch, error)
+
+	DeleteEpochsBefore(ctx context.Context, groupID string, epoch int64) error
+
+	DeleteSupersededEpochs(ctx context.Context, supersededBefore time.Time) error
+
+	GetLatestEpochs(ctx context.Context) ([]types.GroupEpoch, error)
+
+	DeleteEpochs(ctx context.Context, groupID string) error
+
+	StoreCommit(ctx context.Context, commit types.GroupCommitRecord) error
+
+	GetCommitsFrom(ctx context.Context, groupID string, epoch int64, limit int) ([]types.GroupCommitRecord, error)
+
+	StoreKeyPackage(ctx context.Context, keyPackage types.GroupStoredKeyPackage) error
+
+	GetKeyPackage(ctx context.Context, groupID string) (*types.GroupStoredKeyPackage, error)
+
+	DeleteKeyPackage(ctx context.Context, groupID string) error
 }
 
 type sqliteKeyRepository struct {
@@ -69,3 +93,235 @@ func (r *sqliteKeyRepository) GetKey(ctx context.Context, groupID string) (*type
 
 	return &gk, nil
 }
+
+func (r *sqliteKeyRepository) StoreEpoch(ctx context.Context, epoch types.GroupEpoch) error {
+	sqlStmt := `
+		REPLACE INTO group_epochs (group_id, epoch, commit_hash, state, created_at)
+		VALUES (?, ?, ?, ?, ?);
+	`
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		epoch.GroupId,
+		epoch.Epoch,
+		epoch.CommitHash,
+		epoch.State,
+		time.Now().Unix(),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to store epoch %d of group %s: %w", epoch.Epoch, epoch.GroupId, err)
+	}
+	log.Printf("Storage: Stored epoch %d of group %s", epoch.Epoch, epoch.GroupId)
+
+	return nil
+}
+
+func (r *sqliteKeyRepository) GetLatestEpoch(ctx context.Context, groupID string) (*types.GroupEpoch, error) {
+	sqlStmt := `
+		SELECT group_id, epoch, commit_hash, state, created_at
+		FROM group_epochs
+		WHERE group_id = ?
+		ORDER BY epoch DESC
+		LIMIT 1;
+	`
+	return r.queryEpoch(ctx, sqlStmt, groupID)
+}
+
+func (r *sqliteKeyRepository) GetEpoch(ctx context.Context, groupID string, epoch int64) (*types.GroupEpoch, error) {
+	sqlStmt := `
+		SELECT group_id, epoch, commit_hash, state, created_at
+		FROM group_epochs
+		WHERE group_id = ? AND epoch = ?;
+	`
+	return r.queryEpoch(ctx, sqlStmt, groupID, epoch)
+}
+
+func (r *sqliteKeyRepository) queryEpoch(ctx context.Context, sqlStmt string, args ...interface{}) (*types.GroupEpoch, error) {
+	var ge types.GroupEpoch
+	var createdAtUnix int64
+
+	err := r.db.QueryRowContext(ctx, sqlStmt, args...).Scan(
+		&ge.GroupId,
+		&ge.Epoch,
+		&ge.CommitHash,
+		&ge.State,
+		&createdAtUnix,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get group epoch: %w", err)
+	}
+	ge.CreatedAt = time.Unix(createdAtUnix, 0)
+
+	return &ge, nil
+}
+
+// DeleteEpochsBefore removes the state of epochs older than epoch, so their secrets are gone for good.
+func (r *sqliteKeyRepository) DeleteEpochsBefore(ctx context.Context, groupID string, epoch int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM group_epochs WHERE group_id = ? AND epoch < ?;`, groupID, epoch)
+	if err != nil {
+		return fmt.Errorf("failed to delete epochs of group %s before %d: %w", groupID, epoch, err)
+	}
+	return nil
+}
+
+// DeleteSupersededEpochs removes the state of every epoch whose successor was stored before
+// supersededBefore. The latest epoch of a group is never removed.
+func (r *sqliteKeyRepository) DeleteSupersededEpochs(ctx context.Context, supersededBefore time.Time) error {
+	sqlStmt := `
+		DELETE FROM group_epochs
+		WHERE EXISTS (
+			SELECT 1 FROM group_epochs AS newer
+			WHERE newer.group_id = group_epochs.group_id
+			  AND newer.epoch > group_epochs.epoch
+			  AND newer.created_at < ?
+		);
+	`
+	result, err := r.db.ExecContext(ctx, sqlStmt, supersededBefore.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to delete superseded epochs: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("Storage: Deleted %d superseded epoch(s)", n)
+	}
+	return nil
+}
+
+// GetLatestEpochs returns the current epoch of every group with epoch keys.
+func (r *sqliteKeyRepository) GetLatestEpochs(ctx context.Context) ([]types.GroupEpoch, error) {
+	sqlStmt := `
+		SELECT group_id, epoch, commit_hash, state, created_at
+		FROM group_epochs AS e
+		WHERE epoch = (SELECT MAX(epoch) FROM group_epochs WHERE group_id = e.group_id);
+	`
+	rows, err := r.db.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest epochs: %w", err)
+	}
+	defer rows.Close()
+
+	var epochs []types.GroupEpoch
+	for rows.Next() {
+		var ge types.GroupEpoch
+		var createdAtUnix int64
+		if err := rows.Scan(&ge.GroupId, &ge.Epoch, &ge.CommitHash, &ge.State, &createdAtUnix); err != nil {
+			return nil, fmt.Errorf("failed to scan epoch: %w", err)
+		}
+		ge.CreatedAt = time.Unix(createdAtUnix, 0)
+		epochs = append(epochs, ge)
+	}
+	return epochs, rows.Err()
+}
+
+func (r *sqliteKeyRepository) DeleteEpochs(ctx context.Context, groupID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM group_epochs WHERE group_id = ?;`, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete epochs of group %s: %w", groupID, err)
+	}
+	log.Printf("Storage: Deleted key state of group %s", groupID)
+	return nil
+}
+
+func (r *sqliteKeyRepository) StoreCommit(ctx context.Context, commit types.GroupCommitRecord) error {
+	sqlStmt := `
+		REPLACE INTO group_commits (group_id, epoch, commit_hash, commit_data, created_at)
+		VALUES (?, ?, ?, ?, ?);
+	`
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		commit.GroupId,
+		commit.Epoch,
+		commit.CommitHash,
+		commit.Commit,
+		time.Now().Unix(),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to store commit for epoch %d of group %s: %w", commit.Epoch, commit.GroupId, err)
+	}
+
+	return nil
+}
+
+func (r *sqliteKeyRepository) GetCommitsFrom(ctx context.Context, groupID string, epoch int64, limit int) ([]types.GroupCommitRecord, error) {
+	sqlStmt := `
+		SELECT group_id, epoch, commit_hash, commit_data, created_at
+		FROM group_commits
+		WHERE group_id = ? AND epoch >= ?
+		ORDER BY epoch ASC
+		LIMIT ?;
+	`
+	rows, err := r.db.QueryContext(ctx, sqlStmt, groupID, epoch, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query commits of group %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	var commits []types.GroupCommitRecord
+	for rows.Next() {
+		var c types.GroupCommitRecord
+		var createdAtUnix int64
+		if err := rows.Scan(&c.GroupId, &c.Epoch, &c.CommitHash, &c.Commit, &createdAtUnix); err != nil {
+			log.Printf("Storage: Error scanning commit row for group %s: %v", groupID, err)
+			continue
+		}
+		c.CreatedAt = time.Unix(createdAtUnix, 0)
+		commits = append(commits, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating commit rows for %s: %w", groupID, err)
+	}
+
+	return commits, nil
+}
+
+func (r *sqliteKeyRepository) StoreKeyPackage(ctx context.Context, keyPackage types.GroupStoredKeyPackage) error {
+	sqlStmt := `
+		REPLACE INTO group_key_packages (group_id, public_key, private_key, created_at)
+		VALUES (?, ?, ?, ?);
+	`
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		keyPackage.GroupId,
+		keyPackage.PublicKey,
+		keyPackage.PrivateKey,
+		time.Now().Unix(),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to store key package for group %s: %w", keyPackage.GroupId, err)
+	}
+	log.Printf("Storage: Stored key package for group %s", keyPackage.GroupId)
+
+	return nil
+}
+
+func (r *sqliteKeyRepository) GetKeyPackage(ctx context.Context, groupID string) (*types.GroupStoredKeyPackage, error) {
+	sqlStmt := `SELECT group_id, public_key, private_key, created_at FROM group_key_packages WHERE group_id = ?;`
+	var kp types.GroupStoredKeyPackage
+	var createdAtUnix int64
+
+	err := r.db.QueryRowContext(ctx, sqlStmt, groupID).Scan(
+		&kp.GroupId,
+		&kp.PublicKey,
+		&kp.PrivateKey,
+		&createdAtUnix,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get key package for group %s: %w", groupID, err)
+	}
+	kp.CreatedAt = time.Unix(createdAtUnix, 0)
+
+	return &kp, nil
+}
+
+func (r *sqliteKeyRepository) DeleteKeyPackage(ctx context.Context, groupID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM group_key_packages WHERE group_id = ?;`, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete key package for group %s: %w", groupID, err)
+	}
+	return nil
+}
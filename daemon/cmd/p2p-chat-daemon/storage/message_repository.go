@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"strings"
 	"time"
 )
 
@@ -14,9 +15,18 @@ type MessageRepository interface {
 	Store(ctx context.Context, msg types.StoredMessage) (id int64, err error)
 	StoreGroupMessage(ctx context.Context, msg types.StoredGroupMessage) error
 	GetGroupMessages(ctx context.Context, groupID string, limit int, before time.Time) ([]types.StoredGroupMessage, error)
+	GetGroupMessageHashes(ctx context.Context, groupID string) ([]string, error)
+	HasGroupMessage(ctx context.Context, groupID string, messageID string) (bool, error)
+	GetGroupHeads(ctx context.Context, groupID string, limit int) ([]string, error)
+	GetGroupMessageParents(ctx context.Context, groupID string) (map[string][]string, error)
+	GetMissingParents(ctx context.Context, groupID string) ([]string, error)
+	GetGroupMessagesByHashes(ctx context.Context, groupID string, hashes []string) ([]types.StoredGroupMessage, error)
 	GetMessagesByPeerID(ctx context.Context, peerID string, limit int) ([]types.StoredMessage, error)
+	DeleteMessagesByPeerID(ctx context.Context, peerID string) (int64, error)
 }
 
+const groupMissingParentsLimit = 1000
+
 type sqliteMessageRepository struct {
 	db *sql.DB
 }
@@ -64,25 +74,65 @@ func (r *sqliteMessageRepository) Store(ctx context.Context, msg types.StoredMes
 }
 
 func (r *sqliteMessageRepository) StoreGroupMessage(ctx context.Context, msg types.StoredGroupMessage) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	sqlStmt := `
-		INSERT INTO group_messages (group_id, sender_peer_id, content, sent_at)
-		VALUES (?, ?, ?, ?);
+		INSERT OR IGNORE INTO group_messages (group_id, sender_peer_id, content, sent_at, message_id, envelope, message_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?);
 	`
 	sentAtTimestamp := msg.SentAt.Unix()
 	if msg.SentAt.IsZero() {
 		sentAtTimestamp = time.Now().Unix()
 	}
 
-	_, err := r.db.ExecContext(ctx, sqlStmt,
+	var messageID, messageHash interface{}
+	if msg.MessageID != "" {
+		messageID = msg.MessageID
+	}
+	if msg.Hash != "" {
+		messageHash = msg.Hash
+	}
+
+	res, err := tx.ExecContext(ctx, sqlStmt,
 		msg.GroupID,
 		msg.SenderPeerID,
 		msg.EncryptedContent,
 		sentAtTimestamp,
+		messageID,
+		msg.EncryptedEnvelope,
+		messageHash,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert group message for group %s from sender %s: %w", msg.GroupID, msg.SenderPeerID, err)
 	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		log.Printf("Storage: Skipped duplicate group message %s for group %s", msg.MessageID, msg.GroupID)
+		return nil
+	}
+
+	if msg.Hash != "" {
+		for _, parent := range msg.Parents {
+			_, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO group_message_edges (group_id, child_hash, parent_hash) VALUES (?, ?, ?);`,
+				msg.GroupID, msg.Hash, parent,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to store parent %s of group message %s: %w", parent, msg.Hash, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit group message store transaction: %w", err)
+	}
+
 	log.Printf("Storage: Stored group message for group %s from %s", msg.GroupID, msg.SenderPeerID)
 	return nil
 }
@@ -97,7 +147,7 @@ func (r *sqliteMessageRepository) GetGroupMessages(ctx context.Context, groupID
 	}
 
 	querySQL := `
-		SELECT group_id, sender_peer_id, content, sent_at
+		SELECT id, group_id, message_id, message_hash, sender_peer_id, content, envelope, sent_at
 		FROM group_messages
 		WHERE group_id = ? AND sent_at < ?
 		ORDER BY sent_at DESC
@@ -111,25 +161,12 @@ func (r *sqliteMessageRepository) GetGroupMessages(ctx context.Context, groupID
 
 	var messages []types.StoredGroupMessage
 	for rows.Next() {
-		var msg types.StoredGroupMessage
-		var sentAtUnix int64
-
-		var encryptedContentBytes []byte
-
-		err := rows.Scan(
-			&msg.GroupID,
-			&msg.SenderPeerID,
-			&encryptedContentBytes,
-			&sentAtUnix,
-		)
+		msg, err := scanGroupMessage(rows)
 		if err != nil {
 			log.Printf("Storage: Error scanning group message row for group %s: %v", groupID, err)
 			continue
 		}
 
-		msg.EncryptedContent = encryptedContentBytes
-		msg.SentAt = time.Unix(sentAtUnix, 0)
-
 		messages = append([]types.StoredGroupMessage{msg}, messages...)
 	}
 
@@ -141,6 +178,186 @@ func (r *sqliteMessageRepository) GetGroupMessages(ctx context.Context, groupID
 	return messages, nil
 }
 
+// GetGroupMessageHashes returns the hashes of a group's messages that carry a signed
+// envelope, in the order they were stored.
+func (r *sqliteMessageRepository) GetGroupMessageHashes(ctx context.Context, groupID string) ([]string, error) {
+	querySQL := `
+		SELECT message_hash
+		FROM group_messages
+		WHERE group_id = ? AND message_hash IS NOT NULL AND envelope IS NOT NULL
+		ORDER BY id ASC;
+	`
+
+	return r.queryHashes(ctx, querySQL, groupID)
+}
+
+func (r *sqliteMessageRepository) HasGroupMessage(ctx context.Context, groupID string, messageID string) (bool, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(1) FROM group_messages WHERE group_id = ? AND message_id = ?;`,
+		groupID, messageID,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check group message %s for %s: %w", messageID, groupID, err)
+	}
+
+	return count > 0, nil
+}
+
+// GetGroupHeads returns hashes of the group's messages that no stored message references as a parent.
+func (r *sqliteMessageRepository) GetGroupHeads(ctx context.Context, groupID string, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	querySQL := `
+		SELECT m.message_hash
+		FROM group_messages m
+		WHERE m.group_id = ? AND m.message_hash IS NOT NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM group_message_edges e
+			WHERE e.group_id = m.group_id AND e.parent_hash = m.message_hash
+		  )
+		ORDER BY m.sent_at DESC, m.message_hash ASC
+		LIMIT ?;
+	`
+
+	return r.queryHashes(ctx, querySQL, groupID, limit)
+}
+
+// GetGroupMessageParents returns the parent hashes of every stored message of a group, keyed by child hash.
+func (r *sqliteMessageRepository) GetGroupMessageParents(ctx context.Context, groupID string) (map[string][]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT child_hash, parent_hash FROM group_message_edges WHERE group_id = ? ORDER BY child_hash, parent_hash;`,
+		groupID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message edges for group %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	parents := make(map[string][]string)
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			return nil, fmt.Errorf("failed to scan message edge for group %s: %w", groupID, err)
+		}
+		parents[child] = append(parents[child], parent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message edges for group %s: %w", groupID, err)
+	}
+
+	return parents, nil
+}
+
+// GetMissingParents returns parent hashes referenced by stored messages that are not stored themselves.
+func (r *sqliteMessageRepository) GetMissingParents(ctx context.Context, groupID string) ([]string, error) {
+	querySQL := `
+		SELECT DISTINCT e.parent_hash
+		FROM group_message_edges e
+		WHERE e.group_id = ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM group_messages m
+			WHERE m.group_id = e.group_id AND m.message_hash = e.parent_hash
+		  )
+		ORDER BY e.parent_hash
+		LIMIT ?;
+	`
+
+	return r.queryHashes(ctx, querySQL, groupID, groupMissingParentsLimit)
+}
+
+// GetGroupMessagesByHashes returns the stored messages of a group that carry a signed envelope
+// and whose hash is in hashes.
+func (r *sqliteMessageRepository) GetGroupMessagesByHashes(ctx context.Context, groupID string, hashes []string) ([]types.StoredGroupMessage, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(hashes)), ", ")
+	querySQL := fmt.Sprintf(`
+		SELECT id, group_id, message_id, message_hash, sender_peer_id, content, envelope, sent_at
+		FROM group_messages
+		WHERE group_id = ? AND envelope IS NOT NULL AND message_hash IN (%s)
+		ORDER BY id ASC;
+	`, placeholders)
+
+	args := make([]interface{}, 0, len(hashes)+1)
+	args = append(args, groupID)
+	for _, hash := range hashes {
+		args = append(args, hash)
+	}
+
+	rows, err := r.db.QueryContext(ctx, querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group messages by hash for %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	var messages []types.StoredGroupMessage
+	for rows.Next() {
+		msg, err := scanGroupMessage(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning group message row for group %s: %v", groupID, err)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group message rows for %s: %w", groupID, err)
+	}
+
+	return messages, nil
+}
+
+func (r *sqliteMessageRepository) queryHashes(ctx context.Context, querySQL string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message hashes: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan message hash: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+func scanGroupMessage(rows *sql.Rows) (types.StoredGroupMessage, error) {
+	var msg types.StoredGroupMessage
+	var messageID, messageHash sql.NullString
+	var sentAtUnix int64
+
+	err := rows.Scan(
+		&msg.ID,
+		&msg.GroupID,
+		&messageID,
+		&messageHash,
+		&msg.SenderPeerID,
+		&msg.EncryptedContent,
+		&msg.EncryptedEnvelope,
+		&sentAtUnix,
+	)
+	if err != nil {
+		return types.StoredGroupMessage{}, err
+	}
+
+	msg.MessageID = messageID.String
+	msg.Hash = messageHash.String
+	msg.SentAt = time.Unix(sentAtUnix, 0)
+
+	return msg, nil
+}
+
 func (r *sqliteMessageRepository) GetMessagesByPeerID(ctx context.Context, peerID string, limit int) ([]types.StoredMessage, error) {
 	log.Printf("Storage: Retrieving messages for peer %s", peerID)
 
@@ -201,3 +418,24 @@ func (r *sqliteMessageRepository) GetMessagesByPeerID(ctx context.Context, peerI
 	log.Printf("Storage: Retrieved %d messages for peer %s", len(messages), peerID)
 	return messages, nil
 }
+
+// DeleteMessagesByPeerID removes the whole direct conversation with a peer and returns how
+// many messages were deleted.
+func (r *sqliteMessageRepository) DeleteMessagesByPeerID(ctx context.Context, peerID string) (int64, error) {
+	if peerID == "" {
+		return 0, errors.New("peerID cannot be empty")
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM messages WHERE recipient_peer_id = ? OR sender_peer_id = ?`, peerID, peerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete messages for peer %s: %w", peerID, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted messages for peer %s: %w", peerID, err)
+	}
+
+	log.Printf("Storage: Deleted %d messages with peer %s", deleted, peerID)
+	return deleted, nil
+}
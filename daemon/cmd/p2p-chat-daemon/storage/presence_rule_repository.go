@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type PresenceRuleRepository interface {
+	Save(ctx context.Context, rule types.PresenceRule) error
+	Delete(ctx context.Context, subjectType string, subjectID string) error
+	GetAll(ctx context.Context) ([]types.PresenceRule, error)
+}
+
+type sqlitePresenceRuleRepository struct {
+	db *sql.DB
+}
+
+func NewSQLitePresenceRuleRepository(database *DB) (PresenceRuleRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for presence rule repository")
+	}
+	return &sqlitePresenceRuleRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqlitePresenceRuleRepository) Save(ctx context.Context, rule types.PresenceRule) error {
+	sqlStmt := `
+		REPLACE INTO presence_rules (subject_type, subject_id, hide_presence, hide_last_seen, updated_at)
+		VALUES (?, ?, ?, ?, ?);
+	`
+
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		rule.SubjectType,
+		rule.SubjectId,
+		rule.HidePresence,
+		rule.HideLastSeen,
+		rule.UpdatedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save presence rule for %s %s: %w", rule.SubjectType, rule.SubjectId, err)
+	}
+
+	log.Printf("Storage: Saved presence rule for %s %s", rule.SubjectType, rule.SubjectId)
+	return nil
+}
+
+// Delete returns sql.ErrNoRows if there was no rule for the subject.
+func (r *sqlitePresenceRuleRepository) Delete(ctx context.Context, subjectType string, subjectID string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM presence_rules WHERE subject_type = ? AND subject_id = ?;`,
+		subjectType, subjectID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete presence rule for %s %s: %w", subjectType, subjectID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check deleted presence rule for %s %s: %w", subjectType, subjectID, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *sqlitePresenceRuleRepository) GetAll(ctx context.Context) ([]types.PresenceRule, error) {
+	sqlStmt := `
+		SELECT subject_type, subject_id, hide_presence, hide_last_seen, updated_at
+		FROM presence_rules
+		ORDER BY subject_type ASC, subject_id ASC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query presence rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []types.PresenceRule{}
+	for rows.Next() {
+		var rule types.PresenceRule
+		var updatedAtUnix int64
+
+		if err := rows.Scan(&rule.SubjectType, &rule.SubjectId, &rule.HidePresence, &rule.HideLastSeen, &updatedAtUnix); err != nil {
+			log.Printf("Storage: Error scanning presence rule row: %v", err)
+			continue
+		}
+		rule.UpdatedAt = time.Unix(updatedAtUnix, 0)
+
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating presence rule rows: %w", err)
+	}
+
+	return rules, nil
+}
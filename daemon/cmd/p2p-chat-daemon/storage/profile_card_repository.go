@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"p2p-chat-daemon/cmd/p2p-chat-daemon/core/types"
+	"time"
+)
+
+type ProfileCardRepository interface {
+	Store(ctx context.Context, card types.ProfileCard) error
+	Get(ctx context.Context, peerID string) (*types.ProfileCard, error)
+	GetAll(ctx context.Context) (map[string]types.ProfileCard, error)
+}
+
+type sqliteProfileCardRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteProfileCardRepository(database *DB) (ProfileCardRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for profile card repository")
+	}
+	return &sqliteProfileCardRepository{db: database.GetDB()}, nil
+}
+
+func (r *sqliteProfileCardRepository) Store(ctx context.Context, card types.ProfileCard) error {
+	sqlStmt := `
+		REPLACE INTO profile_cards (peer_id, display_name, bio, avatar_hash, version, updated_at, signed_card)
+		VALUES (?, ?, ?, ?, ?, ?, ?);
+	`
+
+	_, err := r.db.ExecContext(ctx, sqlStmt,
+		card.PeerId,
+		card.DisplayName,
+		card.Bio,
+		card.AvatarHash,
+		card.Version,
+		card.UpdatedAt.Unix(),
+		card.Card,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store profile card of %s: %w", card.PeerId, err)
+	}
+
+	log.Printf("Storage: Stored profile card version %d of %s", card.Version, card.PeerId)
+	return nil
+}
+
+func (r *sqliteProfileCardRepository) Get(ctx context.Context, peerID string) (*types.ProfileCard, error) {
+	sqlStmt := `
+		SELECT peer_id, display_name, bio, avatar_hash, version, updated_at, signed_card
+		FROM profile_cards
+		WHERE peer_id = ?;
+	`
+
+	card, err := scanProfileCard(r.db.QueryRowContext(ctx, sqlStmt, peerID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get profile card of %s: %w", peerID, err)
+	}
+
+	return card, nil
+}
+
+func (r *sqliteProfileCardRepository) GetAll(ctx context.Context) (map[string]types.ProfileCard, error) {
+	sqlStmt := `
+		SELECT peer_id, display_name, bio, avatar_hash, version, updated_at, signed_card
+		FROM profile_cards;
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile cards: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]types.ProfileCard)
+	for rows.Next() {
+		card, err := scanProfileCard(rows)
+		if err != nil {
+			log.Printf("Storage: Error scanning profile card row: %v", err)
+			continue
+		}
+		result[card.PeerId] = *card
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating profile card rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func scanProfileCard(row interface{ Scan(dest ...any) error }) (*types.ProfileCard, error) {
+	var card types.ProfileCard
+	var updatedAtUnix int64
+
+	err := row.Scan(
+		&card.PeerId,
+		&card.DisplayName,
+		&card.Bio,
+		&card.AvatarHash,
+		&card.Version,
+		&updatedAtUnix,
+		&card.Card,
+	)
+	if err != nil {
+		return nil, err
+	}
+	card.UpdatedAt = time.Unix(updatedAtUnix, 0)
+
+	return &card, nil
+}
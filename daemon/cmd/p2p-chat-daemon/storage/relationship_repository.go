@@ -13,9 +13,16 @@ import (
 type RelationshipRepository interface {
 	Store(ctx context.Context, relationship types.FriendRelationship) error
 	UpdateStatus(ctx context.Context, relationship types.FriendRelationship) error
+	ResolveSentRequest(ctx context.Context, peerId string, nonce string, status types.FriendStatus, respondedAt time.Time) (bool, error)
+	SetRequestNonce(ctx context.Context, peerId string, nonce string) error
 	GetRelationByPeerId(ctx context.Context, peerId string) (types.FriendRelationship, error)
 	GetAcceptedRelations(ctx context.Context) ([]types.FriendRelationship, error)
 	GetPendingRelations(ctx context.Context) ([]types.FriendRelationship, error)
+	Delete(ctx context.Context, peerId string) error
+	DeleteExpiredRequests(ctx context.Context, now time.Time) (int64, error)
+	RememberRequestNonce(ctx context.Context, peerId string, nonce string) (bool, error)
+	PruneRequestNonces(ctx context.Context, before time.Time) error
+	UpdateLastSeen(ctx context.Context, peerId string, lastSeen time.Time) error
 }
 
 type sqliteRelationshipRepository struct {
@@ -37,8 +44,8 @@ func (r *sqliteRelationshipRepository) Store(ctx context.Context, relationship t
 	defer tx.Rollback()
 
 	msgSQL := `
-		INSERT INTO relationships (peer_id, status, requested_at, approved_at)
-		VALUES (?, ?, ?, ?);
+		INSERT INTO relationships (peer_id, status, requested_at, approved_at, intro_message, expires_at, request_nonce)
+		VALUES (?, ?, ?, ?, ?, ?, ?);
 `
 
 	var requestedAtStr, approvedAtStr interface{}
@@ -54,11 +61,19 @@ func (r *sqliteRelationshipRepository) Store(ctx context.Context, relationship t
 		approvedAtStr = nil
 	}
 
+	var expiresAtStr interface{}
+	if !relationship.ExpiresAt.IsZero() {
+		expiresAtStr = relationship.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
 	_, err = tx.ExecContext(ctx, msgSQL,
 		relationship.PeerID,
 		relationship.Status,
 		requestedAtStr,
 		approvedAtStr,
+		nullIfEmpty(relationship.IntroMessage),
+		expiresAtStr,
+		nullIfEmpty(relationship.RequestNonce),
 	)
 
 	if err != nil {
@@ -110,8 +125,9 @@ func (r *sqliteRelationshipRepository) GetRelationByPeerId(ctx context.Context,
 	var rel types.FriendRelationship
 	var statusStr string
 	var requestedAtStr, approvedAtStr sql.NullString
+	var introMessage, expiresAtStr, requestNonce, lastSeenStr sql.NullString
 
-	sqlStmt := `SELECT peer_id, status, requested_at, approved_at
+	sqlStmt := `SELECT peer_id, status, requested_at, approved_at, intro_message, expires_at, request_nonce, last_seen
                 FROM relationships WHERE peer_id = ?;`
 
 	err := r.db.QueryRowContext(ctx, sqlStmt, peerId).Scan(
@@ -119,6 +135,10 @@ func (r *sqliteRelationshipRepository) GetRelationByPeerId(ctx context.Context,
 		&statusStr,
 		&requestedAtStr,
 		&approvedAtStr,
+		&introMessage,
+		&expiresAtStr,
+		&requestNonce,
+		&lastSeenStr,
 	)
 
 	if err != nil {
@@ -147,12 +167,14 @@ func (r *sqliteRelationshipRepository) GetRelationByPeerId(ctx context.Context,
 			log.Printf("WARN: Could not parse approved_at '%s' for peer %s: %v", approvedAtStr.String, peerId, err)
 		}
 	}
+	scanRequestColumns(&rel, introMessage, expiresAtStr, requestNonce)
+	scanLastSeen(&rel, lastSeenStr)
 
 	return rel, nil
 }
 
 func (r *sqliteRelationshipRepository) GetAcceptedRelations(ctx context.Context) ([]types.FriendRelationship, error) {
-	sqlStmt := `SELECT peer_id, status, requested_at, approved_at
+	sqlStmt := `SELECT peer_id, status, requested_at, approved_at, intro_message, expires_at, request_nonce, last_seen
                 FROM relationships WHERE Status = ?
 				order by peer_id ASC;`
 
@@ -170,6 +192,7 @@ func (r *sqliteRelationshipRepository) GetAcceptedRelations(ctx context.Context)
 	for rows.Next() {
 		var rel types.FriendRelationship
 		var requestedAtStr, approvedAtStr sql.NullString
+		var introMessage, expiresAtStr, requestNonce, lastSeenStr sql.NullString
 		var statusText string
 
 		var errScan error
@@ -178,6 +201,10 @@ func (r *sqliteRelationshipRepository) GetAcceptedRelations(ctx context.Context)
 			&statusText,
 			&requestedAtStr,
 			&approvedAtStr,
+			&introMessage,
+			&expiresAtStr,
+			&requestNonce,
+			&lastSeenStr,
 		)
 		rel.Status = stringToFriendStatus(statusText)
 
@@ -199,6 +226,8 @@ func (r *sqliteRelationshipRepository) GetAcceptedRelations(ctx context.Context)
 			log.Printf("Storage: Error scanning approved friends row: %v", errScan)
 			return nil, fmt.Errorf("error scanning approved friends row: %w", errScan)
 		}
+		scanRequestColumns(&rel, introMessage, expiresAtStr, requestNonce)
+		scanLastSeen(&rel, lastSeenStr)
 
 		friends = append(friends, rel)
 	}
@@ -207,11 +236,13 @@ func (r *sqliteRelationshipRepository) GetAcceptedRelations(ctx context.Context)
 }
 
 func (r *sqliteRelationshipRepository) GetPendingRelations(ctx context.Context) ([]types.FriendRelationship, error) {
-	sqlStmt := `SELECT peer_id, status, requested_at, approved_at
+	sqlStmt := `SELECT peer_id, status, requested_at, approved_at, intro_message, expires_at, request_nonce, last_seen
                 FROM relationships WHERE status IN (?, ?)
+				AND (expires_at IS NULL OR expires_at > ?)
 				order by peer_id ASC;`
 
-	rows, err := r.db.QueryContext(ctx, sqlStmt, types.FriendStatusSent, types.FriendStatusPending)
+	now := time.Now().UTC().Format(time.RFC3339)
+	rows, err := r.db.QueryContext(ctx, sqlStmt, types.FriendStatusSent, types.FriendStatusPending, now)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -226,6 +257,7 @@ func (r *sqliteRelationshipRepository) GetPendingRelations(ctx context.Context)
 	for rows.Next() {
 		var rel types.FriendRelationship
 		var requestedAtStr, approvedAtStr sql.NullString
+		var introMessage, expiresAtStr, requestNonce, lastSeenStr sql.NullString
 		var statusText string
 
 		errScan := rows.Scan(
@@ -233,6 +265,10 @@ func (r *sqliteRelationshipRepository) GetPendingRelations(ctx context.Context)
 			&statusText,
 			&requestedAtStr,
 			&approvedAtStr,
+			&introMessage,
+			&expiresAtStr,
+			&requestNonce,
+			&lastSeenStr,
 		)
 
 		if errScan != nil {
@@ -260,6 +296,9 @@ func (r *sqliteRelationshipRepository) GetPendingRelations(ctx context.Context)
 			}
 		}
 
+		scanRequestColumns(&rel, introMessage, expiresAtStr, requestNonce)
+		scanLastSeen(&rel, lastSeenStr)
+
 		pendingRequests = append(pendingRequests, rel)
 	}
 
@@ -270,6 +309,147 @@ func (r *sqliteRelationshipRepository) GetPendingRelations(ctx context.Context)
 	return pendingRequests, nil
 }
 
+// Delete forgets the relationship with a peer, so a new friend request can start over.
+func (r *sqliteRelationshipRepository) Delete(ctx context.Context, peerId string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM relationships WHERE peer_id = ?;`, peerId)
+	if err != nil {
+		return fmt.Errorf("failed to delete relationship with %s: %w", peerId, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check deleted relationship with %s: %w", peerId, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteExpiredRequests drops pending requests, sent or received, whose expiry has passed.
+func (r *sqliteRelationshipRepository) DeleteExpiredRequests(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM relationships WHERE status IN (?, ?) AND expires_at IS NOT NULL AND expires_at <= ?;`,
+		types.FriendStatusSent, types.FriendStatusPending, now.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired friend requests: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check expired friend requests: %w", err)
+	}
+
+	return affected, nil
+}
+
+// ResolveSentRequest records a peer's answer to the request we sent them. Only a request
+// still waiting on an answer, and carrying the nonce the answer names, changes; it returns
+// false otherwise.
+func (r *sqliteRelationshipRepository) ResolveSentRequest(ctx context.Context, peerId string, nonce string, status types.FriendStatus, respondedAt time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE relationships SET status = ?, approved_at = ?
+		WHERE peer_id = ? AND status = ? AND request_nonce = ?;`,
+		status, respondedAt.Format(time.RFC3339), peerId, types.FriendStatusSent, nonce,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve friend request sent to %s: %w", peerId, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check friend request sent to %s: %w", peerId, err)
+	}
+
+	return affected == 1, nil
+}
+
+// SetRequestNonce gives a relationship the nonce of its request, for requests stored before
+// they carried one.
+func (r *sqliteRelationshipRepository) SetRequestNonce(ctx context.Context, peerId string, nonce string) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE relationships SET request_nonce = ? WHERE peer_id = ?;`, nonce, peerId); err != nil {
+		return fmt.Errorf("failed to set request nonce for %s: %w", peerId, err)
+	}
+	return nil
+}
+
+// RememberRequestNonce records a friend request nonce. It returns false if the peer
+// already used the nonce, which marks the request as a replay.
+func (r *sqliteRelationshipRepository) RememberRequestNonce(ctx context.Context, peerId string, nonce string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO friend_request_nonces (peer_id, nonce, seen_at) VALUES (?, ?, ?);`,
+		peerId, nonce, time.Now().Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to store friend request nonce from %s: %w", peerId, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check friend request nonce from %s: %w", peerId, err)
+	}
+
+	return affected == 1, nil
+}
+
+// PruneRequestNonces forgets nonces seen before a cutoff. Requests that old are rejected as
+// stale, so their nonces are no longer needed to detect replays.
+func (r *sqliteRelationshipRepository) PruneRequestNonces(ctx context.Context, before time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM friend_request_nonces WHERE seen_at < ?;`, before.Unix()); err != nil {
+		return fmt.Errorf("failed to prune friend request nonces: %w", err)
+	}
+	return nil
+}
+
+// UpdateLastSeen records when a friend was last seen online. Times older than the one
+// recorded are ignored.
+func (r *sqliteRelationshipRepository) UpdateLastSeen(ctx context.Context, peerId string, lastSeen time.Time) error {
+	lastSeenStr := lastSeen.UTC().Format(time.RFC3339)
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE relationships SET last_seen = ? WHERE peer_id = ? AND (last_seen IS NULL OR last_seen < ?);`,
+		lastSeenStr, peerId, lastSeenStr,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update last seen of %s: %w", peerId, err)
+	}
+	return nil
+}
+
+func scanRequestColumns(rel *types.FriendRelationship, introMessage, expiresAtStr, requestNonce sql.NullString) {
+	rel.IntroMessage = introMessage.String
+	rel.RequestNonce = requestNonce.String
+
+	if expiresAtStr.Valid {
+		t, err := time.Parse(time.RFC3339, expiresAtStr.String)
+		if err == nil {
+			rel.ExpiresAt = t
+		} else {
+			log.Printf("WARN: Could not parse expires_at '%s' for peer %s: %v", expiresAtStr.String, rel.PeerID, err)
+		}
+	}
+}
+
+func scanLastSeen(rel *types.FriendRelationship, lastSeenStr sql.NullString) {
+	if !lastSeenStr.Valid {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, lastSeenStr.String)
+	if err == nil {
+		rel.LastSeen = t
+	} else {
+		log.Printf("WARN: Could not parse last_seen '%s' for peer %s: %v", lastSeenStr.String, rel.PeerID, err)
+	}
+}
+
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
 func stringToFriendStatus(s string) types.FriendStatus {
 	switch s {
 	case "1":
@@ -280,6 +460,8 @@ func stringToFriendStatus(s string) types.FriendStatus {
 		return types.FriendStatusApproved
 	case "4":
 		return types.FriendStatusRejected
+	case "5":
+		return types.FriendStatusRemoved
 	default:
 		log.Printf("WARN: Unknown friends status string '%s' from DB, defaulting to None.", s)
 		return types.FriendStatusNone
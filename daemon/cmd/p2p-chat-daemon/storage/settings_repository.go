@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SettingsRepository keeps small local settings as key/value pairs.
+type SettingsRepository interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string) error
+}
+
+type sqliteSettingsRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteSettingsRepository(database *DB) (SettingsRepository, error) {
+	if database == nil || database.GetDB() == nil {
+		return nil, errors.New("database connection required for settings repository")
+	}
+	return &sqliteSettingsRepository{db: database.GetDB()}, nil
+}
+
+// Get returns sql.ErrNoRows if the setting was never set.
+func (r *sqliteSettingsRepository) Get(ctx context.Context, key string) (string, error) {
+	var value string
+	err := r.db.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?;`, key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", sql.ErrNoRows
+		}
+		return "", fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func (r *sqliteSettingsRepository) Set(ctx context.Context, key string, value string) error {
+	sqlStmt := `
+		INSERT INTO settings (key, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at;
+	`
+
+	if _, err := r.db.ExecContext(ctx, sqlStmt, key, value, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to set setting %s: %w", key, err)
+	}
+	return nil
+}
@@ -11,13 +11,15 @@ import {
     Typography
 } from '@mui/material';
 import PersonAddIcon from '@mui/icons-material/PersonAdd';
-import {sendFriendRequest} from "../../services/api.js";
+import {resolveHandle, sendFriendRequest, trustHandle} from "../../services/api.js";
 
 const AddFriend = ({open, onClose, onFriendRequestSent}) => {
     const [peerId, setPeerId] = useState('');
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState('');
     const [success, setSuccess] = useState('');
+    const [warning, setWarning] = useState('');
+    const [resolved, setResolved] = useState(null);
 
     const handleSubmit = async (e) => {
         e.preventDefault();
@@ -31,9 +33,36 @@ const AddFriend = ({open, onClose, onFriendRequestSent}) => {
         setSuccess('');
 
         try {
-            await sendFriendRequest(peerId.trim());
+            let target = peerId.trim();
+            if (target.startsWith('@')) {
+                if (resolved?.input !== target) {
+                    const response = await resolveHandle(target);
+                    setResolved({input: target, ...response.data});
+                    if (response.data.owner_changed) {
+                        setWarning(`${target} used to resolve to ${response.data.pinned_peer_id}, but now resolves to ` +
+                            `${response.data.peer_id}. Handles can be taken over; only send if you trust the new Peer ID.`);
+                        return;
+                    }
+                    if (response.data.conflict) {
+                        setWarning(`${target} is also claimed by ${response.data.contenders.length} other peer(s). ` +
+                            `It resolves to ${response.data.peer_id}, but claim times are self-reported; ` +
+                            `confirm this Peer ID with its owner before sending.`);
+                        return;
+                    }
+                    target = response.data.peer_id;
+                } else {
+                    if (resolved.owner_changed) {
+                        await trustHandle(target, resolved.peer_id);
+                    }
+                    target = resolved.peer_id;
+                }
+            }
+
+            await sendFriendRequest(target);
             setSuccess('Friend request sent successfully!');
             setPeerId('');
+            setWarning('');
+            setResolved(null);
             if (onFriendRequestSent) {
                 onFriendRequestSent();
             }
@@ -53,6 +82,8 @@ const AddFriend = ({open, onClose, onFriendRequestSent}) => {
         setPeerId('');
         setError('');
         setSuccess('');
+        setWarning('');
+        setResolved(null);
         onClose();
     };
 
@@ -66,22 +97,26 @@ const AddFriend = ({open, onClose, onFriendRequestSent}) => {
             </DialogTitle>
             <DialogContent>
                 <Typography variant="body2" color="text.secondary" sx={{mb: 2}}>
-                    Enter the Peer ID of the person you want to add as a friend.
+                    Enter the Peer ID or @handle of the person you want to add as a friend.
                 </Typography>
 
                 {error && <Alert severity="error" sx={{mb: 2}}>{error}</Alert>}
+                {warning && <Alert severity="warning" sx={{mb: 2}}>{warning}</Alert>}
                 {success && <Alert severity="success" sx={{mb: 2}}>{success}</Alert>}
 
                 <TextField
                     autoFocus
                     margin="dense"
-                    label="Peer ID"
+                    label="Peer ID or @handle"
                     fullWidth
                     variant="outlined"
                     value={peerId}
-                    onChange={(e) => setPeerId(e.target.value)}
+                    onChange={(e) => {
+                        setPeerId(e.target.value);
+                        setWarning('');
+                    }}
                     disabled={loading}
-                    placeholder="Enter peer ID..."
+                    placeholder="Enter peer ID or @handle..."
                 />
             </DialogContent>
             <DialogActions>
@@ -93,7 +128,7 @@ const AddFriend = ({open, onClose, onFriendRequestSent}) => {
                     variant="contained"
                     disabled={loading || !peerId.trim()}
                 >
-                    {loading ? 'Sending...' : 'Send Request'}
+                    {loading ? 'Sending...' : warning ? 'Send Anyway' : 'Send Request'}
                 </Button>
             </DialogActions>
         </Dialog>
@@ -20,7 +20,7 @@ import NotificationsIcon from '@mui/icons-material/Notifications';
 import GroupIcon from '@mui/icons-material/Group';
 import AddBoxIcon from '@mui/icons-material/AddBox';
 import ContentCopyIcon from '@mui/icons-material/ContentCopy';
-import {checkStatus, getFriendRequests, getFriends, getGroupChats} from '../../services/api';
+import {checkStatus, getAvatarURL, getFriendRequests, getFriends, getGroupChats} from '../../services/api';
 import AddFriend from '../friends/AddFriend';
 import FriendRequests from '../friends/FriendRequests';
 import CreateGroupChat from '../groupchat/CreateGroupChat.jsx';
@@ -48,13 +48,22 @@ const Sidebar = ({refreshTrigger = 0, onSelectChat, onDisplayNameUpdate}) => {
 
     const getDisplayName = (chat) => {
         if (chat.PeerID) {
-            return chat.display_name || formatPeerId(chat.PeerID);
+            return chat.display_name || chat.profile_card?.display_name || formatPeerId(chat.PeerID);
         } else if (chat.group_id) {
             return chat.name || `Group (${chat.members?.length || 0})`;
         }
         return 'Unknown Chat';
     };
 
+    // The name a friend picked for themselves, when we call them something else.
+    const getSecondaryText = (chat) => {
+        const ownName = chat.profile_card?.display_name;
+        if (ownName && chat.display_name && ownName !== chat.display_name) {
+            return `${ownName} · ${formatPeerId(chat.PeerID)}`;
+        }
+        return formatPeerId(chat.PeerID);
+    };
+
     const getInitial = (chat) => {
         const displayName = getDisplayName(chat);
         return displayName.charAt(0).toUpperCase();
@@ -256,7 +265,10 @@ const Sidebar = ({refreshTrigger = 0, onSelectChat, onDisplayNameUpdate}) => {
                                     overlap="circular"
                                     sx={{mr: 2}}
                                 >
-                                    <Avatar sx={{bgcolor: 'secondary.light', width: 40, height: 40}}>
+                                    <Avatar
+                                        sx={{bgcolor: 'secondary.light', width: 40, height: 40}}
+                                        src={item.profile_card?.avatar_hash ? getAvatarURL(item.profile_card.avatar_hash) : undefined}
+                                    >
                                         {getInitial(item)}
                                     </Avatar>
                                 </Badge>
@@ -267,7 +279,7 @@ const Sidebar = ({refreshTrigger = 0, onSelectChat, onDisplayNameUpdate}) => {
                                 secondary={
                                     type === 'group'
                                         ? `${item.members?.length || 0} members`
-                                        : formatPeerId(item.PeerID)
+                                        : getSecondaryText(item)
                                 }
                                 primaryTypographyProps={{
                                     noWrap: true,
@@ -19,18 +19,137 @@ export const unlockWithPassword = (password) => api.post('/setup/unlock-key', {p
 export const registerUser = (password) => api.post('/setup/create-key', {password});
 
 // friends request endpoints
-export const sendFriendRequest = (receiver_peer_id) => api.post('/profile/friend/request', {receiver_peer_id});
+export const sendFriendRequest = (receiver_peer_id, message) => api.post('/profile/friend/request', {
+    receiver_peer_id,
+    message
+});
+export const cancelFriendRequest = (peer_id) => api.post('/profile/friend/cancel', {peer_id});
 export const respondToFriendRequest = (peer_id, is_accepted) => api.patch('/profile/friend/response', {
     peer_id,
     is_accepted
 });
+export const removeFriend = (peer_id, purge_history) => api.post('/profile/friend/remove', {peer_id, purge_history});
 export const getFriends = () => api.get('/profile/friends');
 export const getFriendRequests = () => api.get('/profile/friendRequests');
+export const introduceFriends = (peer_a, peer_b, message) => api.post('/profile/introduction', {peer_a, peer_b, message});
+export const getIntroductions = () => api.get('/profile/introductions');
+export const respondToIntroduction = (intro_id, action) => api.post('/profile/introduction/response', {intro_id, action});
+export const getContactURI = (name) => api.get('/profile/contact', {params: {name}});
+export const getContactQR = (name, size) => api.get('/profile/contact/qr', {params: {name, size}, responseType: 'blob'});
+export const importContact = (uri, message) => api.post('/profile/contact/import', {uri, message});
+export const getProfileCard = () => api.get('/profile/card');
+export const updateProfileCard = (display_name, bio, avatar_hash) => api.post('/profile/card/update', {
+    display_name,
+    bio,
+    avatar_hash
+});
+export const uploadAvatar = (mime_type, content) => api.post('/profile/avatar', {mime_type, content});
+export const getAvatarURL = (hash) => `${API_BASE_URL}/profile/avatar/get?hash=${encodeURIComponent(hash)}`;
+export const exportContacts = () => api.get('/profile/contacts/export');
+export const importContacts = (contactExport, on_conflict) => api.post('/profile/contacts/import', {
+    export: contactExport,
+    on_conflict
+});
+
+// Presence endpoints
+export const getPresence = () => api.get('/presence');
+export const setPresence = (status, message) => api.post('/presence/update', {status, message});
+export const getPresenceRules = () => api.get('/presence/rules');
+export const setPresenceRule = (subject_type, subject_id, hide_presence, hide_last_seen) => api.post('/presence/rules/update', {
+    subject_type,
+    subject_id,
+    hide_presence,
+    hide_last_seen
+});
+
+// Contact label endpoints
+export const setContactNote = (peer_id, note) => api.post('/profile/friend/note', {peer_id, note});
+export const addContactTag = (peer_id, tag) => api.post('/profile/friend/tag', {peer_id, tag});
+export const removeContactTag = (peer_id, tag) => api.post('/profile/friend/tag/remove', {peer_id, tag});
+export const getCircles = () => api.get('/circles');
+export const createCircle = (name, members) => api.post('/circles/create', {name, members});
+export const renameCircle = (circle_id, name) => api.post('/circles/rename', {circle_id, name});
+export const deleteCircle = (circle_id) => api.post('/circles/delete', {circle_id});
+export const addCircleMember = (circle_id, peer_id) => api.post('/circles/members/add', {circle_id, peer_id});
+export const removeCircleMember = (circle_id, peer_id) => api.post('/circles/members/remove', {circle_id, peer_id});
+
+// Block list endpoints
+export const getBlockedPeers = () => api.get('/blocks');
+export const blockPeer = (peer_id, reason) => api.post('/blocks/add', {peer_id, reason});
+export const unblockPeer = (peer_id) => api.post('/blocks/remove', {peer_id});
+export const getNearbyPeers = () => api.get('/nearby');
+export const getNearbyName = () => api.get('/nearby/name/get');
+export const setNearbyName = (display_name) => api.post('/nearby/name', {display_name});
+export const getHandleClaims = () => api.get('/handles');
+export const claimHandle = (handle) => api.post('/handles/claim', {handle});
+export const releaseHandle = (handle) => api.post('/handles/release', {handle});
+export const resolveHandle = (handle) => api.get('/handles/resolve', {params: {handle}});
+export const trustHandle = (handle, peer_id) => api.post('/handles/trust', {handle, peer_id});
 
 // Group chat endpoints
 export const getGroupChats = () => api.get('/group-chats');
 export const getGroupChatMessages = (group_id) => api.post('/group-chat/messages', {group_id});
-export const createGroupChat = (member_peers, name) => api.post('/group-chat', {member_peers, name});
+export const createGroupChat = (member_peers, name, circles) => api.post('/group-chat', {member_peers, name, circles});
+export const syncGroupChatHistory = (group_id) => api.post('/group-chat/sync', {group_id});
+export const getGroupInvitations = () => api.get('/group-chat/invitations');
+export const respondToGroupInvitation = (group_id, is_accepted) => api.patch('/group-chat/invitation/response', {
+    group_id,
+    is_accepted
+});
+export const removeGroupMember = (group_id, peer_id) => api.post('/group-chat/member/remove', {group_id, peer_id});
+export const rotateGroupKeys = (group_id) => api.post('/group-chat/keys/rotate', {group_id});
+export const updateGroupMetadata = (group_id, name, description, avatar_hash) => api.patch('/group-chat/metadata', {
+    group_id,
+    name,
+    description,
+    avatar_hash
+});
+
+export const createGroupInvite = (group_id, expires_in_seconds, single_use) => api.post('/group-chat/invite', {
+    group_id,
+    expires_in_seconds,
+    single_use
+});
+export const getGroupInvites = (group_id) => api.post('/group-chat/invites', {group_id});
+export const revokeGroupInvite = (group_id, token_id) => api.post('/group-chat/invite/revoke', {group_id, token_id});
+export const redeemGroupInvite = (uri, admin_peer_id) => api.post('/group-chat/invite/redeem', {uri, admin_peer_id});
+export const setGroupPublic = (group_id, isPublic) => api.post('/group-chat/public', {group_id, public: isPublic});
+export const getGroupJoinRequests = (group_id) => api.post('/group-chat/join-requests', {group_id});
+export const respondToGroupJoinRequest = (group_id, peer_id, is_accepted) => api.post('/group-chat/join-request/response', {
+    group_id,
+    peer_id,
+    is_accepted
+});
+export const uploadGroupFile = (group_id, name, mime_type, content) => api.post('/group-chat/file', {
+    group_id,
+    name,
+    mime_type,
+    content
+});
+export const getGroupFiles = (group_id) => api.post('/group-chat/files', {group_id});
+export const fetchGroupFile = (group_id, file_id) => api.post('/group-chat/file/fetch', {group_id, file_id});
+export const getGroupFileContent = (group_id, file_id) => api.post('/group-chat/file/content', {group_id, file_id}, {
+    responseType: 'blob'
+});
+export const searchGroupDirectory = (query, refresh) => api.post('/group-directory/search', {query, refresh});
+export const requestToJoinGroup = (group_id, message) => api.post('/group-directory/join', {group_id, message});
+
+export const createChannel = (name, description) => api.post('/channel', {name, description});
+export const getChannels = () => api.get('/channels');
+export const subscribeChannel = (channel_id, owner_peer_id, via_peer_id) => api.post('/channel/subscribe', {
+    channel_id,
+    owner_peer_id,
+    via_peer_id
+});
+export const unsubscribeChannel = (channel_id) => api.post('/channel/unsubscribe', {channel_id});
+export const updateChannelAuthors = (channel_id, authors) => api.post('/channel/authors', {channel_id, authors});
+export const postToChannel = (channel_id, message) => api.post('/channel/post', {channel_id, message});
+export const getChannelPosts = (channel_id) => api.post('/channel/posts', {channel_id});
+export const reactToChannelPost = (channel_id, post_id, reaction) => api.post('/channel/react', {
+    channel_id,
+    post_id,
+    reaction
+});
 
 export const getChatMessages = (peer_id) => api.post('/chat/messages', {peer_id});
 